@@ -26,6 +26,7 @@ require (
 	github.com/paysuper/paysuper-reporter v0.0.0-20191003072342-610371fc9395
 	github.com/paysuper/paysuper-tax-service v0.0.0-20190903084038-7849f394f122
 	github.com/pkg/errors v0.8.1
+	github.com/prometheus/client_golang v1.1.0
 	github.com/spf13/cobra v0.0.5
 	github.com/stretchr/testify v1.4.0
 	github.com/ttacon/libphonenumber v1.0.1
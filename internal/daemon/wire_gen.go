@@ -143,7 +143,7 @@ func BuildHTTP(ctx context.Context, initial config.Initial, observer invoker.Obs
 		cleanup()
 		return nil, nil, err
 	}
-	commonHandlers, cleanup13, err := handlers.ProviderHandlers(initial, services, validate, awareSet, commonConfig)
+	webhookVerifier, cleanup13, err := dispatcher.ProviderWebhookVerifier(commonConfig)
 	if err != nil {
 		cleanup12()
 		cleanup11()
@@ -159,14 +159,33 @@ func BuildHTTP(ctx context.Context, initial config.Initial, observer invoker.Obs
 		cleanup()
 		return nil, nil, err
 	}
+	commonHandlers, cleanup14, err := handlers.ProviderHandlers(initial, services, validate, awareSet, commonConfig, webhookVerifier)
+	if err != nil {
+		cleanup13()
+		cleanup12()
+		cleanup11()
+		cleanup10()
+		cleanup9()
+		cleanup8()
+		cleanup7()
+		cleanup6()
+		cleanup5()
+		cleanup4()
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
 	jwtVerifier := dispatcher.ProviderJwtVerifier(commonConfig)
 	appSet := dispatcher.AppSet{
-		Handlers:    commonHandlers,
-		Services:    services,
-		JwtVerifier: jwtVerifier,
+		Handlers:        commonHandlers,
+		Services:        services,
+		JwtVerifier:     jwtVerifier,
+		WebhookVerifier: webhookVerifier,
 	}
-	dispatcherConfig, cleanup14, err := dispatcher.ProviderCfg(configurator)
+	dispatcherConfig, cleanup15, err := dispatcher.ProviderCfg(configurator)
 	if err != nil {
+		cleanup14()
 		cleanup13()
 		cleanup12()
 		cleanup11()
@@ -182,8 +201,9 @@ func BuildHTTP(ctx context.Context, initial config.Initial, observer invoker.Obs
 		cleanup()
 		return nil, nil, err
 	}
-	dispatcherDispatcher, cleanup15, err := dispatcher.ProviderDispatcher(ctx, awareSet, appSet, dispatcherConfig, commonConfig)
+	dispatcherDispatcher, cleanup16, err := dispatcher.ProviderDispatcher(ctx, awareSet, appSet, dispatcherConfig, commonConfig)
 	if err != nil {
+		cleanup15()
 		cleanup14()
 		cleanup13()
 		cleanup12()
@@ -200,8 +220,9 @@ func BuildHTTP(ctx context.Context, initial config.Initial, observer invoker.Obs
 		cleanup()
 		return nil, nil, err
 	}
-	httpConfig, cleanup16, err := http.Cfg(configurator)
+	httpConfig, cleanup17, err := http.Cfg(configurator)
 	if err != nil {
+		cleanup16()
 		cleanup15()
 		cleanup14()
 		cleanup13()
@@ -219,8 +240,9 @@ func BuildHTTP(ctx context.Context, initial config.Initial, observer invoker.Obs
 		cleanup()
 		return nil, nil, err
 	}
-	httpHTTP, cleanup17, err := http.Provider(ctx, awareSet, dispatcherDispatcher, httpConfig)
+	httpHTTP, cleanup18, err := http.Provider(ctx, awareSet, dispatcherDispatcher, httpConfig)
 	if err != nil {
+		cleanup17()
 		cleanup16()
 		cleanup15()
 		cleanup14()
@@ -240,6 +262,7 @@ func BuildHTTP(ctx context.Context, initial config.Initial, observer invoker.Obs
 		return nil, nil, err
 	}
 	return httpHTTP, func() {
+		cleanup18()
 		cleanup17()
 		cleanup16()
 		cleanup15()
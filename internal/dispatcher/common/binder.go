@@ -13,7 +13,6 @@ import (
 	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
 	"github.com/paysuper/paysuper-payment-link/proto"
 	"io/ioutil"
-	"strconv"
 )
 
 type OrderFormBinder struct{}
@@ -22,23 +21,23 @@ type OrderRevenueDynamicRequestBinder struct{}
 type OrderAccountingPaymentRequestBinder struct{}
 type PaymentCreateProcessBinder struct{}
 type OnboardingMerchantListingBinder struct {
-	LimitDefault, OffsetDefault int32
+	Pagination *PaginationBinder
 }
 type OnboardingChangeMerchantStatusBinder struct{}
 type OnboardingNotificationsListBinder struct {
-	LimitDefault, OffsetDefault int32
+	Pagination *PaginationBinder
 }
 type OnboardingGetPaymentMethodBinder struct{}
 type OnboardingChangePaymentMethodBinder struct{}
 type OnboardingCreateNotificationBinder struct{}
 type PaylinksListBinder struct {
-	LimitDefault, OffsetDefault int32
+	Pagination *PaginationBinder
 }
 type PaylinksUrlBinder struct{}
 type PaylinksCreateBinder struct{}
 type PaylinksUpdateBinder struct{}
 type ProductsGetProductsListBinder struct {
-	LimitDefault, OffsetDefault int32
+	Pagination *PaginationBinder
 }
 type ProductsCreateProductBinder struct{}
 type ProductsUpdateProductBinder struct{}
@@ -170,13 +169,15 @@ func (cb *OnboardingMerchantListingBinder) Bind(i interface{}, ctx echo.Context)
 		return err
 	}
 
-	params := ctx.QueryParams()
-	structure := i.(*grpc.MerchantListingRequest)
+	err = cb.Pagination.Apply(i, ctx)
 
-	if structure.Limit <= 0 {
-		structure.Limit = cb.LimitDefault
+	if err != nil {
+		return err
 	}
 
+	params := ctx.QueryParams()
+	structure := i.(*grpc.MerchantListingRequest)
+
 	if v, ok := params[RequestParameterIsSigned]; ok {
 		if v[0] == "0" || v[0] == "false" {
 			structure.IsSigned = 2
@@ -201,14 +202,16 @@ func (cb *OnboardingNotificationsListBinder) Bind(i interface{}, ctx echo.Contex
 		return err
 	}
 
+	err = cb.Pagination.Apply(i, ctx)
+
+	if err != nil {
+		return err
+	}
+
 	params := ctx.QueryParams()
 	structure := i.(*grpc.ListingNotificationRequest)
 	structure.MerchantId = ctx.Param(RequestParameterMerchantId)
 
-	if structure.Limit <= 0 {
-		structure.Limit = cb.LimitDefault
-	}
-
 	if v, ok := params[RequestParameterIsSystem]; ok {
 		if v[0] == "0" || v[0] == "false" {
 			structure.IsSystem = 1
@@ -311,26 +314,12 @@ func (b *OnboardingCreateNotificationBinder) Bind(i interface{}, ctx echo.Contex
 
 // Bind
 func (b *PaylinksListBinder) Bind(i interface{}, ctx echo.Context) error {
-	params := ctx.QueryParams()
 	structure := i.(*paylink.GetPaylinksRequest)
 
-	structure.Limit = uint32(b.LimitDefault)
-	structure.Offset = uint32(b.OffsetDefault)
-
-	if v, ok := params[RequestParameterLimit]; ok {
-		i, err := strconv.ParseInt(v[0], 10, 32)
-		if err != nil {
-			return err
-		}
-		structure.Limit = uint32(i)
-	}
+	err := b.Pagination.Apply(structure, ctx)
 
-	if v, ok := params[RequestParameterOffset]; ok {
-		i, err := strconv.ParseInt(v[0], 10, 32)
-		if err != nil {
-			return err
-		}
-		structure.Offset = uint32(i)
+	if err != nil {
+		return err
 	}
 
 	structure.ProjectId = ctx.Param(RequestParameterProjectId)
@@ -395,31 +384,15 @@ func (b *PaylinksUpdateBinder) Bind(i interface{}, ctx echo.Context) error {
 
 // Bind
 func (b *ProductsGetProductsListBinder) Bind(i interface{}, ctx echo.Context) error {
-	limit := int32(b.LimitDefault)
-	offset := int32(b.OffsetDefault)
-
 	params := ctx.QueryParams()
+	structure := i.(*grpc.ListProductsRequest)
 
-	if v, ok := params[RequestParameterLimit]; ok {
-		i, err := strconv.ParseInt(v[0], 10, 32)
-		if err != nil {
-			return err
-		}
-		limit = int32(i)
-	}
+	err := b.Pagination.Apply(structure, ctx)
 
-	if v, ok := params[RequestParameterOffset]; ok {
-		i, err := strconv.ParseInt(v[0], 10, 32)
-		if err != nil {
-			return err
-		}
-		offset = int32(i)
+	if err != nil {
+		return err
 	}
 
-	structure := i.(*grpc.ListProductsRequest)
-	structure.Limit = limit
-	structure.Offset = offset
-
 	if v, ok := params[RequestParameterName]; ok {
 		if v[0] != "" {
 			structure.Name = v[0]
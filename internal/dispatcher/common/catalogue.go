@@ -0,0 +1,194 @@
+package common
+
+import (
+	"encoding/json"
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	ErrorCatalogueDefaultLanguage = "en"
+)
+
+// Error definitions declared in errors.go are shared between requests and must not be changed,
+// use CloneError to get a copy for the response.
+
+// FieldError is an error of the request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Details string `json:"details,omitempty"`
+}
+
+// ResponseError is an error response with the list of all field errors,
+// the embedded message is a copy of the first field error
+type ResponseError struct {
+	*grpc.ResponseErrorMessage
+	Errors []*FieldError
+}
+
+// MarshalJSON
+func (e *ResponseError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		Code    string        `json:"code"`
+		Message string        `json:"message"`
+		Details string        `json:"details,omitempty"`
+		Errors  []*FieldError `json:"errors,omitempty"`
+	}{
+		Code:    e.Code,
+		Message: e.Message,
+		Details: e.Details,
+		Errors:  e.Errors,
+	})
+}
+
+// CloneError returns copy of the error definition which may be changed by the request
+func CloneError(def *grpc.ResponseErrorMessage, details ...string) *grpc.ResponseErrorMessage {
+	err := &grpc.ResponseErrorMessage{Code: def.Code, Message: def.Message, Details: def.Details}
+	if len(details) > 0 && details[0] != "" {
+		err.Details = details[0]
+	}
+	return err
+}
+
+// ErrorCatalogue translates error messages, translations are loaded from json files
+// named by the language (ru.json, de.json, ...) which map error code to the translated message,
+// so the english message may be changed without breaking the translations
+type ErrorCatalogue struct {
+	translations map[string]map[string]string
+}
+
+// NewErrorCatalogue loads translations from the directory
+func NewErrorCatalogue(dir string) (*ErrorCatalogue, error) {
+	c := &ErrorCatalogue{translations: make(map[string]map[string]string)}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		messages := make(map[string]string)
+		if err = json.Unmarshal(b, &messages); err != nil {
+			return nil, err
+		}
+
+		lang := strings.ToLower(strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)))
+		c.translations[lang] = messages
+	}
+
+	return c, nil
+}
+
+// Languages returns list of the bundled languages
+func (c *ErrorCatalogue) Languages() []string {
+	langs := make([]string, 0, len(c.translations))
+	for lang := range c.translations {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Translate returns message of the error code in the first supported language of the list or the message itself
+func (c *ErrorCatalogue) Translate(langs []string, code, message string) string {
+	for _, lang := range langs {
+		if lang == ErrorCatalogueDefaultLanguage {
+			return message
+		}
+		messages, ok := c.translations[lang]
+		if !ok {
+			continue
+		}
+		if msg, ok := messages[code]; ok {
+			return msg
+		}
+		return message
+	}
+	return message
+}
+
+// Localize returns translated copy of the error response, unknown values are returned as is
+func (c *ErrorCatalogue) Localize(langs []string, message interface{}) interface{} {
+	switch msg := message.(type) {
+	case *grpc.ResponseErrorMessage:
+		res := CloneError(msg)
+		res.Message = c.Translate(langs, msg.Code, msg.Message)
+		return res
+	case *ResponseError:
+		res := &ResponseError{ResponseErrorMessage: CloneError(msg.ResponseErrorMessage)}
+		res.Message = c.Translate(langs, msg.Code, msg.Message)
+		for _, fe := range msg.Errors {
+			cp := *fe
+			cp.Message = c.Translate(langs, fe.Code, fe.Message)
+			res.Errors = append(res.Errors, &cp)
+		}
+		return res
+	}
+	return message
+}
+
+type acceptLanguage struct {
+	lang    string
+	quality float64
+}
+
+// ParseAcceptLanguage returns languages of the Accept-Language header ordered by quality,
+// regional variants are followed by the primary language (ru-RU, ru)
+func ParseAcceptLanguage(header string) []string {
+	var items []acceptLanguage
+
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		lang := strings.ToLower(strings.TrimSpace(fields[0]))
+		if lang == "" || lang == "*" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		if quality <= 0 {
+			continue
+		}
+
+		items = append(items, acceptLanguage{lang: strings.Replace(lang, "_", "-", -1), quality: quality})
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].quality > items[j].quality
+	})
+
+	var langs []string
+	seen := make(map[string]bool)
+	add := func(lang string) {
+		if !seen[lang] {
+			seen[lang] = true
+			langs = append(langs, lang)
+		}
+	}
+	for _, item := range items {
+		add(item.lang)
+		if i := strings.Index(item.lang, "-"); i > 0 {
+			add(item.lang[:i])
+		}
+	}
+
+	return langs
+}
@@ -0,0 +1,149 @@
+package common
+
+import (
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type CatalogueTestSuite struct {
+	suite.Suite
+	catalogue *ErrorCatalogue
+	// errors are messages of the declared errors by their codes
+	errors map[string][]string
+}
+
+func Test_Catalogue(t *testing.T) {
+	suite.Run(t, new(CatalogueTestSuite))
+}
+
+func (suite *CatalogueTestSuite) SetupTest() {
+	var err error
+	suite.catalogue, err = NewErrorCatalogue("../../../assets/locales/errors")
+	require.NoError(suite.T(), err)
+	require.NotEmpty(suite.T(), suite.catalogue.Languages())
+
+	suite.errors = suite.declaredErrors()
+	require.NotEmpty(suite.T(), suite.errors)
+}
+
+func (suite *CatalogueTestSuite) TearDownTest() {}
+
+// declaredErrors returns messages of the errors declared by NewManagementApiResponseError in the package sources
+// by their codes
+func (suite *CatalogueTestSuite) declaredErrors() map[string][]string {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, ".", func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}, 0)
+	require.NoError(suite.T(), err)
+
+	consts := make(map[string]string)
+	var calls []*ast.CallExpr
+
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			ast.Inspect(file, func(node ast.Node) bool {
+				switch n := node.(type) {
+				case *ast.ValueSpec:
+					for i, name := range n.Names {
+						if i < len(n.Values) {
+							if lit, ok := n.Values[i].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+								consts[name.Name], _ = strconv.Unquote(lit.Value)
+							}
+						}
+					}
+				case *ast.CallExpr:
+					if fn, ok := n.Fun.(*ast.Ident); ok && fn.Name == "NewManagementApiResponseError" && len(n.Args) > 1 {
+						calls = append(calls, n)
+					}
+				}
+				return true
+			})
+		}
+	}
+
+	value := func(expr ast.Expr) (string, bool) {
+		switch arg := expr.(type) {
+		case *ast.BasicLit:
+			v, err := strconv.Unquote(arg.Value)
+			require.NoError(suite.T(), err)
+			return v, true
+		case *ast.Ident:
+			v := consts[arg.Name]
+			require.NotEmpty(suite.T(), v, "constant %s isn't a string", arg.Name)
+			return v, true
+		}
+		return "", false
+	}
+
+	errors := make(map[string][]string)
+
+	for _, call := range calls {
+		code, ok := value(call.Args[0])
+		if !ok {
+			// the code of the other error, e.g. the validation error with details
+			continue
+		}
+		message, ok := value(call.Args[1])
+		if !ok {
+			continue
+		}
+
+		seen := false
+		for _, m := range errors[code] {
+			seen = seen || m == message
+		}
+		if !seen {
+			errors[code] = append(errors[code], message)
+		}
+	}
+
+	return errors
+}
+
+func (suite *CatalogueTestSuite) TestCatalogue_CodesUnique() {
+	for code, messages := range suite.errors {
+		assert.Len(suite.T(), messages, 1, "code %s is used by the different errors: %q", code, messages)
+	}
+}
+
+func (suite *CatalogueTestSuite) TestCatalogue_AllCodesTranslated() {
+	for lang, translations := range suite.catalogue.translations {
+		for code, messages := range suite.errors {
+			assert.NotEmpty(suite.T(), translations[code], "error %s %q isn't translated to %s", code, messages, lang)
+		}
+	}
+}
+
+func (suite *CatalogueTestSuite) TestCatalogue_NoUnknownCodes() {
+	for lang, translations := range suite.catalogue.translations {
+		for code := range translations {
+			_, ok := suite.errors[code]
+			assert.True(suite.T(), ok, "translation of %s to %s has no error", code, lang)
+		}
+	}
+}
+
+func (suite *CatalogueTestSuite) TestCatalogue_Localize_ByCode() {
+	def := NewManagementApiResponseError(ErrorValidationFailed.Code, "the message changed after the translation")
+	langs := []string{"ru"}
+
+	res, ok := suite.catalogue.Localize(langs, def).(*grpc.ResponseErrorMessage)
+	require.True(suite.T(), ok)
+	assert.Equal(suite.T(), ErrorValidationFailed.Code, res.Code)
+	assert.Equal(suite.T(), suite.catalogue.translations["ru"][ErrorValidationFailed.Code], res.Message)
+
+	// the message of the unknown code and of the default language is kept
+	unknown := NewManagementApiResponseError("unknown", "unknown error")
+	assert.Equal(suite.T(), "unknown error", suite.catalogue.Localize(langs, unknown).(*grpc.ResponseErrorMessage).Message)
+	assert.Equal(suite.T(), def.Message, suite.catalogue.Localize([]string{"en", "ru"}, def).(*grpc.ResponseErrorMessage).Message)
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/ProtocolONE/go-core/v2/pkg/logger"
 	"github.com/labstack/echo/v4"
 	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/billing"
 	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
 	"gopkg.in/go-playground/validator.v9"
 	"net/http"
@@ -18,11 +19,49 @@ func CheckProjectAuthRequestSignature(dispatch HandlerSet, ctx echo.Context, pro
 		return echo.NewHTTPError(http.StatusBadRequest, ErrorMessageSignatureHeaderIsEmpty)
 	}
 
+	return checkProjectRequestSignature(dispatch.Services, dispatch.AwareSet.L(), ctx, projectId, signature)
+}
+
+// verifiedProject is a result of the signature check kept in the request context
+type verifiedProject struct {
+	projectId string
+	err       error
+}
+
+// ExtractVerifiedProjectId returns project of the request body if the request is signed by the project,
+// empty string is returned for the requests without project or signature. The signature is checked
+// by the billing server once per request, the result is kept in the request context
+func ExtractVerifiedProjectId(services Services, log logger.Logger, ctx echo.Context) (string, error) {
+	if checked, ok := ctx.Get("verifiedProject").(*verifiedProject); ok {
+		if checked.err != nil {
+			return "", checked.err
+		}
+		return checked.projectId, nil
+	}
+
+	projectId := ExtractRawBodyProjectId(ctx)
+	signature := ctx.Request().Header.Get(HeaderXApiSignatureHeader)
+
+	if projectId == "" || signature == "" {
+		return "", nil
+	}
+
+	err := checkProjectRequestSignature(services, log, ctx, projectId, signature)
+	ctx.Set("verifiedProject", &verifiedProject{projectId: projectId, err: err})
+
+	if err != nil {
+		return "", err
+	}
+
+	return projectId, nil
+}
+
+func checkProjectRequestSignature(services Services, log logger.Logger, ctx echo.Context, projectId, signature string) error {
 	req := &grpc.CheckProjectRequestSignatureRequest{Body: string(ExtractRawBodyContext(ctx)), ProjectId: projectId, Signature: signature}
 
-	rsp, err := dispatch.Services.Billing.CheckProjectRequestSignature(ctx.Request().Context(), req)
+	rsp, err := services.Billing.CheckProjectRequestSignature(ctx.Request().Context(), req)
 	if err != nil {
-		dispatch.AwareSet.L().Error(InternalErrorTemplate, logger.Args("err", err.Error()))
+		log.Error(InternalErrorTemplate, logger.Args("err", err.Error()))
 		return echo.NewHTTPError(http.StatusInternalServerError, ErrorUnknown)
 	}
 
@@ -32,28 +71,185 @@ func CheckProjectAuthRequestSignature(dispatch HandlerSet, ctx echo.Context, pro
 	return nil
 }
 
-// GetValidationError
-func GetValidationError(err error) (rspErr *grpc.ResponseErrorMessage) {
-
-	vErr := err.(validator.ValidationErrors)[0] // TODO: possible out of range
-	val, ok := ValidationErrors[vErr.Field()]
-
-	if ok {
-		rspErr = val
-	} else {
-		val, ok := ValidationNamespaceErrors[vErr.StructNamespace()]
-
-		if ok {
-			rspErr = val
-		} else {
-			if vErr.Tag() == RequestParameterZipUsa {
-				rspErr = ErrorMessageIncorrectZip
-			} else {
-				rspErr = ErrorValidationFailed
+// ExtractUserMerchant returns merchant owned by the authorized user or nil if user hasn't merchant.
+// Merchant requested from billing server once per request, next calls get it from the request context.
+func ExtractUserMerchant(services Services, log logger.Logger, ctx echo.Context) (*billing.Merchant, error) {
+	if merchant, ok := ExtractMerchantContext(ctx); ok {
+		return merchant, nil
+	}
+
+	user := ExtractUserContext(ctx)
+	req := &grpc.GetMerchantByRequest{UserId: user.Id}
+	rsp, err := services.Billing.GetMerchantBy(ctx.Request().Context(), req)
+
+	if err != nil {
+		LogSrvCallFailedGRPC(log, err, pkg.ServiceName, "GetMerchantBy", req)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, ErrorUnknown)
+	}
+
+	var merchant *billing.Merchant
+
+	if rsp.Status == pkg.ResponseStatusOk && rsp.Item != nil {
+		merchant = rsp.Item
+
+		if user.Merchants == nil {
+			user.Merchants = make(map[string]bool)
+		}
+		if user.Roles == nil {
+			user.Roles = make(map[string]bool)
+		}
+
+		user.Merchants[merchant.Id] = true
+		user.Roles[RoleMerchantOwner] = true
+		SetUserContext(ctx, user)
+	}
+
+	SetMerchantContext(ctx, merchant)
+	return merchant, nil
+}
+
+// CheckRouteAccess checks the authorized user against the access policy of the matched route,
+// the routes missed in the policy table are denied, so a new route isn't open by mistake
+func CheckRouteAccess(services Services, log logger.Logger, ctx echo.Context) error {
+	user := ExtractUserContext(ctx)
+	if user.Id == "" {
+		return echo.NewHTTPError(http.StatusUnauthorized, ErrorMessageAccessDenied)
+	}
+
+	policy := FindAccessPolicy(ctx.Request().Method, ctx.Path())
+	if policy == nil {
+		return echo.NewHTTPError(http.StatusForbidden, ErrorMessageAccessDenied)
+	}
+
+	// merchant of the user is needed by the policies with roles or merchant scope only
+	if !user.IsStaff() && (len(policy.Roles) > 0 || policy.IsMerchantScoped()) {
+		if _, err := ExtractUserMerchant(services, log, ctx); err != nil {
+			return err
+		}
+	}
+
+	var merchantId string
+	if policy.MerchantParam != "" {
+		merchantId = ctx.Param(policy.MerchantParam)
+	}
+
+	// the order is requested only if the user may pass the policy by the role
+	if policy.OrderParam != "" && !user.IsStaff() && (len(policy.Roles) == 0 || user.HasAnyRole(policy.Roles...)) {
+		orderMerchantId, err := ExtractOrderMerchantId(services, log, ctx, ctx.Param(policy.OrderParam))
+		if err != nil {
+			return err
+		}
+		merchantId = orderMerchantId
+	}
+
+	if err := policy.CheckAccess(user, merchantId); err != nil {
+		return echo.NewHTTPError(http.StatusForbidden, err)
+	}
+
+	return nil
+}
+
+// ExtractOrderMerchantId returns identifier of the merchant the order belongs to
+func ExtractOrderMerchantId(services Services, log logger.Logger, ctx echo.Context, orderId string) (string, error) {
+	if orderId == "" {
+		return "", echo.NewHTTPError(http.StatusBadRequest, ErrorIncorrectOrderId)
+	}
+
+	req := &grpc.GetOrderRequest{Id: orderId}
+	rsp, err := services.Billing.GetOrderPrivate(ctx.Request().Context(), req)
+
+	if err != nil {
+		LogSrvCallFailedGRPC(log, err, pkg.ServiceName, "GetOrderPrivate", req)
+		return "", echo.NewHTTPError(http.StatusInternalServerError, ErrorUnknown)
+	}
+
+	if rsp.Status != pkg.ResponseStatusOk {
+		return "", echo.NewHTTPError(int(rsp.Status), rsp.Message)
+	}
+
+	if rsp.Item == nil || rsp.Item.Project == nil {
+		return "", nil
+	}
+
+	return rsp.Item.Project.MerchantId, nil
+}
+
+// CheckUserMerchantAccess checks that the authorized user is allowed to work with the merchant.
+// Staff users have access to any merchant, other users only to their own.
+func CheckUserMerchantAccess(dispatch HandlerSet, ctx echo.Context, merchantId string) error {
+	user := ExtractUserContext(ctx)
+
+	if user.Id == "" {
+		return echo.NewHTTPError(http.StatusUnauthorized, ErrorMessageAccessDenied)
+	}
+
+	if user.IsStaff() {
+		return nil
+	}
+
+	merchant, err := ExtractUserMerchant(dispatch.Services, dispatch.AwareSet.L(), ctx)
+
+	if err != nil {
+		return err
+	}
+
+	if merchant == nil || merchant.Id != merchantId {
+		return echo.NewHTTPError(http.StatusForbidden, ErrorMessageMerchantAccessDenied)
+	}
+
+	return nil
+}
+
+// MerchantAccessMiddleware rejects the request if the authorized user has no access to the merchant
+// which identifier placed in the path parameter
+func MerchantAccessMiddleware(dispatch HandlerSet, cfg Config, param string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			if cfg.DisableAuthMiddleware {
+				return next(ctx)
 			}
+
+			if err := CheckUserMerchantAccess(dispatch, ctx, ctx.Param(param)); err != nil {
+				return err
+			}
+
+			return next(ctx)
 		}
 	}
+}
+
+// GetValidationError
+func GetValidationError(err error) *ResponseError {
+	vErrs, ok := err.(validator.ValidationErrors)
+	if !ok || len(vErrs) == 0 {
+		return &ResponseError{ResponseErrorMessage: CloneError(ErrorValidationFailed)}
+	}
+
+	rspErr := &ResponseError{}
+	for _, vErr := range vErrs {
+		def := getValidationErrorDefinition(vErr)
+		rspErr.Errors = append(rspErr.Errors, &FieldError{
+			Field:   vErr.Field(),
+			Code:    def.Code,
+			Message: def.Message,
+			Details: fmt.Sprintf(ErrorMessageMask, vErr.Field(), vErr.Tag()),
+		})
+	}
 
-	rspErr.Details = fmt.Sprintf(ErrorMessageMask, vErr.Field(), vErr.Tag())
+	first := rspErr.Errors[0]
+	rspErr.ResponseErrorMessage = NewManagementApiResponseError(first.Code, first.Message, first.Details)
 	return rspErr
 }
+
+func getValidationErrorDefinition(vErr validator.FieldError) *grpc.ResponseErrorMessage {
+	if def, ok := ValidationErrors[vErr.Field()]; ok {
+		return def
+	}
+	if def, ok := ValidationNamespaceErrors[vErr.StructNamespace()]; ok {
+		return def
+	}
+	if vErr.Tag() == RequestParameterZipUsa || vErr.Tag() == RequestParameterZip {
+		return ErrorMessageIncorrectZip
+	}
+	return ErrorValidationFailed
+}
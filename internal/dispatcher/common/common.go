@@ -1,10 +1,16 @@
 package common
 
 import (
+	"encoding/json"
 	"github.com/ProtocolONE/geoip-service/pkg/proto"
 	"github.com/ProtocolONE/go-core/v2/pkg/provider"
 	"github.com/labstack/echo/v4"
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/billing"
 	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
+	"github.com/paysuper/paysuper-management-api/pkg/event"
+	"github.com/paysuper/paysuper-management-api/pkg/job"
+	"github.com/paysuper/paysuper-management-api/pkg/keyset"
+	"github.com/paysuper/paysuper-management-api/pkg/webhook"
 	paylink "github.com/paysuper/paysuper-payment-link/proto"
 	"github.com/paysuper/paysuper-recurring-repository/pkg/proto/repository"
 	reporterProto "github.com/paysuper/paysuper-reporter/pkg/proto"
@@ -19,12 +25,19 @@ const (
 	AuthProjectGroupPath     = "/api/v1"
 	AuthUserGroupPath        = "/admin/api/v1"
 	WebHookGroupPath         = "/webhook"
+	MetricsPath              = "/metrics"
+	HealthPath               = "/healthz"
+	ReadinessPath            = "/readyz"
 )
 
 // Cursor
 type Cursor struct {
 	Limit, Offset int32
 	Sort          []string
+	// Token is the pagination cursor sent by the client
+	Token string
+	// Position is the position of the keyset cursor, the lists which can't be paged by the position use the offset
+	Position *keyset.Position
 }
 
 // ExtractUserContext
@@ -43,6 +56,32 @@ func ExtractRawBodyContext(ctx echo.Context) []byte {
 	return nil
 }
 
+// ExtractRawBodyProjectId returns project identifier sent in the json request body by the project api calls
+func ExtractRawBodyProjectId(ctx echo.Context) string {
+	body := struct {
+		Project   string `json:"project"`
+		ProjectId string `json:"project_id"`
+		Settings  *struct {
+			ProjectId string `json:"project_id"`
+		} `json:"settings"`
+	}{}
+
+	if err := json.Unmarshal(ExtractRawBodyContext(ctx), &body); err != nil {
+		return ""
+	}
+
+	switch {
+	case body.Project != "":
+		return body.Project
+	case body.ProjectId != "":
+		return body.ProjectId
+	case body.Settings != nil:
+		return body.Settings.ProjectId
+	}
+
+	return ""
+}
+
 // ExtractCursorContext
 func ExtractCursorContext(ctx echo.Context) *Cursor {
 	if cursor, ok := ctx.Get("cursor").(*Cursor); ok {
@@ -51,6 +90,12 @@ func ExtractCursorContext(ctx echo.Context) *Cursor {
 	return &Cursor{}
 }
 
+// ExtractMerchantContext returns merchant of the authorized user if it was resolved early in the request
+func ExtractMerchantContext(ctx echo.Context) (*billing.Merchant, bool) {
+	merchant, ok := ctx.Get("merchant").(*billing.Merchant)
+	return merchant, ok
+}
+
 // SetUserContext
 func SetUserContext(ctx echo.Context, user *AuthUser) {
 	ctx.Set("user", user)
@@ -61,6 +106,11 @@ func SetRawBodyContext(ctx echo.Context, rawBody []byte) {
 	ctx.Set("rawBody", rawBody)
 }
 
+// SetMerchantContext
+func SetMerchantContext(ctx echo.Context, merchant *billing.Merchant) {
+	ctx.Set("merchant", merchant)
+}
+
 // SetCursorContext
 func SetCursorContext(ctx echo.Context, cursor *Cursor) {
 	ctx.Set("cursor", cursor)
@@ -93,6 +143,7 @@ type Services struct {
 	Tax        tax_service.TaxService
 	PayLink    paylink.PaylinkService
 	Reporter   reporterProto.ReporterService
+	Health     ServiceHealthChecker
 }
 
 // Handlers
@@ -100,9 +151,22 @@ type Handlers []Handler
 
 // HandlerSet
 type HandlerSet struct {
-	Services Services
-	Validate *validator.Validate
-	AwareSet provider.AwareSet
+	Services  Services
+	Validate  *validator.Validate
+	AwareSet  provider.AwareSet
+	Webhooks  *webhook.Sender
+	Customers CustomerStorage
+	Jobs      *job.Manager
+	KeyStocks KeyStockStorage
+	Themes    ProjectThemeStorage
+	// OrderEvents delivers status changes of the orders to the event stream subscribers
+	OrderEvents event.Broker
+	// InboundWebhooks keeps callbacks of the payment providers for the inspection and replay
+	InboundWebhooks InboundWebhookStorage
+	// ProviderWebhooks keeps adapters of the callbacks of the payment providers
+	ProviderWebhooks *ProviderWebhookRegistry
+	// WebhookVerifier checks addresses and signatures of the callbacks of the payment providers
+	WebhookVerifier *WebhookVerifier
 }
 
 // AuthUser
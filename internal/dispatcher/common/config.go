@@ -1,6 +1,22 @@
 package common
 
-import "time"
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/ProtocolONE/go-core/v2/pkg/logger"
+	"github.com/globalsign/mgo"
+	"github.com/paysuper/paysuper-management-api/pkg/event"
+	"github.com/paysuper/paysuper-management-api/pkg/job"
+	"github.com/paysuper/paysuper-management-api/pkg/keyfile"
+	"github.com/paysuper/paysuper-management-api/pkg/webhook"
+	"regexp"
+	"time"
+)
+
+// paginationSecretContext separates the pagination secret derived from the auth1 client secret
+const paginationSecretContext = "pagination-cursor"
 
 type Auth1 struct {
 	Issuer       string `envconfig:"AUTH1_ISSUER" default:"https://dev-auth1.tst.protocol.one"`
@@ -9,8 +25,186 @@ type Auth1 struct {
 	RedirectUrl  string `envconfig:"AUTH1_REDIRECTURL" required:"true"`
 }
 
+type Rbac struct {
+	Admins      []string `envconfig:"RBAC_ADMINS"`
+	Accountants []string `envconfig:"RBAC_ACCOUNTANTS"`
+	Support     []string `envconfig:"RBAC_SUPPORT"`
+}
+
+type RateLimit struct {
+	Disabled         bool    `envconfig:"RATE_LIMIT_DISABLED"`
+	AuthProjectRate  float64 `envconfig:"RATE_LIMIT_AUTH_PROJECT_RATE" default:"20"`
+	AuthProjectBurst int     `envconfig:"RATE_LIMIT_AUTH_PROJECT_BURST" default:"40"`
+	AuthUserRate     float64 `envconfig:"RATE_LIMIT_AUTH_USER_RATE" default:"10"`
+	AuthUserBurst    int     `envconfig:"RATE_LIMIT_AUTH_USER_BURST" default:"30"`
+	WebHooksRate     float64 `envconfig:"RATE_LIMIT_WEBHOOKS_RATE" default:"50"`
+	WebHooksBurst    int     `envconfig:"RATE_LIMIT_WEBHOOKS_BURST" default:"100"`
+	CommonRate       float64 `envconfig:"RATE_LIMIT_COMMON_RATE" default:"10"`
+	CommonBurst      int     `envconfig:"RATE_LIMIT_COMMON_BURST" default:"30"`
+}
+
+type Webhooks struct {
+	WebhooksTimeout          time.Duration `envconfig:"WEBHOOKS_TIMEOUT" default:"10s"`
+	WebhooksMaxAttempts      int           `envconfig:"WEBHOOKS_MAX_ATTEMPTS" default:"8"`
+	WebhooksRetryInterval    time.Duration `envconfig:"WEBHOOKS_RETRY_INTERVAL" default:"30s"`
+	WebhooksMaxRetryInterval time.Duration `envconfig:"WEBHOOKS_MAX_RETRY_INTERVAL" default:"6h"`
+	WebhooksPollInterval     time.Duration `envconfig:"WEBHOOKS_POLL_INTERVAL" default:"5s"`
+	KeyStockLowThreshold     int32         `envconfig:"KEY_STOCK_LOW_THRESHOLD" default:"10"`
+}
+
+// SenderConfig
+func (w *Webhooks) SenderConfig() webhook.Config {
+	return webhook.Config{
+		Timeout:          w.WebhooksTimeout,
+		MaxAttempts:      w.WebhooksMaxAttempts,
+		RetryInterval:    w.WebhooksRetryInterval,
+		MaxRetryInterval: w.WebhooksMaxRetryInterval,
+		PollInterval:     w.WebhooksPollInterval,
+	}
+}
+
+type RefundBatchSettings struct {
+	RefundBatchMaxItems    int `envconfig:"REFUND_BATCH_MAX_ITEMS" default:"1000"`
+	RefundBatchSyncItems   int `envconfig:"REFUND_BATCH_SYNC_ITEMS" default:"20"`
+	RefundBatchConcurrency int `envconfig:"REFUND_BATCH_CONCURRENCY" default:"5"`
+}
+
+type Jobs struct {
+	JobsConcurrency int           `envconfig:"JOBS_CONCURRENCY" default:"4"`
+	JobsLifetime    time.Duration `envconfig:"JOBS_LIFETIME" default:"72h"`
+}
+
+// NewJobManager returns manager keeping jobs in the process memory
+func (j *Jobs) NewJobManager(log logger.Logger) *job.Manager {
+	return job.NewManager(job.NewMemoryStorage(j.JobsLifetime), job.Config{Concurrency: j.JobsConcurrency}, log)
+}
+
+type KeyFiles struct {
+	KeyFileMaxSize int64 `envconfig:"KEY_FILE_MAX_SIZE" default:"10485760"`
+	// KeyFormats are regular expressions of the keys by identifier of the platform in JSON object,
+	// e.g. {"gog":"^[A-Z0-9]{18}$"}, the keys are matched in upper case
+	KeyFormats KeyFormats `envconfig:"KEY_FORMATS"`
+}
+
+// KeyFormats is decoded from JSON since the regular expressions contain separators of the envconfig maps
+type KeyFormats map[string]string
+
+// Decode
+func (k *KeyFormats) Decode(value string) error {
+	formats := make(map[string]string)
+	if value != "" {
+		if err := json.Unmarshal([]byte(value), &formats); err != nil {
+			return err
+		}
+	}
+	*k = formats
+	return nil
+}
+
+// KeyFormat returns format of the keys of the platform, configured formats override the default ones.
+// Keys of the known platforms are case insensitive, the keys of the other platforms are matched as they are
+func (k *KeyFiles) KeyFormat(platformId string) (*keyfile.Format, error) {
+	pattern, ok := k.KeyFormats[platformId]
+	if !ok {
+		pattern, ok = keyfile.DefaultFormats[platformId]
+	}
+	if !ok {
+		pattern = keyfile.DefaultFormat
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return &keyfile.Format{Pattern: re, UpperCase: ok}, nil
+}
+
+type OrderEvents struct {
+	OrderEventsBuffer    int           `envconfig:"ORDER_EVENTS_BUFFER" default:"16"`
+	OrderEventsHeartbeat time.Duration `envconfig:"ORDER_EVENTS_HEARTBEAT" default:"15s"`
+}
+
+// NewOrderEventBroker returns broker delivering order events within the process
+func (o *OrderEvents) NewOrderEventBroker() event.Broker {
+	return event.NewMemoryBroker(o.OrderEventsBuffer)
+}
+
+type Security struct {
+	SecurityHstsMaxAge time.Duration `envconfig:"SECURITY_HSTS_MAX_AGE" default:"8760h"`
+	// SecurityCspReportOnly sends the content security policy report only except frame-ancestors which is always enforced,
+	// so the violations of a new policy on the merchant sites can be collected before the policy blocks anything
+	SecurityCspReportOnly bool `envconfig:"SECURITY_CSP_REPORT_ONLY"`
+	// SecurityFrameAncestorsTtl is a time the origins allowed to embed the payment form of the project are cached
+	SecurityFrameAncestorsTtl time.Duration `envconfig:"SECURITY_FRAME_ANCESTORS_TTL" default:"5m"`
+}
+
+// WebhookVerification holds settings of the local verification of the payment providers callbacks,
+// lists of the provider are separated by ";" and keyed by name of the provider
+type WebhookVerification struct {
+	// WebhookSecrets are secrets of the signatures, the new secret is added before the old one is removed on rotation,
+	// callbacks of the providers without secrets and public keys are rejected
+	WebhookSecrets map[string]string `envconfig:"WEBHOOK_SECRETS"`
+	// WebhookPublicKeys are paths to the PEM encoded RSA public keys
+	WebhookPublicKeys map[string]string `envconfig:"WEBHOOK_PUBLIC_KEYS"`
+	// WebhookAlgorithms override default signature algorithms of the providers
+	WebhookAlgorithms map[string]string `envconfig:"WEBHOOK_ALGORITHMS"`
+	// WebhookAllowedIps are ip addresses and networks in CIDR notation, callbacks from any address are allowed if empty
+	WebhookAllowedIps   map[string]string `envconfig:"WEBHOOK_ALLOWED_IPS"`
+	WebhookReplayWindow time.Duration     `envconfig:"WEBHOOK_REPLAY_WINDOW" default:"5m"`
+}
+
+// NewWebhookVerifier returns verifier of the callbacks, it fails if secrets, keys or networks are malformed
+func (w *WebhookVerification) NewWebhookVerifier() (*WebhookVerifier, error) {
+	return NewWebhookVerifier(*w)
+}
+
+// ProviderWebhooks holds settings of the adapters of the payment providers callbacks
+type ProviderWebhooks struct {
+	// WebhookHandlers are names of the payment system handlers of the billing server keyed by name of the provider,
+	// the billing server has the handler of CardPay only, the adapters of the other providers are registered
+	// once the handlers of their payment systems are configured
+	WebhookHandlers map[string]string `envconfig:"WEBHOOK_HANDLERS"`
+}
+
+// NewProviderWebhookRegistry returns registry with adapters of the providers which have the handlers in the billing server
+func (p *ProviderWebhooks) NewProviderWebhookRegistry() *ProviderWebhookRegistry {
+	return NewDefaultProviderWebhookRegistry(p.WebhookHandlers)
+}
+
+type Mongo struct {
+	MongoDsn         string        `envconfig:"MONGO_DSN"`
+	MongoDialTimeout time.Duration `envconfig:"MONGO_DIAL_TIMEOUT" default:"10s"`
+}
+
+// DialMongo returns nil session if the dsn isn't set, storages of the handlers keep data in the process memory then,
+// it's allowed for the single replica only since the data is lost on restart
+func (m *Mongo) DialMongo() (*mgo.Session, error) {
+	if m.MongoDsn == "" {
+		return nil, nil
+	}
+
+	session, err := mgo.DialWithTimeout(m.MongoDsn, m.MongoDialTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	session.SetMode(mgo.Monotonic, true)
+	return session, nil
+}
+
 type Config struct {
 	Auth1
+	Mongo
+	Rbac
+	WebhookVerification
+	ProviderWebhooks
+	Webhooks
+	RefundBatchSettings
+	Jobs
+	KeyFiles
+	Security
+	OrderEvents
 
 	HttpScheme              string `envconfig:"HTTP_SCHEME" default:"https"`
 	PaymentFormJsLibraryUrl string `envconfig:"PAYMENT_FORM_JS_LIBRARY_URL" required:"true"`
@@ -26,10 +220,25 @@ type Config struct {
 	AwsRegionReporter          string `envconfig:"AWS_REGION_REPORTER" default:"eu-west-1"`
 	AwsBucketReporter          string `envconfig:"AWS_BUCKET_REPORTER" required:"true"`
 
-	LimitDefault                 int32 `default:"100"`
-	OffsetDefault                int32 `default:"0"`
-	LimitMax                     int32 `default:"1000"`
-	ReturnPaymentForm            bool  `envconfig:"DEBUG_RETURN_PAYMENT_FORM"`
+	LimitDefault                 int32  `default:"100"`
+	OffsetDefault                int32  `default:"0"`
+	LimitMax                     int32  `default:"1000"`
+	PaginationCursorSecret       string `envconfig:"PAGINATION_CURSOR_SECRET"`
+	ReturnPaymentForm            bool   `envconfig:"DEBUG_RETURN_PAYMENT_FORM"`
 	DisableAuthMiddleware        bool
 	CustomerTokenCookiesLifetime time.Duration // CustomerTokenCookiesLifetime = 2592000
+	IdempotencyKeyLifetime       time.Duration `default:"24h"`
+	CustomerTokenLifetime        time.Duration `envconfig:"CUSTOMER_TOKEN_LIFETIME" default:"24h"`
+}
+
+// PaginationSecret returns secret of the pagination cursors, it's derived from the auth1 client secret
+// if it isn't set, so the cursors issued by one replica are accepted by the others
+func (c *Config) PaginationSecret() string {
+	if c.PaginationCursorSecret != "" {
+		return c.PaginationCursorSecret
+	}
+
+	mac := hmac.New(sha256.New, []byte(c.Auth1.ClientSecret))
+	mac.Write([]byte(paginationSecretContext))
+	return hex.EncodeToString(mac.Sum(nil))
 }
@@ -68,8 +68,15 @@ const (
 	RequestParameterStatus                   = "status"
 	RequestAuthorizationTokenRegex           = "Bearer ([A-z0-9_.-]{10,})"
 	RequestParameterZipUsa                   = "zip_usa"
+	RequestParameterZip                      = "zip"
 	RequestParameterRateId                   = "rate_id"
 	RequestParameterReceiptId                = "receipt_id"
+	RequestParameterWebhookId                = "webhook_id"
+	RequestParameterCustomerId               = "customer_id"
+	RequestParameterFormat                   = "format"
+	RequestParameterColumns                  = "columns"
+	RequestParameterDryRun                   = "dry_run"
+	RequestParameterMethod                   = "method"
 
 	UserProfileFieldNumberOfEmployees = "NumberOfEmployees"
 	UserProfileFieldAnnualIncome      = "AnnualIncome"
@@ -101,6 +108,7 @@ const (
 	QueryParameterNameLimit  = "limit"
 	QueryParameterNameOffset = "offset"
 	QueryParameterNameSort   = "sort[]"
+	QueryParameterNameCursor = "cursor"
 
 	QueryParameterNameUtmMedium   = "utm_medium"
 	QueryParameterNameUtmCampaign = "utm_campaign"
@@ -112,6 +120,21 @@ const (
 	HeaderUserAgent           = "User-Agent"
 	HeaderXApiSignatureHeader = "X-API-SIGNATURE"
 	HeaderReferer             = "referer"
+	HeaderIdempotencyKey      = "Idempotency-Key"
+	HeaderIdempotentReplayed  = "Idempotent-Replayed"
+	HeaderRetryAfter          = "Retry-After"
+	HeaderCustomerToken       = "X-Customer-Token"
+	HeaderLink                = "Link"
+	HeaderNextCursor          = "X-Next-Cursor"
+	HeaderPrevCursor          = "X-Prev-Cursor"
+	HeaderTrailer             = "Trailer"
+	HeaderExportStatus        = "X-Export-Status"
+
+	// ExportStatusComplete and ExportStatusFailed are values of the export status trailer
+	ExportStatusComplete = "complete"
+	ExportStatusFailed   = "failed"
+
+	IdempotencyKeyMaxLength = 255
 
 	// EnvironmentProduction        = "prod"
 	CustomerTokenCookiesName = "_ps_ctkn"
@@ -164,10 +187,10 @@ var (
 		"AX": regexp.MustCompile("^\\d{5}$"),
 		"AL": regexp.MustCompile("^\\d{4}$"),
 		"DZ": regexp.MustCompile("^\\d{5}$"),
-		"AS": regexp.MustCompile("^\\d{5}(-{1}\\d{4,6})$"),
+		"AS": regexp.MustCompile("^\\d{5}(-\\d{4})?$"),
 		"AD": regexp.MustCompile("^[Aa][Dd]\\d{3}$"),
-		"AI": regexp.MustCompile("^[Aa][I][-][2][6][4][0]$"),
-		"AR": regexp.MustCompile("^\\d{4}|[A-Za-z]\\d{4}[a-zA-Z]{3}$"),
+		"AI": regexp.MustCompile("^AI-?2640$"),
+		"AR": regexp.MustCompile("^(\\d{4}|[A-Z]\\d{4}[A-Z]{3})$"),
 		"AM": regexp.MustCompile("^\\d{4}$"),
 		"AC": regexp.MustCompile("^[Aa][Ss][Cc][Nn]\\s{0,1}[1][Zz][Zz]$"),
 		"AU": regexp.MustCompile("^\\d{4}$"),
@@ -175,14 +198,14 @@ var (
 		"AZ": regexp.MustCompile("^[Aa][Zz]\\d{4}$"),
 		"BH": regexp.MustCompile("^\\d{3,4}$"),
 		"BD": regexp.MustCompile("^\\d{4}$"),
-		"BB": regexp.MustCompile("^[Aa][Zz]\\d{5}$"),
+		"BB": regexp.MustCompile("^(BB)?\\d{5}$"),
 		"BY": regexp.MustCompile("^\\d{6}$"),
 		"BE": regexp.MustCompile("^\\d{4}$"),
 		"BM": regexp.MustCompile("^[A-Za-z]{2}\\s([A-Za-z]{2}|\\d{2})$"),
 		"BT": regexp.MustCompile("^\\d{5}$"),
 		"BO": regexp.MustCompile("^\\d{4}$"),
 		"BA": regexp.MustCompile("^\\d{5}$"),
-		"BR": regexp.MustCompile("^\\d{5}-\\d{3}$"),
+		"BR": regexp.MustCompile("^\\d{5}-?\\d{3}$"),
 		"IO": regexp.MustCompile("^[Bb]{2}[Nn][Dd]\\s{0,1}[1][Zz]{2}$"),
 		"VG": regexp.MustCompile("^[Vv][Gg]\\d{4}$"),
 		"BN": regexp.MustCompile("^[A-Za-z]{2}\\d{4}$"),
@@ -192,17 +215,16 @@ var (
 		"CV": regexp.MustCompile("^\\d{4}$"),
 		"KY": regexp.MustCompile("^[Kk][Yy]\\d[-\\s]{0,1}\\d{4}$"),
 		"TD": regexp.MustCompile("^\\d{5}$"),
-		"CL": regexp.MustCompile("^\\d{7}\\s\\(\\d{3}-\\d{4}\\)$"),
+		"CL": regexp.MustCompile("^\\d{7}$"),
 		"CN": regexp.MustCompile("^\\d{6}$"),
 		"CX": regexp.MustCompile("^\\d{4}$"),
 		"CC": regexp.MustCompile("^\\d{4}$"),
 		"CO": regexp.MustCompile("^\\d{6}$"),
-		"CD": regexp.MustCompile("^[Cc][Dd]$"),
 		"CR": regexp.MustCompile("^\\d{4,5}$"),
 		"HR": regexp.MustCompile("^\\d{5}$"),
 		"CU": regexp.MustCompile("^\\d{5}$"),
 		"CY": regexp.MustCompile("^\\d{4}$"),
-		"CZ": regexp.MustCompile("^\\d{5}\\s\\(\\d{3}\\s\\d{2}\\)$"),
+		"CZ": regexp.MustCompile("^\\d{3} ?\\d{2}$"),
 		"DK": regexp.MustCompile("^\\d{4}$"),
 		"DO": regexp.MustCompile("^\\d{5}$"),
 		"EC": regexp.MustCompile("^\\d{6}$"),
@@ -216,16 +238,16 @@ var (
 		"FR": regexp.MustCompile("^\\d{5}$"),
 		"GF": regexp.MustCompile("^973\\d{2}$"),
 		"PF": regexp.MustCompile("^987\\d{2}$"),
-		"GA": regexp.MustCompile("^\\d{2}\\s[a-zA-Z-_ ]\\s\\d{2}$"),
+		"GA": regexp.MustCompile("^\\d{2} [A-Z][A-Z -]* \\d{2}$"),
 		"GE": regexp.MustCompile("^\\d{4}$"),
-		"DE": regexp.MustCompile("^\\d{2,5}$"),
+		"DE": regexp.MustCompile("^\\d{5}$"),
 		"GI": regexp.MustCompile("^[Gg][Xx][1]{2}\\s{0,1}[1][Aa]{2}$"),
 		"GR": regexp.MustCompile("^\\d{3}\\s{0,1}\\d{2}$"),
 		"GL": regexp.MustCompile("^\\d{4}$"),
 		"GP": regexp.MustCompile("^971\\d{2}$"),
 		"GU": regexp.MustCompile("^\\d{5}$"),
 		"GT": regexp.MustCompile("^\\d{5}$"),
-		"GG": regexp.MustCompile("^[A-Za-z]{2}\\d\\s{0,1}\\d[A-Za-z]{2}$"),
+		"GG": regexp.MustCompile("^GY\\d{1,2} ?\\d[A-Z]{2}$"),
 		"GW": regexp.MustCompile("^\\d{4}$"),
 		"HT": regexp.MustCompile("^\\d{4}$"),
 		"HM": regexp.MustCompile("^\\d{4}$"),
@@ -234,18 +256,18 @@ var (
 		"IS": regexp.MustCompile("^\\d{3}$"),
 		"IN": regexp.MustCompile("^\\d{6}$"),
 		"ID": regexp.MustCompile("^\\d{5}$"),
-		"IR": regexp.MustCompile("^\\d{5}-\\d{5}$"),
+		"IR": regexp.MustCompile("^\\d{5}-?\\d{5}$"),
 		"IQ": regexp.MustCompile("^\\d{5}$"),
-		"IM": regexp.MustCompile("^[Ii[Mm]\\d{1,2}\\s\\d\\[A-Z]{2}$"),
+		"IM": regexp.MustCompile("^IM\\d{1,2} ?\\d[A-Z]{2}$"),
 		"IL": regexp.MustCompile("^\\b\\d{5}(\\d{2})?$"),
 		"IT": regexp.MustCompile("^\\d{5}$"),
 		"JM": regexp.MustCompile("^\\d{2}$"),
-		"JP": regexp.MustCompile("^\\d{7}\\s\\(\\d{3}-\\d{4}\\)$"),
+		"JP": regexp.MustCompile("^\\d{3}-?\\d{4}$"),
 		"JE": regexp.MustCompile("^[Jj][Ee]\\d\\s{0,1}\\d[A-Za-z]{2}$"),
 		"JO": regexp.MustCompile("^\\d{5}$"),
 		"KZ": regexp.MustCompile("^\\d{6}$"),
 		"KE": regexp.MustCompile("^\\d{5}$"),
-		"KR": regexp.MustCompile("^\\d{6}\\s\\(\\d{3}-\\d{3}\\)$"),
+		"KR": regexp.MustCompile("^\\d{5}$"),
 		"XK": regexp.MustCompile("^\\d{5}$"),
 		"KW": regexp.MustCompile("^\\d{5}$"),
 		"KG": regexp.MustCompile("^\\d{6}$"),
@@ -312,7 +334,7 @@ var (
 		"SN": regexp.MustCompile("^\\d{5}$"),
 		"RS": regexp.MustCompile("^\\d{5}$"),
 		"SG": regexp.MustCompile("^\\d{6}$"),
-		"SK": regexp.MustCompile("^\\d{5}\\s\\(\\d{3}\\s\\d{2}\\)$"),
+		"SK": regexp.MustCompile("^\\d{3} ?\\d{2}$"),
 		"SI": regexp.MustCompile("^([Ss][Ii][- ]{0,1}){0,1}\\d{4}$"),
 		"ZA": regexp.MustCompile("^\\d{4}$"),
 		"GS": regexp.MustCompile("^[Ss][Ii][Qq]{2}\\s{0,1}[1][Zz]{2}$"),
@@ -332,12 +354,12 @@ var (
 		"TM": regexp.MustCompile("^\\d{6}$"),
 		"TC": regexp.MustCompile("^[Tt][Kk][Cc][Aa]\\s{0,1}[1][Zz]{2}$"),
 		"UA": regexp.MustCompile("^\\d{5}$"),
-		"GB": regexp.MustCompile("^[A-Z]{1,2}[0-9R][0-9A-Z]?\\s*[0-9][A-Z-[CIKMOV]]{2}"),
+		"GB": regexp.MustCompile("^([A-Z]{1,2}\\d[A-Z\\d]? ?\\d[ABD-HJLNP-UW-Z]{2}|GIR ?0AA)$"),
 		"US": regexp.MustCompile("^\\b\\d{5}\\b(?:[- ]{1}\\d{4})?$"),
 		"UY": regexp.MustCompile("^\\d{5}$"),
 		"VI": regexp.MustCompile("^\\d{5}$"),
-		"UZ": regexp.MustCompile("^\\d{3} \\d{3}$"),
-		"VA": regexp.MustCompile("^120$"),
+		"UZ": regexp.MustCompile("^\\d{3} ?\\d{3}$"),
+		"VA": regexp.MustCompile("^00120$"),
 		"VE": regexp.MustCompile("^\\d{4}(\\s[a-zA-Z]{1})?$"),
 		"VN": regexp.MustCompile("^\\d{6}$"),
 		"WF": regexp.MustCompile("^986\\d{2}$"),
@@ -0,0 +1,280 @@
+package common
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"sync"
+	"time"
+)
+
+const (
+	customerTokenCollection     = "customer_token"
+	customerSavedCardCollection = "customer_saved_card"
+	customerUserCollection      = "customer_user"
+	customerTokenPurgeInterval  = time.Minute
+)
+
+// CustomerToken binds the token issued by the project or the payment form cookie to the customer of the project,
+// UserId is an identifier of the customer in the billing, it's known for the tokens of the payment form only
+type CustomerToken struct {
+	Token      string    `bson:"_id"`
+	ProjectId  string    `bson:"project_id"`
+	CustomerId string    `bson:"customer_id"`
+	UserId     string    `bson:"user_id"`
+	ExpiresAt  time.Time `bson:"expires_at"`
+}
+
+type customerUser struct {
+	Id         string `bson:"_id"`
+	ProjectId  string `bson:"project_id"`
+	CustomerId string `bson:"customer_id"`
+	UserId     string `bson:"user_id"`
+}
+
+// CustomerStorage keeps issued customer tokens and preferences of the customers,
+// the storage must be shared by the replicas, otherwise the token issued by one replica is unknown to the others
+type CustomerStorage interface {
+	// SaveToken
+	SaveToken(token *CustomerToken) error
+	// GetToken returns token or nil if the token is unknown or expired
+	GetToken(token string) (*CustomerToken, error)
+	// AddCustomerUser binds the customer of the project to the customer of the billing, the binding never expires
+	AddCustomerUser(projectId, customerId, userId string) error
+	// GetCustomerUsers returns identifiers of the billing customers bound to the customer of the project
+	GetCustomerUsers(projectId, customerId string) ([]string, error)
+	// SetDefaultSavedCard marks saved card as default payment instrument of the customer
+	SetDefaultSavedCard(projectId, customerId, cardId string) error
+	// GetDefaultSavedCard returns identifier of the default saved card or empty string
+	GetDefaultSavedCard(projectId, customerId string) (string, error)
+}
+
+// NewCustomerStorage returns storage in the database of the session or in the process memory if session is nil
+func NewCustomerStorage(session *mgo.Session) (CustomerStorage, error) {
+	if session == nil {
+		return NewCustomerMemoryStorage(), nil
+	}
+
+	tokens, err := newMongoCollection(
+		session,
+		customerTokenCollection,
+		mgo.Index{Key: []string{"expires_at"}, ExpireAfter: time.Second},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := newMongoCollection(
+		session,
+		customerUserCollection,
+		mgo.Index{Key: []string{"project_id", "customer_id"}},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defaults, err := newMongoCollection(session, customerSavedCardCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	return &customerMongoStorage{tokens: tokens, users: users, defaults: defaults}, nil
+}
+
+type customerDefaultSavedCard struct {
+	Id     string `bson:"_id"`
+	CardId string `bson:"card_id"`
+}
+
+type customerMongoStorage struct {
+	tokens   *mongoCollection
+	users    *mongoCollection
+	defaults *mongoCollection
+}
+
+// SaveToken
+func (s *customerMongoStorage) SaveToken(token *CustomerToken) error {
+	return s.tokens.with(func(c *mgo.Collection) error {
+		_, err := c.UpsertId(token.Token, token)
+		return err
+	})
+}
+
+// GetToken checks the expiration time since the expired documents are removed by the database with a delay
+func (s *customerMongoStorage) GetToken(token string) (*CustomerToken, error) {
+	t := &CustomerToken{}
+	err := s.tokens.with(func(c *mgo.Collection) error {
+		return c.Find(bson.M{"_id": token, "expires_at": bson.M{"$gt": time.Now()}}).One(t)
+	})
+
+	if err == mgo.ErrNotFound {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// AddCustomerUser
+func (s *customerMongoStorage) AddCustomerUser(projectId, customerId, userId string) error {
+	user := &customerUser{
+		Id:         projectId + "|" + customerId + "|" + userId,
+		ProjectId:  projectId,
+		CustomerId: customerId,
+		UserId:     userId,
+	}
+	return s.users.with(func(c *mgo.Collection) error {
+		_, err := c.UpsertId(user.Id, user)
+		return err
+	})
+}
+
+// GetCustomerUsers
+func (s *customerMongoStorage) GetCustomerUsers(projectId, customerId string) ([]string, error) {
+	var users []*customerUser
+	err := s.users.with(func(c *mgo.Collection) error {
+		return c.Find(bson.M{"project_id": projectId, "customer_id": customerId}).All(&users)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(users))
+	for i, user := range users {
+		ids[i] = user.UserId
+	}
+	return ids, nil
+}
+
+// SetDefaultSavedCard
+func (s *customerMongoStorage) SetDefaultSavedCard(projectId, customerId, cardId string) error {
+	id := projectId + "|" + customerId
+	err := s.defaults.with(func(c *mgo.Collection) error {
+		if cardId == "" {
+			return c.RemoveId(id)
+		}
+		_, err := c.UpsertId(id, &customerDefaultSavedCard{Id: id, CardId: cardId})
+		return err
+	})
+
+	if err == mgo.ErrNotFound {
+		return nil
+	}
+
+	return err
+}
+
+// GetDefaultSavedCard
+func (s *customerMongoStorage) GetDefaultSavedCard(projectId, customerId string) (string, error) {
+	card := &customerDefaultSavedCard{}
+	err := s.defaults.with(func(c *mgo.Collection) error { return c.FindId(projectId + "|" + customerId).One(card) })
+
+	if err == mgo.ErrNotFound {
+		return "", nil
+	}
+
+	return card.CardId, err
+}
+
+type customerMemoryStorage struct {
+	mx       sync.Mutex
+	tokens   map[string]*CustomerToken
+	users    map[string][]string
+	defaults map[string]string
+	purged   time.Time
+}
+
+// NewCustomerMemoryStorage returns storage keeping customer tokens in the process memory
+func NewCustomerMemoryStorage() CustomerStorage {
+	return &customerMemoryStorage{
+		tokens:   make(map[string]*CustomerToken),
+		users:    make(map[string][]string),
+		defaults: make(map[string]string),
+		purged:   time.Now(),
+	}
+}
+
+// SaveToken
+func (s *customerMemoryStorage) SaveToken(token *CustomerToken) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.purge(time.Now())
+
+	cp := *token
+	s.tokens[token.Token] = &cp
+	return nil
+}
+
+// GetToken
+func (s *customerMemoryStorage) GetToken(token string) (*CustomerToken, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	t, ok := s.tokens[token]
+	if !ok || t.ExpiresAt.Before(time.Now()) {
+		return nil, nil
+	}
+
+	cp := *t
+	return &cp, nil
+}
+
+// AddCustomerUser
+func (s *customerMemoryStorage) AddCustomerUser(projectId, customerId, userId string) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	key := projectId + "|" + customerId
+	for _, id := range s.users[key] {
+		if id == userId {
+			return nil
+		}
+	}
+	s.users[key] = append(s.users[key], userId)
+	return nil
+}
+
+// GetCustomerUsers
+func (s *customerMemoryStorage) GetCustomerUsers(projectId, customerId string) ([]string, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	return append([]string(nil), s.users[projectId+"|"+customerId]...), nil
+}
+
+// SetDefaultSavedCard
+func (s *customerMemoryStorage) SetDefaultSavedCard(projectId, customerId, cardId string) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if cardId == "" {
+		delete(s.defaults, projectId+"|"+customerId)
+		return nil
+	}
+	s.defaults[projectId+"|"+customerId] = cardId
+	return nil
+}
+
+// GetDefaultSavedCard
+func (s *customerMemoryStorage) GetDefaultSavedCard(projectId, customerId string) (string, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	return s.defaults[projectId+"|"+customerId], nil
+}
+
+// purge removes expired tokens
+func (s *customerMemoryStorage) purge(now time.Time) {
+	if now.Sub(s.purged) < customerTokenPurgeInterval {
+		return
+	}
+	for key, t := range s.tokens {
+		if t.ExpiresAt.Before(now) {
+			delete(s.tokens, key)
+		}
+	}
+	s.purged = now
+}
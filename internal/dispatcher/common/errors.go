@@ -118,8 +118,8 @@ var (
 	ErrorMessagePriceGroupCurrencyList                = NewManagementApiResponseError("ma000070", "unable to get price group currencies")
 	ErrorMessagePriceGroupCurrencyByRegion            = NewManagementApiResponseError("ma000071", "unable to get price group currency by region")
 	ErrorMessagePriceGroupRecommendedList             = NewManagementApiResponseError("ma000072", "unable to get price group recommended prices")
-	ErrorMessageGetProductPrice                       = NewManagementApiResponseError("ma000072", "unable to get price of product")
-	ErrorMessageUpdateProductPrice                    = NewManagementApiResponseError("ma000072", "unable to update price of product")
+	ErrorMessageGetProductPrice                       = NewManagementApiResponseError("ma000136", "unable to get price of product")
+	ErrorMessageUpdateProductPrice                    = NewManagementApiResponseError("ma000137", "unable to update price of product")
 	ErrorMessageIncorrectZip                          = NewManagementApiResponseError("ma000073", "incorrect zip code")
 	ErrorMessageIncorrectNumberOfEmployees            = NewManagementApiResponseError("ma000074", "incorrect number of employees value")
 	ErrorMessageIncorrectAnnualIncome                 = NewManagementApiResponseError("ma000075", "incorrect annual income value")
@@ -131,8 +131,8 @@ var (
 	ErrorMessageIncorrectKindOfActivity               = NewManagementApiResponseError("ma000081", "incorrect kind of activity")
 	ErrorMessageIncorrectReview                       = NewManagementApiResponseError("ma000082", "review must be text with length lower than or equal 500 characters")
 	ErrorMessageIncorrectPageId                       = NewManagementApiResponseError("ma000083", "review page identifier must be one of next values: primary_onboarding, merchant_onboarding")
-	ErrorMessageKeyProductIdInvalid                   = NewManagementApiResponseError("ma000082", "key product id is invalid")
-	ErrorMessagePlatformIdInvalid                     = NewManagementApiResponseError("ma000083", "platform id is invalid")
+	ErrorMessageKeyProductIdInvalid                   = NewManagementApiResponseError("ma000138", "key product id is invalid")
+	ErrorMessagePlatformIdInvalid                     = NewManagementApiResponseError("ma000139", "platform id is invalid")
 
 	ErrorMessageIncorrectAlternativeName          = NewManagementApiResponseError("ma000084", "incorrect brand")
 	ErrorMessageIncorrectState                    = NewManagementApiResponseError("ma000085", "incorrect state")
@@ -153,6 +153,39 @@ var (
 	ErrorMessageMerchantNotFound                  = NewManagementApiResponseError("ma000100", "merchant not found")
 	ErrorMessageCreateReportFile                  = NewManagementApiResponseError("ma000101", "unable to create report file")
 	ErrorMessageDownloadReportFile                = NewManagementApiResponseError("ma000102", "unable to download report file")
+	ErrorMessageInsufficientRole                  = NewManagementApiResponseError("ma000103", "user role does not allow this action")
+	ErrorMessageMerchantAccessDenied              = NewManagementApiResponseError("ma000104", "user has no access to the merchant")
+	ErrorMessageIdempotencyKeyInvalid             = NewManagementApiResponseError("ma000105", "idempotency key must be a string with length lower than or equal 255 characters")
+	ErrorMessageIdempotencyKeyConflict            = NewManagementApiResponseError("ma000106", "idempotency key already used with another request parameters")
+	ErrorMessageIdempotencyRequestInProgress      = NewManagementApiResponseError("ma000107", "request with this idempotency key is in progress")
+	ErrorMessageRateLimitExceeded                 = NewManagementApiResponseError("ma000108", "too many requests, retry later")
+	ErrorMessageServiceUnavailable                = NewManagementApiResponseError("ma000109", "service is temporarily unavailable, retry later")
+	ErrorMessageWebhookNotFound                   = NewManagementApiResponseError("ma000110", "webhook subscription not found")
+	ErrorMessageWebhookEventUnknown               = NewManagementApiResponseError("ma000111", "unknown webhook event")
+	ErrorMessageWebhookUrlIncorrect               = NewManagementApiResponseError("ma000112", "webhook url must be an absolute http or https url")
+	ErrorMessageCustomerTokenInvalid              = NewManagementApiResponseError("ma000113", "customer token is invalid or expired")
+	ErrorMessageSavedCardNotFound                 = NewManagementApiResponseError("ma000114", "saved card not found")
+	ErrorMessageExportFormatUnsupported           = NewManagementApiResponseError("ma000115", "export format is not supported")
+	ErrorMessagePaginationCursorInvalid           = NewManagementApiResponseError("ma000116", "pagination cursor is invalid")
+	ErrorMessageRefundBatchNotFound               = NewManagementApiResponseError("ma000117", "refund batch not found")
+	ErrorMessageRefundBatchSizeIncorrect          = NewManagementApiResponseError("ma000118", "refund batch must contain from one to the maximum allowed number of items")
+	ErrorMessageRefundBatchFileIncorrect          = NewManagementApiResponseError("ma000119", "refund batch file must be a csv file with order_id, amount and reason columns")
+	ErrorMessageJobNotFound                       = NewManagementApiResponseError("ma000120", "job not found")
+	ErrorMessageJobFinished                       = NewManagementApiResponseError("ma000121", "job is already finished and can't be cancelled")
+	ErrorMessageKeyFileTooLarge                   = NewManagementApiResponseError("ma000122", "keys file exceeds the maximum allowed size")
+	ErrorMessageKeyFileEncoding                   = NewManagementApiResponseError("ma000123", "keys file must be utf-8 or utf-16 text")
+	ErrorMessageKeyFileInvalidKeys                = NewManagementApiResponseError("ma000124", "keys file contains keys of invalid format")
+	ErrorMessageKeyFileEmpty                      = NewManagementApiResponseError("ma000125", "keys file doesn't contain any keys")
+	ErrorMessageKeyProductPlatformNotFound        = NewManagementApiResponseError("ma000126", "platform of the key product not found")
+	ErrorMessageProjectThemeUrlInsecure           = NewManagementApiResponseError("ma000127", "theme urls must use https scheme")
+	ErrorMessageOrderEventsUnauthorized           = NewManagementApiResponseError("ma000128", "customer token or project signature is required")
+	ErrorMessageInboundWebhookNotFound            = NewManagementApiResponseError("ma000129", "inbound webhook not found")
+	ErrorMessageInboundWebhookNotReplayable       = NewManagementApiResponseError("ma000130", "inbound webhook can't be replayed")
+	ErrorMessageWebhookIpNotAllowed               = NewManagementApiResponseError("ma000131", "ip address is not allowed for callbacks of the provider")
+	ErrorMessageWebhookSignatureInvalid           = NewManagementApiResponseError("ma000132", "signature of the callback is invalid")
+	ErrorMessageWebhookTimestampExpired           = NewManagementApiResponseError("ma000133", "timestamp of the callback is out of the replay window")
+	ErrorMessageWebhookOrderNotFound              = NewManagementApiResponseError("ma000134", "order of the callback not found")
+	ErrorMessageWebhookHandlerMismatch            = NewManagementApiResponseError("ma000135", "order of the callback is paid by the other payment system")
 
 	ValidationErrors = map[string]*grpc.ResponseErrorMessage{
 		UserProfileFieldNumberOfEmployees: ErrorMessageIncorrectNumberOfEmployees,
@@ -0,0 +1,119 @@
+package common
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"sync"
+)
+
+const (
+	HealthStatusOk       = "ok"
+	HealthStatusFail     = "fail"
+	HealthStatusDegraded = "degraded"
+)
+
+// HealthCheck is a named check of the dependency
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+	// Critical checks local state of the replica, the response is failed by the critical checks only,
+	// the failed dependencies are reported as degraded, so an outage of the dependency doesn't unready all replicas
+	Critical bool
+}
+
+// HealthReporter is implemented by the handlers owning external dependencies
+type HealthReporter interface {
+	HealthChecks() []HealthCheck
+}
+
+// ServiceHealthChecker checks availability of the micro services
+type ServiceHealthChecker interface {
+	CheckRegistry(ctx context.Context) error
+	CheckService(ctx context.Context, name string) error
+}
+
+// HealthCheckResult
+type HealthCheckResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// HealthResponse
+type HealthResponse struct {
+	Status string                        `json:"status"`
+	Checks map[string]*HealthCheckResult `json:"checks,omitempty"`
+}
+
+// RunHealthChecks runs checks in parallel, response is failed if any critical check is failed
+// and degraded if any other check is failed
+func RunHealthChecks(ctx context.Context, checks []HealthCheck) *HealthResponse {
+	res := &HealthResponse{
+		Status: HealthStatusOk,
+		Checks: make(map[string]*HealthCheckResult, len(checks)),
+	}
+
+	mx := sync.Mutex{}
+	wg := sync.WaitGroup{}
+
+	for _, check := range checks {
+		wg.Add(1)
+		go func(check HealthCheck) {
+			defer wg.Done()
+
+			result := &HealthCheckResult{Status: HealthStatusOk}
+			if err := check.Check(ctx); err != nil {
+				result.Status = HealthStatusFail
+				result.Error = err.Error()
+			}
+
+			mx.Lock()
+			defer mx.Unlock()
+			res.Checks[check.Name] = result
+			if result.Status == HealthStatusOk {
+				return
+			}
+			if check.Critical {
+				res.Status = HealthStatusFail
+			} else if res.Status == HealthStatusOk {
+				res.Status = HealthStatusDegraded
+			}
+		}(check)
+	}
+
+	wg.Wait()
+	return res
+}
+
+// NewS3BucketHealthCheck returns check of the bucket availability with credentials of the aws manager
+func NewS3BucketHealthCheck(name, accessKeyId, secretAccessKey, region, bucket string) HealthCheck {
+	var (
+		once   sync.Once
+		client *s3.S3
+		err    error
+	)
+
+	return HealthCheck{
+		Name: name,
+		Check: func(ctx context.Context) error {
+			once.Do(func() {
+				var sess *session.Session
+				sess, err = session.NewSession(&aws.Config{
+					Region:      aws.String(region),
+					Credentials: credentials.NewStaticCredentials(accessKeyId, secretAccessKey, ""),
+				})
+				if err == nil {
+					client = s3.New(sess)
+				}
+			})
+			if err != nil {
+				return err
+			}
+
+			_, e := client.HeadBucketWithContext(ctx, &s3.HeadBucketInput{Bucket: aws.String(bucket)})
+			return e
+		},
+	}
+}
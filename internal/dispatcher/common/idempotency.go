@@ -0,0 +1,230 @@
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"sync"
+	"time"
+)
+
+const (
+	idempotencyCollection = "idempotency_key"
+)
+
+// IdempotencyRecord is a stored result of the request executed with Idempotency-Key header
+type IdempotencyRecord struct {
+	RequestHash string    `bson:"request_hash"`
+	Completed   bool      `bson:"completed"`
+	Status      int       `bson:"status"`
+	ContentType string    `bson:"content_type"`
+	Body        []byte    `bson:"body"`
+	ExpiresAt   time.Time `bson:"expires_at"`
+}
+
+// IdempotencyStorage
+type IdempotencyStorage interface {
+	// Get returns record by the key or nil if the key is unknown or expired
+	Get(key string) (*IdempotencyRecord, error)
+	// Reserve creates not completed record for the key, returns false if the key already exists
+	Reserve(key, requestHash string, ttl time.Duration) (bool, error)
+	// Complete saves response of the request to the reserved record
+	Complete(key string, status int, contentType string, body []byte) error
+	// Release removes the reserved record, so the request can be repeated with the same key
+	Release(key string) error
+}
+
+// IdempotencyRequestHash
+func IdempotencyRequestHash(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// NewIdempotencyStorage returns storage in the database of the session or in the process memory if session is nil,
+// the storage must be shared by the replicas, otherwise the request repeated on the other replica is executed again
+func NewIdempotencyStorage(session *mgo.Session) (IdempotencyStorage, error) {
+	if session == nil {
+		return NewIdempotencyMemoryStorage(), nil
+	}
+
+	c, err := newMongoCollection(
+		session,
+		idempotencyCollection,
+		mgo.Index{Key: []string{"expires_at"}, ExpireAfter: time.Second},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &idempotencyMongoStorage{records: c}, nil
+}
+
+type idempotencyMongoStorage struct {
+	records *mongoCollection
+}
+
+// Get checks the expiration time since the expired documents are removed by the database with a delay
+func (s *idempotencyMongoStorage) Get(key string) (*IdempotencyRecord, error) {
+	rec := &IdempotencyRecord{}
+	err := s.records.with(func(c *mgo.Collection) error {
+		return c.Find(bson.M{"_id": key, "expires_at": bson.M{"$gt": time.Now()}}).One(rec)
+	})
+
+	if err == mgo.ErrNotFound {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+// Reserve inserts the record, so only one replica reserves the key, the expired record is replaced by the conditional update
+func (s *idempotencyMongoStorage) Reserve(key, requestHash string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	rec := &IdempotencyRecord{RequestHash: requestHash, ExpiresAt: now.Add(ttl)}
+
+	err := s.records.with(func(c *mgo.Collection) error {
+		return c.Insert(bson.M{
+			"_id":          key,
+			"request_hash": rec.RequestHash,
+			"completed":    false,
+			"expires_at":   rec.ExpiresAt,
+		})
+	})
+	if err == nil {
+		return true, nil
+	}
+	if !mgo.IsDup(err) {
+		return false, err
+	}
+
+	err = s.records.with(func(c *mgo.Collection) error {
+		return c.Update(bson.M{"_id": key, "expires_at": bson.M{"$lte": now}}, rec)
+	})
+	if err == mgo.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Complete
+func (s *idempotencyMongoStorage) Complete(key string, status int, contentType string, body []byte) error {
+	err := s.records.with(func(c *mgo.Collection) error {
+		return c.UpdateId(key, bson.M{"$set": bson.M{
+			"completed":    true,
+			"status":       status,
+			"content_type": contentType,
+			"body":         body,
+		}})
+	})
+	if err == mgo.ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+// Release
+func (s *idempotencyMongoStorage) Release(key string) error {
+	err := s.records.with(func(c *mgo.Collection) error {
+		return c.RemoveId(key)
+	})
+	if err == mgo.ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+type idempotencyMemoryStorage struct {
+	mx      sync.Mutex
+	records map[string]*IdempotencyRecord
+	purged  time.Time
+}
+
+// NewIdempotencyMemoryStorage returns storage keeping records in the process memory
+func NewIdempotencyMemoryStorage() IdempotencyStorage {
+	return &idempotencyMemoryStorage{
+		records: make(map[string]*IdempotencyRecord),
+		purged:  time.Now(),
+	}
+}
+
+// Get
+func (s *idempotencyMemoryStorage) Get(key string) (*IdempotencyRecord, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	rec, ok := s.records[key]
+	if !ok || rec.ExpiresAt.Before(time.Now()) {
+		return nil, nil
+	}
+
+	cp := *rec
+	return &cp, nil
+}
+
+// Reserve
+func (s *idempotencyMemoryStorage) Reserve(key, requestHash string, ttl time.Duration) (bool, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	now := time.Now()
+	s.purge(now)
+
+	if rec, ok := s.records[key]; ok && rec.ExpiresAt.After(now) {
+		return false, nil
+	}
+
+	s.records[key] = &IdempotencyRecord{
+		RequestHash: requestHash,
+		ExpiresAt:   now.Add(ttl),
+	}
+	return true, nil
+}
+
+// Complete
+func (s *idempotencyMemoryStorage) Complete(key string, status int, contentType string, body []byte) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	rec, ok := s.records[key]
+	if !ok {
+		return nil
+	}
+
+	rec.Completed = true
+	rec.Status = status
+	rec.ContentType = contentType
+	rec.Body = body
+	return nil
+}
+
+// Release
+func (s *idempotencyMemoryStorage) Release(key string) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	delete(s.records, key)
+	return nil
+}
+
+func (s *idempotencyMemoryStorage) purge(now time.Time) {
+	if now.Sub(s.purged) < time.Minute {
+		return
+	}
+	for key, rec := range s.records {
+		if rec.ExpiresAt.Before(now) {
+			delete(s.records, key)
+		}
+	}
+	s.purged = now
+}
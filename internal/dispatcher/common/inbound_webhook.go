@@ -0,0 +1,381 @@
+package common
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/labstack/echo/v4"
+	"github.com/paysuper/paysuper-management-api/pkg/keyset"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	InboundWebhookTypePayment = "payment"
+	InboundWebhookTypeRefund  = "refund"
+
+	inboundWebhookLogSize    = 5000
+	inboundWebhookCollection = "inbound_webhook"
+	// inboundWebhookLogTtl limits the callbacks kept in the database
+	inboundWebhookLogTtl = 90 * 24 * time.Hour
+)
+
+// inboundWebhookHiddenHeaders are headers of the callback which aren't stored since they may contain credentials
+var inboundWebhookHiddenHeaders = map[string]bool{
+	echo.HeaderAuthorization: true,
+	echo.HeaderCookie:        true,
+}
+
+// InboundWebhookResult is a result of the callback processing by the billing server
+type InboundWebhookResult struct {
+	ResponseCode int         `json:"response_code" bson:"response_code"`
+	Error        interface{} `json:"error,omitempty" bson:"error"`
+	UserId       string      `json:"user_id,omitempty" bson:"user_id"`
+	ProcessedAt  time.Time   `json:"processed_at" bson:"processed_at"`
+}
+
+// InboundWebhook is a callback of the payment provider stored as it was received, so it can be replayed
+type InboundWebhook struct {
+	Id        string                  `json:"id" bson:"_id"`
+	Provider  string                  `json:"provider" bson:"provider"`
+	Type      string                  `json:"type" bson:"type"`
+	OrderId   string                  `json:"order_id,omitempty" bson:"order_id"`
+	Headers   map[string]string       `json:"headers" bson:"headers"`
+	Body      string                  `json:"body" bson:"body"`
+	Signature string                  `json:"signature" bson:"signature"`
+	Result    *InboundWebhookResult   `json:"result" bson:"result"`
+	Replays   []*InboundWebhookResult `json:"replays" bson:"replays"`
+	CreatedAt time.Time               `json:"created_at" bson:"created_at"`
+}
+
+// InboundWebhookFilter
+type InboundWebhookFilter struct {
+	Provider     string `query:"provider" validate:"omitempty,max=32"`
+	Type         string `query:"type" validate:"omitempty,oneof=payment refund"`
+	OrderId      string `query:"order_id" validate:"omitempty,max=64"`
+	ResponseCode int    `query:"response_code" validate:"omitempty,min=100,max=599"`
+	// Failed selects callbacks which weren't processed successfully by the last attempt
+	Failed bool `query:"failed"`
+	// Query is a substring of the body of the callback
+	Query string `query:"query" validate:"omitempty,max=255"`
+}
+
+// InboundWebhookList
+type InboundWebhookList struct {
+	Count int               `json:"count"`
+	Items []*InboundWebhook `json:"items"`
+}
+
+// InboundWebhookStorage keeps callbacks of the payment providers
+type InboundWebhookStorage interface {
+	// SaveWebhook creates or replaces the callback
+	SaveWebhook(w *InboundWebhook) error
+	// GetWebhook returns nil if the callback is unknown
+	GetWebhook(id string) (*InboundWebhook, error)
+	// ListWebhooks returns callbacks matching the filter, the newest callbacks come first,
+	// the page starts after the position if it's set, otherwise after the offset
+	ListWebhooks(filter *InboundWebhookFilter, limit, offset int, pos *keyset.Position) (*InboundWebhookList, error)
+}
+
+// NewInboundWebhookStorage returns storage in the database of the session or in the process memory if session is nil
+func NewInboundWebhookStorage(session *mgo.Session) (InboundWebhookStorage, error) {
+	if session == nil {
+		return NewInboundWebhookMemoryStorage(), nil
+	}
+
+	c, err := newMongoCollection(
+		session,
+		inboundWebhookCollection,
+		mgo.Index{Key: []string{"created_at"}, ExpireAfter: inboundWebhookLogTtl},
+		mgo.Index{Key: []string{"-created_at", "-_id"}},
+		mgo.Index{Key: []string{"provider", "-created_at"}},
+		mgo.Index{Key: []string{"order_id"}},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &inboundWebhookMongoStorage{webhooks: c}, nil
+}
+
+// inboundWebhookDocument keeps the response code of the last processing of the callback for the filter
+type inboundWebhookDocument struct {
+	InboundWebhook   `bson:",inline"`
+	LastResponseCode int `bson:"last_response_code"`
+}
+
+type inboundWebhookMongoStorage struct {
+	webhooks *mongoCollection
+}
+
+// SaveWebhook
+func (s *inboundWebhookMongoStorage) SaveWebhook(w *InboundWebhook) error {
+	doc := &inboundWebhookDocument{InboundWebhook: *w}
+	if last := w.LastResult(); last != nil {
+		doc.LastResponseCode = last.ResponseCode
+	}
+
+	return s.webhooks.with(func(c *mgo.Collection) error {
+		_, err := c.UpsertId(w.Id, doc)
+		return err
+	})
+}
+
+// GetWebhook
+func (s *inboundWebhookMongoStorage) GetWebhook(id string) (*InboundWebhook, error) {
+	doc := &inboundWebhookDocument{}
+	err := s.webhooks.with(func(c *mgo.Collection) error { return c.FindId(id).One(doc) })
+
+	if err == mgo.ErrNotFound {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &doc.InboundWebhook, nil
+}
+
+// ListWebhooks
+func (s *inboundWebhookMongoStorage) ListWebhooks(filter *InboundWebhookFilter, limit, offset int, pos *keyset.Position) (*InboundWebhookList, error) {
+	query := bson.M{}
+
+	if filter.Provider != "" {
+		query["provider"] = filter.Provider
+	}
+	if filter.Type != "" {
+		query["type"] = filter.Type
+	}
+	if filter.OrderId != "" {
+		query["order_id"] = filter.OrderId
+	}
+	if filter.ResponseCode != 0 {
+		query["last_response_code"] = filter.ResponseCode
+	}
+	if filter.Failed {
+		query["last_response_code"] = bson.M{"$ne": http.StatusOK}
+		if filter.ResponseCode != 0 {
+			query["last_response_code"] = bson.M{"$eq": filter.ResponseCode, "$ne": http.StatusOK}
+		}
+	}
+	if filter.Query != "" {
+		query["body"] = bson.RegEx{Pattern: regexp.QuoteMeta(filter.Query)}
+	}
+
+	list := &InboundWebhookList{Items: make([]*InboundWebhook, 0)}
+	err := s.webhooks.with(func(c *mgo.Collection) error {
+		var err error
+		if list.Count, err = c.Find(query).Count(); err != nil {
+			return err
+		}
+
+		page := c.Find(query).Sort(pos.Sort()...).Skip(offset)
+		if pos != nil {
+			page = c.Find(pos.Query(query)).Sort(pos.Sort()...)
+		}
+
+		docs := make([]*inboundWebhookDocument, 0)
+		if err = page.Limit(limit).All(&docs); err != nil {
+			return err
+		}
+
+		for _, doc := range docs {
+			list.Items = append(list.Items, &doc.InboundWebhook)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	pos.Reverse(len(list.Items), func(i, j int) { list.Items[i], list.Items[j] = list.Items[j], list.Items[i] })
+	return list, nil
+}
+
+type inboundWebhookMemoryStorage struct {
+	mx       sync.RWMutex
+	webhooks []*InboundWebhook
+}
+
+// NewInboundWebhookMemoryStorage returns storage keeping last callbacks of the payment providers in the process memory
+func NewInboundWebhookMemoryStorage() InboundWebhookStorage {
+	return &inboundWebhookMemoryStorage{
+		webhooks: make([]*InboundWebhook, 0),
+	}
+}
+
+// SaveWebhook
+func (s *inboundWebhookMemoryStorage) SaveWebhook(w *InboundWebhook) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	cp := copyInboundWebhook(w)
+	for i, item := range s.webhooks {
+		if item.Id == w.Id {
+			s.webhooks[i] = cp
+			return nil
+		}
+	}
+
+	s.webhooks = append(s.webhooks, cp)
+	if len(s.webhooks) > inboundWebhookLogSize {
+		s.webhooks = s.webhooks[len(s.webhooks)-inboundWebhookLogSize:]
+	}
+	return nil
+}
+
+// GetWebhook
+func (s *inboundWebhookMemoryStorage) GetWebhook(id string) (*InboundWebhook, error) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	for _, w := range s.webhooks {
+		if w.Id == id {
+			return copyInboundWebhook(w), nil
+		}
+	}
+	return nil, nil
+}
+
+// ListWebhooks
+func (s *inboundWebhookMemoryStorage) ListWebhooks(filter *InboundWebhookFilter, limit, offset int, pos *keyset.Position) (*InboundWebhookList, error) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	matched := make([]*InboundWebhook, 0)
+	for i := len(s.webhooks) - 1; i >= 0; i-- {
+		if filter.Match(s.webhooks[i]) {
+			matched = append(matched, s.webhooks[i])
+		}
+	}
+
+	list := &InboundWebhookList{Count: len(matched), Items: make([]*InboundWebhook, 0)}
+
+	if pos != nil {
+		offset = 0
+		page := make([]*InboundWebhook, 0)
+		for _, w := range matched {
+			if pos.Match(w.CreatedAt, w.Id) {
+				page = append(page, w)
+			}
+		}
+
+		// the backward page is the newer callbacks nearest to the position, they are at the end of the newest first list
+		if pos.Backward && len(page) > limit {
+			page = page[len(page)-limit:]
+		}
+		matched = page
+	}
+
+	for i := offset; i < len(matched) && len(list.Items) < limit; i++ {
+		list.Items = append(list.Items, copyInboundWebhook(matched[i]))
+	}
+	return list, nil
+}
+
+// Match checks that the callback satisfies all conditions of the filter
+func (f *InboundWebhookFilter) Match(w *InboundWebhook) bool {
+	last := w.LastResult()
+
+	switch {
+	case f.Provider != "" && f.Provider != w.Provider,
+		f.Type != "" && f.Type != w.Type,
+		f.OrderId != "" && f.OrderId != w.OrderId,
+		f.ResponseCode != 0 && (last == nil || f.ResponseCode != last.ResponseCode),
+		f.Failed && last != nil && last.ResponseCode == http.StatusOK,
+		f.Query != "" && !strings.Contains(w.Body, f.Query):
+		return false
+	}
+
+	return true
+}
+
+// LastResult returns result of the last replay or the result of the original processing
+func (w *InboundWebhook) LastResult() *InboundWebhookResult {
+	if len(w.Replays) > 0 {
+		return w.Replays[len(w.Replays)-1]
+	}
+	return w.Result
+}
+
+// Position returns position of the callback in the list
+func (w *InboundWebhook) Position() keyset.Position {
+	return keyset.Position{CreatedAt: w.CreatedAt, Id: w.Id}
+}
+
+// NewInboundWebhook returns callback of the request, the raw body must be extracted by the middleware before
+func NewInboundWebhook(ctx echo.Context, id, provider, typ, signatureHeader string) *InboundWebhook {
+	headers := make(map[string]string)
+	for name, values := range ctx.Request().Header {
+		if !inboundWebhookHiddenHeaders[name] {
+			headers[name] = strings.Join(values, ", ")
+		}
+	}
+
+	return &InboundWebhook{
+		Id:        id,
+		Provider:  provider,
+		Type:      typ,
+		Headers:   headers,
+		Body:      string(ExtractRawBodyContext(ctx)),
+		Signature: ctx.Request().Header.Get(signatureHeader),
+		Replays:   make([]*InboundWebhookResult, 0),
+		CreatedAt: time.Now(),
+	}
+}
+
+// NewInboundWebhookResult returns result of the callback by the error returned by the handler or the response status
+func NewInboundWebhookResult(ctx echo.Context, err error) *InboundWebhookResult {
+	result := &InboundWebhookResult{ResponseCode: ctx.Response().Status, ProcessedAt: time.Now()}
+
+	if err != nil {
+		result.ResponseCode = http.StatusInternalServerError
+		result.Error = err.Error()
+
+		if httpErr, ok := err.(*echo.HTTPError); ok {
+			result.ResponseCode = httpErr.Code
+			result.Error = httpErr.Message
+		}
+	}
+
+	return result
+}
+
+// ExtractInboundWebhookContext returns callback of the request stored by the webhook handler middleware
+func ExtractInboundWebhookContext(ctx echo.Context) *InboundWebhook {
+	if w, ok := ctx.Get("inboundWebhook").(*InboundWebhook); ok {
+		return w
+	}
+	return &InboundWebhook{}
+}
+
+// SetInboundWebhookContext
+func SetInboundWebhookContext(ctx echo.Context, w *InboundWebhook) {
+	ctx.Set("inboundWebhook", w)
+}
+
+func copyInboundWebhook(w *InboundWebhook) *InboundWebhook {
+	cp := *w
+
+	cp.Headers = make(map[string]string, len(w.Headers))
+	for name, value := range w.Headers {
+		cp.Headers[name] = value
+	}
+
+	if w.Result != nil {
+		result := *w.Result
+		cp.Result = &result
+	}
+
+	cp.Replays = make([]*InboundWebhookResult, len(w.Replays))
+	for i, r := range w.Replays {
+		replay := *r
+		cp.Replays[i] = &replay
+	}
+
+	return &cp
+}
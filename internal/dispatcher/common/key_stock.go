@@ -0,0 +1,293 @@
+package common
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"sync"
+	"time"
+)
+
+const (
+	keyStockCollection     = "key_stock"
+	keyStockSaleCollection = "key_stock_sale"
+
+	// keyStockSaleTtl limits the sales kept to count each sold key once, callbacks of the order come within days
+	keyStockSaleTtl = 30 * 24 * time.Hour
+)
+
+// KeyStock is a state of the keys stock of the key product platform kept by the api. Billing server reports
+// the available keys only, so the api counts the keys uploaded through it and the keys of the paid orders
+type KeyStock struct {
+	Id           string     `json:"-" bson:"_id"`
+	MerchantId   string     `json:"merchant_id" bson:"merchant_id"`
+	KeyProductId string     `json:"key_product_id" bson:"key_product_id"`
+	PlatformId   string     `json:"platform_id" bson:"platform_id"`
+	Threshold    int32      `json:"threshold" bson:"threshold"`
+	Uploaded     int32      `json:"uploaded" bson:"uploaded"`
+	Sold         int32      `json:"sold" bson:"sold"`
+	LastUploadAt *time.Time `json:"last_upload_at,omitempty" bson:"last_upload_at,omitempty"`
+	LowAlerted   bool       `json:"-" bson:"low_alerted"`
+}
+
+// KeyStockThresholdRequest changes low stock threshold of the key product platform, zero threshold resets it to default
+type KeyStockThresholdRequest struct {
+	Threshold int32 `json:"threshold" validate:"min=0"`
+}
+
+// KeyInventoryItem is a stock of keys of the key product platform, available keys are reported by the billing server,
+// reserved keys are the uploaded keys which are neither available nor sold
+type KeyInventoryItem struct {
+	KeyProductId string     `json:"key_product_id"`
+	PlatformId   string     `json:"platform_id"`
+	PlatformName string     `json:"platform_name"`
+	Available    int32      `json:"available"`
+	Reserved     int32      `json:"reserved"`
+	Sold         int32      `json:"sold"`
+	Uploaded     int32      `json:"uploaded"`
+	Threshold    int32      `json:"threshold"`
+	Low          bool       `json:"low"`
+	LastUploadAt *time.Time `json:"last_upload_at,omitempty"`
+}
+
+// KeyStockStorage keeps thresholds, counters and alerts of the key products stocks,
+// the storage must be shared by the replicas, otherwise each replica alerts the merchant
+type KeyStockStorage interface {
+	// GetKeyStock returns empty stock if the key product platform is unknown
+	GetKeyStock(merchantId, keyProductId, platformId string) (*KeyStock, error)
+	// SetKeyStockThreshold changes threshold of the stock and returns the changed stock
+	SetKeyStockThreshold(merchantId, keyProductId, platformId string, threshold int32) (*KeyStock, error)
+	// AddKeyStockUploaded increases count of the uploaded keys
+	AddKeyStockUploaded(merchantId, keyProductId, platformId string, count int32, at time.Time) error
+	// AddKeyStockSold increases count of the sold keys once for the order
+	AddKeyStockSold(orderId, merchantId, keyProductId, platformId string) error
+	// SetKeyStockLow marks the stock as low or replenished, it returns false if the stock was already marked so
+	SetKeyStockLow(merchantId, keyProductId, platformId string, low bool) (bool, error)
+}
+
+// NewKeyStockStorage returns storage in the database of the session or in the process memory if session is nil
+func NewKeyStockStorage(session *mgo.Session) (KeyStockStorage, error) {
+	if session == nil {
+		return NewKeyStockMemoryStorage(), nil
+	}
+
+	stocks, err := newMongoCollection(session, keyStockCollection, mgo.Index{Key: []string{"merchant_id"}})
+	if err != nil {
+		return nil, err
+	}
+
+	sales, err := newMongoCollection(
+		session,
+		keyStockSaleCollection,
+		mgo.Index{Key: []string{"created_at"}, ExpireAfter: keyStockSaleTtl},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &keyStockMongoStorage{stocks: stocks, sales: sales}, nil
+}
+
+type keyStockSale struct {
+	Id        string    `bson:"_id"`
+	CreatedAt time.Time `bson:"created_at"`
+}
+
+type keyStockMongoStorage struct {
+	stocks *mongoCollection
+	sales  *mongoCollection
+}
+
+// GetKeyStock
+func (s *keyStockMongoStorage) GetKeyStock(merchantId, keyProductId, platformId string) (*KeyStock, error) {
+	stock := &KeyStock{}
+	err := s.stocks.with(func(c *mgo.Collection) error {
+		return c.FindId(keyStockId(merchantId, keyProductId, platformId)).One(stock)
+	})
+
+	if err == mgo.ErrNotFound {
+		return newKeyStock(merchantId, keyProductId, platformId), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return stock, nil
+}
+
+// SetKeyStockThreshold
+func (s *keyStockMongoStorage) SetKeyStockThreshold(merchantId, keyProductId, platformId string, threshold int32) (*KeyStock, error) {
+	stock := &KeyStock{}
+	change := mgo.Change{
+		Update: bson.M{
+			"$set":         bson.M{"threshold": threshold},
+			"$setOnInsert": keyStockInsert(merchantId, keyProductId, platformId),
+		},
+		Upsert:    true,
+		ReturnNew: true,
+	}
+	err := s.stocks.with(func(c *mgo.Collection) error {
+		_, err := c.FindId(keyStockId(merchantId, keyProductId, platformId)).Apply(change, stock)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return stock, nil
+}
+
+// AddKeyStockUploaded
+func (s *keyStockMongoStorage) AddKeyStockUploaded(merchantId, keyProductId, platformId string, count int32, at time.Time) error {
+	return s.stocks.with(func(c *mgo.Collection) error {
+		_, err := c.UpsertId(keyStockId(merchantId, keyProductId, platformId), bson.M{
+			"$inc":         bson.M{"uploaded": count},
+			"$set":         bson.M{"last_upload_at": at},
+			"$setOnInsert": keyStockInsert(merchantId, keyProductId, platformId),
+		})
+		return err
+	})
+}
+
+// AddKeyStockSold counts the sale once since the order may be processed by several callbacks
+func (s *keyStockMongoStorage) AddKeyStockSold(orderId, merchantId, keyProductId, platformId string) error {
+	err := s.sales.with(func(c *mgo.Collection) error {
+		return c.Insert(&keyStockSale{Id: orderId + "|" + keyProductId, CreatedAt: time.Now()})
+	})
+	if mgo.IsDup(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.stocks.with(func(c *mgo.Collection) error {
+		_, err := c.UpsertId(keyStockId(merchantId, keyProductId, platformId), bson.M{
+			"$inc":         bson.M{"sold": 1},
+			"$setOnInsert": keyStockInsert(merchantId, keyProductId, platformId),
+		})
+		return err
+	})
+}
+
+// SetKeyStockLow changes the mark by the conditional update, so only one replica observes the change
+func (s *keyStockMongoStorage) SetKeyStockLow(merchantId, keyProductId, platformId string, low bool) (bool, error) {
+	id := keyStockId(merchantId, keyProductId, platformId)
+	changed := false
+
+	err := s.stocks.with(func(c *mgo.Collection) error {
+		_, err := c.UpsertId(id, bson.M{"$setOnInsert": keyStockInsert(merchantId, keyProductId, platformId)})
+		if err != nil {
+			return err
+		}
+
+		err = c.Update(bson.M{"_id": id, "low_alerted": bson.M{"$ne": low}}, bson.M{"$set": bson.M{"low_alerted": low}})
+		if err == mgo.ErrNotFound {
+			return nil
+		}
+		changed = err == nil
+		return err
+	})
+
+	return changed, err
+}
+
+type keyStockMemoryStorage struct {
+	mx     sync.Mutex
+	stocks map[string]*KeyStock
+	sales  map[string]bool
+}
+
+// NewKeyStockMemoryStorage returns storage keeping stocks of the key products in the process memory
+func NewKeyStockMemoryStorage() KeyStockStorage {
+	return &keyStockMemoryStorage{
+		stocks: make(map[string]*KeyStock),
+		sales:  make(map[string]bool),
+	}
+}
+
+// GetKeyStock
+func (s *keyStockMemoryStorage) GetKeyStock(merchantId, keyProductId, platformId string) (*KeyStock, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	cp := *s.stock(merchantId, keyProductId, platformId)
+	return &cp, nil
+}
+
+// SetKeyStockThreshold
+func (s *keyStockMemoryStorage) SetKeyStockThreshold(merchantId, keyProductId, platformId string, threshold int32) (*KeyStock, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	stock := s.stock(merchantId, keyProductId, platformId)
+	stock.Threshold = threshold
+
+	cp := *stock
+	return &cp, nil
+}
+
+// AddKeyStockUploaded
+func (s *keyStockMemoryStorage) AddKeyStockUploaded(merchantId, keyProductId, platformId string, count int32, at time.Time) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	stock := s.stock(merchantId, keyProductId, platformId)
+	stock.Uploaded += count
+	stock.LastUploadAt = &at
+	return nil
+}
+
+// AddKeyStockSold
+func (s *keyStockMemoryStorage) AddKeyStockSold(orderId, merchantId, keyProductId, platformId string) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if s.sales[orderId+"|"+keyProductId] {
+		return nil
+	}
+	s.sales[orderId+"|"+keyProductId] = true
+
+	s.stock(merchantId, keyProductId, platformId).Sold++
+	return nil
+}
+
+// SetKeyStockLow
+func (s *keyStockMemoryStorage) SetKeyStockLow(merchantId, keyProductId, platformId string, low bool) (bool, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	stock := s.stock(merchantId, keyProductId, platformId)
+	if stock.LowAlerted == low {
+		return false, nil
+	}
+	stock.LowAlerted = low
+	return true, nil
+}
+
+// stock returns stock of the key product platform, the unknown stock is created
+func (s *keyStockMemoryStorage) stock(merchantId, keyProductId, platformId string) *KeyStock {
+	id := keyStockId(merchantId, keyProductId, platformId)
+	stock, ok := s.stocks[id]
+	if !ok {
+		stock = newKeyStock(merchantId, keyProductId, platformId)
+		s.stocks[id] = stock
+	}
+	return stock
+}
+
+func newKeyStock(merchantId, keyProductId, platformId string) *KeyStock {
+	return &KeyStock{
+		Id:           keyStockId(merchantId, keyProductId, platformId),
+		MerchantId:   merchantId,
+		KeyProductId: keyProductId,
+		PlatformId:   platformId,
+	}
+}
+
+func keyStockId(merchantId, keyProductId, platformId string) string {
+	return merchantId + "|" + keyProductId + "|" + platformId
+}
+
+func keyStockInsert(merchantId, keyProductId, platformId string) bson.M {
+	return bson.M{"merchant_id": merchantId, "key_product_id": keyProductId, "platform_id": platformId}
+}
@@ -1,5 +1,25 @@
 package common
 
+import (
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
+	"strings"
+)
+
+const (
+	maskedValue = "***"
+	// panVisibleLength is a number of the last digits of the card number kept in the logs
+	panVisibleLength = 4
+)
+
+// paymentCardFields are fields of the payment form which must not be logged
+var paymentCardFields = []string{
+	pkg.PaymentCreateFieldCvv,
+	pkg.PaymentCreateFieldMonth,
+	pkg.PaymentCreateFieldYear,
+	pkg.PaymentCreateFieldHolder,
+}
+
 // RequestResponseHeadersToString
 func RequestResponseHeadersToString(headers map[string][]string) string {
 	var out string
@@ -8,3 +28,34 @@ func RequestResponseHeadersToString(headers map[string][]string) string {
 	}
 	return out
 }
+
+// MaskPaymentCreateRequest returns copy of the request safe for logging, the card number keeps only its last digits
+// and the other card fields are hidden
+func MaskPaymentCreateRequest(req *grpc.PaymentCreateRequest) *grpc.PaymentCreateRequest {
+	cp := *req
+	cp.Data = make(map[string]string, len(req.Data))
+
+	for k, v := range req.Data {
+		cp.Data[k] = v
+	}
+
+	if pan, ok := cp.Data[pkg.PaymentCreateFieldPan]; ok {
+		cp.Data[pkg.PaymentCreateFieldPan] = maskPan(pan)
+	}
+
+	for _, field := range paymentCardFields {
+		if _, ok := cp.Data[field]; ok {
+			cp.Data[field] = maskedValue
+		}
+	}
+
+	return &cp
+}
+
+func maskPan(pan string) string {
+	pan = strings.TrimSpace(pan)
+	if len(pan) <= panVisibleLength {
+		return maskedValue
+	}
+	return maskedValue + pan[len(pan)-panVisibleLength:]
+}
@@ -0,0 +1,54 @@
+package common
+
+import (
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type LoggerTestSuite struct {
+	suite.Suite
+}
+
+func Test_Logger(t *testing.T) {
+	suite.Run(t, new(LoggerTestSuite))
+}
+
+func (suite *LoggerTestSuite) SetupTest() {}
+
+func (suite *LoggerTestSuite) TearDownTest() {}
+
+func (suite *LoggerTestSuite) TestLogger_MaskPaymentCreateRequest() {
+	req := &grpc.PaymentCreateRequest{
+		Data: map[string]string{
+			pkg.PaymentCreateFieldOrderId: "order",
+			pkg.PaymentCreateFieldPan:     "4000000000000002",
+			pkg.PaymentCreateFieldCvv:     "123",
+			pkg.PaymentCreateFieldMonth:   "02",
+			pkg.PaymentCreateFieldYear:    "2030",
+			pkg.PaymentCreateFieldHolder:  "Mr. Card Holder",
+		},
+		Ip: "127.0.0.1",
+	}
+
+	masked := MaskPaymentCreateRequest(req)
+
+	assert.Equal(suite.T(), "order", masked.Data[pkg.PaymentCreateFieldOrderId])
+	assert.Equal(suite.T(), "***0002", masked.Data[pkg.PaymentCreateFieldPan])
+	assert.Equal(suite.T(), maskedValue, masked.Data[pkg.PaymentCreateFieldCvv])
+	assert.Equal(suite.T(), maskedValue, masked.Data[pkg.PaymentCreateFieldMonth])
+	assert.Equal(suite.T(), maskedValue, masked.Data[pkg.PaymentCreateFieldYear])
+	assert.Equal(suite.T(), maskedValue, masked.Data[pkg.PaymentCreateFieldHolder])
+	assert.Equal(suite.T(), req.Ip, masked.Ip)
+
+	// the request sent to the billing is not changed
+	assert.Equal(suite.T(), "4000000000000002", req.Data[pkg.PaymentCreateFieldPan])
+	assert.Equal(suite.T(), "123", req.Data[pkg.PaymentCreateFieldCvv])
+}
+
+func (suite *LoggerTestSuite) TestLogger_MaskPaymentCreateRequest_ShortPan() {
+	req := &grpc.PaymentCreateRequest{Data: map[string]string{pkg.PaymentCreateFieldPan: "400"}}
+	assert.Equal(suite.T(), maskedValue, MaskPaymentCreateRequest(req).Data[pkg.PaymentCreateFieldPan])
+}
@@ -0,0 +1,32 @@
+package common
+
+import (
+	"github.com/globalsign/mgo"
+)
+
+// mongoCollection opens the collection in the copy of the shared session for each operation,
+// so the operations of the concurrent requests don't wait for each other on the single socket
+type mongoCollection struct {
+	session *mgo.Session
+	name    string
+}
+
+// newMongoCollection ensures indexes of the collection
+func newMongoCollection(session *mgo.Session, name string, indexes ...mgo.Index) (*mongoCollection, error) {
+	c := &mongoCollection{session: session, name: name}
+
+	for _, index := range indexes {
+		if err := c.with(func(col *mgo.Collection) error { return col.EnsureIndex(index) }); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+func (c *mongoCollection) with(fn func(col *mgo.Collection) error) error {
+	s := c.session.Copy()
+	defer s.Close()
+
+	return fn(s.DB("").C(c.name))
+}
@@ -0,0 +1,23 @@
+package common
+
+const (
+	OrderEventsPath      = "/orders/:order_id/events"
+	OrderEventTypeStatus = "order.status"
+	OrderStatusProcessed = "processed"
+
+	HeaderCacheControl    = "Cache-Control"
+	HeaderXAccelBuffering = "X-Accel-Buffering"
+	MIMETextEventStream   = "text/event-stream"
+)
+
+// EventStreamPaths are the routes streaming responses until the client disconnects,
+// the responses of such routes aren't buffered by the middlewares
+var EventStreamPaths = map[string]bool{
+	AuthProjectGroupPath + OrderEventsPath: true,
+}
+
+// OrderStatusEvent is a data of the event published when status of the order is changed
+type OrderStatusEvent struct {
+	OrderId string `json:"order_id"`
+	Status  string `json:"status"`
+}
@@ -0,0 +1,294 @@
+package common
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/paysuper/paysuper-management-api/pkg/keyset"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// PageCursor is a position in the list encoded to the opaque next_cursor and prev_cursor tokens.
+// The lists kept by the api are keyset lists, their cursor keeps the creation time and the id of the edge item
+// of the page, so the new items don't shift the pages. Billing services accept limit and offset only,
+// so the cursor of their lists is an offset cursor keeping the sort, the limit and the offset of the page
+type PageCursor struct {
+	Sort     []string `json:"s,omitempty"`
+	Limit    int32    `json:"l"`
+	Offset   int32    `json:"o"`
+	Key      int64    `json:"k,omitempty"`
+	LastId   string   `json:"i,omitempty"`
+	Backward bool     `json:"b,omitempty"`
+}
+
+// Position returns position of the keyset cursor or nil for the offset cursor
+func (c *PageCursor) Position() *keyset.Position {
+	if c.LastId == "" {
+		return nil
+	}
+
+	return &keyset.Position{CreatedAt: time.Unix(0, c.Key).UTC(), Id: c.LastId, Backward: c.Backward}
+}
+
+// Page describes the returned page of the list to build the cursors of the neighbour pages
+type Page struct {
+	Cursor   *Cursor
+	Count    int64
+	Returned int
+	// Keyset is set for the list of keyset.Item, the cursors of such list keep positions of the edge items
+	Keyset      bool
+	First, Last keyset.Position
+}
+
+var keysetItemType = reflect.TypeOf((*keyset.Item)(nil)).Elem()
+
+// NewPage returns page of the returned items, items must be a slice
+func NewPage(cursor *Cursor, count int64, items interface{}) *Page {
+	page := &Page{Cursor: cursor, Count: count}
+
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Slice {
+		return page
+	}
+
+	page.Returned = v.Len()
+	page.Keyset = v.Type().Elem().Implements(keysetItemType)
+
+	if page.Keyset && page.Returned > 0 {
+		page.First = v.Index(0).Interface().(keyset.Item).Position()
+		page.Last = v.Index(page.Returned - 1).Interface().(keyset.Item).Position()
+	}
+
+	return page
+}
+
+// EncodeCursor returns signed token of the cursor
+func EncodeCursor(secret string, cursor *PageCursor) string {
+	b, _ := json.Marshal(cursor)
+	payload := base64.RawURLEncoding.EncodeToString(b)
+	return payload + "." + cursorSignature(secret, payload)
+}
+
+// DecodeCursor returns cursor of the token, tokens with the wrong signature are rejected
+func DecodeCursor(secret, token string) (*PageCursor, error) {
+	parts := strings.Split(token, ".")
+
+	if len(parts) != 2 || !hmac.Equal([]byte(parts[1]), []byte(cursorSignature(secret, parts[0]))) {
+		return nil, ErrorMessagePaginationCursorInvalid
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrorMessagePaginationCursorInvalid
+	}
+
+	cursor := &PageCursor{}
+	if err = json.Unmarshal(b, cursor); err != nil || cursor.Limit < 0 || cursor.Offset < 0 {
+		return nil, ErrorMessagePaginationCursorInvalid
+	}
+
+	return cursor, nil
+}
+
+func cursorSignature(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// SetPaginationLinks writes next_cursor and prev_cursor of the page to the response headers
+// and the Link header with the urls of the neighbour pages (RFC 8288)
+func SetPaginationLinks(ctx echo.Context, secret string, page *Page) {
+	cursor := page.Cursor
+	if cursor == nil || cursor.Limit <= 0 {
+		return
+	}
+
+	if page.Keyset {
+		setKeysetPaginationLinks(ctx, secret, page)
+		return
+	}
+
+	var links []string
+
+	next := int64(cursor.Offset) + int64(page.Returned)
+	if page.Returned >= int(cursor.Limit) && next < page.Count {
+		token := EncodeCursor(secret, &PageCursor{
+			Sort:   cursor.Sort,
+			Limit:  cursor.Limit,
+			Offset: int32(next),
+		})
+		ctx.Response().Header().Set(HeaderNextCursor, token)
+		links = append(links, paginationLink(ctx, token, "next"))
+	}
+
+	if cursor.Offset > 0 {
+		prev := cursor.Offset - cursor.Limit
+		if prev < 0 {
+			prev = 0
+		}
+		token := EncodeCursor(secret, &PageCursor{
+			Sort:   cursor.Sort,
+			Limit:  cursor.Limit,
+			Offset: prev,
+		})
+		ctx.Response().Header().Set(HeaderPrevCursor, token)
+		links = append(links, paginationLink(ctx, token, "prev"))
+	}
+
+	if len(links) > 0 {
+		ctx.Response().Header().Set(HeaderLink, strings.Join(links, ", "))
+	}
+}
+
+// setKeysetPaginationLinks writes the cursors of the neighbour pages of the keyset list
+func setKeysetPaginationLinks(ctx echo.Context, secret string, page *Page) {
+	cursor := page.Cursor
+	if page.Returned == 0 {
+		return
+	}
+
+	full := page.Returned >= int(cursor.Limit)
+	hasNext, hasPrev := full, cursor.Position != nil || cursor.Offset > 0
+
+	// the backward page is requested from the next page, so the next page exists,
+	// the previous page exists if the backward page is full
+	if cursor.Position != nil && cursor.Position.Backward {
+		hasNext, hasPrev = true, full
+	}
+
+	var links []string
+
+	if hasNext {
+		token := EncodeCursor(secret, &PageCursor{
+			Sort:   cursor.Sort,
+			Limit:  cursor.Limit,
+			Key:    page.Last.CreatedAt.UnixNano(),
+			LastId: page.Last.Id,
+		})
+		ctx.Response().Header().Set(HeaderNextCursor, token)
+		links = append(links, paginationLink(ctx, token, "next"))
+	}
+
+	if hasPrev {
+		token := EncodeCursor(secret, &PageCursor{
+			Sort:     cursor.Sort,
+			Limit:    cursor.Limit,
+			Key:      page.First.CreatedAt.UnixNano(),
+			LastId:   page.First.Id,
+			Backward: true,
+		})
+		ctx.Response().Header().Set(HeaderPrevCursor, token)
+		links = append(links, paginationLink(ctx, token, "prev"))
+	}
+
+	if len(links) > 0 {
+		ctx.Response().Header().Set(HeaderLink, strings.Join(links, ", "))
+	}
+}
+
+func paginationLink(ctx echo.Context, token, rel string) string {
+	u := *ctx.Request().URL
+	query := u.Query()
+	query.Del(QueryParameterNameLimit)
+	query.Del(QueryParameterNameOffset)
+	query.Del(QueryParameterNameSort)
+	query.Set(QueryParameterNameCursor, token)
+	u.RawQuery = query.Encode()
+
+	return fmt.Sprintf(`<%s>; rel="%s"`, u.RequestURI(), rel)
+}
+
+// PaginationBinder binds request with the default binder and sets limit, offset and sort
+// of the request structure from the cursor of the request context
+type PaginationBinder struct {
+	LimitDefault, OffsetDefault, LimitMax int32
+}
+
+// NewPaginationBinder
+func NewPaginationBinder(cfg Config) *PaginationBinder {
+	return &PaginationBinder{
+		LimitDefault:  cfg.LimitDefault,
+		OffsetDefault: cfg.OffsetDefault,
+		LimitMax:      cfg.LimitMax,
+	}
+}
+
+// Bind
+func (b *PaginationBinder) Bind(i interface{}, ctx echo.Context) error {
+	db := new(echo.DefaultBinder)
+	if err := db.Bind(i, ctx); err != nil {
+		return err
+	}
+
+	return b.Apply(i, ctx)
+}
+
+// Apply sets pagination fields of the request structure without binding of other fields
+func (b *PaginationBinder) Apply(i interface{}, ctx echo.Context) error {
+	cursor := b.Cursor(ctx)
+
+	v := reflect.ValueOf(i)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	v = v.Elem()
+
+	if err := setPaginationInt(v.FieldByName("Limit"), cursor.Limit); err != nil {
+		return err
+	}
+
+	if err := setPaginationInt(v.FieldByName("Offset"), cursor.Offset); err != nil {
+		return err
+	}
+
+	sort := v.FieldByName("Sort")
+	if cursor.Token != "" && sort.IsValid() && sort.CanSet() && sort.Type() == reflect.TypeOf([]string{}) {
+		sort.Set(reflect.ValueOf(cursor.Sort))
+	}
+
+	return nil
+}
+
+// Cursor returns cursor of the request context with the defaults of the binder applied
+func (b *PaginationBinder) Cursor(ctx echo.Context) *Cursor {
+	cursor := *ExtractCursorContext(ctx)
+
+	if cursor.Limit <= 0 {
+		cursor.Limit = b.LimitDefault
+	}
+
+	if b.LimitMax > 0 && cursor.Limit > b.LimitMax {
+		cursor.Limit = b.LimitMax
+	}
+
+	if cursor.Offset < 0 {
+		cursor.Offset = b.OffsetDefault
+	}
+
+	SetCursorContext(ctx, &cursor)
+
+	return &cursor
+}
+
+func setPaginationInt(field reflect.Value, value int32) error {
+	if !field.IsValid() || !field.CanSet() {
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		field.SetInt(int64(value))
+	case reflect.Uint, reflect.Uint32, reflect.Uint64:
+		field.SetUint(uint64(value))
+	default:
+		return fmt.Errorf("pagination field of type %s is not supported", field.Kind())
+	}
+
+	return nil
+}
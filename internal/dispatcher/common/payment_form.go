@@ -0,0 +1,82 @@
+package common
+
+import (
+	"html/template"
+	"path/filepath"
+)
+
+const (
+	PaymentMethodGroupBankCard = "BANKCARD"
+	PaymentMethodGroupQiwi     = "QIWI"
+	PaymentMethodGroupWebMoney = "WEBMONEY"
+	PaymentMethodGroupBitcoin  = "BITCOIN"
+	PaymentMethodGroupAlipay   = "ALIPAY"
+
+	paymentFormLayoutName       = "payment_form"
+	paymentFormTemplateNameMask = "payment_form:"
+)
+
+// PaymentFormTemplates is a registry of the payment form templates by the group alias of the payment method,
+// each template defines "payment_method" rendered within the shared layout
+var PaymentFormTemplates = map[string]string{
+	PaymentMethodGroupBankCard: "bank_card.html",
+	PaymentMethodGroupQiwi:     "qiwi.html",
+	PaymentMethodGroupWebMoney: "webmoney.html",
+	PaymentMethodGroupBitcoin:  "bitcoin.html",
+	PaymentMethodGroupAlipay:   "alipay.html",
+}
+
+// PaymentFormMethod is a payment method of the server side payment form
+type PaymentFormMethod struct {
+	Id    string
+	Name  string
+	Group string
+	Url   string
+}
+
+// PaymentForm is a data of the server side payment form of the order
+type PaymentForm struct {
+	OrderId     string
+	ProjectName string
+	Amount      float64
+	Currency    string
+	ActionUrl   string
+	Method      *PaymentFormMethod
+	Methods     []*PaymentFormMethod
+	Theme       *ProjectTheme
+}
+
+// PaymentFormTemplateName returns name of the payment form template of the payment method group
+func PaymentFormTemplateName(group string) string {
+	return paymentFormTemplateNameMask + group
+}
+
+// ParsePaymentForms parses the payment form of each registered payment method group with the layout
+// from the layout subdirectory, the forms share templates of the base
+func ParsePaymentForms(base *template.Template, dir string) (map[string]*template.Template, error) {
+	layout, err := base.Clone()
+	if err != nil {
+		return nil, err
+	}
+
+	if layout, err = layout.ParseGlob(filepath.Join(dir, "layout", "*.html")); err != nil {
+		return nil, err
+	}
+
+	forms := make(map[string]*template.Template, len(PaymentFormTemplates))
+
+	for group, file := range PaymentFormTemplates {
+		form, err := layout.Clone()
+		if err != nil {
+			return nil, err
+		}
+
+		if form, err = form.ParseFiles(filepath.Join(dir, file)); err != nil {
+			return nil, err
+		}
+
+		forms[PaymentFormTemplateName(group)] = form
+	}
+
+	return forms, nil
+}
@@ -0,0 +1,155 @@
+package common
+
+import (
+	"github.com/globalsign/mgo"
+	"html/template"
+	"sync"
+	"time"
+)
+
+// ProjectThemeLink is a link in the footer of the payment form
+type ProjectThemeLink struct {
+	Title string `json:"title" validate:"required,max=100"`
+	Url   string `json:"url" validate:"required,url,max=2048"`
+}
+
+// ProjectTheme is a branding of the hosted payment form of the project
+type ProjectTheme struct {
+	ProjectId       string              `json:"project_id" bson:"_id"`
+	LogoUrl         string              `json:"logo_url" validate:"omitempty,url,max=2048"`
+	PrimaryColor    string              `json:"primary_color" validate:"omitempty,hexcolor"`
+	BackgroundColor string              `json:"background_color" validate:"omitempty,hexcolor"`
+	TextColor       string              `json:"text_color" validate:"omitempty,hexcolor"`
+	FontFamily      string              `json:"font_family" validate:"omitempty,max=100,excludesall=\"'();/@[\\]{}<>"`
+	FontUrl         string              `json:"font_url" validate:"omitempty,url,max=2048"`
+	CustomCss       string              `json:"custom_css" validate:"omitempty,max=20000,excludes=<"`
+	FooterLinks     []*ProjectThemeLink `json:"footer_links" validate:"max=10,dive"`
+	UpdatedAt       *time.Time          `json:"updated_at,omitempty"`
+}
+
+// CustomStyles returns custom css of the theme inserted into the payment form as is,
+// the css can't close the style element since it doesn't contain "<"
+func (t *ProjectTheme) CustomStyles() template.CSS {
+	return template.CSS(t.CustomCss)
+}
+
+// Urls returns all urls of the theme
+func (t *ProjectTheme) Urls() []string {
+	urls := []string{t.LogoUrl, t.FontUrl}
+	for _, link := range t.FooterLinks {
+		urls = append(urls, link.Url)
+	}
+	return urls
+}
+
+// ProjectThemeStorage keeps themes of the projects
+type ProjectThemeStorage interface {
+	// GetTheme returns nil if the project has no theme
+	GetTheme(projectId string) (*ProjectTheme, error)
+	// SaveTheme creates or replaces theme of the project
+	SaveTheme(theme *ProjectTheme) error
+	// DeleteTheme
+	DeleteTheme(projectId string) error
+}
+
+const (
+	projectThemeCollection = "project_theme"
+)
+
+// NewProjectThemeStorage returns storage in the database of the session or in the process memory if session is nil
+func NewProjectThemeStorage(session *mgo.Session) (ProjectThemeStorage, error) {
+	if session == nil {
+		return NewProjectThemeMemoryStorage(), nil
+	}
+
+	c, err := newMongoCollection(session, projectThemeCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	return &projectThemeMongoStorage{themes: c}, nil
+}
+
+type projectThemeMongoStorage struct {
+	themes *mongoCollection
+}
+
+// GetTheme
+func (s *projectThemeMongoStorage) GetTheme(projectId string) (*ProjectTheme, error) {
+	theme := &ProjectTheme{}
+	err := s.themes.with(func(c *mgo.Collection) error { return c.FindId(projectId).One(theme) })
+
+	if err == mgo.ErrNotFound {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return theme, nil
+}
+
+// SaveTheme
+func (s *projectThemeMongoStorage) SaveTheme(theme *ProjectTheme) error {
+	return s.themes.with(func(c *mgo.Collection) error {
+		_, err := c.UpsertId(theme.ProjectId, theme)
+		return err
+	})
+}
+
+// DeleteTheme
+func (s *projectThemeMongoStorage) DeleteTheme(projectId string) error {
+	err := s.themes.with(func(c *mgo.Collection) error { return c.RemoveId(projectId) })
+
+	if err == mgo.ErrNotFound {
+		return nil
+	}
+
+	return err
+}
+
+type projectThemeMemoryStorage struct {
+	mx     sync.Mutex
+	themes map[string]*ProjectTheme
+}
+
+// NewProjectThemeMemoryStorage returns storage keeping themes of the projects in the process memory
+func NewProjectThemeMemoryStorage() ProjectThemeStorage {
+	return &projectThemeMemoryStorage{
+		themes: make(map[string]*ProjectTheme),
+	}
+}
+
+// GetTheme
+func (s *projectThemeMemoryStorage) GetTheme(projectId string) (*ProjectTheme, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	theme, ok := s.themes[projectId]
+	if !ok {
+		return nil, nil
+	}
+
+	cp := *theme
+	return &cp, nil
+}
+
+// SaveTheme
+func (s *projectThemeMemoryStorage) SaveTheme(theme *ProjectTheme) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	cp := *theme
+	s.themes[theme.ProjectId] = &cp
+	return nil
+}
+
+// DeleteTheme
+func (s *projectThemeMemoryStorage) DeleteTheme(projectId string) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	delete(s.themes, projectId)
+	return nil
+}
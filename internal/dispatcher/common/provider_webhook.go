@@ -0,0 +1,129 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrProviderWebhookTypeUnknown = errors.New("callback type of the provider is unknown")
+)
+
+// ProviderWebhookRoute is a path of the callbacks of the type in the webhook group
+type ProviderWebhookRoute struct {
+	Path string
+	Type string
+}
+
+// ProviderWebhook is a callback parsed by the adapter of the payment provider
+type ProviderWebhook struct {
+	Type    string
+	OrderId string
+	// Signature is set by the providers sending signature in the body, the signature header is used otherwise
+	Signature string
+	// Payload is a callback of the provider validated by the api before sending to the billing server
+	Payload interface{}
+}
+
+// ProviderWebhookSignature is a signature of the callback extracted before the body is parsed
+type ProviderWebhookSignature struct {
+	// Algorithm is a default signature algorithm of the provider
+	Algorithm string
+	// Value is a hex or base64 encoded signature
+	Value string
+	// Content is a signed content, the digest algorithms put the secret between Content and ContentSuffix
+	Content       []byte
+	ContentSuffix []byte
+	// Timestamp is a time the callback was sent at, it's zero if the provider doesn't send it
+	Timestamp time.Time
+}
+
+// ProviderWebhookAdapter converts callbacks of the payment provider to the requests of the billing server,
+// new provider is added by the adapter registered in NewDefaultProviderWebhookRegistry
+type ProviderWebhookAdapter interface {
+	// Provider returns name of the provider the callbacks are stored with
+	Provider() string
+	// Routes returns paths of the callbacks of the provider
+	Routes() []*ProviderWebhookRoute
+	// SignatureHeader returns name of the header with the signature, it's empty if the signature is in the body
+	SignatureHeader() string
+	// Parse unmarshals body of the callback, it returns ErrProviderWebhookTypeUnknown for unsupported types
+	Parse(typ string, body []byte) (*ProviderWebhook, error)
+	// Signature returns signature of the callback verified by the api before the body is parsed
+	Signature(typ string, header http.Header, body []byte) (*ProviderWebhookSignature, error)
+	// Request maps validated callback to the request of the billing server
+	Request(w *ProviderWebhook, body []byte, signature string) *grpc.CallbackRequest
+}
+
+// ProviderWebhookRegistry keeps adapters of the payment providers, it's filled on start and read only after that
+type ProviderWebhookRegistry struct {
+	adapters []ProviderWebhookAdapter
+	names    map[string]ProviderWebhookAdapter
+	paths    map[string]string
+}
+
+// NewProviderWebhookRegistry returns empty registry
+func NewProviderWebhookRegistry() *ProviderWebhookRegistry {
+	return &ProviderWebhookRegistry{
+		names: make(map[string]ProviderWebhookAdapter),
+		paths: make(map[string]string),
+	}
+}
+
+// NewDefaultProviderWebhookRegistry returns registry with adapters of the supported providers, CardPay is handled
+// by the billing server with pkg.PaymentSystemHandlerCardPay, the other providers are added if the handlers
+// of their payment systems are given, so their callbacks aren't sent to the billing server which can't process them
+func NewDefaultProviderWebhookRegistry(handlers map[string]string) *ProviderWebhookRegistry {
+	r := NewProviderWebhookRegistry()
+	adapters := []ProviderWebhookAdapter{&CardPayWebhookAdapter{}}
+
+	if handler := handlers[ProviderQiwi]; handler != "" {
+		adapters = append(adapters, &QiwiWebhookAdapter{Handler: handler})
+	}
+
+	if handler := handlers[ProviderWebMoney]; handler != "" {
+		adapters = append(adapters, &WebMoneyWebhookAdapter{Handler: handler})
+	}
+
+	for _, a := range adapters {
+		if err := r.Register(a); err != nil {
+			panic(err)
+		}
+	}
+
+	return r
+}
+
+// Register adds adapter, name of the provider and paths of its callbacks must be unique
+func (r *ProviderWebhookRegistry) Register(a ProviderWebhookAdapter) error {
+	if _, ok := r.names[a.Provider()]; ok {
+		return fmt.Errorf("webhook adapter of provider %s already registered", a.Provider())
+	}
+
+	for _, route := range a.Routes() {
+		if provider, ok := r.paths[route.Path]; ok {
+			return fmt.Errorf("webhook path %s of provider %s already used by provider %s", route.Path, a.Provider(), provider)
+		}
+	}
+
+	for _, route := range a.Routes() {
+		r.paths[route.Path] = a.Provider()
+	}
+
+	r.names[a.Provider()] = a
+	r.adapters = append(r.adapters, a)
+	return nil
+}
+
+// Get returns nil if the provider is unknown
+func (r *ProviderWebhookRegistry) Get(provider string) ProviderWebhookAdapter {
+	return r.names[provider]
+}
+
+// Adapters returns adapters in the order of registration
+func (r *ProviderWebhookRegistry) Adapters() []ProviderWebhookAdapter {
+	return r.adapters
+}
@@ -0,0 +1,87 @@
+package common
+
+import (
+	"encoding/json"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/billing"
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
+	"net/http"
+)
+
+const (
+	ProviderCardPay = "cardpay"
+
+	CardPayWebhookPaymentPath = "/cardpay/payment"
+	CardPayWebhookRefundPath  = "/cardpay/refund"
+)
+
+// CardPayWebhookAdapter
+type CardPayWebhookAdapter struct{}
+
+// Provider
+func (a *CardPayWebhookAdapter) Provider() string {
+	return ProviderCardPay
+}
+
+// Routes
+func (a *CardPayWebhookAdapter) Routes() []*ProviderWebhookRoute {
+	return []*ProviderWebhookRoute{
+		{Path: CardPayWebhookPaymentPath, Type: InboundWebhookTypePayment},
+		{Path: CardPayWebhookRefundPath, Type: InboundWebhookTypeRefund},
+	}
+}
+
+// SignatureHeader
+func (a *CardPayWebhookAdapter) SignatureHeader() string {
+	return CardPayPaymentResponseHeaderSignature
+}
+
+// Parse
+func (a *CardPayWebhookAdapter) Parse(typ string, body []byte) (*ProviderWebhook, error) {
+	w := &ProviderWebhook{Type: typ}
+
+	switch typ {
+	case InboundWebhookTypePayment:
+		st := &billing.CardPayPaymentCallback{}
+		if err := json.Unmarshal(body, st); err != nil {
+			return nil, err
+		}
+
+		if st.MerchantOrder != nil {
+			w.OrderId = st.MerchantOrder.Id
+		}
+		w.Payload = st
+	case InboundWebhookTypeRefund:
+		st := &billing.CardPayRefundCallback{}
+		if err := json.Unmarshal(body, st); err != nil {
+			return nil, err
+		}
+
+		if st.MerchantOrder != nil {
+			w.OrderId = st.MerchantOrder.Id
+		}
+		w.Payload = st
+	default:
+		return nil, ErrProviderWebhookTypeUnknown
+	}
+
+	return w, nil
+}
+
+// Signature returns SHA-512 digest of the body with the callback secret of the project appended
+func (a *CardPayWebhookAdapter) Signature(typ string, header http.Header, body []byte) (*ProviderWebhookSignature, error) {
+	return &ProviderWebhookSignature{
+		Algorithm: WebhookSignatureSha512,
+		Value:     header.Get(CardPayPaymentResponseHeaderSignature),
+		Content:   body,
+	}, nil
+}
+
+// Request
+func (a *CardPayWebhookAdapter) Request(w *ProviderWebhook, body []byte, signature string) *grpc.CallbackRequest {
+	return &grpc.CallbackRequest{
+		Handler:   pkg.PaymentSystemHandlerCardPay,
+		Body:      body,
+		Signature: signature,
+	}
+}
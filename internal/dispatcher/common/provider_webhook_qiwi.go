@@ -0,0 +1,178 @@
+package common
+
+import (
+	"encoding/json"
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
+	"net/http"
+	"strings"
+)
+
+const (
+	ProviderQiwi               = "qiwi"
+	QiwiWebhookSignatureHeader = "X-Api-Signature-SHA256"
+
+	QiwiWebhookPaymentPath = "/qiwi/payment"
+	QiwiWebhookRefundPath  = "/qiwi/refund"
+
+	qiwiSignatureFieldSeparator = "|"
+)
+
+// QiwiAmount
+type QiwiAmount struct {
+	Value    string `json:"value" validate:"required,numeric"`
+	Currency string `json:"currency" validate:"required,len=3"`
+}
+
+// QiwiStatus
+type QiwiStatus struct {
+	Value           string `json:"value" validate:"required"`
+	ChangedDateTime string `json:"changedDateTime"`
+}
+
+// QiwiBill is a bill of the order, identifier of the bill is identifier of the order
+type QiwiBill struct {
+	SiteId             string      `json:"siteId" validate:"required"`
+	BillId             string      `json:"billId" validate:"required"`
+	Amount             *QiwiAmount `json:"amount" validate:"required"`
+	Status             *QiwiStatus `json:"status" validate:"required"`
+	Comment            string      `json:"comment"`
+	CreationDateTime   string      `json:"creationDateTime"`
+	ExpirationDateTime string      `json:"expirationDateTime"`
+}
+
+// QiwiPaymentCallback is a notification of the bill status change
+type QiwiPaymentCallback struct {
+	Bill    *QiwiBill `json:"bill" validate:"required"`
+	Version string    `json:"version" validate:"required"`
+}
+
+// QiwiRefund
+type QiwiRefund struct {
+	RefundId string      `json:"refundId" validate:"required"`
+	BillId   string      `json:"billId" validate:"required"`
+	Amount   *QiwiAmount `json:"amount" validate:"required"`
+	Status   *QiwiStatus `json:"status" validate:"required"`
+	DateTime string      `json:"dateTime"`
+}
+
+// QiwiRefundCallback is a notification of the refund status change
+type QiwiRefundCallback struct {
+	Refund  *QiwiRefund `json:"refund" validate:"required"`
+	Type    string      `json:"type" validate:"omitempty,eq=REFUND"`
+	Version string      `json:"version" validate:"required"`
+}
+
+// QiwiWebhookAdapter
+type QiwiWebhookAdapter struct {
+	// Handler is a name of the payment system handler of the billing server processing the callbacks
+	Handler string
+}
+
+// Provider
+func (a *QiwiWebhookAdapter) Provider() string {
+	return ProviderQiwi
+}
+
+// Routes
+func (a *QiwiWebhookAdapter) Routes() []*ProviderWebhookRoute {
+	return []*ProviderWebhookRoute{
+		{Path: QiwiWebhookPaymentPath, Type: InboundWebhookTypePayment},
+		{Path: QiwiWebhookRefundPath, Type: InboundWebhookTypeRefund},
+	}
+}
+
+// SignatureHeader
+func (a *QiwiWebhookAdapter) SignatureHeader() string {
+	return QiwiWebhookSignatureHeader
+}
+
+// Parse
+func (a *QiwiWebhookAdapter) Parse(typ string, body []byte) (*ProviderWebhook, error) {
+	w := &ProviderWebhook{Type: typ}
+
+	switch typ {
+	case InboundWebhookTypePayment:
+		st := &QiwiPaymentCallback{}
+		if err := json.Unmarshal(body, st); err != nil {
+			return nil, err
+		}
+
+		if st.Bill != nil {
+			w.OrderId = st.Bill.BillId
+		}
+		w.Payload = st
+	case InboundWebhookTypeRefund:
+		st := &QiwiRefundCallback{}
+		if err := json.Unmarshal(body, st); err != nil {
+			return nil, err
+		}
+
+		if st.Refund != nil {
+			w.OrderId = st.Refund.BillId
+		}
+		w.Payload = st
+	default:
+		return nil, ErrProviderWebhookTypeUnknown
+	}
+
+	return w, nil
+}
+
+// Signature returns HMAC-SHA256 of the notification fields joined by "|", the bill notification is signed by
+// amount.currency|amount.value|billId|siteId|status.value and the refund one has refundId in place of siteId,
+// the missing fields are left empty, so the notification is rejected by the signature or by the validation then
+func (a *QiwiWebhookAdapter) Signature(typ string, header http.Header, body []byte) (*ProviderWebhookSignature, error) {
+	var (
+		amount          *QiwiAmount
+		status          *QiwiStatus
+		billId, otherId string
+	)
+
+	switch typ {
+	case InboundWebhookTypePayment:
+		st := &QiwiPaymentCallback{}
+		if err := json.Unmarshal(body, st); err != nil {
+			return nil, err
+		}
+
+		if st.Bill != nil {
+			amount, status, billId, otherId = st.Bill.Amount, st.Bill.Status, st.Bill.BillId, st.Bill.SiteId
+		}
+	case InboundWebhookTypeRefund:
+		st := &QiwiRefundCallback{}
+		if err := json.Unmarshal(body, st); err != nil {
+			return nil, err
+		}
+
+		if st.Refund != nil {
+			amount, status, billId, otherId = st.Refund.Amount, st.Refund.Status, st.Refund.BillId, st.Refund.RefundId
+		}
+	default:
+		return nil, ErrProviderWebhookTypeUnknown
+	}
+
+	if amount == nil {
+		amount = &QiwiAmount{}
+	}
+
+	if status == nil {
+		status = &QiwiStatus{}
+	}
+
+	fields := []string{amount.Currency, amount.Value, billId, otherId, status.Value}
+
+	return &ProviderWebhookSignature{
+		Algorithm: WebhookSignatureHmacSha256,
+		Value:     header.Get(QiwiWebhookSignatureHeader),
+		Content:   []byte(strings.Join(fields, qiwiSignatureFieldSeparator)),
+	}, nil
+}
+
+// Request
+func (a *QiwiWebhookAdapter) Request(w *ProviderWebhook, body []byte, signature string) *grpc.CallbackRequest {
+	return &grpc.CallbackRequest{
+		Handler:   a.Handler,
+		Body:      body,
+		Signature: signature,
+	}
+}
@@ -0,0 +1,140 @@
+package common
+
+import (
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	ProviderWebMoney = "webmoney"
+
+	WebMoneyWebhookPaymentPath = "/webmoney/payment"
+
+	// WebMoneyOrderIdField is a merchant field of the payment request returned by WebMoney in the callback as is
+	WebMoneyOrderIdField = "PS_ORDER_ID"
+
+	webMoneyTransDateLayout = "20060102 15:04:05"
+)
+
+// webMoneyLocation is a time zone of the dates of WebMoney callbacks
+var webMoneyLocation = time.FixedZone("MSK", 3*60*60)
+
+// WebMoneyPaymentCallback is a payment notification sent to the result url of the merchant, the pre-request
+// notifications have to be disabled in the settings of the purse since they don't contain the payment
+type WebMoneyPaymentCallback struct {
+	PayeePurse     string `validate:"required"`
+	Amount         string `validate:"required,numeric"`
+	PaymentNo      string `validate:"required"`
+	Mode           string `validate:"omitempty,oneof=0 1"`
+	SysInvsNo      string `validate:"required"`
+	SysTransNo     string `validate:"required"`
+	SysTransDate   string `validate:"required"`
+	PayerPurse     string `validate:"required"`
+	PayerWm        string `validate:"required"`
+	Hash           string `validate:"required"`
+	OrderId        string `validate:"required"`
+	PaymentDesc    string
+	PaymerNumber   string
+	PaymerEmail    string
+	TelepatPhone   string
+	TelepatOrderId string
+}
+
+// WebMoneyWebhookAdapter
+type WebMoneyWebhookAdapter struct {
+	// Handler is a name of the payment system handler of the billing server processing the callbacks
+	Handler string
+}
+
+// Provider
+func (a *WebMoneyWebhookAdapter) Provider() string {
+	return ProviderWebMoney
+}
+
+// Routes
+func (a *WebMoneyWebhookAdapter) Routes() []*ProviderWebhookRoute {
+	return []*ProviderWebhookRoute{
+		{Path: WebMoneyWebhookPaymentPath, Type: InboundWebhookTypePayment},
+	}
+}
+
+// SignatureHeader returns empty name since WebMoney sends the signature in LMI_HASH field of the body
+func (a *WebMoneyWebhookAdapter) SignatureHeader() string {
+	return ""
+}
+
+// Parse
+func (a *WebMoneyWebhookAdapter) Parse(typ string, body []byte) (*ProviderWebhook, error) {
+	if typ != InboundWebhookTypePayment {
+		return nil, ErrProviderWebhookTypeUnknown
+	}
+
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, err
+	}
+
+	st := &WebMoneyPaymentCallback{
+		PayeePurse:     values.Get("LMI_PAYEE_PURSE"),
+		Amount:         values.Get("LMI_PAYMENT_AMOUNT"),
+		PaymentNo:      values.Get("LMI_PAYMENT_NO"),
+		Mode:           values.Get("LMI_MODE"),
+		SysInvsNo:      values.Get("LMI_SYS_INVS_NO"),
+		SysTransNo:     values.Get("LMI_SYS_TRANS_NO"),
+		SysTransDate:   values.Get("LMI_SYS_TRANS_DATE"),
+		PayerPurse:     values.Get("LMI_PAYER_PURSE"),
+		PayerWm:        values.Get("LMI_PAYER_WM"),
+		Hash:           values.Get("LMI_HASH"),
+		OrderId:        values.Get(WebMoneyOrderIdField),
+		PaymentDesc:    values.Get("LMI_PAYMENT_DESC"),
+		PaymerNumber:   values.Get("LMI_PAYMER_NUMBER"),
+		PaymerEmail:    values.Get("LMI_PAYMER_EMAIL"),
+		TelepatPhone:   values.Get("LMI_TELEPAT_PHONENUMBER"),
+		TelepatOrderId: values.Get("LMI_TELEPAT_ORDERID"),
+	}
+
+	return &ProviderWebhook{Type: typ, OrderId: st.OrderId, Signature: st.Hash, Payload: st}, nil
+}
+
+// Signature returns SHA-256 digest of the payment fields with the secret key of the purse between them
+func (a *WebMoneyWebhookAdapter) Signature(typ string, header http.Header, body []byte) (*ProviderWebhookSignature, error) {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, err
+	}
+
+	s := &ProviderWebhookSignature{
+		Algorithm: WebhookSignatureSha256,
+		Value:     values.Get("LMI_HASH"),
+		Content: []byte(strings.Join([]string{
+			values.Get("LMI_PAYEE_PURSE"),
+			values.Get("LMI_PAYMENT_AMOUNT"),
+			values.Get("LMI_PAYMENT_NO"),
+			values.Get("LMI_MODE"),
+			values.Get("LMI_SYS_INVS_NO"),
+			values.Get("LMI_SYS_TRANS_NO"),
+			values.Get("LMI_SYS_TRANS_DATE"),
+		}, "")),
+		ContentSuffix: []byte(values.Get("LMI_PAYER_PURSE") + values.Get("LMI_PAYER_WM")),
+	}
+
+	if date := values.Get("LMI_SYS_TRANS_DATE"); date != "" {
+		if s.Timestamp, err = time.ParseInLocation(webMoneyTransDateLayout, date, webMoneyLocation); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Request
+func (a *WebMoneyWebhookAdapter) Request(w *ProviderWebhook, body []byte, signature string) *grpc.CallbackRequest {
+	return &grpc.CallbackRequest{
+		Handler:   a.Handler,
+		Body:      body,
+		Signature: signature,
+	}
+}
@@ -0,0 +1,110 @@
+package common
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	RateLimitGroupAuthProject = "auth_project"
+	RateLimitGroupAuthUser    = "auth_user"
+	RateLimitGroupWebHooks    = "webhooks"
+	RateLimitGroupCommon      = "common"
+
+	rateLimitPurgeInterval = time.Minute
+)
+
+// RateLimitRule describes token bucket of the group, Rate is a number of requests per second
+// and Burst is a bucket capacity. Zero Rate or Burst means the group is not limited.
+type RateLimitRule struct {
+	Rate  float64
+	Burst int
+}
+
+// Rules returns limits of each route group
+func (r *RateLimit) Rules() map[string]RateLimitRule {
+	if r.Disabled {
+		return map[string]RateLimitRule{}
+	}
+	return map[string]RateLimitRule{
+		RateLimitGroupAuthProject: {Rate: r.AuthProjectRate, Burst: r.AuthProjectBurst},
+		RateLimitGroupAuthUser:    {Rate: r.AuthUserRate, Burst: r.AuthUserBurst},
+		RateLimitGroupWebHooks:    {Rate: r.WebHooksRate, Burst: r.WebHooksBurst},
+		RateLimitGroupCommon:      {Rate: r.CommonRate, Burst: r.CommonBurst},
+	}
+}
+
+type rateLimitBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// RateLimiter is a token bucket limiter with the separate bucket for each key
+type RateLimiter struct {
+	mx      sync.Mutex
+	rule    RateLimitRule
+	buckets map[string]*rateLimitBucket
+	purged  time.Time
+}
+
+// NewRateLimiter
+func NewRateLimiter(rule RateLimitRule) *RateLimiter {
+	return &RateLimiter{
+		rule:    rule,
+		buckets: make(map[string]*rateLimitBucket),
+		purged:  time.Now(),
+	}
+}
+
+// SetRule replaces limits of the limiter, all buckets are dropped
+func (l *RateLimiter) SetRule(rule RateLimitRule) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	l.rule = rule
+	l.buckets = make(map[string]*rateLimitBucket)
+}
+
+// Allow takes a token from the bucket of the key, if the bucket is empty it returns false
+// and the time after which the token will be available
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	if l.rule.Rate <= 0 || l.rule.Burst <= 0 {
+		return true, 0
+	}
+
+	now := time.Now()
+	l.purge(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &rateLimitBucket{tokens: float64(l.rule.Burst), updated: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(l.rule.Burst), b.tokens+now.Sub(b.updated).Seconds()*l.rule.Rate)
+	b.updated = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	return false, time.Duration((1 - b.tokens) / l.rule.Rate * float64(time.Second))
+}
+
+// purge removes buckets which are full again, they are equal to the new ones
+func (l *RateLimiter) purge(now time.Time) {
+	if now.Sub(l.purged) < rateLimitPurgeInterval {
+		return
+	}
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.updated).Seconds()*l.rule.Rate >= float64(l.rule.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+	l.purged = now
+}
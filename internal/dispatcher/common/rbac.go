@@ -0,0 +1,280 @@
+package common
+
+import (
+	"net/http"
+)
+
+const (
+	RoleAdmin         = "admin"
+	RoleMerchantOwner = "merchant_owner"
+	RoleAccountant    = "accountant"
+	RoleSupport       = "support"
+)
+
+// AccessPolicy describes who may call a route of the AuthUser group, each route of the group must have a policy.
+// Roles is a list of roles any of which grants access, an empty list means any authorized user.
+// MerchantParam is a name of the path parameter holding merchant identifier,
+// if it set then user must be a member of this merchant or has a staff role.
+// OrderParam is a name of the path parameter holding order identifier,
+// the merchant of the order is checked the same way as the merchant of MerchantParam.
+type AccessPolicy struct {
+	Roles         []string
+	MerchantParam string
+	OrderParam    string
+}
+
+var (
+	// StaffRoles are roles of the paysuper employees, they are not limited by the merchant scope
+	StaffRoles = []string{RoleAdmin, RoleAccountant, RoleSupport}
+
+	rolesAdmin           = []string{RoleAdmin}
+	rolesAdminAccountant = []string{RoleAdmin, RoleAccountant}
+	rolesFinance         = []string{RoleAdmin, RoleAccountant, RoleMerchantOwner}
+	rolesMerchant        = []string{RoleAdmin, RoleSupport, RoleMerchantOwner}
+	rolesAdminSupport    = []string{RoleAdmin, RoleSupport}
+	rolesOwner           = []string{RoleAdmin, RoleMerchantOwner}
+	rolesAny             = []string{RoleAdmin, RoleAccountant, RoleSupport, RoleMerchantOwner}
+
+	// AccessPolicies is a policy table of the AuthUser group routes, key is a method and route template
+	// built by AccessPolicyKey. Routes without policy are denied, so the new routes are added here
+	// even if they are open to everyone.
+	AccessPolicies = map[string]*AccessPolicy{
+		// the routes used by the user before the merchant is created
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/user/profile"):       {},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/user/profile/:id"):   {},
+		AccessPolicyKey(http.MethodPatch, AuthUserGroupPath+"/user/profile"):     {},
+		AccessPolicyKey(http.MethodPost, AuthUserGroupPath+"/user/feedback"):     {},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/merchants/user"):     {},
+		AccessPolicyKey(http.MethodPut, AuthUserGroupPath+"/merchants/company"):  {},
+		AccessPolicyKey(http.MethodPut, AuthUserGroupPath+"/merchants/contacts"): {},
+		AccessPolicyKey(http.MethodPut, AuthUserGroupPath+"/merchants/banking"):  {},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/merchants/tariffs"):  {},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/platforms"):          {},
+
+		// jobs are checked by the handler, the user gets its own jobs only
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/jobs/:id"):    {},
+		AccessPolicyKey(http.MethodDelete, AuthUserGroupPath+"/jobs/:id"): {},
+
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/merchants"):                         {Roles: StaffRoles},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/merchants/:id"):                     {Roles: rolesMerchant, MerchantParam: RequestParameterId},
+		AccessPolicyKey(http.MethodPatch, AuthUserGroupPath+"/merchants/:id"):                   {Roles: rolesMerchant, MerchantParam: RequestParameterId},
+		AccessPolicyKey(http.MethodPut, AuthUserGroupPath+"/merchants/:id/company"):             {Roles: rolesMerchant, MerchantParam: RequestParameterId},
+		AccessPolicyKey(http.MethodPut, AuthUserGroupPath+"/merchants/:id/contacts"):            {Roles: rolesMerchant, MerchantParam: RequestParameterId},
+		AccessPolicyKey(http.MethodPut, AuthUserGroupPath+"/merchants/:id/banking"):             {Roles: rolesMerchant, MerchantParam: RequestParameterId},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/merchants/:id/status"):              {Roles: rolesMerchant, MerchantParam: RequestParameterId},
+		AccessPolicyKey(http.MethodPut, AuthUserGroupPath+"/merchants/:id/change-status"):       {Roles: rolesAdmin},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/merchants/:id/agreement"):           {Roles: rolesMerchant, MerchantParam: RequestParameterId},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/merchants/:id/agreement/document"):  {Roles: rolesMerchant, MerchantParam: RequestParameterId},
+		AccessPolicyKey(http.MethodPost, AuthUserGroupPath+"/merchants/:id/agreement/document"): {Roles: rolesMerchant, MerchantParam: RequestParameterId},
+		AccessPolicyKey(http.MethodPut, AuthUserGroupPath+"/merchants/:id/agreement/signature"): {Roles: rolesMerchant, MerchantParam: RequestParameterId},
+		AccessPolicyKey(http.MethodPost, AuthUserGroupPath+"/merchants/:id/tariffs"):            {Roles: rolesMerchant, MerchantParam: RequestParameterId},
+
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/merchants/:id/webhooks"):                        {Roles: rolesMerchant, MerchantParam: RequestParameterId},
+		AccessPolicyKey(http.MethodPost, AuthUserGroupPath+"/merchants/:id/webhooks"):                       {Roles: rolesMerchant, MerchantParam: RequestParameterId},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/merchants/:id/webhooks/:webhook_id"):            {Roles: rolesMerchant, MerchantParam: RequestParameterId},
+		AccessPolicyKey(http.MethodPut, AuthUserGroupPath+"/merchants/:id/webhooks/:webhook_id"):            {Roles: rolesMerchant, MerchantParam: RequestParameterId},
+		AccessPolicyKey(http.MethodDelete, AuthUserGroupPath+"/merchants/:id/webhooks/:webhook_id"):         {Roles: rolesMerchant, MerchantParam: RequestParameterId},
+		AccessPolicyKey(http.MethodPost, AuthUserGroupPath+"/merchants/:id/webhooks/:webhook_id/secret"):    {Roles: rolesMerchant, MerchantParam: RequestParameterId},
+		AccessPolicyKey(http.MethodPost, AuthUserGroupPath+"/merchants/:id/webhooks/:webhook_id/ping"):      {Roles: rolesMerchant, MerchantParam: RequestParameterId},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/merchants/:id/webhooks/:webhook_id/deliveries"): {Roles: rolesMerchant, MerchantParam: RequestParameterId},
+
+		AccessPolicyKey(http.MethodPost, AuthUserGroupPath+"/merchants/:merchant_id/notifications"):                              {Roles: StaffRoles},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/merchants/:merchant_id/notifications"):                               {Roles: rolesMerchant, MerchantParam: RequestParameterMerchantId},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/merchants/:merchant_id/notifications/:notification_id"):              {Roles: rolesMerchant, MerchantParam: RequestParameterMerchantId},
+		AccessPolicyKey(http.MethodPut, AuthUserGroupPath+"/merchants/:merchant_id/notifications/:notification_id/mark-as-read"): {Roles: rolesMerchant, MerchantParam: RequestParameterMerchantId},
+
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/merchants/:id/dashboard/main"):             {Roles: rolesFinance, MerchantParam: RequestParameterId},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/merchants/:id/dashboard/revenue_dynamics"): {Roles: rolesFinance, MerchantParam: RequestParameterId},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/merchants/:id/dashboard/base"):             {Roles: rolesFinance, MerchantParam: RequestParameterId},
+
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/balance/:merchant_id"): {Roles: rolesFinance, MerchantParam: RequestParameterMerchantId},
+
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/balance"): {Roles: rolesFinance},
+
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/order"):                              {Roles: rolesAny},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/order/export"):                       {Roles: rolesFinance},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/order/:id"):                          {Roles: rolesAny, OrderParam: RequestParameterId},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/order/:order_id/refunds"):            {Roles: rolesAny, OrderParam: RequestParameterOrderId},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/order/:order_id/refunds/:refund_id"): {Roles: rolesAny, OrderParam: RequestParameterOrderId},
+		AccessPolicyKey(http.MethodPost, AuthUserGroupPath+"/order/:order_id/refunds"):           {Roles: rolesMerchant, OrderParam: RequestParameterOrderId},
+		AccessPolicyKey(http.MethodPut, AuthUserGroupPath+"/order/:order_id/replace_code"):       {Roles: rolesMerchant, OrderParam: RequestParameterOrderId},
+
+		// the resources belong to the merchant of the user, it's checked by the billing server since the path has no merchant
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/projects"):                     {Roles: rolesMerchant},
+		AccessPolicyKey(http.MethodPost, AuthUserGroupPath+"/projects"):                    {Roles: rolesMerchant},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/projects/:id"):                 {Roles: rolesMerchant},
+		AccessPolicyKey(http.MethodPatch, AuthUserGroupPath+"/projects/:id"):               {Roles: rolesMerchant},
+		AccessPolicyKey(http.MethodDelete, AuthUserGroupPath+"/projects/:id"):              {Roles: rolesMerchant},
+		AccessPolicyKey(http.MethodPost, AuthUserGroupPath+"/projects/:id/sku"):            {Roles: rolesMerchant},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/products"):                     {Roles: rolesMerchant},
+		AccessPolicyKey(http.MethodPost, AuthUserGroupPath+"/products"):                    {Roles: rolesMerchant},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/products/:id"):                 {Roles: rolesMerchant},
+		AccessPolicyKey(http.MethodPut, AuthUserGroupPath+"/products/:id"):                 {Roles: rolesMerchant},
+		AccessPolicyKey(http.MethodDelete, AuthUserGroupPath+"/products/:id"):              {Roles: rolesMerchant},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/products/:id/prices"):          {Roles: rolesMerchant},
+		AccessPolicyKey(http.MethodPut, AuthUserGroupPath+"/products/:id/prices"):          {Roles: rolesMerchant},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/paylinks/project/:project_id"): {Roles: rolesMerchant},
+		AccessPolicyKey(http.MethodPost, AuthUserGroupPath+"/paylinks"):                    {Roles: rolesMerchant},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/paylinks/:id"):                 {Roles: rolesMerchant},
+		AccessPolicyKey(http.MethodPut, AuthUserGroupPath+"/paylinks/:id"):                 {Roles: rolesMerchant},
+		AccessPolicyKey(http.MethodDelete, AuthUserGroupPath+"/paylinks/:id"):              {Roles: rolesMerchant},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/paylinks/:id/stat"):            {Roles: rolesMerchant},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/paylinks/:id/url"):             {Roles: rolesMerchant},
+
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/keys/:key_id"):                                                  {Roles: rolesMerchant},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/key-products"):                                                  {Roles: rolesMerchant},
+		AccessPolicyKey(http.MethodPost, AuthUserGroupPath+"/key-products"):                                                 {Roles: rolesMerchant},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/key-products/inventory"):                                        {Roles: rolesMerchant},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/key-products/:key_product_id"):                                  {Roles: rolesMerchant},
+		AccessPolicyKey(http.MethodPut, AuthUserGroupPath+"/key-products/:key_product_id"):                                  {Roles: rolesMerchant},
+		AccessPolicyKey(http.MethodDelete, AuthUserGroupPath+"/key-products/:key_product_id"):                               {Roles: rolesMerchant},
+		AccessPolicyKey(http.MethodPost, AuthUserGroupPath+"/key-products/:key_product_id/publish"):                         {Roles: rolesMerchant},
+		AccessPolicyKey(http.MethodPost, AuthUserGroupPath+"/key-products/:key_product_id/unpublish"):                       {Roles: rolesMerchant},
+		AccessPolicyKey(http.MethodPost, AuthUserGroupPath+"/key-products/:key_product_id/platforms/:platform_id/file"):     {Roles: rolesMerchant},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/key-products/:key_product_id/platforms/:platform_id/count"):     {Roles: rolesMerchant},
+		AccessPolicyKey(http.MethodPut, AuthUserGroupPath+"/key-products/:key_product_id/platforms/:platform_id/threshold"): {Roles: rolesMerchant},
+
+		AccessPolicyKey(http.MethodPost, AuthUserGroupPath+"/report_file"):               {Roles: rolesFinance},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/report_file/download/:file"): {Roles: rolesFinance},
+
+		AccessPolicyKey(http.MethodPost, AuthUserGroupPath+"/refunds/batch"):    {Roles: rolesAdminSupport},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/refunds/batch/:id"): {Roles: rolesAdminSupport},
+
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/products/merchant/:id"): {Roles: rolesMerchant, MerchantParam: RequestParameterId},
+
+		AccessPolicyKey(http.MethodDelete, AuthUserGroupPath+"/projects/:project_id/customers/:customer_id/saved_cards"): {Roles: rolesAdminSupport},
+
+		// the project belongs to the merchant of the user, it's checked by the handler since the path has no merchant
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/projects/:id/theme"):          {Roles: rolesMerchant},
+		AccessPolicyKey(http.MethodPut, AuthUserGroupPath+"/projects/:id/theme"):          {Roles: rolesMerchant},
+		AccessPolicyKey(http.MethodDelete, AuthUserGroupPath+"/projects/:id/theme"):       {Roles: rolesMerchant},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/projects/:id/theme/preview"):  {Roles: rolesMerchant},
+		AccessPolicyKey(http.MethodPost, AuthUserGroupPath+"/projects/:id/theme/preview"): {Roles: rolesMerchant},
+
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/inbound_webhooks"):             {Roles: rolesAdminSupport},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/inbound_webhooks/:id"):         {Roles: rolesAdminSupport},
+		AccessPolicyKey(http.MethodPost, AuthUserGroupPath+"/inbound_webhooks/:id/replay"): {Roles: rolesAdmin},
+
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/taxes"):        {Roles: rolesAdminAccountant},
+		AccessPolicyKey(http.MethodPost, AuthUserGroupPath+"/taxes"):       {Roles: rolesAdminAccountant},
+		AccessPolicyKey(http.MethodDelete, AuthUserGroupPath+"/taxes/:id"): {Roles: rolesAdminAccountant},
+
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/vat_reports"):                  {Roles: rolesAdminAccountant},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/vat_reports/country/:country"): {Roles: rolesAdminAccountant},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/vat_reports/details/:id"):      {Roles: rolesAdminAccountant},
+		AccessPolicyKey(http.MethodPost, AuthUserGroupPath+"/vat_reports/status/:id"):      {Roles: rolesAdminAccountant},
+
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/royalty_reports"):                       {Roles: rolesFinance},
+		AccessPolicyKey(http.MethodPost, AuthUserGroupPath+"/royalty_reports"):                      {Roles: rolesAdmin},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/royalty_reports/:id"):                   {Roles: rolesFinance},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/royalty_reports/:id/transactions"):      {Roles: rolesFinance},
+		AccessPolicyKey(http.MethodPost, AuthUserGroupPath+"/royalty_reports/:id/accept"):           {Roles: rolesOwner},
+		AccessPolicyKey(http.MethodPost, AuthUserGroupPath+"/royalty_reports/:id/decline"):          {Roles: rolesOwner},
+		AccessPolicyKey(http.MethodPost, AuthUserGroupPath+"/royalty_reports/:id/change"):           {Roles: rolesAdminAccountant},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/payout_documents"):                      {Roles: rolesFinance},
+		AccessPolicyKey(http.MethodPost, AuthUserGroupPath+"/payout_documents"):                     {Roles: rolesFinance},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/payout_documents/:id"):                  {Roles: rolesFinance},
+		AccessPolicyKey(http.MethodPost, AuthUserGroupPath+"/payout_documents/:id"):                 {Roles: rolesAdminAccountant},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/payout_documents/:id/signurl/merchant"): {Roles: rolesOwner},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/payout_documents/:id/signurl/ps"):       {Roles: rolesAdminAccountant},
+
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/payment_costs/channel/system"):           {Roles: rolesAdminAccountant},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/payment_costs/channel/system/all"):       {Roles: rolesAdminAccountant},
+		AccessPolicyKey(http.MethodPost, AuthUserGroupPath+"/payment_costs/channel/system"):          {Roles: rolesAdmin},
+		AccessPolicyKey(http.MethodPut, AuthUserGroupPath+"/payment_costs/channel/system/:id"):       {Roles: rolesAdmin},
+		AccessPolicyKey(http.MethodDelete, AuthUserGroupPath+"/payment_costs/channel/system/:id"):    {Roles: rolesAdmin},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/payment_costs/money_back/system"):        {Roles: rolesAdminAccountant},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/payment_costs/money_back/system/all"):    {Roles: rolesAdminAccountant},
+		AccessPolicyKey(http.MethodPost, AuthUserGroupPath+"/payment_costs/money_back/system"):       {Roles: rolesAdmin},
+		AccessPolicyKey(http.MethodPut, AuthUserGroupPath+"/payment_costs/money_back/system/:id"):    {Roles: rolesAdmin},
+		AccessPolicyKey(http.MethodDelete, AuthUserGroupPath+"/payment_costs/money_back/system/:id"): {Roles: rolesAdmin},
+
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/payment_costs/channel/merchant/:id"):                      {Roles: rolesFinance, MerchantParam: RequestParameterId},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/payment_costs/channel/merchant/:id/all"):                  {Roles: rolesFinance, MerchantParam: RequestParameterId},
+		AccessPolicyKey(http.MethodPost, AuthUserGroupPath+"/payment_costs/channel/merchant/:id"):                     {Roles: rolesAdminAccountant},
+		AccessPolicyKey(http.MethodPut, AuthUserGroupPath+"/payment_costs/channel/merchant/:merchant_id/:rate_id"):    {Roles: rolesAdminAccountant},
+		AccessPolicyKey(http.MethodDelete, AuthUserGroupPath+"/payment_costs/channel/merchant/:id"):                   {Roles: rolesAdminAccountant},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/payment_costs/money_back/merchant/:id"):                   {Roles: rolesFinance, MerchantParam: RequestParameterId},
+		AccessPolicyKey(http.MethodGet, AuthUserGroupPath+"/payment_costs/money_back/merchant/:id/all"):               {Roles: rolesFinance, MerchantParam: RequestParameterId},
+		AccessPolicyKey(http.MethodPost, AuthUserGroupPath+"/payment_costs/money_back/merchant/:id"):                  {Roles: rolesAdminAccountant},
+		AccessPolicyKey(http.MethodPut, AuthUserGroupPath+"/payment_costs/money_back/merchant/:merchant_id/:rate_id"): {Roles: rolesAdminAccountant},
+		AccessPolicyKey(http.MethodDelete, AuthUserGroupPath+"/payment_costs/money_back/merchant/:id"):                {Roles: rolesAdminAccountant},
+	}
+)
+
+// AccessPolicyKey
+func AccessPolicyKey(method, path string) string {
+	return method + " " + path
+}
+
+// FindAccessPolicy returns policy of the route or nil if the route has no policy and must be denied
+func FindAccessPolicy(method, path string) *AccessPolicy {
+	return AccessPolicies[AccessPolicyKey(method, path)]
+}
+
+// UserRoles returns staff roles assigned to the user in the config
+func (r *Rbac) UserRoles(userId string) []string {
+	var roles []string
+	assigned := map[string][]string{
+		RoleAdmin:      r.Admins,
+		RoleAccountant: r.Accountants,
+		RoleSupport:    r.Support,
+	}
+	for role, ids := range assigned {
+		for _, id := range ids {
+			if id == userId {
+				roles = append(roles, role)
+				break
+			}
+		}
+	}
+	return roles
+}
+
+// HasRole
+func (u *AuthUser) HasRole(role string) bool {
+	return u.Roles[role]
+}
+
+// HasAnyRole
+func (u *AuthUser) HasAnyRole(roles ...string) bool {
+	for _, role := range roles {
+		if u.Roles[role] {
+			return true
+		}
+	}
+	return false
+}
+
+// IsStaff
+func (u *AuthUser) IsStaff() bool {
+	return u.HasAnyRole(StaffRoles...)
+}
+
+// HasMerchant
+func (u *AuthUser) HasMerchant(merchantId string) bool {
+	return u.Merchants[merchantId]
+}
+
+// CheckAccess checks the user against the policy, merchantId is a value of the policy merchant parameter
+// or the merchant of the order of the policy order parameter
+func (p *AccessPolicy) CheckAccess(user *AuthUser, merchantId string) error {
+	if len(p.Roles) > 0 && !user.HasAnyRole(p.Roles...) {
+		return ErrorMessageInsufficientRole
+	}
+
+	if !p.IsMerchantScoped() || user.IsStaff() {
+		return nil
+	}
+
+	if merchantId == "" || !user.HasMerchant(merchantId) {
+		return ErrorMessageMerchantAccessDenied
+	}
+
+	return nil
+}
+
+// IsMerchantScoped checks that the route of the policy is limited by the merchant of the user
+func (p *AccessPolicy) IsMerchantScoped() bool {
+	return p.MerchantParam != "" || p.OrderParam != ""
+}
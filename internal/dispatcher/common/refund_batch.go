@@ -0,0 +1,41 @@
+package common
+
+import (
+	"time"
+)
+
+const (
+	RefundBatchStatusProcessing = "processing"
+	RefundBatchStatusCompleted  = "completed"
+	RefundBatchStatusCancelled  = "cancelled"
+
+	RefundBatchItemStatusPending   = "pending"
+	RefundBatchItemStatusSucceeded = "succeeded"
+	RefundBatchItemStatusFailed    = "failed"
+	RefundBatchItemStatusInvalid   = "invalid"
+)
+
+// RefundBatchItem is a refund of the batch with the result of its execution
+type RefundBatchItem struct {
+	Index    int         `json:"index"`
+	OrderId  string      `json:"order_id"`
+	Amount   float64     `json:"amount"`
+	Reason   string      `json:"reason"`
+	Status   string      `json:"status"`
+	RefundId string      `json:"refund_id,omitempty"`
+	Error    interface{} `json:"error,omitempty"`
+}
+
+// RefundBatch is a report of the batch refund, background batches are identified by their jobs
+type RefundBatch struct {
+	Id         string             `json:"id,omitempty"`
+	CreatorId  string             `json:"creator_id"`
+	Status     string             `json:"status"`
+	Total      int                `json:"total"`
+	Processed  int                `json:"processed"`
+	Succeeded  int                `json:"succeeded"`
+	Failed     int                `json:"failed"`
+	Items      []*RefundBatchItem `json:"items"`
+	CreatedAt  time.Time          `json:"created_at"`
+	FinishedAt *time.Time         `json:"finished_at,omitempty"`
+}
@@ -0,0 +1,333 @@
+package common
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	CspReportPath = "/csp-report"
+
+	HeaderContentSecurityPolicy           = "Content-Security-Policy"
+	HeaderContentSecurityPolicyReportOnly = "Content-Security-Policy-Report-Only"
+	HeaderStrictTransportSecurity         = "Strict-Transport-Security"
+	HeaderXFrameOptions                   = "X-Frame-Options"
+	HeaderXContentTypeOptions             = "X-Content-Type-Options"
+	HeaderReferrerPolicy                  = "Referrer-Policy"
+
+	CspSourceSelf   = "'self'"
+	CspSourceNone   = "'none'"
+	CspSourceNonce  = "'nonce'"
+	CspSourceInline = "'unsafe-inline'"
+
+	CspDirectiveFrameAncestors = "frame-ancestors"
+	CspDirectiveReportUri      = "report-uri"
+
+	cspNonceSize             = 16
+	cspNonceSourceMask       = "'nonce-%s'"
+	xFrameOptionsDeny        = "DENY"
+	xContentTypeOptions      = "nosniff"
+	referrerPolicy           = "strict-origin-when-cross-origin"
+	strictTransportSecurity  = "max-age=%d; includeSubDomains"
+	docsScriptSource         = "https://cdn.jsdelivr.net"
+	docsStyleSource          = "https://fonts.googleapis.com"
+	docsFontSource           = "https://fonts.gstatic.com"
+	themePreviewPathTemplate = AuthUserGroupPath + "/projects/:id/theme/preview"
+
+	frameAncestorsDefaultTtl = 5 * time.Minute
+	frameAncestorsMaxSize    = 10000
+)
+
+// CspDirective is a directive of the content security policy with its sources,
+// CspSourceNonce source is replaced by the nonce of the request
+type CspDirective struct {
+	Name    string
+	Sources []string
+}
+
+// SecurityPolicy is a set of the security headers of the route
+type SecurityPolicy struct {
+	Csp []CspDirective
+	// Framed page can be embedded by the frame ancestors set by the handler, other pages can't be embedded at all
+	Framed bool
+}
+
+// CspReport is a violation report sent by the browser to the report uri of the policy
+type CspReport struct {
+	DocumentUri        string `json:"document-uri"`
+	Referrer           string `json:"referrer"`
+	ViolatedDirective  string `json:"violated-directive"`
+	EffectiveDirective string `json:"effective-directive"`
+	OriginalPolicy     string `json:"original-policy"`
+	Disposition        string `json:"disposition"`
+	BlockedUri         string `json:"blocked-uri"`
+	SourceFile         string `json:"source-file"`
+	LineNumber         int    `json:"line-number"`
+	ColumnNumber       int    `json:"column-number"`
+	StatusCode         int    `json:"status-code"`
+}
+
+// CspReportRequest
+type CspReportRequest struct {
+	Report *CspReport `json:"csp-report"`
+}
+
+// DefaultSecurityPolicy is a policy of the routes without own policy, such pages don't run scripts at all
+var DefaultSecurityPolicy = &SecurityPolicy{
+	Csp: []CspDirective{
+		{Name: "default-src", Sources: []string{CspSourceNone}},
+		{Name: "style-src", Sources: []string{CspSourceSelf}},
+		{Name: "img-src", Sources: []string{CspSourceSelf}},
+		{Name: "base-uri", Sources: []string{CspSourceNone}},
+		{Name: "form-action", Sources: []string{CspSourceSelf}},
+	},
+}
+
+// NewSecurityPolicies returns the security policies by the path of the route
+func NewSecurityPolicies(cfg *Config) map[string]*SecurityPolicy {
+	scripts := []string{CspSourceSelf, CspSourceNonce}
+	connects := []string{CspSourceSelf}
+
+	if origin := UrlOrigin(cfg.PaymentFormJsLibraryUrl); origin != "" {
+		scripts = append(scripts, origin)
+		connects = append(connects, origin)
+	}
+
+	if origin := UrlOrigin(cfg.WebsocketUrl); origin != "" {
+		connects = append(connects, origin)
+	}
+
+	// theme of the project loads logo and fonts by secure urls, card forms of 3-D Secure are posted to the banks
+	form := []CspDirective{
+		{Name: "default-src", Sources: []string{CspSourceSelf}},
+		{Name: "script-src", Sources: scripts},
+		{Name: "style-src", Sources: []string{CspSourceSelf, CspSourceNonce, "https:"}},
+		{Name: "img-src", Sources: []string{CspSourceSelf, "https:", "data:"}},
+		{Name: "font-src", Sources: []string{CspSourceSelf, "https:", "data:"}},
+		{Name: "connect-src", Sources: connects},
+		{Name: "frame-src", Sources: []string{"https:"}},
+		{Name: "form-action", Sources: []string{CspSourceSelf, "https:"}},
+		{Name: "object-src", Sources: []string{CspSourceNone}},
+		{Name: "base-uri", Sources: []string{CspSourceSelf}},
+	}
+
+	// redoc injects its styles at runtime, so the styles of the docs are allowed inline without nonce
+	docs := []CspDirective{
+		{Name: "default-src", Sources: []string{CspSourceSelf}},
+		{Name: "script-src", Sources: []string{CspSourceSelf, CspSourceNonce, docsScriptSource}},
+		{Name: "style-src", Sources: []string{CspSourceSelf, CspSourceInline, docsStyleSource}},
+		{Name: "font-src", Sources: []string{CspSourceSelf, docsFontSource}},
+		{Name: "img-src", Sources: []string{CspSourceSelf, "https:", "data:"}},
+		{Name: "worker-src", Sources: []string{"blob:"}},
+		{Name: "object-src", Sources: []string{CspSourceNone}},
+		{Name: "base-uri", Sources: []string{CspSourceSelf}},
+	}
+
+	return map[string]*SecurityPolicy{
+		"/order/:id":             {Csp: form, Framed: true},
+		"/order/:id/form":        {Csp: form, Framed: true},
+		themePreviewPathTemplate: {Csp: form},
+		"/docs":                  {Csp: docs},
+	}
+}
+
+// ContentSecurityPolicy returns value of the content security policy header of the request
+func (p *SecurityPolicy) ContentSecurityPolicy(nonce string, frameAncestors []string, reportUri string) string {
+	directives := make([]string, 0, len(p.Csp)+2)
+
+	for _, d := range p.Csp {
+		sources := make([]string, len(d.Sources))
+
+		for i, s := range d.Sources {
+			if s == CspSourceNonce {
+				s = fmt.Sprintf(cspNonceSourceMask, nonce)
+			}
+			sources[i] = s
+		}
+
+		directives = append(directives, d.Name+" "+strings.Join(sources, " "))
+	}
+
+	directives = append(directives, p.frameAncestorsDirectives(frameAncestors, reportUri)...)
+
+	return strings.Join(directives, "; ")
+}
+
+// FrameAncestorsPolicy returns value of the content security policy header restricting the embedding of the page only
+func (p *SecurityPolicy) FrameAncestorsPolicy(frameAncestors []string, reportUri string) string {
+	return strings.Join(p.frameAncestorsDirectives(frameAncestors, reportUri), "; ")
+}
+
+func (p *SecurityPolicy) frameAncestorsDirectives(frameAncestors []string, reportUri string) []string {
+	if !p.Framed || len(frameAncestors) == 0 {
+		frameAncestors = []string{CspSourceNone}
+	}
+
+	directives := []string{CspDirectiveFrameAncestors + " " + strings.Join(frameAncestors, " ")}
+
+	if reportUri != "" {
+		directives = append(directives, CspDirectiveReportUri+" "+reportUri)
+	}
+
+	return directives
+}
+
+// SetHeaders sets the security headers of the response
+func (p *SecurityPolicy) SetHeaders(ctx echo.Context, cfg *Config) {
+	header := ctx.Response().Header()
+	frameAncestors := ExtractFrameAncestorsContext(ctx)
+	csp := p.ContentSecurityPolicy(ExtractCspNonceContext(ctx), frameAncestors, CspReportPath)
+
+	// browsers ignore frame-ancestors of the report only policy, so the embedding is restricted
+	// by the enforced policy even if the rest of the policy is reported only
+	if cfg.SecurityCspReportOnly {
+		header.Set(HeaderContentSecurityPolicyReportOnly, csp)
+		header.Set(HeaderContentSecurityPolicy, p.FrameAncestorsPolicy(frameAncestors, CspReportPath))
+	} else {
+		header.Set(HeaderContentSecurityPolicy, csp)
+	}
+
+	// X-Frame-Options can't list several origins, so the framed page relies on frame-ancestors only
+	if !p.Framed {
+		header.Set(HeaderXFrameOptions, xFrameOptionsDeny)
+	}
+
+	header.Set(HeaderXContentTypeOptions, xContentTypeOptions)
+	header.Set(HeaderReferrerPolicy, referrerPolicy)
+
+	if cfg.HttpScheme == "https" && cfg.SecurityHstsMaxAge > 0 {
+		header.Set(HeaderStrictTransportSecurity, fmt.Sprintf(strictTransportSecurity, int64(cfg.SecurityHstsMaxAge/time.Second)))
+	}
+}
+
+// FrameAncestors caches origins allowed to embed the payment form by the project,
+// so the project isn't requested from the billing server on each render of the form
+type FrameAncestors struct {
+	mx    sync.Mutex
+	ttl   time.Duration
+	items map[string]*frameAncestorsItem
+}
+
+type frameAncestorsItem struct {
+	origins  []string
+	expireAt time.Time
+}
+
+// NewFrameAncestors returns cache keeping the origins for ttl, default ttl is used if it isn't set
+func NewFrameAncestors(ttl time.Duration) *FrameAncestors {
+	if ttl <= 0 {
+		ttl = frameAncestorsDefaultTtl
+	}
+
+	return &FrameAncestors{ttl: ttl, items: make(map[string]*frameAncestorsItem)}
+}
+
+// Get returns origins of the project, ok is false if the origins aren't cached or expired
+func (f *FrameAncestors) Get(projectId string) ([]string, bool) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	item, ok := f.items[projectId]
+
+	if !ok || time.Now().After(item.expireAt) {
+		return nil, false
+	}
+
+	return item.origins, true
+}
+
+// Set caches origins of the project, expired origins are removed when the cache is full
+func (f *FrameAncestors) Set(projectId string, origins []string) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	now := time.Now()
+
+	if len(f.items) >= frameAncestorsMaxSize {
+		for id, item := range f.items {
+			if now.After(item.expireAt) {
+				delete(f.items, id)
+			}
+		}
+	}
+
+	if len(f.items) >= frameAncestorsMaxSize {
+		return
+	}
+
+	f.items[projectId] = &frameAncestorsItem{origins: origins, expireAt: now.Add(f.ttl)}
+}
+
+// AllowedUrlsOrigins returns secure origins of the urls the project is allowed to create orders from,
+// the sites creating orders embed the payment form
+func AllowedUrlsOrigins(urls []string) []string {
+	origins := make([]string, 0, len(urls))
+	seen := make(map[string]bool, len(urls))
+
+	for _, u := range urls {
+		origin := UrlOrigin(u)
+
+		if !strings.HasPrefix(origin, "https://") || seen[origin] {
+			continue
+		}
+
+		seen[origin] = true
+		origins = append(origins, origin)
+	}
+
+	return origins
+}
+
+// NewCspNonce returns random nonce of the scripts and styles of the page
+func NewCspNonce() (string, error) {
+	b := make([]byte, cspNonceSize)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// UrlOrigin returns origin of the absolute url or empty string if the url has no host
+func UrlOrigin(raw string) string {
+	u, err := url.Parse(raw)
+
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return ""
+	}
+
+	return strings.ToLower(u.Scheme) + "://" + strings.ToLower(u.Host)
+}
+
+// ExtractCspNonceContext
+func ExtractCspNonceContext(ctx echo.Context) string {
+	if nonce, ok := ctx.Get("cspNonce").(string); ok {
+		return nonce
+	}
+	return ""
+}
+
+// SetCspNonceContext
+func SetCspNonceContext(ctx echo.Context, nonce string) {
+	ctx.Set("cspNonce", nonce)
+}
+
+// ExtractFrameAncestorsContext
+func ExtractFrameAncestorsContext(ctx echo.Context) []string {
+	if origins, ok := ctx.Get("frameAncestors").([]string); ok {
+		return origins
+	}
+	return nil
+}
+
+// SetFrameAncestorsContext sets origins allowed to embed the page, it takes effect on the framed routes only
+func SetFrameAncestorsContext(ctx echo.Context, origins []string) {
+	ctx.Set("frameAncestors", origins)
+}
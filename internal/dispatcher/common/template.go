@@ -12,19 +12,41 @@ var FuncMap = template.FuncMap{
 		a, _ := json.Marshal(v)
 		return template.JS(a)
 	},
+	// Nonce returns nonce of the inline scripts and styles of the page, it's replaced on render of each request
+	"Nonce": func() string {
+		return ""
+	},
 }
 
 // Template
 type Template struct {
-	tpl *template.Template
+	tpl   *template.Template
+	forms map[string]*template.Template
 }
 
-// Render
+// Render renders the payment forms within their layout and other templates by the file name,
+// inline scripts and styles get the content security policy nonce of the request
 func (t *Template) Render(w io.Writer, name string, data interface{}, c echo.Context) error {
-	return t.tpl.ExecuteTemplate(w, name, data)
+	tpl, layout := t.tpl, name
+	if form, ok := t.forms[name]; ok {
+		tpl, layout = form, paymentFormLayoutName
+	}
+
+	nonce := ""
+	if c != nil {
+		nonce = ExtractCspNonceContext(c)
+	}
+
+	// parsed templates are never executed, so each request renders own clone with the nonce of the request
+	clone, err := tpl.Clone()
+	if err != nil {
+		return err
+	}
+
+	return clone.Funcs(template.FuncMap{"Nonce": func() string { return nonce }}).ExecuteTemplate(w, layout, data)
 }
 
 // NewTemplate
-func NewTemplate(tpl *template.Template) *Template {
-	return &Template{tpl: tpl}
+func NewTemplate(tpl *template.Template, forms map[string]*template.Template) *Template {
+	return &Template{tpl: tpl, forms: forms}
 }
@@ -0,0 +1,115 @@
+package common
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"sync"
+	"time"
+)
+
+const (
+	webhookReplayCollection    = "webhook_replay"
+	webhookReplayPurgeInterval = time.Minute
+)
+
+// WebhookReplayStorage keeps signatures of the processed callbacks until the end of the replay window,
+// the storage must be shared by the replicas, otherwise the callback may be processed again by the other replica
+type WebhookReplayStorage interface {
+	// Seen checks that the callback with the key was processed and its replay window isn't expired at the time
+	Seen(key string, at time.Time) (bool, error)
+	// Add marks the callback with the key as processed until the time
+	Add(key string, expireAt time.Time) error
+}
+
+// NewWebhookReplayStorage returns storage in the database of the session or in the process memory if session is nil
+func NewWebhookReplayStorage(session *mgo.Session) (WebhookReplayStorage, error) {
+	if session == nil {
+		return NewWebhookReplayMemoryStorage(), nil
+	}
+
+	c, err := newMongoCollection(
+		session,
+		webhookReplayCollection,
+		mgo.Index{Key: []string{"expire_at"}, ExpireAfter: time.Second},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &webhookReplayMongoStorage{replays: c}, nil
+}
+
+type webhookReplay struct {
+	Key      string    `bson:"_id"`
+	ExpireAt time.Time `bson:"expire_at"`
+}
+
+type webhookReplayMongoStorage struct {
+	replays *mongoCollection
+}
+
+// Seen checks the expiration time since the expired documents are removed by the database with a delay
+func (s *webhookReplayMongoStorage) Seen(key string, at time.Time) (bool, error) {
+	n := 0
+	err := s.replays.with(func(c *mgo.Collection) error {
+		var err error
+		n, err = c.Find(bson.M{"_id": key, "expire_at": bson.M{"$gt": at}}).Count()
+		return err
+	})
+	return n > 0, err
+}
+
+// Add
+func (s *webhookReplayMongoStorage) Add(key string, expireAt time.Time) error {
+	return s.replays.with(func(c *mgo.Collection) error {
+		_, err := c.UpsertId(key, &webhookReplay{Key: key, ExpireAt: expireAt})
+		return err
+	})
+}
+
+type webhookReplayMemoryStorage struct {
+	mx     sync.Mutex
+	seen   map[string]time.Time
+	purged time.Time
+}
+
+// NewWebhookReplayMemoryStorage returns storage keeping signatures of the processed callbacks in the process memory
+func NewWebhookReplayMemoryStorage() WebhookReplayStorage {
+	return &webhookReplayMemoryStorage{
+		seen:   make(map[string]time.Time),
+		purged: time.Now(),
+	}
+}
+
+// Seen
+func (s *webhookReplayMemoryStorage) Seen(key string, at time.Time) (bool, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	expireAt, ok := s.seen[key]
+	return ok && expireAt.After(at), nil
+}
+
+// Add
+func (s *webhookReplayMemoryStorage) Add(key string, expireAt time.Time) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	now := time.Now()
+	s.purge(now)
+	s.seen[key] = expireAt
+	return nil
+}
+
+// purge removes expired signatures
+func (s *webhookReplayMemoryStorage) purge(now time.Time) {
+	if now.Sub(s.purged) < webhookReplayPurgeInterval {
+		return
+	}
+	for key, expireAt := range s.seen {
+		if !expireAt.After(now) {
+			delete(s.seen, key)
+		}
+	}
+	s.purged = now
+}
@@ -0,0 +1,355 @@
+package common
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	WebhookSignatureHmacSha256 = "hmac-sha256"
+	WebhookSignatureHmacSha512 = "hmac-sha512"
+	WebhookSignatureSha256     = "sha256"
+	WebhookSignatureSha512     = "sha512"
+	WebhookSignatureRsaSha256  = "rsa-sha256"
+	WebhookSignatureRsaSha512  = "rsa-sha512"
+
+	webhookVerificationListSeparator = ";"
+)
+
+var (
+	ErrWebhookIpNotAllowed      = errors.New("ip address is not allowed for callbacks of the provider")
+	ErrWebhookSignatureInvalid  = errors.New("signature of the callback is invalid")
+	ErrWebhookTimestampExpired  = errors.New("timestamp of the callback is out of the replay window")
+	ErrWebhookReplayed          = errors.New("callback was already processed")
+	ErrWebhookNotConfigured     = errors.New("neither secrets nor public keys of the provider are configured")
+	ErrWebhookAlgorithmUnknown  = errors.New("signature algorithm is unknown")
+	ErrWebhookPublicKeyNotRsa   = errors.New("public key is not rsa key")
+	ErrWebhookPublicKeyNotFound = errors.New("pem block of the public key not found")
+)
+
+// webhookSignatureHashes are hash functions of the signature algorithms
+var webhookSignatureHashes = map[string]crypto.Hash{
+	WebhookSignatureHmacSha256: crypto.SHA256,
+	WebhookSignatureHmacSha512: crypto.SHA512,
+	WebhookSignatureSha256:     crypto.SHA256,
+	WebhookSignatureSha512:     crypto.SHA512,
+	WebhookSignatureRsaSha256:  crypto.SHA256,
+	WebhookSignatureRsaSha512:  crypto.SHA512,
+}
+
+// WebhookVerificationRule is a parsed verification settings of the provider
+type WebhookVerificationRule struct {
+	// Algorithm overrides default algorithm of the provider if it's set
+	Algorithm   string
+	Secrets     []string
+	PublicKeys  []*rsa.PublicKey
+	AllowedNets []*net.IPNet
+}
+
+// Rules returns verification settings of each configured provider
+func (w *WebhookVerification) Rules() (map[string]*WebhookVerificationRule, error) {
+	rules := make(map[string]*WebhookVerificationRule)
+	rule := func(provider string) *WebhookVerificationRule {
+		if _, ok := rules[provider]; !ok {
+			rules[provider] = &WebhookVerificationRule{}
+		}
+		return rules[provider]
+	}
+
+	for provider, secrets := range w.WebhookSecrets {
+		rule(provider).Secrets = splitWebhookVerificationList(secrets)
+	}
+
+	for provider, paths := range w.WebhookPublicKeys {
+		for _, path := range splitWebhookVerificationList(paths) {
+			key, err := readWebhookPublicKey(path)
+			if err != nil {
+				return nil, fmt.Errorf("public key %s of provider %s: %s", path, provider, err)
+			}
+			rule(provider).PublicKeys = append(rule(provider).PublicKeys, key)
+		}
+	}
+
+	for provider, algorithm := range w.WebhookAlgorithms {
+		if _, ok := webhookSignatureHashes[algorithm]; !ok {
+			return nil, fmt.Errorf("signature algorithm %s of provider %s is unknown", algorithm, provider)
+		}
+		rule(provider).Algorithm = algorithm
+	}
+
+	for provider, ips := range w.WebhookAllowedIps {
+		for _, ip := range splitWebhookVerificationList(ips) {
+			network, err := parseWebhookNetwork(ip)
+			if err != nil {
+				return nil, fmt.Errorf("allowed ip %s of provider %s: %s", ip, provider, err)
+			}
+			rule(provider).AllowedNets = append(rule(provider).AllowedNets, network)
+		}
+	}
+
+	return rules, nil
+}
+
+// WebhookVerifier checks callbacks of the payment providers before they are parsed and sent to the billing server,
+// the callbacks of the providers without secrets and keys are rejected
+type WebhookVerifier struct {
+	mx     sync.RWMutex
+	rules  map[string]*WebhookVerificationRule
+	window time.Duration
+	// replays are signatures of the processed callbacks
+	replays WebhookReplayStorage
+}
+
+// NewWebhookVerifier returns verifier keeping the processed callbacks in the process memory,
+// the shared storage is set by SetReplayStorage when the handlers are created
+func NewWebhookVerifier(cfg WebhookVerification) (*WebhookVerifier, error) {
+	v := &WebhookVerifier{
+		replays: NewWebhookReplayMemoryStorage(),
+	}
+	if err := v.SetConfig(cfg); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// SetReplayStorage replaces storage of the processed callbacks
+func (v *WebhookVerifier) SetReplayStorage(replays WebhookReplayStorage) {
+	v.mx.Lock()
+	defer v.mx.Unlock()
+
+	v.replays = replays
+}
+
+// SetConfig replaces rules of the verifier, the previous rules are kept if the settings are malformed
+func (v *WebhookVerifier) SetConfig(cfg WebhookVerification) error {
+	rules, err := cfg.Rules()
+	if err != nil {
+		return err
+	}
+
+	v.mx.Lock()
+	defer v.mx.Unlock()
+
+	v.rules = rules
+	v.window = cfg.WebhookReplayWindow
+	return nil
+}
+
+// AllowIp checks that callbacks of the provider may be sent from the address
+func (v *WebhookVerifier) AllowIp(provider, ip string) error {
+	v.mx.RLock()
+	defer v.mx.RUnlock()
+
+	rule, ok := v.rules[provider]
+	if !ok || len(rule.AllowedNets) == 0 {
+		return nil
+	}
+
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return ErrWebhookIpNotAllowed
+	}
+
+	for _, network := range rule.AllowedNets {
+		if network.Contains(addr) {
+			return nil
+		}
+	}
+
+	return ErrWebhookIpNotAllowed
+}
+
+// Configured checks that the provider has secrets or public keys to verify its callbacks
+func (v *WebhookVerifier) Configured(provider string) bool {
+	v.mx.RLock()
+	defer v.mx.RUnlock()
+
+	rule, ok := v.rules[provider]
+	return ok && (len(rule.Secrets) > 0 || len(rule.PublicKeys) > 0)
+}
+
+// Verify checks signature of the callback, then its timestamp and that the callback wasn't processed before,
+// so the unsigned requests can't probe the processed callbacks
+func (v *WebhookVerifier) Verify(provider string, s *ProviderWebhookSignature) error {
+	v.mx.RLock()
+	defer v.mx.RUnlock()
+
+	rule, ok := v.rules[provider]
+	if !ok || (len(rule.Secrets) == 0 && len(rule.PublicKeys) == 0) {
+		return ErrWebhookNotConfigured
+	}
+
+	if err := v.verifySignature(rule, s); err != nil {
+		return err
+	}
+
+	if v.window <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+
+	if !s.Timestamp.IsZero() && (s.Timestamp.Before(now.Add(-v.window)) || s.Timestamp.After(now.Add(v.window))) {
+		return ErrWebhookTimestampExpired
+	}
+
+	seen, err := v.replays.Seen(webhookSeenKey(provider, s), now)
+	if err != nil {
+		return err
+	}
+	if seen {
+		return ErrWebhookReplayed
+	}
+
+	return nil
+}
+
+func (v *WebhookVerifier) verifySignature(rule *WebhookVerificationRule, s *ProviderWebhookSignature) error {
+	algorithm := s.Algorithm
+	if rule.Algorithm != "" {
+		algorithm = rule.Algorithm
+	}
+
+	signature, err := decodeWebhookSignature(s.Value)
+	if err != nil || len(signature) == 0 {
+		return ErrWebhookSignatureInvalid
+	}
+
+	h, ok := webhookSignatureHashes[algorithm]
+	if !ok {
+		return ErrWebhookAlgorithmUnknown
+	}
+
+	switch algorithm {
+	case WebhookSignatureHmacSha256, WebhookSignatureHmacSha512:
+		for _, secret := range rule.Secrets {
+			mac := hmac.New(h.New, []byte(secret))
+			writeWebhookContent(mac, s, "")
+			if hmac.Equal(mac.Sum(nil), signature) {
+				return nil
+			}
+		}
+	case WebhookSignatureSha256, WebhookSignatureSha512:
+		for _, secret := range rule.Secrets {
+			digest := h.New()
+			writeWebhookContent(digest, s, secret)
+			if subtle.ConstantTimeCompare(digest.Sum(nil), signature) == 1 {
+				return nil
+			}
+		}
+	case WebhookSignatureRsaSha256, WebhookSignatureRsaSha512:
+		digest := h.New()
+		writeWebhookContent(digest, s, "")
+		hashed := digest.Sum(nil)
+
+		for _, key := range rule.PublicKeys {
+			if rsa.VerifyPKCS1v15(key, h, hashed, signature) == nil {
+				return nil
+			}
+		}
+	}
+
+	return ErrWebhookSignatureInvalid
+}
+
+// Processed marks the callback as processed, the same callback is reported as replayed within the replay window
+func (v *WebhookVerifier) Processed(provider string, s *ProviderWebhookSignature) error {
+	v.mx.RLock()
+	defer v.mx.RUnlock()
+
+	if v.window <= 0 || s.Value == "" {
+		return nil
+	}
+
+	return v.replays.Add(webhookSeenKey(provider, s), time.Now().Add(v.window))
+}
+
+func webhookSeenKey(provider string, s *ProviderWebhookSignature) string {
+	return provider + ":" + strings.ToLower(s.Value)
+}
+
+func writeWebhookContent(h hash.Hash, s *ProviderWebhookSignature, secret string) {
+	h.Write(s.Content)
+	h.Write([]byte(secret))
+	h.Write(s.ContentSuffix)
+}
+
+// decodeWebhookSignature decodes hex signatures, the others are expected to be base64 encoded
+func decodeWebhookSignature(value string) ([]byte, error) {
+	if signature, err := hex.DecodeString(value); err == nil {
+		return signature, nil
+	}
+	return base64.StdEncoding.DecodeString(value)
+}
+
+func readWebhookPublicKey(path string) (*rsa.PublicKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrWebhookPublicKeyNotFound
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, ErrWebhookPublicKeyNotRsa
+	}
+
+	return rsaKey, nil
+}
+
+// parseWebhookNetwork parses network in CIDR notation or single ip address
+func parseWebhookNetwork(value string) (*net.IPNet, error) {
+	if strings.Contains(value, "/") {
+		_, network, err := net.ParseCIDR(value)
+		return network, err
+	}
+
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil, errors.New("invalid ip address")
+	}
+
+	bits := 8 * net.IPv4len
+	if ip.To4() == nil {
+		bits = 8 * net.IPv6len
+	} else {
+		ip = ip.To4()
+	}
+
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+func splitWebhookVerificationList(value string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, webhookVerificationListSeparator) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,92 @@
+package common
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	ZipCountryField = "Country"
+)
+
+var (
+	// ZipGeneralRegexp is a format of the zip codes of the countries missing in ZipRegexp
+	ZipGeneralRegexp = regexp.MustCompile("^[0-9A-Z][0-9A-Z -]{1,9}$")
+
+	// zipInwardCodeLength is a length of the trailing part of the zip code separated by space in the formats
+	// of the countries, the space is inserted by normalization if the zip code is written without it
+	zipInwardCodeLength = map[string]int{
+		"AC": 3,
+		"BM": 2,
+		"CA": 3,
+		"CZ": 2,
+		"FK": 3,
+		"GB": 3,
+		"GG": 3,
+		"GI": 3,
+		"GR": 2,
+		"GS": 3,
+		"IM": 3,
+		"IO": 3,
+		"JE": 3,
+		"MS": 4,
+		"MT": 4,
+		"NL": 2,
+		"PN": 3,
+		"SE": 2,
+		"SH": 3,
+		"SK": 2,
+		"TC": 3,
+		"UZ": 3,
+	}
+)
+
+// ZipValidateRequest
+type ZipValidateRequest struct {
+	Country string `query:"country" validate:"required,len=2,alpha"`
+	Zip     string `query:"zip" validate:"required,max=30"`
+}
+
+// ZipValidateResponse contains normalized zip code and result of its validation
+type ZipValidateResponse struct {
+	Country string `json:"country"`
+	Zip     string `json:"zip"`
+	Valid   bool   `json:"valid"`
+}
+
+// BillingAddress is a part of the billing address of the customer validated by the api
+type BillingAddress struct {
+	Country string `validate:"required,len=2"`
+	Zip     string `validate:"omitempty,zip=Country"`
+}
+
+// NormalizeZip upper-cases zip code, removes extra spaces and separates inward code of the countries using it
+func NormalizeZip(country, zip string) string {
+	zip = strings.Join(strings.Fields(strings.ToUpper(zip)), " ")
+
+	if n, ok := zipInwardCodeLength[strings.ToUpper(country)]; ok && len(zip) > n && !strings.Contains(zip, " ") {
+		zip = zip[:len(zip)-n] + " " + zip[len(zip)-n:]
+	}
+
+	return zip
+}
+
+// IsZipRequired checks that the country has the known format of the zip codes,
+// many countries missing in ZipRegexp don't use the zip codes at all
+func IsZipRequired(country string) bool {
+	_, ok := ZipRegexp[strings.ToUpper(country)]
+	return ok
+}
+
+// ValidateZip returns normalized zip code and checks it by the format of the country
+func ValidateZip(country, zip string) (string, bool) {
+	country = strings.ToUpper(country)
+	zip = NormalizeZip(country, zip)
+
+	reg, ok := ZipRegexp[country]
+	if !ok {
+		reg = ZipGeneralRegexp
+	}
+
+	return zip, reg.MatchString(zip)
+}
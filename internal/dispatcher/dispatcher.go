@@ -10,8 +10,13 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
+	"github.com/paysuper/paysuper-management-api/pkg/trace"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"html/template"
 	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
 )
 
 // Dispatcher
@@ -20,31 +25,51 @@ type Dispatcher struct {
 	cfg    Config
 	appSet AppSet
 	provider.LMT
-	globalCfg *common.Config
+	globalCfg    *common.Config
+	idempotency  common.IdempotencyStorage
+	rateLimiters map[string]*common.RateLimiter
+	// templates are parsed once, readiness reports the result of the parsing
+	templatesOnce sync.Once
+	templates     *common.Template
+	templatesErr  error
+	// draining is set when the service is shutting down
+	draining int32
 }
 
 // dispatch
 func (d *Dispatcher) Dispatch(echoHttp *echo.Echo) error {
 
-	t, e := template.New("").Funcs(common.FuncMap).ParseGlob(d.cfg.WorkDir + "/assets/web/template/*.html")
+	t, e := d.parsedTemplates()
 	if e != nil {
 		return e
 	}
-	echoHttp.Renderer = common.NewTemplate(t)
+	echoHttp.Renderer = t
+
+	catalogue, e := common.NewErrorCatalogue(d.cfg.WorkDir + "/assets/locales/errors")
+	if e != nil {
+		return e
+	}
+	echoHttp.HTTPErrorHandler = d.HTTPErrorHandler(catalogue)
 
 	// Called after routes
+	echoHttp.Use(d.TracingMiddleware) // 6
+	echoHttp.Use(d.MetricsMiddleware) // 5
 	echoHttp.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{
 		Output: logger.NewLevelWriter(d.L(), logger.LevelInfo),
 		Format: `{"id":"${id}","remote_ip":"${remote_ip}",` +
 			`"host":"${host}","method":"${method}","uri":"${uri}","user_agent":"${user_agent}",` +
 			`"status":${status},"error":"${error}","latency":${latency},"latency_human":"${latency_human}"` +
 			`,"bytes_in":${bytes_in},"bytes_out":${bytes_out}}`,
-	})) // 3
-	echoHttp.Use(d.RecoverMiddleware()) // 2
+	})) // 4
+	echoHttp.Use(d.RecoverMiddleware())      // 3
+	echoHttp.Use(d.CircuitBreakerMiddleware) // 2
 	echoHttp.Use(middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowHeaders: []string{"authorization", "content-type"},
+		AllowHeaders: []string{"authorization", "content-type", "idempotency-key", "traceparent", "x-customer-token"},
 	})) // 1
 	// Called before routes
+	rateLimit := d.RateLimitMiddleware(common.RateLimitGroupCommon, d.commonRateLimitSkipper, d.rateLimitKeyByIp)
+	echoHttp.Use(d.SecurityHeadersMiddleware())  // 4
+	echoHttp.Use(rateLimit)                      // 3
 	echoHttp.Use(d.RawBodyPreMiddleware)         // 2
 	echoHttp.Use(d.LimitOffsetSortPreMiddleware) // 1
 	// init group routes
@@ -57,6 +82,9 @@ func (d *Dispatcher) Dispatch(echoHttp *echo.Echo) error {
 	d.authProjectGroup(grp.AuthProject)
 	d.authUserGroup(grp.AuthUser)
 	d.webHookGroup(grp.WebHooks)
+	echoHttp.GET(common.MetricsPath, echo.WrapHandler(promhttp.Handler()))
+	d.healthRoutes(echoHttp)
+	d.securityRoutes(echoHttp)
 	// init routes
 	for _, handler := range d.appSet.Handlers {
 		handler.Route(grp)
@@ -64,6 +92,34 @@ func (d *Dispatcher) Dispatch(echoHttp *echo.Echo) error {
 	return nil
 }
 
+// Drain marks the service as not ready, so it's removed from the load balancer before the shutdown
+func (d *Dispatcher) Drain() {
+	atomic.StoreInt32(&d.draining, 1)
+}
+
+// parsedTemplates returns templates parsed on the first call
+func (d *Dispatcher) parsedTemplates() (*common.Template, error) {
+	d.templatesOnce.Do(func() {
+		d.templates, d.templatesErr = d.parseTemplates()
+	})
+	return d.templates, d.templatesErr
+}
+
+func (d *Dispatcher) parseTemplates() (*common.Template, error) {
+	t, e := template.New("").Funcs(common.FuncMap).ParseGlob(d.cfg.WorkDir + "/assets/web/template/*.html")
+	if e != nil {
+		return nil, e
+	}
+	if t, e = t.ParseGlob(d.cfg.WorkDir + "/assets/web/template/partials/*.html"); e != nil {
+		return nil, e
+	}
+	forms, e := common.ParsePaymentForms(t, d.cfg.WorkDir+"/assets/web/template/payment")
+	if e != nil {
+		return nil, e
+	}
+	return common.NewTemplate(t, forms), nil
+}
+
 func (d *Dispatcher) commonRoutes(echoHttp *echo.Echo) {
 	echoHttp.Static("/", d.cfg.WorkDir+"/assets/web/static")
 	echoHttp.Static("/spec", d.cfg.WorkDir+"/api")
@@ -75,6 +131,9 @@ func (d *Dispatcher) commonRoutes(echoHttp *echo.Echo) {
 func (d *Dispatcher) authProjectGroup(grp *echo.Group) {
 	// Called after routes
 	grp.Use(d.BodyDumpMiddleware()) // 1
+	// Called before routes
+	grp.Use(d.RateLimitMiddleware(common.RateLimitGroupAuthProject, nil, d.rateLimitKeyByProject))
+	grp.Use(d.IdempotencyMiddleware)
 }
 
 func (d *Dispatcher) accessGroup(grp *echo.Group) {
@@ -103,18 +162,49 @@ func (d *Dispatcher) authUserGroup(grp *echo.Group) {
 		) // 1
 		// Called before routes
 		grp.Use(d.GetUserDetailsMiddleware) // 1
+		grp.Use(d.AuthUserRolesMiddleware)  // 2
+		grp.Use(d.AccessControlMiddleware)  // 3
 	}
+	grp.Use(d.RateLimitMiddleware(common.RateLimitGroupAuthUser, nil, d.rateLimitKeyByUser))
+	grp.Use(d.IdempotencyMiddleware)
 }
 
 func (d *Dispatcher) webHookGroup(grp *echo.Group) {
 	// Called after routes
 	grp.Use(d.BodyDumpMiddleware()) // 1
+	// Called before routes
+	grp.Use(d.RateLimitMiddleware(common.RateLimitGroupWebHooks, nil, d.rateLimitKeyByIp)) // 1
+}
+
+func (d *Dispatcher) initRateLimiters(cfg common.RateLimit) {
+	rules := cfg.Rules()
+	if d.rateLimiters == nil {
+		d.rateLimiters = make(map[string]*common.RateLimiter)
+		for _, group := range []string{
+			common.RateLimitGroupAuthProject,
+			common.RateLimitGroupAuthUser,
+			common.RateLimitGroupWebHooks,
+			common.RateLimitGroupCommon,
+		} {
+			d.rateLimiters[group] = common.NewRateLimiter(rules[group])
+		}
+		return
+	}
+	for group, limiter := range d.rateLimiters {
+		limiter.SetRule(rules[group])
+	}
 }
 
 // Config
 type Config struct {
-	Debug   bool `fallback:"shared.debug"`
-	WorkDir string
+	Debug         bool `fallback:"shared.debug"`
+	WorkDir       string
+	TraceExporter string
+	// Global holds hot reloadable settings of the global config
+	Global struct {
+		RateLimit           common.RateLimit
+		WebhookVerification common.WebhookVerification
+	}
 	invoker *invoker.Invoker
 }
 
@@ -130,19 +220,37 @@ func (c *Config) Reload(ctx context.Context) {
 
 // AppSet
 type AppSet struct {
-	Handlers    common.Handlers
-	Services    common.Services
-	JwtVerifier *jwtverifier.JwtVerifier
+	Handlers        common.Handlers
+	Services        common.Services
+	JwtVerifier     *jwtverifier.JwtVerifier
+	WebhookVerifier *common.WebhookVerifier
 }
 
 // New
 func New(ctx context.Context, set provider.AwareSet, appSet AppSet, cfg *Config, globalCfg *common.Config) *Dispatcher {
 	set.Logger = set.Logger.WithFields(logger.Fields{"service": common.Prefix})
-	return &Dispatcher{
-		ctx:       ctx,
-		cfg:       *cfg,
-		appSet:    appSet,
-		LMT:       &set,
-		globalCfg: globalCfg,
+	d := &Dispatcher{
+		ctx:         ctx,
+		cfg:         *cfg,
+		appSet:      appSet,
+		LMT:         &set,
+		globalCfg:   globalCfg,
+		idempotency: common.NewIdempotencyMemoryStorage(),
+	}
+	// the limits are read from the reloadable settings only, so the startup and the reload can't diverge
+	d.initRateLimiters(cfg.Global.RateLimit)
+	if cfg.TraceExporter == trace.ExporterStdout {
+		trace.SetTracer(trace.NewTracer(trace.NewWriterExporter(os.Stdout)))
 	}
+	cfg.OnReload(func(ctx context.Context) {
+		d.initRateLimiters(cfg.Global.RateLimit)
+		d.L().Info("rate limits reloaded")
+		// secrets of the providers are rotated without restart, malformed settings don't replace the current ones
+		if err := appSet.WebhookVerifier.SetConfig(cfg.Global.WebhookVerification); err != nil {
+			d.L().Error("webhook verification settings not reloaded", logger.PairArgs("err", err.Error()))
+			return
+		}
+		d.L().Info("webhook verification settings reloaded")
+	})
+	return d
 }
@@ -0,0 +1,101 @@
+package dispatcher
+
+import (
+	"context"
+	"errors"
+	geoip "github.com/ProtocolONE/geoip-service/pkg"
+	"github.com/labstack/echo/v4"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
+	paylinkServiceConst "github.com/paysuper/paysuper-payment-link/pkg"
+	reporterPkg "github.com/paysuper/paysuper-reporter/pkg"
+	taxServiceConst "github.com/paysuper/paysuper-tax-service/pkg"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	healthCheckTimeout = 5 * time.Second
+)
+
+var (
+	errorHealthShutdown = errors.New("service is shutting down")
+
+	healthServices = map[string]string{
+		"billing":  pkg.ServiceName,
+		"paylink":  paylinkServiceConst.ServiceName,
+		"tax":      taxServiceConst.ServiceName,
+		"reporter": reporterPkg.ServiceName,
+		"geo":      geoip.ServiceName,
+	}
+)
+
+func (d *Dispatcher) healthRoutes(echoHttp *echo.Echo) {
+	echoHttp.GET(common.HealthPath, d.liveness)
+	echoHttp.GET(common.ReadinessPath, d.readiness)
+}
+
+// liveness reports that the process is able to serve requests, dependencies aren't checked
+func (d *Dispatcher) liveness(ctx echo.Context) error {
+	return ctx.JSON(http.StatusOK, &common.HealthResponse{Status: common.HealthStatusOk})
+}
+
+// readiness fails by the local state only, i.e. once the service is drained for the graceful shutdown
+// or its templates are broken, state of the dependencies is reported without failing the readiness
+func (d *Dispatcher) readiness(ctx echo.Context) error {
+	reqCtx, cancel := context.WithTimeout(ctx.Request().Context(), healthCheckTimeout)
+	defer cancel()
+
+	res := common.RunHealthChecks(reqCtx, d.readinessChecks())
+	status := http.StatusOK
+	if res.Status == common.HealthStatusFail {
+		status = http.StatusServiceUnavailable
+	}
+
+	return ctx.JSON(status, res)
+}
+
+func (d *Dispatcher) readinessChecks() []common.HealthCheck {
+	checks := []common.HealthCheck{
+		{
+			Name: "shutdown",
+			Check: func(ctx context.Context) error {
+				if atomic.LoadInt32(&d.draining) == 1 || d.ctx.Err() != nil {
+					return errorHealthShutdown
+				}
+				return nil
+			},
+			Critical: true,
+		},
+		{
+			Name: "templates",
+			Check: func(ctx context.Context) error {
+				_, err := d.parsedTemplates()
+				return err
+			},
+			Critical: true,
+		},
+	}
+
+	if checker := d.appSet.Services.Health; checker != nil {
+		checks = append(checks, common.HealthCheck{Name: "registry", Check: checker.CheckRegistry})
+		for name, service := range healthServices {
+			service := service
+			checks = append(checks, common.HealthCheck{
+				Name: name,
+				Check: func(ctx context.Context) error {
+					return checker.CheckService(ctx, service)
+				},
+			})
+		}
+	}
+
+	for _, handler := range d.appSet.Handlers {
+		if reporter, ok := handler.(common.HealthReporter); ok {
+			checks = append(checks, reporter.HealthChecks()...)
+		}
+	}
+
+	return checks
+}
@@ -0,0 +1,42 @@
+package dispatcher
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	metricsNamespace   = "management_api"
+	metricsUnknownPath = "unknown"
+)
+
+var (
+	httpRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_requests_total",
+			Help:      "Total number of the handled http requests.",
+		},
+		[]string{"path", "method", "status"},
+	)
+	httpRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of the handled http requests.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"path", "method", "status"},
+	)
+	cspViolationsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "csp_violations_total",
+			Help:      "Total number of the reported content security policy violations.",
+		},
+		[]string{"directive"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(httpRequestsTotal, httpRequestDuration, cspViolationsTotal)
+}
@@ -7,11 +7,37 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
+	"github.com/paysuper/paysuper-management-api/pkg/micro"
+	"github.com/paysuper/paysuper-management-api/pkg/trace"
+	"io"
 	"io/ioutil"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 )
 
+const (
+	idempotencyKeyLifetimeDefault = 24 * time.Hour
+	circuitBreakerRetryAfter      = 30
+)
+
+type idempotencyResponseWriter struct {
+	io.Writer
+	http.ResponseWriter
+}
+
+// Write
+func (w *idempotencyResponseWriter) Write(b []byte) (int, error) {
+	return w.Writer.Write(b)
+}
+
+// WriteHeader
+func (w *idempotencyResponseWriter) WriteHeader(code int) {
+	w.ResponseWriter.WriteHeader(code)
+}
+
 // RecoverMiddleware
 func (d *Dispatcher) RecoverMiddleware() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -31,6 +57,85 @@ func (d *Dispatcher) RecoverMiddleware() echo.MiddlewareFunc {
 	}
 }
 
+// TracingMiddleware starts span around the handler and sets request identifier used by the logger
+func (d *Dispatcher) TracingMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		req := ctx.Request()
+		reqCtx := req.Context()
+		if parent, ok := trace.ParseTraceparent(req.Header.Get(trace.HeaderTraceparent)); ok {
+			reqCtx = trace.ContextWithRemoteSpanContext(reqCtx, parent)
+		}
+
+		reqCtx, span := trace.GetTracer().Start(reqCtx, req.Method+" "+ctx.Path())
+		defer span.Finish()
+
+		requestId := req.Header.Get(echo.HeaderXRequestID)
+		if requestId == "" {
+			requestId = span.Context.TraceId
+		}
+		ctx.Response().Header().Set(echo.HeaderXRequestID, requestId)
+		ctx.Response().Header().Set(trace.HeaderTraceparent, span.Context.Traceparent())
+
+		span.SetAttribute("http.method", req.Method)
+		span.SetAttribute("http.route", ctx.Path())
+		span.SetAttribute("http.request_id", requestId)
+		ctx.SetRequest(req.WithContext(reqCtx))
+
+		err := next(ctx)
+		span.SetAttribute("http.status_code", responseStatus(ctx, err))
+		span.SetError(err)
+
+		return err
+	}
+}
+
+// MetricsMiddleware collects count and latency of the requests labelled by route template, method and status
+func (d *Dispatcher) MetricsMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		start := time.Now()
+		err := next(ctx)
+		status := responseStatus(ctx, err)
+
+		path := ctx.Path()
+		if path == "" {
+			path = metricsUnknownPath
+		}
+
+		labels := []string{path, ctx.Request().Method, strconv.Itoa(status)}
+		httpRequestsTotal.WithLabelValues(labels...).Inc()
+		httpRequestDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+
+		return err
+	}
+}
+
+// responseStatus returns status of the response, errors are not written to the response yet
+func responseStatus(ctx echo.Context, err error) int {
+	if err == nil {
+		return ctx.Response().Status
+	}
+	if httpErr, ok := err.(*echo.HTTPError); ok {
+		return httpErr.Code
+	}
+	return http.StatusInternalServerError
+}
+
+// CircuitBreakerMiddleware responds with 503 if the handler failed because of the open circuit of the called service
+func (d *Dispatcher) CircuitBreakerMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		reqCtx := micro.NewCircuitContext(ctx.Request().Context())
+		ctx.SetRequest(ctx.Request().WithContext(reqCtx))
+
+		err := next(ctx)
+		if err != nil && micro.CircuitOpened(reqCtx) {
+			ctx.Response().Header().Set(common.HeaderRetryAfter, strconv.Itoa(circuitBreakerRetryAfter))
+			return echo.NewHTTPError(http.StatusServiceUnavailable, common.ErrorMessageServiceUnavailable)
+		}
+
+		return err
+	}
+}
+
 // GetUserDetailsMiddleware
 func (d *Dispatcher) GetUserDetailsMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(ctx echo.Context) error {
@@ -60,6 +165,207 @@ func (d *Dispatcher) GetUserDetailsMiddleware(next echo.HandlerFunc) echo.Handle
 	}
 }
 
+// AuthUserRolesMiddleware
+func (d *Dispatcher) AuthUserRolesMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		user := common.ExtractUserContext(ctx)
+		if user.Roles == nil {
+			user.Roles = make(map[string]bool)
+		}
+		if user.Merchants == nil {
+			user.Merchants = make(map[string]bool)
+		}
+		for _, role := range d.globalCfg.Rbac.UserRoles(user.Id) {
+			user.Roles[role] = true
+		}
+		common.SetUserContext(ctx, user)
+		return next(ctx)
+	}
+}
+
+// AccessControlMiddleware
+func (d *Dispatcher) AccessControlMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		if err := common.CheckRouteAccess(d.appSet.Services, d.L(), ctx); err != nil {
+			return err
+		}
+
+		return next(ctx)
+	}
+}
+
+// IdempotencyMiddleware replays the saved response for repeated mutating requests with the same Idempotency-Key header
+func (d *Dispatcher) IdempotencyMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		method := ctx.Request().Method
+		if method != http.MethodPost && method != http.MethodPut && method != http.MethodPatch {
+			return next(ctx)
+		}
+
+		key := ctx.Request().Header.Get(common.HeaderIdempotencyKey)
+		if key == "" {
+			return next(ctx)
+		}
+		if len(key) > common.IdempotencyKeyMaxLength {
+			return echo.NewHTTPError(http.StatusBadRequest, common.ErrorMessageIdempotencyKeyInvalid)
+		}
+
+		// the project is verified before the lookup, so the saved response isn't returned to anyone sending its id
+		projectId, err := common.ExtractVerifiedProjectId(d.appSet.Services, d.L(), ctx)
+		if err != nil {
+			return err
+		}
+
+		var scope string
+		if user := common.ExtractUserContext(ctx); user.Id != "" {
+			scope = "user:" + user.Id
+		} else if projectId != "" {
+			scope = "project:" + projectId
+		} else {
+			scope = "ip:" + ctx.RealIP()
+		}
+
+		storageKey := scope + ":" + key
+		hash := common.IdempotencyRequestHash(method, ctx.Request().URL.Path, common.ExtractRawBodyContext(ctx))
+
+		rec, err := d.idempotency.Get(storageKey)
+		if err != nil {
+			d.L().Error("idempotency storage get failed", logger.PairArgs("err", err.Error(), "key", storageKey))
+			return echo.NewHTTPError(http.StatusInternalServerError, common.ErrorUnknown)
+		}
+
+		if rec == nil {
+			lifetime := d.globalCfg.IdempotencyKeyLifetime
+			if lifetime <= 0 {
+				lifetime = idempotencyKeyLifetimeDefault
+			}
+
+			reserved, err := d.idempotency.Reserve(storageKey, hash, lifetime)
+			if err != nil {
+				d.L().Error("idempotency storage reserve failed", logger.PairArgs("err", err.Error(), "key", storageKey))
+				return echo.NewHTTPError(http.StatusInternalServerError, common.ErrorUnknown)
+			}
+			if !reserved {
+				return echo.NewHTTPError(http.StatusConflict, common.ErrorMessageIdempotencyRequestInProgress)
+			}
+
+			return d.executeIdempotent(ctx, next, storageKey)
+		}
+
+		if rec.RequestHash != hash {
+			return echo.NewHTTPError(http.StatusUnprocessableEntity, common.ErrorMessageIdempotencyKeyConflict)
+		}
+		if !rec.Completed {
+			return echo.NewHTTPError(http.StatusConflict, common.ErrorMessageIdempotencyRequestInProgress)
+		}
+
+		ctx.Response().Header().Set(common.HeaderIdempotentReplayed, "true")
+		return ctx.Blob(rec.Status, rec.ContentType, rec.Body)
+	}
+}
+
+func (d *Dispatcher) executeIdempotent(ctx echo.Context, next echo.HandlerFunc, storageKey string) error {
+	buf := new(bytes.Buffer)
+	res := ctx.Response()
+	writer := res.Writer
+	res.Writer = &idempotencyResponseWriter{Writer: io.MultiWriter(writer, buf), ResponseWriter: writer}
+
+	err := next(ctx)
+	res.Writer = writer
+
+	// Errors and server failures are not saved, the client is free to retry such requests
+	if err != nil || res.Status >= http.StatusInternalServerError {
+		if e := d.idempotency.Release(storageKey); e != nil {
+			d.L().Error("idempotency storage release failed", logger.PairArgs("err", e.Error(), "key", storageKey))
+		}
+		return err
+	}
+
+	e := d.idempotency.Complete(storageKey, res.Status, res.Header().Get(echo.HeaderContentType), buf.Bytes())
+	if e != nil {
+		d.L().Error("idempotency storage complete failed", logger.PairArgs("err", e.Error(), "key", storageKey))
+	}
+
+	return nil
+}
+
+// RateLimitMiddleware throttles requests of the group by the token bucket of the key returned by keyFn
+func (d *Dispatcher) RateLimitMiddleware(group string, skipper middleware.Skipper, keyFn func(echo.Context) string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			if skipper != nil && skipper(ctx) {
+				return next(ctx)
+			}
+
+			limiter, ok := d.rateLimiters[group]
+			if !ok {
+				return next(ctx)
+			}
+
+			allowed, retryAfter := limiter.Allow(keyFn(ctx))
+			if !allowed {
+				seconds := int(math.Ceil(retryAfter.Seconds()))
+				if seconds < 1 {
+					seconds = 1
+				}
+				ctx.Response().Header().Set(common.HeaderRetryAfter, strconv.Itoa(seconds))
+				return echo.NewHTTPError(http.StatusTooManyRequests, common.ErrorMessageRateLimitExceeded)
+			}
+
+			return next(ctx)
+		}
+	}
+}
+
+// commonRateLimitSkipper skips routes of the groups, they have own limits, and service routes
+func (d *Dispatcher) commonRateLimitSkipper(ctx echo.Context) bool {
+	path := ctx.Path()
+	if path == common.MetricsPath || path == common.HealthPath || path == common.ReadinessPath {
+		return true
+	}
+	for _, prefix := range []string{common.AuthProjectGroupPath, common.AuthUserGroupPath, common.WebHookGroupPath} {
+		if strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Dispatcher) rateLimitKeyByIp(ctx echo.Context) string {
+	return "ip:" + ctx.RealIP()
+}
+
+// rateLimitKeyByProject keys the requests by the address and the project of the body, the signature isn't checked
+// here, so the rejected requests don't cost a call of the billing server, it's checked after the request is admitted
+func (d *Dispatcher) rateLimitKeyByProject(ctx echo.Context) string {
+	if projectId := common.ExtractRawBodyProjectId(ctx); projectId != "" {
+		return d.rateLimitKeyByIp(ctx) + ":project:" + projectId
+	}
+	return d.rateLimitKeyByIp(ctx)
+}
+
+func (d *Dispatcher) rateLimitKeyByUser(ctx echo.Context) string {
+	if user := common.ExtractUserContext(ctx); user.Id != "" {
+		return "user:" + user.Id
+	}
+	return d.rateLimitKeyByIp(ctx)
+}
+
+// HTTPErrorHandler translates error response to the language requested by Accept-Language header
+func (d *Dispatcher) HTTPErrorHandler(catalogue *common.ErrorCatalogue) echo.HTTPErrorHandler {
+	return func(err error, ctx echo.Context) {
+		if httpErr, ok := err.(*echo.HTTPError); ok {
+			langs := common.ParseAcceptLanguage(ctx.Request().Header.Get(common.HeaderAcceptLanguage))
+			if len(langs) > 0 {
+				localized := *httpErr
+				localized.Message = catalogue.Localize(langs, httpErr.Message)
+				err = &localized
+			}
+		}
+		ctx.Echo().DefaultHTTPErrorHandler(err, ctx)
+	}
+}
+
 // LimitOffsetSortPreMiddleware
 func (d *Dispatcher) LimitOffsetSortPreMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
@@ -82,6 +388,24 @@ func (d *Dispatcher) LimitOffsetSortPreMiddleware(next echo.HandlerFunc) echo.Ha
 		if s, ok := qParams[common.QueryParameterNameSort]; ok {
 			sort = s
 		}
+		// Cursor replaces limit, offset and sort of the request
+		if token := c.QueryParam(common.QueryParameterNameCursor); token != "" {
+			pc, err := common.DecodeCursor(d.globalCfg.PaginationSecret(), token)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, common.ErrorMessagePaginationCursorInvalid)
+			}
+			if len(pc.Sort) > 0 {
+				sort = pc.Sort
+			}
+			common.SetCursorContext(c, &common.Cursor{
+				Limit:    pc.Limit,
+				Offset:   pc.Offset,
+				Sort:     sort,
+				Token:    token,
+				Position: pc.Position(),
+			})
+			return next(c)
+		}
 		//
 		common.SetCursorContext(c, &common.Cursor{
 			Limit:  int32(limit),
@@ -92,6 +416,31 @@ func (d *Dispatcher) LimitOffsetSortPreMiddleware(next echo.HandlerFunc) echo.Ha
 	}
 }
 
+// SecurityHeadersMiddleware sets content security policy and other security headers by the policy of the route
+func (d *Dispatcher) SecurityHeadersMiddleware() echo.MiddlewareFunc {
+	policies := common.NewSecurityPolicies(d.globalCfg)
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			nonce, err := common.NewCspNonce()
+			if err != nil {
+				d.L().Error(common.InternalErrorTemplate, logger.PairArgs("err", err.Error()))
+				return echo.NewHTTPError(http.StatusInternalServerError, common.ErrorInternal)
+			}
+			common.SetCspNonceContext(c, nonce)
+
+			policy, ok := policies[c.Path()]
+			if !ok {
+				policy = common.DefaultSecurityPolicy
+			}
+			// headers are set right before the response is written, so the handler is able to set frame ancestors
+			c.Response().Before(func() {
+				policy.SetHeaders(c, d.globalCfg)
+			})
+			return next(c)
+		}
+	}
+}
+
 // RawBodyPreMiddleware
 func (d *Dispatcher) RawBodyPreMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
@@ -103,15 +452,24 @@ func (d *Dispatcher) RawBodyPreMiddleware(next echo.HandlerFunc) echo.HandlerFun
 	}
 }
 
-// BodyDumpMiddleware
+// BodyDumpMiddleware logs requests and responses, the event streams are skipped
+// since their responses would be kept in memory until the client disconnects
 func (d *Dispatcher) BodyDumpMiddleware() echo.MiddlewareFunc {
-	return middleware.BodyDump(func(ctx echo.Context, reqBody, resBody []byte) {
-		data := map[string]interface{}{
-			"request_headers":  common.RequestResponseHeadersToString(ctx.Request().Header),
-			"request_body":     string(reqBody),
-			"response_headers": common.RequestResponseHeadersToString(ctx.Response().Header()),
-			"response_body":    string(resBody),
-		}
-		d.L().Info(ctx.Path(), logger.WithFields(data))
+	return middleware.BodyDumpWithConfig(middleware.BodyDumpConfig{
+		Skipper: bodyDumpSkipper,
+		Handler: func(ctx echo.Context, reqBody, resBody []byte) {
+			data := map[string]interface{}{
+				"request_headers":  common.RequestResponseHeadersToString(ctx.Request().Header),
+				"request_body":     string(reqBody),
+				"response_headers": common.RequestResponseHeadersToString(ctx.Response().Header()),
+				"response_body":    string(resBody),
+			}
+			d.L().Info(ctx.Path(), logger.WithFields(data))
+		},
 	})
 }
+
+func bodyDumpSkipper(ctx echo.Context) bool {
+	return common.EventStreamPaths[ctx.Path()] ||
+		strings.Contains(ctx.Request().Header.Get(echo.HeaderAccept), common.MIMETextEventStream)
+}
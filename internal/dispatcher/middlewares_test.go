@@ -0,0 +1,194 @@
+package dispatcher_test
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	billMock "github.com/paysuper/paysuper-billing-server/pkg/mocks"
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
+	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
+	"github.com/paysuper/paysuper-management-api/internal/mock"
+	"github.com/paysuper/paysuper-management-api/internal/test"
+	"github.com/paysuper/paysuper-management-api/pkg/micro"
+	"github.com/paysuper/paysuper-management-api/pkg/trace"
+	"github.com/stretchr/testify/assert"
+	mock2 "github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"testing"
+)
+
+const middlewaresTestPath = "/middlewares"
+
+// middlewaresTestRoute is a route of the AuthProject group covered by the dispatcher middlewares
+type middlewaresTestRoute struct {
+	circuitOpen bool
+}
+
+func (r *middlewaresTestRoute) Route(groups *common.Groups) {
+	groups.AuthProject.GET(middlewaresTestPath, r.handle)
+	groups.AuthProject.POST(middlewaresTestPath, r.handle)
+}
+
+func (r *middlewaresTestRoute) handle(ctx echo.Context) error {
+	if r.circuitOpen {
+		micro.MarkCircuitOpen(ctx.Request().Context())
+		return errors.New("circuit breaker is open")
+	}
+	return ctx.JSON(http.StatusOK, map[string]string{})
+}
+
+type MiddlewaresTestSuite struct {
+	suite.Suite
+	route   *middlewaresTestRoute
+	billing grpc.BillingService
+	caller  *test.EchoReqResCaller
+}
+
+func Test_Middlewares(t *testing.T) {
+	suite.Run(t, new(MiddlewaresTestSuite))
+}
+
+func (suite *MiddlewaresTestSuite) SetupTest() {
+	suite.route = &middlewaresTestRoute{}
+	suite.billing = mock.NewBillingServerOkMock()
+	suite.caller = suite.setUp(test.DefaultSettings())
+}
+
+func (suite *MiddlewaresTestSuite) TearDownTest() {}
+
+func (suite *MiddlewaresTestSuite) setUp(settings map[string]interface{}) *test.EchoReqResCaller {
+	srv := common.Services{
+		Billing: suite.billing,
+	}
+	caller, err := test.SetUp(settings, srv, func(set *test.TestSet, mw test.Middleware) common.Handlers {
+		return common.Handlers{
+			suite.route,
+		}
+	})
+	if err != nil {
+		panic(err)
+	}
+	return caller
+}
+
+func (suite *MiddlewaresTestSuite) TestMiddlewares_RateLimitExceeded() {
+	settings := test.DefaultSettings()
+	global := settings["dispatcher"].(map[string]interface{})["global"].(map[string]interface{})
+	global["rateLimit"] = map[string]interface{}{
+		"authProjectRate":  0.01,
+		"authProjectBurst": 1,
+	}
+	caller := suite.setUp(settings)
+
+	res, err := caller.Builder().
+		Path(common.AuthProjectGroupPath + middlewaresTestPath).
+		Exec(suite.T())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+
+	res, err = caller.Builder().
+		Path(common.AuthProjectGroupPath + middlewaresTestPath).
+		Exec(suite.T())
+	assert.Error(suite.T(), err)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusTooManyRequests, httpErr.Code)
+	assert.Equal(suite.T(), common.ErrorMessageRateLimitExceeded, httpErr.Message)
+	assert.NotEmpty(suite.T(), res.Header().Get(common.HeaderRetryAfter))
+}
+
+func (suite *MiddlewaresTestSuite) TestMiddlewares_RateLimitByProject() {
+	bs := &billMock.BillingService{}
+	suite.billing = bs
+
+	settings := test.DefaultSettings()
+	global := settings["dispatcher"].(map[string]interface{})["global"].(map[string]interface{})
+	global["rateLimit"] = map[string]interface{}{
+		"authProjectRate":  0.01,
+		"authProjectBurst": 1,
+	}
+	caller := suite.setUp(settings)
+
+	post := func(projectId string) error {
+		_, err := caller.Builder().
+			Method(http.MethodPost).
+			Path(common.AuthProjectGroupPath + middlewaresTestPath).
+			Init(func(request *http.Request, middleware test.Middleware) {
+				request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				request.Header.Set(common.HeaderXApiSignatureHeader, "forged_signature")
+			}).
+			BodyString(`{"project": "` + projectId + `"}`).
+			Exec(suite.T())
+		return err
+	}
+
+	// the projects of the same address have own buckets
+	assert.NoError(suite.T(), post("5cd5620f06ae110001509185"))
+	assert.NoError(suite.T(), post("5ced34d689fce60bf4440829"))
+
+	err := post("5cd5620f06ae110001509185")
+	assert.Error(suite.T(), err)
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusTooManyRequests, httpErr.Code)
+
+	// the signature is checked by the handlers of the admitted requests only
+	bs.AssertNotCalled(suite.T(), "CheckProjectRequestSignature", mock2.Anything, mock2.Anything, mock2.Anything)
+}
+
+func (suite *MiddlewaresTestSuite) TestMiddlewares_Metrics() {
+	_, err := suite.caller.Builder().
+		Path(common.AuthProjectGroupPath + middlewaresTestPath).
+		Exec(suite.T())
+	assert.NoError(suite.T(), err)
+
+	res, err := suite.caller.Builder().
+		Path(common.MetricsPath).
+		Exec(suite.T())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+	assert.Contains(suite.T(), res.Body.String(), "management_api_http_requests_total")
+	assert.Contains(suite.T(), res.Body.String(), `path="`+common.AuthProjectGroupPath+middlewaresTestPath+`"`)
+}
+
+func (suite *MiddlewaresTestSuite) TestMiddlewares_Tracing() {
+	exporter := trace.NewMemoryExporter()
+	trace.SetTracer(trace.NewTracer(exporter))
+	defer trace.SetTracer(trace.NewTracer(nil))
+
+	parent := trace.SpanContext{TraceId: "0af7651916cd43dd8448eb211c80319c", SpanId: "b7ad6b7169203331"}
+
+	res, err := suite.caller.Builder().
+		Path(common.AuthProjectGroupPath + middlewaresTestPath).
+		Init(func(request *http.Request, middleware test.Middleware) {
+			request.Header.Set(trace.HeaderTraceparent, parent.Traceparent())
+		}).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+	assert.Equal(suite.T(), parent.TraceId, res.Header().Get(echo.HeaderXRequestID))
+
+	spans := exporter.Spans()
+	assert.Len(suite.T(), spans, 1)
+	assert.Equal(suite.T(), http.MethodGet+" "+common.AuthProjectGroupPath+middlewaresTestPath, spans[0].Name)
+	assert.Equal(suite.T(), parent.TraceId, spans[0].Context.TraceId)
+	assert.Equal(suite.T(), parent.SpanId, spans[0].ParentId)
+	assert.Equal(suite.T(), http.StatusOK, spans[0].Attributes["http.status_code"])
+}
+
+func (suite *MiddlewaresTestSuite) TestMiddlewares_CircuitOpen() {
+	suite.route.circuitOpen = true
+
+	res, err := suite.caller.Builder().
+		Path(common.AuthProjectGroupPath + middlewaresTestPath).
+		Exec(suite.T())
+	assert.Error(suite.T(), err)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusServiceUnavailable, httpErr.Code)
+	assert.Equal(suite.T(), common.ErrorMessageServiceUnavailable, httpErr.Message)
+	assert.NotEmpty(suite.T(), res.Header().Get(common.HeaderRetryAfter))
+}
@@ -24,6 +24,7 @@ import (
 	taxServiceConst "github.com/paysuper/paysuper-tax-service/pkg"
 	tax_service "github.com/paysuper/paysuper-tax-service/proto"
 	"gopkg.in/go-playground/validator.v9"
+	"time"
 )
 
 // ProviderCfg
@@ -55,8 +56,17 @@ func ProviderJwtVerifier(cfg *common.Config) *jwtverifier.JwtVerifier {
 	})
 }
 
+// ProviderWebhookVerifier
+func ProviderWebhookVerifier(cfg *common.Config) (*common.WebhookVerifier, func(), error) {
+	v, e := cfg.NewWebhookVerifier()
+	return v, func() {}, e
+}
+
 // ProviderServices
 func ProviderServices(srv *micro.Micro) common.Services {
+	srv.AddCallPolicies(
+		micro.CallPolicy{Service: pkg.ServiceName, Method: "BillingService.UploadKeysFile", Timeout: 10 * time.Minute},
+	)
 	return common.Services{
 		Repository: repository.NewRepositoryService(constant.PayOneRepositoryServiceName, srv.Client()),
 		Geo:        proto.NewGeoIpService(geoip.ServiceName, srv.Client()),
@@ -64,6 +74,7 @@ func ProviderServices(srv *micro.Micro) common.Services {
 		Tax:        tax_service.NewTaxService(taxServiceConst.ServiceName, srv.Client()),
 		PayLink:    paylink.NewPaylinkService(paylinkServiceConst.ServiceName, srv.Client()),
 		Reporter:   reporterProto.NewReporterService(reporterPkg.ServiceName, srv.Client()),
+		Health:     srv,
 	}
 }
 
@@ -82,6 +93,9 @@ func ProviderValidators(v *validators.ValidatorSet) (validate *validator.Validat
 	if err = validate.RegisterValidation("zip_usa", v.ZipUsaValidator); err != nil {
 		return
 	}
+	if err = validate.RegisterValidation(common.RequestParameterZip, v.ZipValidator); err != nil {
+		return
+	}
 	if err = validate.RegisterValidation("name", v.NameValidator); err != nil {
 		return
 	}
@@ -108,8 +122,25 @@ func ProviderValidators(v *validators.ValidatorSet) (validate *validator.Validat
 
 // ProviderDispatcher
 func ProviderDispatcher(ctx context.Context, set provider.AwareSet, appSet AppSet, cfg *Config, globalCfg *common.Config) (*Dispatcher, func(), error) {
+	session, err := globalCfg.DialMongo()
+	if err != nil {
+		return nil, func() {}, err
+	}
+	closeSession := func() {
+		if session != nil {
+			session.Close()
+		}
+	}
+
+	idempotency, err := common.NewIdempotencyStorage(session)
+	if err != nil {
+		closeSession()
+		return nil, func() {}, err
+	}
+
 	d := New(ctx, set, appSet, cfg, globalCfg)
-	return d, func() {}, nil
+	d.idempotency = idempotency
+	return d, closeSession, nil
 }
 
 var (
@@ -118,6 +149,7 @@ var (
 		ProviderDispatcher,
 		ProviderServices,
 		ProviderJwtVerifier,
+		ProviderWebhookVerifier,
 		ProviderValidators,
 		ProviderCfg,
 		ProviderGlobalCfg,
@@ -127,6 +159,7 @@ var (
 	WireTestSet = wire.NewSet(
 		ProviderDispatcher,
 		ProviderJwtVerifier,
+		ProviderWebhookVerifier,
 		ProviderValidators,
 		ProviderCfg,
 		ProviderGlobalCfg,
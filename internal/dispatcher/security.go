@@ -0,0 +1,84 @@
+package dispatcher
+
+import (
+	"encoding/json"
+	"github.com/ProtocolONE/go-core/v2/pkg/logger"
+	"github.com/labstack/echo/v4"
+	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
+	"net/http"
+	"strings"
+)
+
+const (
+	cspReportMaxSize          = 64 * 1024
+	cspReportUnknownDirective = "unknown"
+)
+
+// cspReportDirectives limits directive label of the violations metric since the reports are sent by anyone
+var cspReportDirectives = map[string]bool{
+	"default-src":     true,
+	"script-src":      true,
+	"script-src-elem": true,
+	"script-src-attr": true,
+	"style-src":       true,
+	"style-src-elem":  true,
+	"style-src-attr":  true,
+	"img-src":         true,
+	"font-src":        true,
+	"connect-src":     true,
+	"frame-src":       true,
+	"worker-src":      true,
+	"object-src":      true,
+	"form-action":     true,
+	"base-uri":        true,
+	"frame-ancestors": true,
+}
+
+func (d *Dispatcher) securityRoutes(echoHttp *echo.Echo) {
+	echoHttp.POST(common.CspReportPath, d.cspReport)
+}
+
+// cspReport collects reports of the content security policy violations sent by the browsers
+func (d *Dispatcher) cspReport(ctx echo.Context) error {
+	body := common.ExtractRawBodyContext(ctx)
+
+	if len(body) > cspReportMaxSize {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, common.ErrorRequestParamsIncorrect)
+	}
+
+	req := &common.CspReportRequest{}
+
+	if err := json.Unmarshal(body, req); err != nil || req.Report == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, common.ErrorRequestParamsIncorrect)
+	}
+
+	report := req.Report
+	directive := report.EffectiveDirective
+
+	// browsers of the first versions of the specification send violated directive only
+	if directive == "" {
+		if fields := strings.Fields(report.ViolatedDirective); len(fields) > 0 {
+			directive = fields[0]
+		}
+	}
+
+	if !cspReportDirectives[directive] {
+		directive = cspReportUnknownDirective
+	}
+
+	cspViolationsTotal.WithLabelValues(directive).Inc()
+
+	d.L().Info(
+		"content security policy violation",
+		logger.PairArgs(
+			"directive", directive,
+			"document_uri", report.DocumentUri,
+			"blocked_uri", report.BlockedUri,
+			"source_file", report.SourceFile,
+			"line_number", report.LineNumber,
+			"disposition", report.Disposition,
+		),
+	)
+
+	return ctx.NoContent(http.StatusNoContent)
+}
@@ -8,11 +8,14 @@ import (
 	"github.com/globalsign/mgo/bson"
 	"github.com/labstack/echo/v4"
 	"github.com/paysuper/paysuper-billing-server/pkg"
+	billMock "github.com/paysuper/paysuper-billing-server/pkg/mocks"
 	"github.com/paysuper/paysuper-billing-server/pkg/proto/billing"
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
 	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
 	"github.com/paysuper/paysuper-management-api/internal/mock"
 	"github.com/paysuper/paysuper-management-api/internal/test"
 	"github.com/stretchr/testify/assert"
+	mock2 "github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 	"net/http"
 	"strings"
@@ -22,7 +25,7 @@ import (
 
 type CardPayTestSuite struct {
 	suite.Suite
-	router *CardPayWebHook
+	router *ProviderWebhooksRoute
 	caller *test.EchoReqResCaller
 }
 
@@ -37,7 +40,7 @@ func (suite *CardPayTestSuite) SetupTest() {
 		Billing: mock.NewBillingServerOkMock(),
 	}
 	suite.caller, e = test.SetUp(settings, srv, func(set *test.TestSet, mw test.Middleware) common.Handlers {
-		suite.router = NewCardPayWebHook(set.HandlerSet, set.GlobalConfig)
+		suite.router = NewProviderWebhooksRoute(set.HandlerSet, set.GlobalConfig)
 		return common.Handlers{
 			suite.router,
 		}
@@ -45,6 +48,13 @@ func (suite *CardPayTestSuite) SetupTest() {
 	if e != nil {
 		panic(e)
 	}
+
+	e = suite.router.dispatch.WebhookVerifier.SetConfig(common.WebhookVerification{
+		WebhookSecrets: map[string]string{common.ProviderCardPay: "secret_key"},
+	})
+	if e != nil {
+		panic(e)
+	}
 }
 
 func (suite *CardPayTestSuite) TearDownTest() {}
@@ -83,7 +93,7 @@ func (suite *CardPayTestSuite) TestCardPay_RefundCallback_Ok() {
 	hash := sha512.New()
 	hash.Write([]byte(string(b) + "secret_key"))
 
-	path := common.WebHookGroupPath + cardPayWebHookRefundNotifyPath
+	path := common.WebHookGroupPath + common.CardPayWebhookRefundPath
 	res, err := suite.caller.Request(http.MethodPost, path, bytes.NewReader(b), func(request *http.Request, middleware test.Middleware) {
 		request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 		request.Header.Set(common.CardPayPaymentResponseHeaderSignature, hex.EncodeToString(hash.Sum(nil)))
@@ -100,7 +110,7 @@ func (suite *CardPayTestSuite) TestCardPay_RefundCallback_BindError() {
 	hash := sha512.New()
 	hash.Write([]byte(refundReq + "secret_key"))
 
-	path := common.WebHookGroupPath + cardPayWebHookRefundNotifyPath
+	path := common.WebHookGroupPath + common.CardPayWebhookRefundPath
 	_, err := suite.caller.Request(http.MethodPost, path, strings.NewReader(refundReq), func(request *http.Request, middleware test.Middleware) {
 		request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 		request.Header.Set(common.CardPayPaymentResponseHeaderSignature, hex.EncodeToString(hash.Sum(nil)))
@@ -143,7 +153,7 @@ func (suite *CardPayTestSuite) TestCardPay_RefundCallback_ValidationError() {
 	hash := sha512.New()
 	hash.Write([]byte(string(b) + "secret_key"))
 
-	path := common.WebHookGroupPath + cardPayWebHookRefundNotifyPath
+	path := common.WebHookGroupPath + common.CardPayWebhookRefundPath
 	_, err = suite.caller.Request(http.MethodPost, path, bytes.NewReader(b), func(request *http.Request, middleware test.Middleware) {
 		request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 		request.Header.Set(common.CardPayPaymentResponseHeaderSignature, hex.EncodeToString(hash.Sum(nil)))
@@ -192,7 +202,7 @@ func (suite *CardPayTestSuite) TestCardPay_RefundCallback_BillingServerSystemErr
 
 	suite.router.dispatch.Services.Billing = mock.NewBillingServerSystemErrorMock()
 
-	path := common.WebHookGroupPath + cardPayWebHookRefundNotifyPath
+	path := common.WebHookGroupPath + common.CardPayWebhookRefundPath
 	_, err = suite.caller.Request(http.MethodPost, path, bytes.NewReader(b), func(request *http.Request, middleware test.Middleware) {
 		request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 		request.Header.Set(common.CardPayPaymentResponseHeaderSignature, hex.EncodeToString(hash.Sum(nil)))
@@ -240,7 +250,7 @@ func (suite *CardPayTestSuite) TestCardPay_RefundCallback_BillingServer_Error()
 
 	suite.router.dispatch.Services.Billing = mock.NewBillingServerErrorMock()
 
-	path := common.WebHookGroupPath + cardPayWebHookRefundNotifyPath
+	path := common.WebHookGroupPath + common.CardPayWebhookRefundPath
 	_, err = suite.caller.Request(http.MethodPost, path, bytes.NewReader(b), func(request *http.Request, middleware test.Middleware) {
 		request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 		request.Header.Set(common.CardPayPaymentResponseHeaderSignature, hex.EncodeToString(hash.Sum(nil)))
@@ -288,7 +298,7 @@ func (suite *CardPayTestSuite) TestCardPay_RefundCallback_BillingServerTemporary
 
 	suite.router.dispatch.Services.Billing = mock.NewBillingServerOkTemporaryMock()
 
-	path := common.WebHookGroupPath + cardPayWebHookRefundNotifyPath
+	path := common.WebHookGroupPath + common.CardPayWebhookRefundPath
 	res, err := suite.caller.Request(http.MethodPost, path, bytes.NewReader(b), func(request *http.Request, middleware test.Middleware) {
 		request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 		request.Header.Set(common.CardPayPaymentResponseHeaderSignature, hex.EncodeToString(hash.Sum(nil)))
@@ -305,3 +315,65 @@ func (suite *CardPayTestSuite) TestCardPay_RefundCallback_BillingServerTemporary
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), mock.SomeError.Message, v)
 }
+
+func (suite *CardPayTestSuite) TestCardPay_RefundCallback_PublishOrderStatus() {
+	orderId := bson.NewObjectId().Hex()
+	order := &billing.OrderViewPublic{Uuid: "8d3c7f52-1b2a-4f6e-9d0c-3e4f5a6b7c8d", Status: "refunded"}
+
+	bs := &billMock.BillingService{}
+	bs.On("ProcessRefundCallback", mock2.Anything, mock2.Anything, mock2.Anything).
+		Return(&grpc.PaymentNotifyResponse{Status: pkg.ResponseStatusOk}, nil)
+	bs.On("GetOrderPublic", mock2.Anything, mock2.MatchedBy(func(req *grpc.GetOrderRequest) bool {
+		return req.Id == orderId
+	}), mock2.Anything).
+		Return(&grpc.GetOrderPublicResponse{Status: pkg.ResponseStatusOk, Item: order}, nil)
+	suite.router.dispatch.Services.Billing = bs
+
+	broker := &orderEventsBrokerMock{}
+	suite.router.dispatch.OrderEvents = broker
+
+	refundReq := &billing.CardPayRefundCallback{
+		MerchantOrder: &billing.CardPayMerchantOrder{
+			Id: orderId,
+		},
+		PaymentMethod: "BANKCARD",
+		PaymentData: &billing.CardPayRefundCallbackPaymentData{
+			Id:              bson.NewObjectId().Hex(),
+			RemainingAmount: 0,
+		},
+		RefundData: &billing.CardPayRefundCallbackRefundData{
+			Amount:   100,
+			Created:  time.Now().Format("2006-01-02T15:04:05Z"),
+			Id:       bson.NewObjectId().Hex(),
+			Currency: "RUB",
+			Status:   pkg.CardPayPaymentResponseStatusCompleted,
+			AuthCode: bson.NewObjectId().Hex(),
+			Is_3D:    true,
+			Rrn:      bson.NewObjectId().Hex(),
+		},
+		CallbackTime: time.Now().Format("2006-01-02T15:04:05Z"),
+		Customer: &billing.CardPayCustomer{
+			Email: "test@unut.test",
+			Id:    "test@unut.test",
+		},
+	}
+
+	b, err := json.Marshal(refundReq)
+	assert.NoError(suite.T(), err)
+
+	hash := sha512.New()
+	hash.Write([]byte(string(b) + "secret_key"))
+
+	path := common.WebHookGroupPath + common.CardPayWebhookRefundPath
+	res, err := suite.caller.Request(http.MethodPost, path, bytes.NewReader(b), func(request *http.Request, middleware test.Middleware) {
+		request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		request.Header.Set(common.CardPayPaymentResponseHeaderSignature, hex.EncodeToString(hash.Sum(nil)))
+	})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+
+	assert.Len(suite.T(), broker.published, 1)
+	assert.Equal(suite.T(), order.Uuid, broker.published[0].Topic)
+	assert.Equal(suite.T(), common.OrderEventTypeStatus, broker.published[0].Type)
+	assert.Equal(suite.T(), &common.OrderStatusEvent{OrderId: order.Uuid, Status: "refunded"}, broker.published[0].Data)
+}
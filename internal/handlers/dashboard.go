@@ -32,9 +32,11 @@ func NewDashboardRoute(set common.HandlerSet, cfg *common.Config) *DashboardRout
 }
 
 func (h *DashboardRoute) Route(groups *common.Groups) {
-	groups.AuthUser.GET(dashboardMainPath, h.getMainReports)
-	groups.AuthUser.GET(dashboardRevenueDynamicsPath, h.getRevenueDynamicsReport)
-	groups.AuthUser.GET(dashboardBasePath, h.getBaseReports)
+	merchantAccess := common.MerchantAccessMiddleware(h.dispatch, h.cfg, common.RequestParameterId)
+
+	groups.AuthUser.GET(dashboardMainPath, h.getMainReports, merchantAccess)
+	groups.AuthUser.GET(dashboardRevenueDynamicsPath, h.getRevenueDynamicsReport, merchantAccess)
+	groups.AuthUser.GET(dashboardBasePath, h.getBaseReports, merchantAccess)
 }
 
 // @Description get main reports data for dashboard
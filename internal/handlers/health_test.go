@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
+	"github.com/paysuper/paysuper-management-api/internal/mock"
+	"github.com/paysuper/paysuper-management-api/internal/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"testing"
+)
+
+type serviceHealthCheckerMock struct {
+	failed map[string]bool
+}
+
+func (m *serviceHealthCheckerMock) CheckRegistry(ctx context.Context) error {
+	return nil
+}
+
+func (m *serviceHealthCheckerMock) CheckService(ctx context.Context, name string) error {
+	if m.failed[name] {
+		return errors.New("service not found")
+	}
+	return nil
+}
+
+type HealthTestSuite struct {
+	suite.Suite
+	caller *test.EchoReqResCaller
+	health *serviceHealthCheckerMock
+}
+
+func Test_Health(t *testing.T) {
+	suite.Run(t, new(HealthTestSuite))
+}
+
+func (suite *HealthTestSuite) SetupTest() {
+	var e error
+	settings := test.DefaultSettings()
+	suite.health = &serviceHealthCheckerMock{failed: map[string]bool{}}
+	srv := common.Services{
+		Billing: mock.NewBillingServerOkMock(),
+		Health:  suite.health,
+	}
+	suite.caller, e = test.SetUp(settings, srv, func(set *test.TestSet, mw test.Middleware) common.Handlers {
+		return common.Handlers{
+			NewZipCodeRoute(set.HandlerSet, set.GlobalConfig),
+		}
+	})
+	if e != nil {
+		panic(e)
+	}
+}
+
+func (suite *HealthTestSuite) TearDownTest() {}
+
+func (suite *HealthTestSuite) TestHealth_Liveness_Ok() {
+	res, err := suite.caller.Builder().
+		Path(common.HealthPath).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+
+	data := &common.HealthResponse{}
+	err = json.Unmarshal(res.Body.Bytes(), data)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), common.HealthStatusOk, data.Status)
+}
+
+func (suite *HealthTestSuite) TestHealth_Readiness_Ok() {
+	res, err := suite.caller.Builder().
+		Path(common.ReadinessPath).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+
+	data := &common.HealthResponse{}
+	err = json.Unmarshal(res.Body.Bytes(), data)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), common.HealthStatusOk, data.Status)
+
+	for _, name := range []string{"shutdown", "templates", "registry", "billing", "paylink", "tax", "reporter", "geo"} {
+		assert.Contains(suite.T(), data.Checks, name)
+		assert.Equal(suite.T(), common.HealthStatusOk, data.Checks[name].Status)
+	}
+}
+
+func (suite *HealthTestSuite) TestHealth_Readiness_ServiceUnavailable() {
+	suite.health.failed[pkg.ServiceName] = true
+
+	res, err := suite.caller.Builder().
+		Path(common.ReadinessPath).
+		Exec(suite.T())
+
+	// the replica stays ready, the outage of the dependency is reported only
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+
+	data := &common.HealthResponse{}
+	err = json.Unmarshal(res.Body.Bytes(), data)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), common.HealthStatusDegraded, data.Status)
+	assert.Equal(suite.T(), common.HealthStatusFail, data.Checks["billing"].Status)
+	assert.NotEmpty(suite.T(), data.Checks["billing"].Error)
+	assert.Equal(suite.T(), common.HealthStatusOk, data.Checks["tax"].Status)
+}
+
+func (suite *HealthTestSuite) TestHealth_Readiness_Draining() {
+	srv := common.Services{
+		Billing: mock.NewBillingServerOkMock(),
+		Health:  suite.health,
+	}
+	d, _, err := test.BuildDispatcher(context.Background(), test.DefaultSettings(), srv, common.Handlers{}, nil)
+	assert.NoError(suite.T(), err)
+
+	d.Drain()
+
+	res, err := test.NewTestRequest(d, &test.MiddlewareTestUp{}).Builder().
+		Path(common.ReadinessPath).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusServiceUnavailable, res.Code)
+
+	data := &common.HealthResponse{}
+	err = json.Unmarshal(res.Body.Bytes(), data)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), common.HealthStatusFail, data.Checks["shutdown"].Status)
+	assert.Equal(suite.T(), common.HealthStatusOk, data.Checks["templates"].Status)
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"github.com/ProtocolONE/go-core/v2/pkg/logger"
+	"github.com/ProtocolONE/go-core/v2/pkg/provider"
+	"github.com/labstack/echo/v4"
+	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
+	"net/http"
+	"time"
+)
+
+const (
+	inboundWebhooksPath         = "/inbound_webhooks"
+	inboundWebhooksIdPath       = "/inbound_webhooks/:id"
+	inboundWebhooksIdReplayPath = "/inbound_webhooks/:id/replay"
+)
+
+type InboundWebhooksRoute struct {
+	dispatch common.HandlerSet
+	cfg      common.Config
+	provider.LMT
+}
+
+func NewInboundWebhooksRoute(set common.HandlerSet, cfg *common.Config) *InboundWebhooksRoute {
+	set.AwareSet.Logger = set.AwareSet.Logger.WithFields(logger.Fields{"router": "InboundWebhooksRoute"})
+	return &InboundWebhooksRoute{
+		dispatch: set,
+		LMT:      &set.AwareSet,
+		cfg:      *cfg,
+	}
+}
+
+func (h *InboundWebhooksRoute) Route(groups *common.Groups) {
+	groups.AuthUser.GET(inboundWebhooksPath, h.listInboundWebhooks)
+	groups.AuthUser.GET(inboundWebhooksIdPath, h.getInboundWebhook)
+	groups.AuthUser.POST(inboundWebhooksIdReplayPath, h.replayInboundWebhook)
+}
+
+// Get callbacks of the payment providers, the newest callbacks come first
+// GET /admin/api/v1/inbound_webhooks?provider=cardpay&type=payment&failed=true&query=%order_id%&limit=10&offset=0
+func (h *InboundWebhooksRoute) listInboundWebhooks(ctx echo.Context) error {
+	filter := &common.InboundWebhookFilter{}
+
+	if err := ctx.Bind(filter); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, common.ErrorRequestParamsIncorrect)
+	}
+
+	if err := h.dispatch.Validate.Struct(filter); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, common.GetValidationError(err))
+	}
+
+	cursor := common.NewPaginationBinder(h.cfg).Cursor(ctx)
+	list, err := h.dispatch.InboundWebhooks.ListWebhooks(filter, int(cursor.Limit), int(cursor.Offset), cursor.Position)
+
+	if err != nil {
+		h.L().Error(common.InternalErrorTemplate, logger.PairArgs("err", err.Error()))
+		return echo.NewHTTPError(http.StatusInternalServerError, common.ErrorInternal)
+	}
+
+	common.SetPaginationLinks(ctx, h.cfg.PaginationSecret(), common.NewPage(cursor, int64(list.Count), list.Items))
+	return ctx.JSON(http.StatusOK, list)
+}
+
+// Get callback of the payment provider with its headers, raw body and results of the processing
+// GET /admin/api/v1/inbound_webhooks/5ced34d689fce60bf4440829
+func (h *InboundWebhooksRoute) getInboundWebhook(ctx echo.Context) error {
+	w, err := h.getWebhook(ctx)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, w)
+}
+
+// Re-submit stored callback to the billing server, result of the replay is added to the callback
+// POST /admin/api/v1/inbound_webhooks/5ced34d689fce60bf4440829/replay
+func (h *InboundWebhooksRoute) replayInboundWebhook(ctx echo.Context) error {
+	w, err := h.getWebhook(ctx)
+	if err != nil {
+		return err
+	}
+
+	adapter := h.dispatch.ProviderWebhooks.Get(w.Provider)
+	if adapter == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, common.ErrorMessageInboundWebhookNotReplayable)
+	}
+
+	body := []byte(w.Body)
+	parsed, err := adapter.Parse(w.Type, body)
+
+	if err != nil || (w.Type == common.InboundWebhookTypePayment && parsed.OrderId == "") {
+		return echo.NewHTTPError(http.StatusBadRequest, common.ErrorMessageInboundWebhookNotReplayable)
+	}
+
+	req := adapter.Request(parsed, body, w.Signature)
+	httpStatus, msg, err := processProviderWebhook(ctx.Request().Context(), h.dispatch.Services.Billing, w.Type, parsed.OrderId, req)
+
+	if err != nil {
+		h.L().Error(common.InternalErrorTemplate, logger.PairArgs("err", err.Error(), "webhook_id", w.Id))
+		return echo.NewHTTPError(http.StatusInternalServerError, common.ErrorUnknown)
+	}
+
+	result := &common.InboundWebhookResult{
+		ResponseCode: httpStatus,
+		UserId:       common.ExtractUserContext(ctx).Id,
+		ProcessedAt:  time.Now(),
+	}
+
+	if msg != "" {
+		result.Error = msg
+	}
+
+	w.Replays = append(w.Replays, result)
+
+	if err = h.dispatch.InboundWebhooks.SaveWebhook(w); err != nil {
+		h.L().Error(common.InternalErrorTemplate, logger.PairArgs("err", err.Error()))
+		return echo.NewHTTPError(http.StatusInternalServerError, common.ErrorInternal)
+	}
+
+	if httpStatus == http.StatusOK && parsed.OrderId != "" {
+		publishOrderStatus(ctx, h.dispatch, h.L(), parsed.OrderId)
+
+		if w.Type == common.InboundWebhookTypePayment {
+			submitKeyStockSale(h.dispatch, h.L(), h.cfg.KeyStockLowThreshold, parsed.OrderId)
+		}
+	}
+
+	h.L().Info(
+		"inbound webhook replayed",
+		logger.PairArgs("webhook_id", w.Id, "user_id", result.UserId, "response_code", httpStatus),
+	)
+
+	return ctx.JSON(http.StatusOK, w)
+}
+
+func (h *InboundWebhooksRoute) getWebhook(ctx echo.Context) (*common.InboundWebhook, error) {
+	w, err := h.dispatch.InboundWebhooks.GetWebhook(ctx.Param(common.RequestParameterId))
+
+	if err != nil {
+		h.L().Error(common.InternalErrorTemplate, logger.PairArgs("err", err.Error()))
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, common.ErrorInternal)
+	}
+
+	if w == nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, common.ErrorMessageInboundWebhookNotFound)
+	}
+
+	return w, nil
+}
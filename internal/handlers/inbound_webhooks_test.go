@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/globalsign/mgo/bson"
+	"github.com/labstack/echo/v4"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	billMock "github.com/paysuper/paysuper-billing-server/pkg/mocks"
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/billing"
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
+	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
+	"github.com/paysuper/paysuper-management-api/internal/test"
+	"github.com/stretchr/testify/assert"
+	mock2 "github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type InboundWebhooksTestSuite struct {
+	suite.Suite
+	router   *InboundWebhooksRoute
+	webhooks *ProviderWebhooksRoute
+	caller   *test.EchoReqResCaller
+	billing  *billMock.BillingService
+}
+
+func Test_InboundWebhooks(t *testing.T) {
+	suite.Run(t, new(InboundWebhooksTestSuite))
+}
+
+func (suite *InboundWebhooksTestSuite) SetupTest() {
+	suite.billing = &billMock.BillingService{}
+	suite.billing.On("GetOrderPrivate", mock2.Anything, mock2.Anything, mock2.Anything).
+		Return(&grpc.GetOrderPrivateResponse{
+			Status: pkg.ResponseStatusOk,
+			Item:   &billing.Order{PaymentMethod: &billing.PaymentMethodOrder{Handler: pkg.PaymentSystemHandlerCardPay}},
+		}, nil)
+	suite.billing.On("ProcessRefundCallback", mock2.Anything, mock2.Anything, mock2.Anything).
+		Return(&grpc.PaymentNotifyResponse{Status: pkg.ResponseStatusOk}, nil)
+	suite.billing.On("PaymentCallbackProcess", mock2.Anything, mock2.Anything, mock2.Anything).
+		Return(&grpc.PaymentNotifyResponse{}, nil)
+	suite.billing.On("GetOrderPublic", mock2.Anything, mock2.Anything, mock2.Anything).
+		Return(&grpc.GetOrderPublicResponse{Status: pkg.ResponseStatusNotFound}, nil)
+
+	var e error
+	settings := test.DefaultSettings()
+	srv := common.Services{
+		Billing: suite.billing,
+	}
+	suite.caller, e = test.SetUp(settings, srv, func(set *test.TestSet, mw test.Middleware) common.Handlers {
+		suite.router = NewInboundWebhooksRoute(set.HandlerSet, set.GlobalConfig)
+		suite.webhooks = NewProviderWebhooksRoute(set.HandlerSet, set.GlobalConfig)
+		return common.Handlers{
+			suite.router,
+			suite.webhooks,
+		}
+	})
+	if e != nil {
+		panic(e)
+	}
+
+	e = suite.router.dispatch.WebhookVerifier.SetConfig(common.WebhookVerification{
+		WebhookSecrets: map[string]string{common.ProviderCardPay: "secret_key"},
+	})
+	if e != nil {
+		panic(e)
+	}
+}
+
+func (suite *InboundWebhooksTestSuite) TearDownTest() {}
+
+func (suite *InboundWebhooksTestSuite) TestInboundWebhooks_CardPayCallback_Stored() {
+	orderId := bson.NewObjectId().Hex()
+	body := `{"merchant_order": {"id": "` + orderId + `"}}`
+	digest := sha512.Sum512([]byte(body + "secret_key"))
+	signature := hex.EncodeToString(digest[:])
+
+	_, err := suite.caller.Builder().
+		Method(http.MethodPost).
+		Path(common.WebHookGroupPath + common.CardPayWebhookRefundPath).
+		Init(func(request *http.Request, middleware test.Middleware) {
+			request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			request.Header.Set(echo.HeaderAuthorization, "Bearer secret")
+			request.Header.Set(common.CardPayPaymentResponseHeaderSignature, signature)
+		}).
+		BodyString(body).
+		Exec(suite.T())
+
+	// the body misses required fields of the refund callback, but it's stored anyway
+	assert.Error(suite.T(), err)
+
+	res, err := suite.caller.Builder().
+		Path(common.AuthUserGroupPath+inboundWebhooksPath).
+		SetQueryParam("type", common.InboundWebhookTypeRefund).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+
+	list := &common.InboundWebhookList{}
+	assert.NoError(suite.T(), json.Unmarshal(res.Body.Bytes(), list))
+	assert.Equal(suite.T(), 1, list.Count)
+
+	w := list.Items[0]
+	assert.Equal(suite.T(), common.ProviderCardPay, w.Provider)
+	assert.Equal(suite.T(), common.InboundWebhookTypeRefund, w.Type)
+	assert.Equal(suite.T(), body, w.Body)
+	assert.Equal(suite.T(), signature, w.Signature)
+	assert.Equal(suite.T(), signature, w.Headers[common.CardPayPaymentResponseHeaderSignature])
+	assert.NotContains(suite.T(), w.Headers, echo.HeaderAuthorization)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Result.ResponseCode)
+	assert.NotNil(suite.T(), w.Result.Error)
+}
+
+func (suite *InboundWebhooksTestSuite) TestInboundWebhooks_List_Filter() {
+	storage := suite.router.dispatch.InboundWebhooks
+	assert.NoError(suite.T(), storage.SaveWebhook(suite.newWebhook("order_1", http.StatusOK)))
+	assert.NoError(suite.T(), storage.SaveWebhook(suite.newWebhook("order_2", http.StatusGone)))
+	assert.NoError(suite.T(), storage.SaveWebhook(suite.newWebhook("order_3", http.StatusOK)))
+
+	res, err := suite.caller.Builder().
+		Path(common.AuthUserGroupPath+inboundWebhooksPath).
+		SetQueryParam("failed", "true").
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	list := &common.InboundWebhookList{}
+	assert.NoError(suite.T(), json.Unmarshal(res.Body.Bytes(), list))
+	assert.Equal(suite.T(), 1, list.Count)
+	assert.Equal(suite.T(), "order_2", list.Items[0].OrderId)
+
+	res, err = suite.caller.Builder().
+		Path(common.AuthUserGroupPath+inboundWebhooksPath).
+		SetQueryParam("query", "order_3").
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	list = &common.InboundWebhookList{}
+	assert.NoError(suite.T(), json.Unmarshal(res.Body.Bytes(), list))
+	assert.Equal(suite.T(), 1, list.Count)
+	assert.Equal(suite.T(), "order_3", list.Items[0].OrderId)
+
+	res, err = suite.caller.Builder().
+		Path(common.AuthUserGroupPath + inboundWebhooksPath).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	list = &common.InboundWebhookList{}
+	assert.NoError(suite.T(), json.Unmarshal(res.Body.Bytes(), list))
+	assert.Equal(suite.T(), 3, list.Count)
+	assert.Equal(suite.T(), "order_3", list.Items[0].OrderId)
+}
+
+func (suite *InboundWebhooksTestSuite) TestInboundWebhooks_List_KeysetCursor() {
+	storage := suite.router.dispatch.InboundWebhooks
+	for _, orderId := range []string{"order_1", "order_2", "order_3"} {
+		assert.NoError(suite.T(), storage.SaveWebhook(suite.newWebhook(orderId, http.StatusOK)))
+	}
+
+	orderIds := func(body []byte) []string {
+		list := &common.InboundWebhookList{}
+		assert.NoError(suite.T(), json.Unmarshal(body, list))
+		ids := make([]string, len(list.Items))
+		for i, w := range list.Items {
+			ids[i] = w.OrderId
+		}
+		return ids
+	}
+
+	res, err := suite.caller.Builder().
+		Path(common.AuthUserGroupPath+inboundWebhooksPath).
+		SetQueryParam(common.QueryParameterNameLimit, "2").
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []string{"order_3", "order_2"}, orderIds(res.Body.Bytes()))
+	assert.Empty(suite.T(), res.Header().Get(common.HeaderPrevCursor))
+
+	next := res.Header().Get(common.HeaderNextCursor)
+	cursor, err := common.DecodeCursor(suite.router.cfg.PaginationSecret(), next)
+	assert.NoError(suite.T(), err)
+	assert.NotEmpty(suite.T(), cursor.LastId)
+	assert.Zero(suite.T(), cursor.Offset)
+
+	// the new callback doesn't shift the next page as the offset would
+	assert.NoError(suite.T(), storage.SaveWebhook(suite.newWebhook("order_4", http.StatusOK)))
+
+	res, err = suite.caller.Builder().
+		Path(common.AuthUserGroupPath+inboundWebhooksPath).
+		SetQueryParam(common.QueryParameterNameCursor, next).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []string{"order_1"}, orderIds(res.Body.Bytes()))
+	assert.Empty(suite.T(), res.Header().Get(common.HeaderNextCursor))
+
+	res, err = suite.caller.Builder().
+		Path(common.AuthUserGroupPath+inboundWebhooksPath).
+		SetQueryParam(common.QueryParameterNameCursor, res.Header().Get(common.HeaderPrevCursor)).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []string{"order_3", "order_2"}, orderIds(res.Body.Bytes()))
+	assert.NotEmpty(suite.T(), res.Header().Get(common.HeaderNextCursor))
+	assert.NotEmpty(suite.T(), res.Header().Get(common.HeaderPrevCursor))
+}
+
+func (suite *InboundWebhooksTestSuite) TestInboundWebhooks_List_ValidationError() {
+	_, err := suite.caller.Builder().
+		Path(common.AuthUserGroupPath+inboundWebhooksPath).
+		SetQueryParam("type", "unknown").
+		Exec(suite.T())
+
+	assert.Error(suite.T(), err)
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
+}
+
+func (suite *InboundWebhooksTestSuite) TestInboundWebhooks_Get_NotFound() {
+	_, err := suite.caller.Builder().
+		Path(common.AuthUserGroupPath+inboundWebhooksIdPath).
+		Params(":"+common.RequestParameterId, bson.NewObjectId().Hex()).
+		Exec(suite.T())
+
+	assert.Error(suite.T(), err)
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusNotFound, httpErr.Code)
+	assert.Equal(suite.T(), common.ErrorMessageInboundWebhookNotFound, httpErr.Message)
+}
+
+func (suite *InboundWebhooksTestSuite) TestInboundWebhooks_Replay_Ok() {
+	w := suite.newWebhook("order_1", http.StatusGone)
+	assert.NoError(suite.T(), suite.router.dispatch.InboundWebhooks.SaveWebhook(w))
+
+	res, err := suite.caller.Builder().
+		Method(http.MethodPost).
+		Path(common.AuthUserGroupPath+inboundWebhooksIdReplayPath).
+		Params(":"+common.RequestParameterId, w.Id).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+
+	replayed := &common.InboundWebhook{}
+	assert.NoError(suite.T(), json.Unmarshal(res.Body.Bytes(), replayed))
+	assert.Len(suite.T(), replayed.Replays, 1)
+	assert.Equal(suite.T(), http.StatusOK, replayed.Replays[0].ResponseCode)
+	assert.Equal(suite.T(), http.StatusGone, replayed.Result.ResponseCode)
+
+	suite.billing.AssertCalled(suite.T(), "PaymentCallbackProcess", mock2.Anything, mock2.MatchedBy(func(req *grpc.PaymentNotifyRequest) bool {
+		return req.OrderId == "order_1" && string(req.Request) == w.Body && req.Signature == w.Signature
+	}), mock2.Anything)
+
+	stored, err := suite.router.dispatch.InboundWebhooks.GetWebhook(w.Id)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), stored.Replays, 1)
+}
+
+func (suite *InboundWebhooksTestSuite) TestInboundWebhooks_Replay_NotReplayable() {
+	w := suite.newWebhook("", http.StatusBadRequest)
+	assert.NoError(suite.T(), suite.router.dispatch.InboundWebhooks.SaveWebhook(w))
+
+	_, err := suite.caller.Builder().
+		Method(http.MethodPost).
+		Path(common.AuthUserGroupPath+inboundWebhooksIdReplayPath).
+		Params(":"+common.RequestParameterId, w.Id).
+		Exec(suite.T())
+
+	assert.Error(suite.T(), err)
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
+	assert.Equal(suite.T(), common.ErrorMessageInboundWebhookNotReplayable, httpErr.Message)
+	suite.billing.AssertNotCalled(suite.T(), "PaymentCallbackProcess", mock2.Anything, mock2.Anything, mock2.Anything)
+}
+
+func (suite *InboundWebhooksTestSuite) newWebhook(orderId string, code int) *common.InboundWebhook {
+	return &common.InboundWebhook{
+		Id:        bson.NewObjectId().Hex(),
+		Provider:  common.ProviderCardPay,
+		Type:      common.InboundWebhookTypePayment,
+		OrderId:   orderId,
+		Headers:   map[string]string{},
+		Body:      `{"merchant_order": {"id": "` + orderId + `"}}`,
+		Signature: "signature_" + orderId,
+		Result:    &common.InboundWebhookResult{ResponseCode: code, ProcessedAt: time.Now()},
+		CreatedAt: time.Now(),
+	}
+}
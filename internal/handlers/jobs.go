@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"github.com/ProtocolONE/go-core/v2/pkg/logger"
+	"github.com/ProtocolONE/go-core/v2/pkg/provider"
+	"github.com/labstack/echo/v4"
+	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
+	"github.com/paysuper/paysuper-management-api/pkg/job"
+	"net/http"
+	"strings"
+)
+
+const (
+	jobsIdPath = "/jobs/:id"
+)
+
+const (
+	jobTypeUploadKeys  = "key_products.upload_keys"
+	jobTypeReportFile  = "report_file.create"
+	jobTypeRefundBatch = "refunds.batch"
+	jobTypeKeyStock    = "key_products.stock_sale"
+)
+
+type JobsRoute struct {
+	dispatch common.HandlerSet
+	cfg      common.Config
+	provider.LMT
+}
+
+func NewJobsRoute(set common.HandlerSet, cfg *common.Config) *JobsRoute {
+	set.AwareSet.Logger = set.AwareSet.Logger.WithFields(logger.Fields{"router": "JobsRoute"})
+	return &JobsRoute{
+		dispatch: set,
+		LMT:      &set.AwareSet,
+		cfg:      *cfg,
+	}
+}
+
+func (h *JobsRoute) Route(groups *common.Groups) {
+	groups.AuthUser.GET(jobsIdPath, h.getJob)
+	groups.AuthUser.DELETE(jobsIdPath, h.cancelJob)
+}
+
+// Get progress, result and errors of the job
+// GET /admin/api/v1/jobs/5ced34d689fce60bf4440829
+func (h *JobsRoute) getJob(ctx echo.Context) error {
+	j, err := getUserJob(ctx, h.dispatch.Jobs, ctx.Param(common.RequestParameterId), "")
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, j)
+}
+
+// Cancel queued or running job
+// DELETE /admin/api/v1/jobs/5ced34d689fce60bf4440829
+func (h *JobsRoute) cancelJob(ctx echo.Context) error {
+	j, err := getUserJob(ctx, h.dispatch.Jobs, ctx.Param(common.RequestParameterId), "")
+	if err != nil {
+		return err
+	}
+
+	j, err = h.dispatch.Jobs.Cancel(j.Id)
+
+	if err == job.ErrFinished {
+		return echo.NewHTTPError(http.StatusConflict, common.ErrorMessageJobFinished)
+	}
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, common.ErrorMessageJobNotFound)
+	}
+
+	return ctx.JSON(http.StatusAccepted, j)
+}
+
+// getUserJob returns job started by the user, staff can see all jobs. Jobs of other users
+// and jobs of the other type are not found
+func getUserJob(ctx echo.Context, jobs *job.Manager, id, typ string) (*job.Job, error) {
+	j, err := jobs.Get(id)
+
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, common.ErrorMessageJobNotFound)
+	}
+
+	user := common.ExtractUserContext(ctx)
+
+	if (typ != "" && j.Type != typ) || (j.OwnerId != user.Id && !user.IsStaff()) {
+		return nil, echo.NewHTTPError(http.StatusNotFound, common.ErrorMessageJobNotFound)
+	}
+
+	return j, nil
+}
+
+// jobAccepted responds with the submitted job and its status url
+func jobAccepted(ctx echo.Context, j *job.Job) error {
+	ctx.Response().Header().Set(echo.HeaderLocation, common.AuthUserGroupPath+strings.Replace(jobsIdPath, ":id", j.Id, 1))
+	return ctx.JSON(http.StatusAccepted, j)
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/labstack/echo/v4"
+	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
+	"github.com/paysuper/paysuper-management-api/internal/mock"
+	"github.com/paysuper/paysuper-management-api/internal/test"
+	"github.com/paysuper/paysuper-management-api/pkg/job"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// waitJobFinished waits for the job from the accepted response to finish
+func waitJobFinished(t *testing.T, jobs *job.Manager, res *httptest.ResponseRecorder) *job.Job {
+	accepted := &job.Job{}
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), accepted))
+
+	for i := 0; i < 250; i++ {
+		j, err := jobs.Get(accepted.Id)
+		require.NoError(t, err)
+
+		if j.Finished() {
+			return j
+		}
+		time.Sleep(time.Millisecond * 20)
+	}
+
+	require.FailNow(t, "job is not finished in time")
+	return nil
+}
+
+type JobsTestSuite struct {
+	suite.Suite
+	router *JobsRoute
+	caller *test.EchoReqResCaller
+	user   *common.AuthUser
+}
+
+func Test_Jobs(t *testing.T) {
+	suite.Run(t, new(JobsTestSuite))
+}
+
+func (suite *JobsTestSuite) SetupTest() {
+	suite.user = &common.AuthUser{Id: "ffffffffffffffffffffffff"}
+
+	var e error
+	settings := test.DefaultSettings()
+	srv := common.Services{
+		Billing: mock.NewBillingServerOkMock(),
+	}
+	suite.caller, e = test.SetUp(settings, srv, func(set *test.TestSet, mw test.Middleware) common.Handlers {
+		mw.Pre(test.PreAuthUserMiddleware(suite.user))
+		suite.router = NewJobsRoute(set.HandlerSet, set.GlobalConfig)
+		return common.Handlers{
+			suite.router,
+		}
+	})
+	if e != nil {
+		panic(e)
+	}
+}
+
+func (suite *JobsTestSuite) TearDownTest() {
+	suite.router.dispatch.Jobs.Stop()
+}
+
+func (suite *JobsTestSuite) submit(ownerId string, fn job.Func) *job.Job {
+	j, err := suite.router.dispatch.Jobs.Submit("test", ownerId, fn)
+	assert.NoError(suite.T(), err)
+	return j
+}
+
+func (suite *JobsTestSuite) TestJobs_Get_Ok() {
+	j := suite.submit(suite.user.Id, func(ctx context.Context, progress job.Progress) (interface{}, error) {
+		progress.SetTotal(2)
+		progress.Add(2)
+		return map[string]int{"keys": 2}, nil
+	})
+
+	for i := 0; i < 250 && !j.Finished(); i++ {
+		time.Sleep(time.Millisecond * 20)
+
+		res, err := suite.caller.Builder().
+			Params(":"+common.RequestParameterId, j.Id).
+			Path(common.AuthUserGroupPath + jobsIdPath).
+			Exec(suite.T())
+
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), http.StatusOK, res.Code)
+		assert.NoError(suite.T(), json.Unmarshal(res.Body.Bytes(), j))
+	}
+
+	assert.Equal(suite.T(), job.StatusSucceeded, j.Status)
+	assert.Equal(suite.T(), 2, j.Done)
+	assert.JSONEq(suite.T(), `{"keys": 2}`, string(j.Result))
+}
+
+func (suite *JobsTestSuite) TestJobs_Get_ForeignJob() {
+	j := suite.submit("eeeeeeeeeeeeeeeeeeeeeeee", func(ctx context.Context, progress job.Progress) (interface{}, error) {
+		return nil, nil
+	})
+
+	_, err := suite.caller.Builder().
+		Params(":"+common.RequestParameterId, j.Id).
+		Path(common.AuthUserGroupPath + jobsIdPath).
+		Exec(suite.T())
+
+	assert.Error(suite.T(), err)
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusNotFound, httpErr.Code)
+	assert.Equal(suite.T(), common.ErrorMessageJobNotFound, httpErr.Message)
+}
+
+func (suite *JobsTestSuite) TestJobs_Cancel_Ok() {
+	started := make(chan struct{})
+	j := suite.submit(suite.user.Id, func(ctx context.Context, progress job.Progress) (interface{}, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	<-started
+
+	res, err := suite.caller.Builder().
+		Method(http.MethodDelete).
+		Params(":"+common.RequestParameterId, j.Id).
+		Path(common.AuthUserGroupPath + jobsIdPath).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusAccepted, res.Code)
+
+	j = waitJobFinished(suite.T(), suite.router.dispatch.Jobs, res)
+	assert.Equal(suite.T(), job.StatusCancelled, j.Status)
+
+	_, err = suite.caller.Builder().
+		Method(http.MethodDelete).
+		Params(":"+common.RequestParameterId, j.Id).
+		Path(common.AuthUserGroupPath + jobsIdPath).
+		Exec(suite.T())
+
+	assert.Error(suite.T(), err)
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusConflict, httpErr.Code)
+	assert.Equal(suite.T(), common.ErrorMessageJobFinished, httpErr.Message)
+}
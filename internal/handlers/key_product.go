@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"context"
+	"fmt"
 	"github.com/ProtocolONE/geoip-service/pkg/proto"
 	"github.com/ProtocolONE/go-core/v2/pkg/logger"
 	"github.com/ProtocolONE/go-core/v2/pkg/provider"
@@ -9,6 +11,10 @@ import (
 	"github.com/paysuper/paysuper-billing-server/pkg"
 	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
 	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
+	"github.com/paysuper/paysuper-management-api/pkg/job"
+	"github.com/paysuper/paysuper-management-api/pkg/keyfile"
+	"github.com/paysuper/paysuper-management-api/pkg/webhook"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"strings"
@@ -23,6 +29,15 @@ const (
 	platformsPath                 = "/platforms"
 	keyProductsPlatformsFilePath  = "/key-products/:key_product_id/platforms/:platform_id/file"
 	keyProductsPlatformsCountPath = "/key-products/:key_product_id/platforms/:platform_id/count"
+	keyProductsInventoryPath      = "/key-products/inventory"
+	keyProductsThresholdPath      = "/key-products/:key_product_id/platforms/:platform_id/threshold"
+)
+
+const (
+	uploadKeysTimeout = time.Minute * 10
+
+	keyStockLowNotificationTitle   = "Key product stock is low"
+	keyStockLowNotificationMessage = "Only %d keys of the key product %s are left for the platform %s, the threshold is %d"
 )
 
 type KeyProductRoute struct {
@@ -52,6 +67,8 @@ func (h *KeyProductRoute) Route(groups *common.Groups) {
 
 	groups.AuthUser.POST(keyProductsPlatformsFilePath, h.uploadKeys)
 	groups.AuthUser.GET(keyProductsPlatformsCountPath, h.getCountOfKeys)
+	groups.AuthUser.GET(keyProductsInventoryPath, h.getKeyInventory)
+	groups.AuthUser.PUT(keyProductsThresholdPath, h.setKeyStockThreshold)
 
 	groups.AuthProject.GET(keyProductsIdPath, h.getKeyProduct)
 }
@@ -117,18 +134,10 @@ func (h *KeyProductRoute) publishKeyProduct(ctx echo.Context) error {
 func (h *KeyProductRoute) getPlatformsList(ctx echo.Context) error {
 	req := &grpc.ListPlatformsRequest{}
 
-	if err := ctx.Bind(req); err != nil {
+	if err := common.NewPaginationBinder(h.cfg).Bind(req, ctx); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, common.ErrorRequestParamsIncorrect)
 	}
 
-	if req.Limit == 0 {
-		req.Limit = h.cfg.LimitDefault
-	}
-
-	if req.Limit > h.cfg.LimitMax {
-		req.Limit = h.cfg.LimitMax
-	}
-
 	if err := h.dispatch.Validate.Struct(req); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, common.GetValidationError(err))
 	}
@@ -288,18 +297,10 @@ func (h *KeyProductRoute) createKeyProduct(ctx echo.Context) error {
 func (h *KeyProductRoute) getKeyProductList(ctx echo.Context) error {
 	authUser := common.ExtractUserContext(ctx)
 	req := &grpc.ListKeyProductsRequest{}
-	if err := ctx.Bind(req); err != nil {
+	if err := common.NewPaginationBinder(h.cfg).Bind(req, ctx); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, common.ErrorRequestParamsIncorrect)
 	}
 
-	if req.Limit > h.cfg.LimitMax {
-		req.Limit = h.cfg.LimitMax
-	}
-
-	if req.Limit <= 0 {
-		req.Limit = h.cfg.LimitDefault
-	}
-
 	merchant, err := h.dispatch.Services.Billing.GetMerchantBy(ctx.Request().Context(), &grpc.GetMerchantByRequest{UserId: authUser.Id})
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, common.ErrorUnknown)
@@ -368,6 +369,12 @@ func (h *KeyProductRoute) getKeyProduct(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, res.KeyProduct)
 }
 
+// keysUploadResult is a result of the keys upload job, the report shows the lines skipped by the upload
+type keysUploadResult struct {
+	*grpc.PlatformKeysFileResponse
+	Report *keyfile.Report `json:"report"`
+}
+
 func (h *KeyProductRoute) uploadKeys(ctx echo.Context) error {
 	authUser := common.ExtractUserContext(ctx)
 	req := &grpc.PlatformKeysFileRequest{}
@@ -385,13 +392,51 @@ func (h *KeyProductRoute) uploadKeys(ctx echo.Context) error {
 	}
 	defer src.Close()
 
-	req.File, err = ioutil.ReadAll(src)
+	if file.Size > h.cfg.KeyFileMaxSize {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, common.ErrorMessageKeyFileTooLarge)
+	}
+
+	// size of the multipart file may be absent, so the reading is limited as well
+	data, err := ioutil.ReadAll(io.LimitReader(src, h.cfg.KeyFileMaxSize+1))
 
 	if err != nil {
 		h.L().Error(common.ErrorMessageCantReadFile.String(), logger.PairArgs("err", err.Error()))
 		return echo.NewHTTPError(http.StatusBadRequest, common.ErrorMessageCantReadFile)
 	}
 
+	if int64(len(data)) > h.cfg.KeyFileMaxSize {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, common.ErrorMessageKeyFileTooLarge)
+	}
+
+	format, err := h.cfg.KeyFormat(ctx.Param("platform_id"))
+	if err != nil {
+		h.L().Error(common.InternalErrorTemplate, logger.PairArgs("err", err.Error(), "platform_id", ctx.Param("platform_id")))
+		return echo.NewHTTPError(http.StatusInternalServerError, common.ErrorInternal)
+	}
+
+	keys, report, err := keyfile.Parse(data, format)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, common.ErrorMessageKeyFileEncoding)
+	}
+
+	// dry run shows content of the file to the merchant before anything is sent to the billing server
+	if dryRun := ctx.QueryParam(common.RequestParameterDryRun); dryRun == "1" || dryRun == "true" {
+		return ctx.JSON(http.StatusOK, report)
+	}
+
+	// invalid lines are skipped and reported in the result of the job, the file is rejected if nothing is left to upload
+	if len(keys) == 0 && report.Invalid > 0 {
+		details := fmt.Sprintf("%d of %d lines contain invalid keys, first at line %d", report.Invalid, report.Lines, report.InvalidExamples[0].Number)
+		return echo.NewHTTPError(http.StatusBadRequest, common.CloneError(common.ErrorMessageKeyFileInvalidKeys, details))
+	}
+
+	if len(keys) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, common.ErrorMessageKeyFileEmpty)
+	}
+
+	// billing server receives normalized file without blank lines and duplicates
+	req.File = keyfile.Bytes(keys)
+
 	merchant, err := h.dispatch.Services.Billing.GetMerchantBy(ctx.Request().Context(), &grpc.GetMerchantByRequest{UserId: authUser.Id})
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, common.ErrorUnknown)
@@ -408,17 +453,38 @@ func (h *KeyProductRoute) uploadKeys(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, common.GetValidationError(err))
 	}
 
-	res, err := h.dispatch.Services.Billing.UploadKeysFile(ctx.Request().Context(), req, client.WithRequestTimeout(time.Minute*10))
+	// ingestion of the large file takes minutes, so the file is sent to the billing server in background
+	j, err := h.dispatch.Jobs.Submit(jobTypeUploadKeys, authUser.Id, func(ctx context.Context, progress job.Progress) (interface{}, error) {
+		progress.SetTotal(1)
+
+		res, err := h.dispatch.Services.Billing.UploadKeysFile(ctx, req, client.WithRequestTimeout(uploadKeysTimeout))
+		if err != nil {
+			common.LogSrvCallFailedGRPC(h.L(), err, pkg.ServiceName, "UploadKeysFile", req.KeyProductId)
+			return nil, common.ErrorInternal
+		}
+
+		if res.Status != pkg.ResponseStatusOk {
+			return res, res.Message
+		}
+
+		err = h.dispatch.KeyStocks.AddKeyStockUploaded(req.MerchantId, req.KeyProductId, req.PlatformId, res.KeysProcessed, time.Now())
+
+		if err != nil {
+			h.L().Error(common.InternalErrorTemplate, logger.PairArgs("err", err.Error()))
+		}
+
+		checkKeyStock(ctx, h.dispatch, h.L(), h.cfg.KeyStockLowThreshold, req.MerchantId, req.KeyProductId, req.PlatformId)
+
+		progress.Add(1)
+		return &keysUploadResult{PlatformKeysFileResponse: res, Report: report}, nil
+	})
+
 	if err != nil {
 		h.L().Error(common.InternalErrorTemplate, logger.PairArgs("err", err.Error()))
 		return echo.NewHTTPError(http.StatusInternalServerError, common.ErrorInternal)
 	}
 
-	if res.Status != pkg.ResponseStatusOk {
-		return echo.NewHTTPError(int(res.Status), res.Message)
-	}
-
-	return ctx.JSON(http.StatusOK, res)
+	return jobAccepted(ctx, j)
 }
 
 func (h *KeyProductRoute) getCountOfKeys(ctx echo.Context) error {
@@ -454,6 +520,290 @@ func (h *KeyProductRoute) getCountOfKeys(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, res)
 }
 
+// @Description Get stock of keys of all key products of the merchant by platforms
+// @Example GET /admin/api/v1/key-products/inventory
+func (h *KeyProductRoute) getKeyInventory(ctx echo.Context) error {
+	authUser := common.ExtractUserContext(ctx)
+	merchant, err := h.dispatch.Services.Billing.GetMerchantBy(ctx.Request().Context(), &grpc.GetMerchantByRequest{UserId: authUser.Id})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, common.ErrorUnknown)
+	}
+	if merchant.Status != pkg.ResponseStatusOk {
+		return echo.NewHTTPError(http.StatusBadRequest, merchant.Message)
+	}
+
+	items := []*common.KeyInventoryItem{}
+	req := &grpc.ListKeyProductsRequest{MerchantId: merchant.Item.Id, Limit: h.cfg.LimitMax}
+
+	for {
+		res, err := h.dispatch.Services.Billing.GetKeyProducts(ctx.Request().Context(), req)
+		if err != nil {
+			h.L().Error(common.InternalErrorTemplate, logger.PairArgs("err", err.Error()))
+			return echo.NewHTTPError(http.StatusInternalServerError, common.ErrorInternal)
+		}
+
+		if res.Message != nil {
+			return echo.NewHTTPError(int(res.Status), res.Message)
+		}
+
+		for _, product := range res.Products {
+			for _, platform := range product.Platforms {
+				item, err := h.getKeyInventoryItem(ctx.Request().Context(), req.MerchantId, product.Id, platform)
+				if err != nil {
+					return err
+				}
+				items = append(items, item)
+			}
+		}
+
+		req.Offset += int32(len(res.Products))
+
+		if len(res.Products) == 0 || int64(req.Offset) >= int64(res.Count) {
+			break
+		}
+	}
+
+	return ctx.JSON(http.StatusOK, items)
+}
+
+// @Description Set low stock threshold of the key product platform, zero threshold resets it to default
+// @Example PUT /admin/api/v1/key-products/:key_product_id/platforms/:platform_id/threshold
+func (h *KeyProductRoute) setKeyStockThreshold(ctx echo.Context) error {
+	authUser := common.ExtractUserContext(ctx)
+	req := &common.KeyStockThresholdRequest{}
+
+	if err := ctx.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, common.ErrorRequestParamsIncorrect)
+	}
+
+	if err := h.dispatch.Validate.Struct(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, common.GetValidationError(err))
+	}
+
+	merchant, err := h.dispatch.Services.Billing.GetMerchantBy(ctx.Request().Context(), &grpc.GetMerchantByRequest{UserId: authUser.Id})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, common.ErrorUnknown)
+	}
+	if merchant.Status != pkg.ResponseStatusOk {
+		return echo.NewHTTPError(http.StatusBadRequest, merchant.Message)
+	}
+
+	productReq := &grpc.RequestKeyProductMerchant{Id: ctx.Param("key_product_id"), MerchantId: merchant.Item.Id}
+
+	if err := h.dispatch.Validate.Struct(productReq); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, common.GetValidationError(err))
+	}
+
+	res, err := h.dispatch.Services.Billing.GetKeyProduct(ctx.Request().Context(), productReq)
+	if err != nil {
+		h.L().Error(common.InternalErrorTemplate, logger.PairArgs("err", err.Error()))
+		return echo.NewHTTPError(http.StatusInternalServerError, common.ErrorInternal)
+	}
+
+	if res.Status != pkg.ResponseStatusOk {
+		return echo.NewHTTPError(int(res.Status), res.Message)
+	}
+
+	platformId := ctx.Param("platform_id")
+	found := false
+
+	for _, platform := range res.Product.Platforms {
+		if platform.Id == platformId {
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		return echo.NewHTTPError(http.StatusNotFound, common.ErrorMessageKeyProductPlatformNotFound)
+	}
+
+	stock, err := h.dispatch.KeyStocks.SetKeyStockThreshold(productReq.MerchantId, productReq.Id, platformId, req.Threshold)
+
+	if err != nil {
+		h.L().Error(common.InternalErrorTemplate, logger.PairArgs("err", err.Error()))
+		return echo.NewHTTPError(http.StatusInternalServerError, common.ErrorInternal)
+	}
+
+	// stock is checked against the new threshold, so the merchant raising it is alerted at once
+	checkKeyStock(ctx.Request().Context(), h.dispatch, h.L(), h.cfg.KeyStockLowThreshold, productReq.MerchantId, productReq.Id, platformId)
+
+	return ctx.JSON(http.StatusOK, stock)
+}
+
+// getKeyInventoryItem returns stock of the key product platform
+func (h *KeyProductRoute) getKeyInventoryItem(
+	ctx context.Context,
+	merchantId, keyProductId string,
+	platform *grpc.PlatformPrice,
+) (*common.KeyInventoryItem, error) {
+	req := &grpc.GetPlatformKeyCountRequest{KeyProductId: keyProductId, PlatformId: platform.Id, MerchantId: merchantId}
+	res, err := h.dispatch.Services.Billing.GetAvailableKeysCount(ctx, req)
+
+	if err != nil {
+		common.LogSrvCallFailedGRPC(h.L(), err, pkg.ServiceName, "GetAvailableKeysCount", req)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, common.ErrorInternal)
+	}
+
+	if res.Status != pkg.ResponseStatusOk {
+		return nil, echo.NewHTTPError(int(res.Status), res.Message)
+	}
+
+	stock, err := h.dispatch.KeyStocks.GetKeyStock(merchantId, keyProductId, platform.Id)
+	if err != nil {
+		h.L().Error(common.InternalErrorTemplate, logger.PairArgs("err", err.Error()))
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, common.ErrorInternal)
+	}
+
+	item := &common.KeyInventoryItem{
+		KeyProductId: keyProductId,
+		PlatformId:   platform.Id,
+		PlatformName: platform.Name,
+		Available:    res.Count,
+		Sold:         stock.Sold,
+		Uploaded:     stock.Uploaded,
+		Threshold:    keyStockThreshold(stock, h.cfg.KeyStockLowThreshold),
+		LastUploadAt: stock.LastUploadAt,
+	}
+
+	// keys uploaded before the api started to count them aren't known, so the reserved keys can't be negative
+	if reserved := item.Uploaded - item.Available - item.Sold; reserved > 0 {
+		item.Reserved = reserved
+	}
+
+	item.Low = item.Available < item.Threshold
+
+	return item, nil
+}
+
+// submitKeyStockSale runs processKeyStockSale in the background job, so the callback of the payment isn't delayed
+// by the calls of the billing server and isn't cancelled with the request, the job has no owner so only the staff sees it
+func submitKeyStockSale(set common.HandlerSet, log logger.Logger, defaultThreshold int32, orderId string) {
+	_, err := set.Jobs.Submit(jobTypeKeyStock, "", func(ctx context.Context, _ job.Progress) (interface{}, error) {
+		processKeyStockSale(ctx, set, log, defaultThreshold, orderId)
+		return nil, nil
+	})
+
+	if err != nil {
+		log.Error(common.InternalErrorTemplate, logger.PairArgs("err", err.Error(), "order_id", orderId))
+	}
+}
+
+// processKeyStockSale counts the keys sold by the paid order and checks the stocks of its key products,
+// the orders of the key products have the platform of the keys
+func processKeyStockSale(ctx context.Context, set common.HandlerSet, log logger.Logger, defaultThreshold int32, orderId string) {
+	req := &grpc.GetOrderRequest{Id: orderId}
+	res, err := set.Services.Billing.GetOrderPrivate(ctx, req)
+
+	if err != nil {
+		common.LogSrvCallFailedGRPC(log, err, pkg.ServiceName, "GetOrderPrivate", req)
+		return
+	}
+
+	if res.Status != pkg.ResponseStatusOk || res.Item == nil {
+		return
+	}
+
+	order := res.Item
+
+	if order.Status != common.OrderStatusProcessed || order.PlatformId == "" || order.Project == nil {
+		return
+	}
+
+	for _, keyProductId := range order.Products {
+		err = set.KeyStocks.AddKeyStockSold(order.Id, order.Project.MerchantId, keyProductId, order.PlatformId)
+
+		if err != nil {
+			log.Error(common.InternalErrorTemplate, logger.PairArgs("err", err.Error(), "order_id", order.Id))
+		}
+
+		checkKeyStock(ctx, set, log, defaultThreshold, order.Project.MerchantId, keyProductId, order.PlatformId)
+	}
+}
+
+// checkKeyStock is called when the stock of the key product platform is changed, it alerts the merchant
+// with the webhook and the notification once the stock drops below the threshold,
+// the alert is repeated after the stock is replenished and drops again
+func checkKeyStock(ctx context.Context, set common.HandlerSet, log logger.Logger, defaultThreshold int32, merchantId, keyProductId, platformId string) {
+	req := &grpc.GetPlatformKeyCountRequest{KeyProductId: keyProductId, PlatformId: platformId, MerchantId: merchantId}
+	res, err := set.Services.Billing.GetAvailableKeysCount(ctx, req)
+
+	if err != nil {
+		common.LogSrvCallFailedGRPC(log, err, pkg.ServiceName, "GetAvailableKeysCount", req)
+		return
+	}
+
+	if res.Status != pkg.ResponseStatusOk {
+		log.Error("unable to get count of available keys", logger.PairArgs("err", res.Message, "key_product_id", keyProductId))
+		return
+	}
+
+	stock, err := set.KeyStocks.GetKeyStock(merchantId, keyProductId, platformId)
+	if err != nil {
+		log.Error(common.InternalErrorTemplate, logger.PairArgs("err", err.Error(), "key_product_id", keyProductId))
+		return
+	}
+
+	threshold := keyStockThreshold(stock, defaultThreshold)
+	low := res.Count < threshold
+
+	// the mark is changed by one replica only, so the merchant is alerted once
+	changed, err := set.KeyStocks.SetKeyStockLow(merchantId, keyProductId, platformId, low)
+	if err != nil {
+		log.Error(common.InternalErrorTemplate, logger.PairArgs("err", err.Error(), "key_product_id", keyProductId))
+		return
+	}
+
+	if !changed || !low {
+		return
+	}
+
+	set.Webhooks.Publish(merchantId, webhook.EventKeyProductStockLow, map[string]interface{}{
+		"key_product_id": keyProductId,
+		"platform_id":    platformId,
+		"count":          res.Count,
+		"threshold":      threshold,
+	})
+
+	merchantReq := &grpc.GetMerchantByRequest{MerchantId: merchantId}
+	merchant, err := set.Services.Billing.GetMerchantBy(ctx, merchantReq)
+
+	if err != nil {
+		common.LogSrvCallFailedGRPC(log, err, pkg.ServiceName, "GetMerchantBy", merchantReq)
+		return
+	}
+
+	if merchant.Status != pkg.ResponseStatusOk || merchant.Item.User == nil {
+		log.Error("unable to create low stock notification", logger.PairArgs("err", merchant.Message, "merchant_id", merchantId))
+		return
+	}
+
+	notificationReq := &grpc.NotificationRequest{
+		MerchantId: merchantId,
+		UserId:     merchant.Item.User.Id,
+		Title:      keyStockLowNotificationTitle,
+		Message:    fmt.Sprintf(keyStockLowNotificationMessage, res.Count, keyProductId, platformId, threshold),
+	}
+	notification, err := set.Services.Billing.CreateNotification(ctx, notificationReq)
+
+	if err != nil {
+		common.LogSrvCallFailedGRPC(log, err, pkg.ServiceName, "CreateNotification", notificationReq)
+		return
+	}
+
+	if notification.Status != pkg.ResponseStatusOk {
+		log.Error("unable to create low stock notification", logger.PairArgs("err", notification.Message, "merchant_id", merchantId))
+	}
+}
+
+// keyStockThreshold returns threshold of the stock or the default one
+func keyStockThreshold(stock *common.KeyStock, defaultThreshold int32) int32 {
+	if stock.Threshold > 0 {
+		return stock.Threshold
+	}
+	return defaultThreshold
+}
+
 func (h *KeyProductRoute) getCountryFromAcceptLanguage(acceptLanguage string) (string, string) {
 	it := strings.Split(acceptLanguage, ",")
 
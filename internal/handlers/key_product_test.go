@@ -11,11 +11,16 @@ import (
 	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
 	"github.com/paysuper/paysuper-management-api/internal/mock"
 	"github.com/paysuper/paysuper-management-api/internal/test"
+	"github.com/paysuper/paysuper-management-api/pkg/job"
+	"github.com/paysuper/paysuper-management-api/pkg/keyfile"
 	"github.com/paysuper/paysuper-reporter/pkg"
 	"github.com/stretchr/testify/assert"
 	mock2 "github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"os"
 	"testing"
 )
 
@@ -609,3 +614,257 @@ func (suite *KeyProductTestSuite) TestProject_getKeyProduct_Ok() {
 	assert.Equal(suite.T(), http.StatusOK, res.Code)
 	assert.NotEmpty(suite.T(), res.Body.String())
 }
+
+func (suite *KeyProductTestSuite) uploadKeys(content string, dryRun bool) (*httptest.ResponseRecorder, error) {
+	return suite.uploadPlatformKeys("steam", content, dryRun)
+}
+
+func (suite *KeyProductTestSuite) uploadPlatformKeys(platformId, content string, dryRun bool) (*httptest.ResponseRecorder, error) {
+	filePath := os.TempDir() + string(os.PathSeparator) + bson.NewObjectId().Hex() + ".txt"
+	assert.NoError(suite.T(), ioutil.WriteFile(filePath, []byte(content), 0666))
+	defer os.Remove(filePath)
+
+	builder := suite.caller.Builder().
+		Params(":key_product_id", "5ced34d689fce60bf4440829", ":platform_id", platformId).
+		Path(common.AuthUserGroupPath + keyProductsPlatformsFilePath)
+
+	if dryRun {
+		builder.SetQueryParam(common.RequestParameterDryRun, "true")
+	}
+
+	return builder.ExecFileUpload(suite.T(), nil, common.RequestParameterFile, filePath)
+}
+
+func (suite *KeyProductTestSuite) TestProject_UploadKeys_DryRun_Ok() {
+	content := "\ufeffAAAAA-BBBBB-CCCCC\r\n\r\nAAAAA-BBBBB-CCCCC\r\nunknown\r\nAAAAA-BBBBB-DDDDD\r\n"
+	res, err := suite.uploadKeys(content, true)
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+
+	report := &keyfile.Report{}
+	assert.NoError(suite.T(), json.Unmarshal(res.Body.Bytes(), report))
+	assert.Equal(suite.T(), keyfile.EncodingUtf8, report.Encoding)
+	assert.Equal(suite.T(), keyfile.LineEndingsCrLf, report.LineEndings)
+	assert.Equal(suite.T(), 5, report.Lines)
+	assert.Equal(suite.T(), 1, report.Blank)
+	assert.Equal(suite.T(), 2, report.Valid)
+	assert.Equal(suite.T(), 1, report.Duplicate)
+	assert.Equal(suite.T(), 1, report.Invalid)
+	assert.Equal(suite.T(), 3, report.DuplicateExamples[0].Number)
+	assert.Equal(suite.T(), "unknown", report.InvalidExamples[0].Key)
+}
+
+func (suite *KeyProductTestSuite) TestProject_UploadKeys_Ok() {
+	res, err := suite.uploadKeys("AAAAA-BBBBB-CCCCC\nAAAAA-BBBBB-DDDDD\n", false)
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusAccepted, res.Code)
+
+	j := waitJobFinished(suite.T(), suite.router.dispatch.Jobs, res)
+	assert.Equal(suite.T(), job.StatusSucceeded, j.Status)
+}
+
+func (suite *KeyProductTestSuite) TestProject_UploadKeys_InvalidKeys() {
+	_, err := suite.uploadKeys("unknown\nAAAAA-BBBBB\n", false)
+
+	assert.Error(suite.T(), err)
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
+	assert.Equal(suite.T(), common.ErrorMessageKeyFileInvalidKeys.Code, httpErr.Message.(*grpc.ResponseErrorMessage).Code)
+}
+
+// uploadedKeys uploads the file and returns the file received by the billing server
+func (suite *KeyProductTestSuite) uploadedKeys(platformId, content string) (string, *job.Job) {
+	billingService := suite.mockKeyInventory(10)
+
+	res, err := suite.uploadPlatformKeys(platformId, content, false)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusAccepted, res.Code)
+
+	j := waitJobFinished(suite.T(), suite.router.dispatch.Jobs, res)
+	assert.Equal(suite.T(), job.StatusSucceeded, j.Status)
+
+	for _, call := range billingService.Calls {
+		if call.Method == "UploadKeysFile" {
+			return string(call.Arguments.Get(1).(*grpc.PlatformKeysFileRequest).File), j
+		}
+	}
+
+	suite.T().Fatal("keys file isn't uploaded")
+	return "", nil
+}
+
+func (suite *KeyProductTestSuite) TestProject_UploadKeys_InvalidKeysSkipped() {
+	file, j := suite.uploadedKeys("steam", "AAAAA-BBBBB-CCCCC\nunknown\nAAAAA-BBBBB-DDDDD\n")
+	assert.Equal(suite.T(), "AAAAA-BBBBB-CCCCC\nAAAAA-BBBBB-DDDDD", file)
+
+	result := &keysUploadResult{}
+	assert.NoError(suite.T(), j.DecodeResult(result))
+	assert.EqualValues(suite.T(), 2, result.KeysProcessed)
+	assert.Equal(suite.T(), 1, result.Report.Invalid)
+	assert.Equal(suite.T(), 2, result.Report.InvalidExamples[0].Number)
+}
+
+func (suite *KeyProductTestSuite) TestProject_UploadKeys_LowerCase() {
+	file, _ := suite.uploadedKeys("steam", "aaaaa-bbbbb-ccccc\nAAAAA-BBBBB-CCCCC\n")
+	assert.Equal(suite.T(), "AAAAA-BBBBB-CCCCC", file)
+}
+
+func (suite *KeyProductTestSuite) TestProject_UploadKeys_DefaultFormat_Spaces() {
+	file, _ := suite.uploadedKeys("gog", "  Gift Code 0001  \ngift code 0001\n")
+	assert.Equal(suite.T(), "Gift Code 0001\ngift code 0001", file)
+}
+
+func (suite *KeyProductTestSuite) TestProject_UploadKeys_ConfiguredFormat() {
+	formats := common.KeyFormats{}
+	assert.NoError(suite.T(), formats.Decode(`{"gog":"^[A-Z]{2}:[0-9]{2},[0-9]{2}$"}`))
+	suite.router.cfg.KeyFormats = formats
+
+	file, _ := suite.uploadedKeys("gog", "ab:12,34\nAB-12-34\n")
+	assert.Equal(suite.T(), "AB:12,34", file)
+}
+
+func (suite *KeyProductTestSuite) TestProject_UploadKeys_TooLarge() {
+	suite.router.cfg.KeyFileMaxSize = 10
+	_, err := suite.uploadKeys("AAAAA-BBBBB-CCCCC\n", false)
+
+	assert.Error(suite.T(), err)
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusRequestEntityTooLarge, httpErr.Code)
+	assert.Equal(suite.T(), common.ErrorMessageKeyFileTooLarge, httpErr.Message)
+}
+
+func (suite *KeyProductTestSuite) TestProject_UploadKeys_Encoding() {
+	_, err := suite.uploadKeys("AAAAA\x00BBBBB", false)
+
+	assert.Error(suite.T(), err)
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
+	assert.Equal(suite.T(), common.ErrorMessageKeyFileEncoding, httpErr.Message)
+}
+
+func (suite *KeyProductTestSuite) mockKeyInventory(count int32) *billMock.BillingService {
+	billingService := &billMock.BillingService{}
+	billingService.On("GetMerchantBy", mock2.Anything, mock2.Anything).Return(&grpc.GetMerchantResponse{
+		Status: pkg.ResponseStatusOk,
+		Item: &billing.Merchant{
+			Id:   "ffffffffffffffffffffffff",
+			User: &billing.MerchantUser{Id: "5cd5620f06ae110001509185"},
+		},
+	}, nil)
+	billingService.On("GetKeyProducts", mock2.Anything, mock2.Anything).Return(&grpc.ListKeyProductsResponse{
+		Status: pkg.ResponseStatusOk,
+		Count:  1,
+		Products: []*grpc.KeyProduct{
+			{Id: "5ced34d689fce60bf4440829", Platforms: []*grpc.PlatformPrice{{Id: "steam", Name: "Steam"}}},
+		},
+	}, nil)
+	billingService.On("GetKeyProduct", mock2.Anything, mock2.Anything).Return(&grpc.KeyProductResponse{
+		Status:  pkg.ResponseStatusOk,
+		Product: &grpc.KeyProduct{Id: "5ced34d689fce60bf4440829", Platforms: []*grpc.PlatformPrice{{Id: "steam", Name: "Steam"}}},
+	}, nil)
+	billingService.On("GetAvailableKeysCount", mock2.Anything, mock2.Anything).
+		Return(&grpc.GetPlatformKeyCountResponse{Status: pkg.ResponseStatusOk, Count: count}, nil)
+	billingService.On("UploadKeysFile", mock2.Anything, mock2.Anything, mock2.Anything).
+		Return(&grpc.PlatformKeysFileResponse{Status: pkg.ResponseStatusOk, KeysProcessed: 2, TotalCount: count}, nil)
+	billingService.On("CreateNotification", mock2.Anything, mock2.Anything).
+		Return(&grpc.CreateNotificationResponse{Status: pkg.ResponseStatusOk}, nil)
+	suite.router.dispatch.Services.Billing = billingService
+
+	return billingService
+}
+
+func (suite *KeyProductTestSuite) getKeyInventory() []*common.KeyInventoryItem {
+	res, err := suite.caller.Builder().
+		Path(common.AuthUserGroupPath + keyProductsInventoryPath).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+
+	var items []*common.KeyInventoryItem
+	assert.NoError(suite.T(), json.Unmarshal(res.Body.Bytes(), &items))
+	return items
+}
+
+func (suite *KeyProductTestSuite) TestProject_GetKeyInventory_LowStock_Ok() {
+	billingService := suite.mockKeyInventory(3)
+
+	items := suite.getKeyInventory()
+	assert.Len(suite.T(), items, 1)
+	assert.Equal(suite.T(), "steam", items[0].PlatformId)
+	assert.Equal(suite.T(), "Steam", items[0].PlatformName)
+	assert.EqualValues(suite.T(), 3, items[0].Available)
+	assert.Equal(suite.T(), suite.router.cfg.KeyStockLowThreshold, items[0].Threshold)
+	assert.True(suite.T(), items[0].Low)
+
+	// stock isn't changed by the request, so the merchant isn't alerted
+	billingService.AssertNotCalled(suite.T(), "CreateNotification", mock2.Anything, mock2.Anything)
+}
+
+func (suite *KeyProductTestSuite) TestProject_UploadKeys_LowStock_Notified() {
+	billingService := suite.mockKeyInventory(3)
+
+	for i := 0; i < 2; i++ {
+		res, err := suite.uploadKeys("AAAAA-BBBBB-CCCCC\nAAAAA-BBBBB-DDDDD\n", false)
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), http.StatusAccepted, res.Code)
+
+		j := waitJobFinished(suite.T(), suite.router.dispatch.Jobs, res)
+		assert.Equal(suite.T(), job.StatusSucceeded, j.Status)
+	}
+
+	// merchant is notified once until the stock is replenished above the threshold
+	billingService.AssertNumberOfCalls(suite.T(), "CreateNotification", 1)
+	billingService.AssertCalled(suite.T(), "CreateNotification", mock2.Anything, mock2.MatchedBy(func(req *grpc.NotificationRequest) bool {
+		return req.MerchantId == "ffffffffffffffffffffffff" && req.UserId == "5cd5620f06ae110001509185"
+	}))
+
+	items := suite.getKeyInventory()
+	assert.EqualValues(suite.T(), 4, items[0].Uploaded)
+	assert.EqualValues(suite.T(), 1, items[0].Reserved)
+	assert.EqualValues(suite.T(), 0, items[0].Sold)
+	assert.NotNil(suite.T(), items[0].LastUploadAt)
+}
+
+func (suite *KeyProductTestSuite) TestProject_SetKeyStockThreshold_Ok() {
+	billingService := suite.mockKeyInventory(3)
+
+	res, err := suite.caller.Builder().
+		Method(http.MethodPut).
+		Params(":key_product_id", "5ced34d689fce60bf4440829", ":platform_id", "steam").
+		Path(common.AuthUserGroupPath + keyProductsThresholdPath).
+		Init(test.ReqInitJSON()).
+		BodyString(`{"threshold": 2}`).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+
+	items := suite.getKeyInventory()
+	assert.EqualValues(suite.T(), 2, items[0].Threshold)
+	assert.False(suite.T(), items[0].Low)
+	billingService.AssertNotCalled(suite.T(), "CreateNotification", mock2.Anything, mock2.Anything)
+}
+
+func (suite *KeyProductTestSuite) TestProject_SetKeyStockThreshold_PlatformNotFound() {
+	suite.mockKeyInventory(3)
+
+	_, err := suite.caller.Builder().
+		Method(http.MethodPut).
+		Params(":key_product_id", "5ced34d689fce60bf4440829", ":platform_id", "gog").
+		Path(common.AuthUserGroupPath + keyProductsThresholdPath).
+		Init(test.ReqInitJSON()).
+		BodyString(`{"threshold": 2}`).
+		Exec(suite.T())
+
+	assert.Error(suite.T(), err)
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusNotFound, httpErr.Code)
+	assert.Equal(suite.T(), common.ErrorMessageKeyProductPlatformNotFound, httpErr.Message)
+}
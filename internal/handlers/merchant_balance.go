@@ -33,7 +33,11 @@ func NewBalanceRoute(set common.HandlerSet, cfg *common.Config) *BalanceRoute {
 
 func (h *BalanceRoute) Route(groups *common.Groups) {
 	groups.AuthUser.GET(balancePath, h.getMerchantBalance)
-	groups.AuthUser.GET(balanceMerchantPath, h.getMerchantBalance)
+	groups.AuthUser.GET(
+		balanceMerchantPath,
+		h.getMerchantBalance,
+		common.MerchantAccessMiddleware(h.dispatch, h.cfg, common.RequestParameterMerchantId),
+	)
 }
 
 // Get merchant balance
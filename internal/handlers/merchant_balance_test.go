@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"github.com/globalsign/mgo/bson"
+	"github.com/labstack/echo/v4"
 	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
 	"github.com/paysuper/paysuper-management-api/internal/mock"
 	"github.com/paysuper/paysuper-management-api/internal/test"
@@ -15,6 +16,7 @@ type BalanceTestSuite struct {
 	suite.Suite
 	router *BalanceRoute
 	caller *test.EchoReqResCaller
+	user   *common.AuthUser
 }
 
 func Test_Balance(t *testing.T) {
@@ -22,7 +24,7 @@ func Test_Balance(t *testing.T) {
 }
 
 func (suite *BalanceTestSuite) SetupTest() {
-	user := &common.AuthUser{
+	suite.user = &common.AuthUser{
 		Id:    "ffffffffffffffffffffffff",
 		Email: "test@unit.test",
 	}
@@ -32,7 +34,7 @@ func (suite *BalanceTestSuite) SetupTest() {
 		Billing: mock.NewBillingServerOkMock(),
 	}
 	suite.caller, e = test.SetUp(settings, srv, func(set *test.TestSet, mw test.Middleware) common.Handlers {
-		mw.Pre(test.PreAuthUserMiddleware(user))
+		mw.Pre(test.PreAuthUserMiddleware(suite.user))
 		suite.router = NewBalanceRoute(set.HandlerSet, set.GlobalConfig)
 		return common.Handlers{
 			suite.router,
@@ -74,6 +76,56 @@ func (suite *BalanceTestSuite) TestBalance_Ok_getBalanceForOtherMerchant() {
 	}
 }
 
+func (suite *BalanceTestSuite) TestBalance_Ok_getBalanceForOwnMerchantWithAuth() {
+	suite.router.cfg.DisableAuthMiddleware = false
+
+	res, err := suite.caller.Builder().
+		Method(http.MethodGet).
+		Params(":"+common.RequestParameterMerchantId, mock.OnboardingMerchantMock.Id).
+		Path(common.AuthUserGroupPath + balanceMerchantPath).
+		Init(test.ReqInitJSON()).
+		Exec(suite.T())
+
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), http.StatusOK, res.Code)
+		assert.NotEmpty(suite.T(), res.Body.String())
+	}
+}
+
+func (suite *BalanceTestSuite) TestBalance_Ok_getBalanceForOtherMerchantByStaff() {
+	suite.router.cfg.DisableAuthMiddleware = false
+	suite.user.Roles = map[string]bool{common.RoleAdmin: true}
+
+	res, err := suite.caller.Builder().
+		Method(http.MethodGet).
+		Params(":"+common.RequestParameterMerchantId, bson.NewObjectId().Hex()).
+		Path(common.AuthUserGroupPath + balanceMerchantPath).
+		Init(test.ReqInitJSON()).
+		Exec(suite.T())
+
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), http.StatusOK, res.Code)
+		assert.NotEmpty(suite.T(), res.Body.String())
+	}
+}
+
+func (suite *BalanceTestSuite) TestBalance_Fail_getBalanceForOtherMerchantAccessDenied() {
+	suite.router.cfg.DisableAuthMiddleware = false
+
+	_, err := suite.caller.Builder().
+		Method(http.MethodGet).
+		Params(":"+common.RequestParameterMerchantId, bson.NewObjectId().Hex()).
+		Path(common.AuthUserGroupPath + balanceMerchantPath).
+		Init(test.ReqInitJSON()).
+		Exec(suite.T())
+
+	assert.Error(suite.T(), err)
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusForbidden, httpErr.Code)
+	assert.Equal(suite.T(), common.ErrorMessageMerchantAccessDenied, httpErr.Message)
+}
+
 func (suite *BalanceTestSuite) TestBalance_Fail_getBalanceForMerchantNotFound() {
 	assert.Equal(suite.T(), common.TestStubImplementMe, "implement me!")
 }
@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"github.com/ProtocolONE/go-core/v2/pkg/logger"
+	"github.com/ProtocolONE/go-core/v2/pkg/provider"
+	"github.com/globalsign/mgo/bson"
+	"github.com/labstack/echo/v4"
+	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
+	"github.com/paysuper/paysuper-management-api/pkg/webhook"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	merchantsWebhooksPath           = "/merchants/:id/webhooks"
+	merchantsWebhooksIdPath         = "/merchants/:id/webhooks/:webhook_id"
+	merchantsWebhooksSecretPath     = "/merchants/:id/webhooks/:webhook_id/secret"
+	merchantsWebhooksPingPath       = "/merchants/:id/webhooks/:webhook_id/ping"
+	merchantsWebhooksDeliveriesPath = "/merchants/:id/webhooks/:webhook_id/deliveries"
+)
+
+type MerchantWebhookRequest struct {
+	Url     string   `json:"url" validate:"required,url"`
+	Events  []string `json:"events" validate:"required,min=1"`
+	Enabled *bool    `json:"enabled"`
+}
+
+type MerchantWebhooksRoute struct {
+	dispatch common.HandlerSet
+	cfg      common.Config
+	provider.LMT
+}
+
+func NewMerchantWebhooksRoute(set common.HandlerSet, cfg *common.Config) *MerchantWebhooksRoute {
+	set.AwareSet.Logger = set.AwareSet.Logger.WithFields(logger.Fields{"router": "MerchantWebhooksRoute"})
+	return &MerchantWebhooksRoute{
+		dispatch: set,
+		LMT:      &set.AwareSet,
+		cfg:      *cfg,
+	}
+}
+
+func (h *MerchantWebhooksRoute) Route(groups *common.Groups) {
+	merchantAccess := common.MerchantAccessMiddleware(h.dispatch, h.cfg, common.RequestParameterId)
+
+	groups.AuthUser.GET(merchantsWebhooksPath, h.listWebhooks, merchantAccess)
+	groups.AuthUser.POST(merchantsWebhooksPath, h.createWebhook, merchantAccess)
+	groups.AuthUser.GET(merchantsWebhooksIdPath, h.getWebhook, merchantAccess)
+	groups.AuthUser.PUT(merchantsWebhooksIdPath, h.updateWebhook, merchantAccess)
+	groups.AuthUser.DELETE(merchantsWebhooksIdPath, h.deleteWebhook, merchantAccess)
+	groups.AuthUser.POST(merchantsWebhooksSecretPath, h.rotateWebhookSecret, merchantAccess)
+	groups.AuthUser.POST(merchantsWebhooksPingPath, h.pingWebhook, merchantAccess)
+	groups.AuthUser.GET(merchantsWebhooksDeliveriesPath, h.listWebhookDeliveries, merchantAccess)
+}
+
+// Get list of the merchant webhook subscriptions
+// GET /admin/api/v1/merchants/5bdc39a95d1e1100019fb7df/webhooks
+func (h *MerchantWebhooksRoute) listWebhooks(ctx echo.Context) error {
+	list, err := h.dispatch.Webhooks.Storage().ListSubscriptions(ctx.Param(common.RequestParameterId))
+	if err != nil {
+		h.L().Error(common.InternalErrorTemplate, logger.PairArgs("err", err.Error()))
+		return echo.NewHTTPError(http.StatusInternalServerError, common.ErrorInternal)
+	}
+
+	items := make([]*webhook.Subscription, len(list))
+	for i, sub := range list {
+		items[i] = sub.Public()
+	}
+
+	return ctx.JSON(http.StatusOK, items)
+}
+
+// Create webhook subscription, the secret for the signature verification is returned only in this response
+// POST /admin/api/v1/merchants/5bdc39a95d1e1100019fb7df/webhooks
+//
+// @Example curl -X POST -H "Accept: application/json" -H "Content-Type: application/json" \
+//      -H "Authorization: Bearer %access_token_here%" \
+//      -d '{"url": "https://example.com/paysuper", "events": ["royalty_report.accepted"]}' \
+//      https://api.paysuper.online/admin/api/v1/merchants/5bdc39a95d1e1100019fb7df/webhooks
+func (h *MerchantWebhooksRoute) createWebhook(ctx echo.Context) error {
+	req, err := h.bindWebhookRequest(ctx)
+	if err != nil {
+		return err
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		h.L().Error(common.InternalErrorTemplate, logger.PairArgs("err", err.Error()))
+		return echo.NewHTTPError(http.StatusInternalServerError, common.ErrorInternal)
+	}
+
+	now := time.Now()
+	sub := &webhook.Subscription{
+		Id:         bson.NewObjectId().Hex(),
+		MerchantId: ctx.Param(common.RequestParameterId),
+		Url:        req.Url,
+		Events:     req.Events,
+		Enabled:    req.Enabled == nil || *req.Enabled,
+		Secret:     secret,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	if err = h.dispatch.Webhooks.Storage().CreateSubscription(sub); err != nil {
+		h.L().Error(common.InternalErrorTemplate, logger.PairArgs("err", err.Error()))
+		return echo.NewHTTPError(http.StatusInternalServerError, common.ErrorInternal)
+	}
+
+	return ctx.JSON(http.StatusCreated, sub)
+}
+
+// Get webhook subscription
+// GET /admin/api/v1/merchants/5bdc39a95d1e1100019fb7df/webhooks/5ced34d689fce60bf4440829
+func (h *MerchantWebhooksRoute) getWebhook(ctx echo.Context) error {
+	sub, err := h.getSubscription(ctx)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(http.StatusOK, sub.Public())
+}
+
+// Update url, events or state of the webhook subscription
+// PUT /admin/api/v1/merchants/5bdc39a95d1e1100019fb7df/webhooks/5ced34d689fce60bf4440829
+func (h *MerchantWebhooksRoute) updateWebhook(ctx echo.Context) error {
+	sub, err := h.getSubscription(ctx)
+	if err != nil {
+		return err
+	}
+
+	req, err := h.bindWebhookRequest(ctx)
+	if err != nil {
+		return err
+	}
+
+	sub.Url = req.Url
+	sub.Events = req.Events
+	if req.Enabled != nil {
+		sub.Enabled = *req.Enabled
+	}
+	sub.UpdatedAt = time.Now()
+
+	if err = h.dispatch.Webhooks.Storage().UpdateSubscription(sub); err != nil {
+		return h.storageError(err)
+	}
+
+	return ctx.JSON(http.StatusOK, sub.Public())
+}
+
+// Delete webhook subscription with its delivery log
+// DELETE /admin/api/v1/merchants/5bdc39a95d1e1100019fb7df/webhooks/5ced34d689fce60bf4440829
+func (h *MerchantWebhooksRoute) deleteWebhook(ctx echo.Context) error {
+	err := h.dispatch.Webhooks.Storage().DeleteSubscription(
+		ctx.Param(common.RequestParameterId),
+		ctx.Param(common.RequestParameterWebhookId),
+	)
+	if err != nil {
+		return h.storageError(err)
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// Generate new secret of the webhook subscription, the previous secret stops working immediately
+// POST /admin/api/v1/merchants/5bdc39a95d1e1100019fb7df/webhooks/5ced34d689fce60bf4440829/secret
+func (h *MerchantWebhooksRoute) rotateWebhookSecret(ctx echo.Context) error {
+	sub, err := h.getSubscription(ctx)
+	if err != nil {
+		return err
+	}
+
+	if sub.Secret, err = webhook.NewSecret(); err != nil {
+		h.L().Error(common.InternalErrorTemplate, logger.PairArgs("err", err.Error()))
+		return echo.NewHTTPError(http.StatusInternalServerError, common.ErrorInternal)
+	}
+	sub.UpdatedAt = time.Now()
+
+	if err = h.dispatch.Webhooks.Storage().UpdateSubscription(sub); err != nil {
+		return h.storageError(err)
+	}
+
+	return ctx.JSON(http.StatusOK, sub)
+}
+
+// Send test event to the webhook subscription and return the delivery result
+// POST /admin/api/v1/merchants/5bdc39a95d1e1100019fb7df/webhooks/5ced34d689fce60bf4440829/ping
+func (h *MerchantWebhooksRoute) pingWebhook(ctx echo.Context) error {
+	sub, err := h.getSubscription(ctx)
+	if err != nil {
+		return err
+	}
+
+	delivery, err := h.dispatch.Webhooks.Ping(sub)
+	if err != nil {
+		return h.storageError(err)
+	}
+
+	return ctx.JSON(http.StatusOK, delivery)
+}
+
+// Get delivery log of the webhook subscription, the newest deliveries come first
+// GET /admin/api/v1/merchants/5bdc39a95d1e1100019fb7df/webhooks/5ced34d689fce60bf4440829/deliveries?limit=10&offset=0
+func (h *MerchantWebhooksRoute) listWebhookDeliveries(ctx echo.Context) error {
+	cursor := common.NewPaginationBinder(h.cfg).Cursor(ctx)
+	list, err := h.dispatch.Webhooks.Storage().ListDeliveries(
+		ctx.Param(common.RequestParameterId),
+		ctx.Param(common.RequestParameterWebhookId),
+		int(cursor.Limit),
+		int(cursor.Offset),
+		cursor.Position,
+	)
+	if err != nil {
+		return h.storageError(err)
+	}
+	common.SetPaginationLinks(ctx, h.cfg.PaginationSecret(), common.NewPage(cursor, int64(list.Count), list.Items))
+	return ctx.JSON(http.StatusOK, list)
+}
+
+func (h *MerchantWebhooksRoute) bindWebhookRequest(ctx echo.Context) (*MerchantWebhookRequest, error) {
+	req := &MerchantWebhookRequest{}
+	if err := ctx.Bind(req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, common.ErrorRequestParamsIncorrect)
+	}
+
+	if err := h.dispatch.Validate.Struct(req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, common.GetValidationError(err))
+	}
+
+	u, err := url.Parse(req.Url)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || !webhook.IsAllowedHost(u.Hostname()) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, common.ErrorMessageWebhookUrlIncorrect)
+	}
+
+	for _, event := range req.Events {
+		if !webhook.IsEvent(event) {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, common.ErrorMessageWebhookEventUnknown)
+		}
+	}
+
+	return req, nil
+}
+
+func (h *MerchantWebhooksRoute) getSubscription(ctx echo.Context) (*webhook.Subscription, error) {
+	sub, err := h.dispatch.Webhooks.Storage().GetSubscription(
+		ctx.Param(common.RequestParameterId),
+		ctx.Param(common.RequestParameterWebhookId),
+	)
+	if err != nil {
+		return nil, h.storageError(err)
+	}
+	return sub, nil
+}
+
+func (h *MerchantWebhooksRoute) storageError(err error) error {
+	if err == webhook.ErrNotFound {
+		return echo.NewHTTPError(http.StatusNotFound, common.ErrorMessageWebhookNotFound)
+	}
+	h.L().Error(common.InternalErrorTemplate, logger.PairArgs("err", err.Error()))
+	return echo.NewHTTPError(http.StatusInternalServerError, common.ErrorInternal)
+}
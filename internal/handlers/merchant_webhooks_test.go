@@ -0,0 +1,358 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/globalsign/mgo/bson"
+	"github.com/labstack/echo/v4"
+	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
+	"github.com/paysuper/paysuper-management-api/internal/mock"
+	"github.com/paysuper/paysuper-management-api/internal/test"
+	"github.com/paysuper/paysuper-management-api/pkg/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type MerchantWebhooksTestSuite struct {
+	suite.Suite
+	router     *MerchantWebhooksRoute
+	caller     *test.EchoReqResCaller
+	merchantId string
+}
+
+func Test_MerchantWebhooks(t *testing.T) {
+	suite.Run(t, new(MerchantWebhooksTestSuite))
+}
+
+func (suite *MerchantWebhooksTestSuite) SetupTest() {
+	var e error
+	settings := test.DefaultSettings()
+	srv := common.Services{
+		Billing: mock.NewBillingServerOkMock(),
+	}
+	suite.caller, e = test.SetUp(settings, srv, func(set *test.TestSet, mw test.Middleware) common.Handlers {
+		suite.router = NewMerchantWebhooksRoute(set.HandlerSet, set.GlobalConfig)
+		return common.Handlers{
+			suite.router,
+		}
+	})
+	if e != nil {
+		panic(e)
+	}
+	suite.merchantId = bson.NewObjectId().Hex()
+}
+
+func (suite *MerchantWebhooksTestSuite) TearDownTest() {}
+
+func (suite *MerchantWebhooksTestSuite) createWebhook(url string) *webhook.Subscription {
+	body := `{"url": "` + url + `", "events": ["` + webhook.EventMerchantStatusChanged + `"]}`
+	res, err := suite.caller.Builder().
+		Method(http.MethodPost).
+		Params(":"+common.RequestParameterId, suite.merchantId).
+		Path(common.AuthUserGroupPath + merchantsWebhooksPath).
+		Init(test.ReqInitJSON()).
+		BodyString(body).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusCreated, res.Code)
+
+	sub := &webhook.Subscription{}
+	assert.NoError(suite.T(), json.Unmarshal(res.Body.Bytes(), sub))
+	return sub
+}
+
+// createLocalWebhook saves subscription to the test server directly, since the api refuses the loopback urls,
+// the deliveries are sent with the client of the test server
+func (suite *MerchantWebhooksTestSuite) createLocalWebhook(srv *httptest.Server) *webhook.Subscription {
+	secret, err := webhook.NewSecret()
+	assert.NoError(suite.T(), err)
+
+	sub := &webhook.Subscription{
+		Id:         bson.NewObjectId().Hex(),
+		MerchantId: suite.merchantId,
+		Url:        srv.URL,
+		Events:     []string{webhook.EventMerchantStatusChanged},
+		Enabled:    true,
+		Secret:     secret,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+	assert.NoError(suite.T(), suite.router.dispatch.Webhooks.Storage().CreateSubscription(sub))
+
+	suite.router.dispatch.Webhooks.SetHttpClient(srv.Client())
+	return sub
+}
+
+func (suite *MerchantWebhooksTestSuite) ping(sub *webhook.Subscription) *webhook.Delivery {
+	res, err := suite.caller.Builder().
+		Method(http.MethodPost).
+		Params(":"+common.RequestParameterId, suite.merchantId, ":"+common.RequestParameterWebhookId, sub.Id).
+		Path(common.AuthUserGroupPath + merchantsWebhooksPingPath).
+		Init(test.ReqInitJSON()).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+
+	delivery := &webhook.Delivery{}
+	assert.NoError(suite.T(), json.Unmarshal(res.Body.Bytes(), delivery))
+	return delivery
+}
+
+func (suite *MerchantWebhooksTestSuite) TestMerchantWebhooks_Create_Ok() {
+	sub := suite.createWebhook("https://example.com/paysuper")
+	assert.NotEmpty(suite.T(), sub.Id)
+	assert.NotEmpty(suite.T(), sub.Secret)
+	assert.True(suite.T(), sub.Enabled)
+	assert.Equal(suite.T(), suite.merchantId, sub.MerchantId)
+
+	res, err := suite.caller.Builder().
+		Params(":"+common.RequestParameterId, suite.merchantId).
+		Path(common.AuthUserGroupPath + merchantsWebhooksPath).
+		Init(test.ReqInitJSON()).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+
+	var list []*webhook.Subscription
+	assert.NoError(suite.T(), json.Unmarshal(res.Body.Bytes(), &list))
+	assert.Len(suite.T(), list, 1)
+	assert.Equal(suite.T(), sub.Id, list[0].Id)
+	assert.Empty(suite.T(), list[0].Secret)
+}
+
+func (suite *MerchantWebhooksTestSuite) TestMerchantWebhooks_Create_UnknownEvent() {
+	_, err := suite.caller.Builder().
+		Method(http.MethodPost).
+		Params(":"+common.RequestParameterId, suite.merchantId).
+		Path(common.AuthUserGroupPath + merchantsWebhooksPath).
+		Init(test.ReqInitJSON()).
+		BodyString(`{"url": "https://example.com/paysuper", "events": ["unknown"]}`).
+		Exec(suite.T())
+
+	assert.Error(suite.T(), err)
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
+	assert.Equal(suite.T(), common.ErrorMessageWebhookEventUnknown, httpErr.Message)
+}
+
+func (suite *MerchantWebhooksTestSuite) TestMerchantWebhooks_Create_IncorrectUrl() {
+	_, err := suite.caller.Builder().
+		Method(http.MethodPost).
+		Params(":"+common.RequestParameterId, suite.merchantId).
+		Path(common.AuthUserGroupPath + merchantsWebhooksPath).
+		Init(test.ReqInitJSON()).
+		BodyString(`{"url": "ftp://example.com/paysuper", "events": ["` + webhook.EventMerchantStatusChanged + `"]}`).
+		Exec(suite.T())
+
+	assert.Error(suite.T(), err)
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
+	assert.Equal(suite.T(), common.ErrorMessageWebhookUrlIncorrect, httpErr.Message)
+}
+
+func (suite *MerchantWebhooksTestSuite) TestMerchantWebhooks_Get_ForeignMerchant() {
+	sub := suite.createWebhook("https://example.com/paysuper")
+
+	_, err := suite.caller.Builder().
+		Params(":"+common.RequestParameterId, bson.NewObjectId().Hex(), ":"+common.RequestParameterWebhookId, sub.Id).
+		Path(common.AuthUserGroupPath + merchantsWebhooksIdPath).
+		Init(test.ReqInitJSON()).
+		Exec(suite.T())
+
+	assert.Error(suite.T(), err)
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusNotFound, httpErr.Code)
+	assert.Equal(suite.T(), common.ErrorMessageWebhookNotFound, httpErr.Message)
+}
+
+func (suite *MerchantWebhooksTestSuite) TestMerchantWebhooks_RotateSecret_Ok() {
+	sub := suite.createWebhook("https://example.com/paysuper")
+
+	res, err := suite.caller.Builder().
+		Method(http.MethodPost).
+		Params(":"+common.RequestParameterId, suite.merchantId, ":"+common.RequestParameterWebhookId, sub.Id).
+		Path(common.AuthUserGroupPath + merchantsWebhooksSecretPath).
+		Init(test.ReqInitJSON()).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+
+	rotated := &webhook.Subscription{}
+	assert.NoError(suite.T(), json.Unmarshal(res.Body.Bytes(), rotated))
+	assert.NotEmpty(suite.T(), rotated.Secret)
+	assert.NotEqual(suite.T(), sub.Secret, rotated.Secret)
+}
+
+func (suite *MerchantWebhooksTestSuite) TestMerchantWebhooks_Create_InternalUrl() {
+	urls := []string{
+		"http://127.0.0.1/paysuper",
+		"http://169.254.169.254/latest/meta-data",
+		"http://10.0.0.1/paysuper",
+		"https://192.168.1.10/paysuper",
+		"http://[::1]:8080/paysuper",
+		"http://localhost/paysuper",
+		"http://api.localhost/paysuper",
+	}
+
+	for _, u := range urls {
+		_, err := suite.caller.Builder().
+			Method(http.MethodPost).
+			Params(":"+common.RequestParameterId, suite.merchantId).
+			Path(common.AuthUserGroupPath + merchantsWebhooksPath).
+			Init(test.ReqInitJSON()).
+			BodyString(`{"url": "` + u + `", "events": ["` + webhook.EventMerchantStatusChanged + `"]}`).
+			Exec(suite.T())
+
+		assert.Error(suite.T(), err, u)
+		httpErr, ok := err.(*echo.HTTPError)
+		assert.True(suite.T(), ok)
+		assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
+		assert.Equal(suite.T(), common.ErrorMessageWebhookUrlIncorrect, httpErr.Message)
+	}
+}
+
+func (suite *MerchantWebhooksTestSuite) TestMerchantWebhooks_Ping_Signed() {
+	var body []byte
+	var signature string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+		signature = r.Header.Get(webhook.HeaderSignature)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	sub := suite.createLocalWebhook(srv)
+	delivery := suite.ping(sub)
+
+	assert.Equal(suite.T(), webhook.DeliveryStatusSucceeded, delivery.Status)
+	assert.Equal(suite.T(), http.StatusOK, delivery.ResponseCode)
+	assert.True(suite.T(), webhook.Verify(sub.Secret, signature, body))
+	assert.False(suite.T(), webhook.Verify("wrong", signature, body))
+}
+
+func (suite *MerchantWebhooksTestSuite) TestMerchantWebhooks_Ping_ResponseBodyHidden() {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte("internal service response"))
+	}))
+	defer srv.Close()
+
+	delivery := suite.ping(suite.createLocalWebhook(srv))
+
+	assert.Equal(suite.T(), webhook.DeliveryStatusFailed, delivery.Status)
+	assert.Equal(suite.T(), http.StatusForbidden, delivery.ResponseCode)
+	assert.NotContains(suite.T(), delivery.Error, "internal service response")
+}
+
+func (suite *MerchantWebhooksTestSuite) TestMerchantWebhooks_Ping_InternalAddress() {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	sub := suite.createLocalWebhook(srv)
+	// the default client of the sender refuses the loopback address of the test server
+	suite.router.dispatch.Webhooks = webhook.NewSender(
+		suite.router.dispatch.Webhooks.Storage(),
+		suite.router.cfg.SenderConfig(),
+		suite.router.L(),
+	)
+
+	delivery := suite.ping(sub)
+
+	assert.Equal(suite.T(), 0, calls)
+	assert.Equal(suite.T(), webhook.DeliveryStatusFailed, delivery.Status)
+	assert.Contains(suite.T(), delivery.Error, webhook.ErrAddressNotAllowed.Error())
+}
+
+func (suite *MerchantWebhooksTestSuite) TestMerchantWebhooks_Publish_RetryAndLog() {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	sub := suite.createLocalWebhook(srv)
+	sender := suite.router.dispatch.Webhooks
+
+	sender.Publish(suite.merchantId, webhook.EventMerchantStatusChanged, map[string]interface{}{"status": 1})
+	sender.Publish(suite.merchantId, webhook.EventRoyaltyReportAccepted, map[string]interface{}{"report_id": "1"})
+	sender.Publish("", webhook.EventMerchantStatusChanged, map[string]interface{}{"status": 2})
+	sender.ProcessDue()
+	sender.ProcessDue()
+
+	assert.Equal(suite.T(), 1, calls)
+
+	res, err := suite.caller.Builder().
+		Params(":"+common.RequestParameterId, suite.merchantId, ":"+common.RequestParameterWebhookId, sub.Id).
+		Path(common.AuthUserGroupPath + merchantsWebhooksDeliveriesPath).
+		Init(test.ReqInitJSON()).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+
+	list := &webhook.DeliveryList{}
+	assert.NoError(suite.T(), json.Unmarshal(res.Body.Bytes(), list))
+	assert.Equal(suite.T(), 1, list.Count)
+	assert.Equal(suite.T(), webhook.EventMerchantStatusChanged, list.Items[0].Event)
+	assert.Equal(suite.T(), webhook.DeliveryStatusPending, list.Items[0].Status)
+	assert.Equal(suite.T(), 1, list.Items[0].Attempts)
+	assert.Equal(suite.T(), http.StatusInternalServerError, list.Items[0].ResponseCode)
+	assert.True(suite.T(), list.Items[0].NextAttemptAt.After(time.Now()))
+}
+
+func (suite *MerchantWebhooksTestSuite) TestMerchantWebhooks_Broadcast_SubscribedOnly() {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		assert.Equal(suite.T(), webhook.EventVatReportStatusChanged, r.Header.Get(webhook.HeaderEvent))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	sub := suite.createLocalWebhook(srv)
+	sender := suite.router.dispatch.Webhooks
+
+	sender.Broadcast(webhook.EventVatReportStatusChanged, map[string]interface{}{"report_id": "1", "status": "paid"})
+	sender.ProcessDue()
+	assert.Equal(suite.T(), 0, calls)
+
+	sub.Events = append(sub.Events, webhook.EventVatReportStatusChanged)
+	assert.NoError(suite.T(), sender.Storage().UpdateSubscription(sub))
+
+	sender.Broadcast(webhook.EventVatReportStatusChanged, map[string]interface{}{"report_id": "1", "status": "paid"})
+	sender.ProcessDue()
+	assert.Equal(suite.T(), 1, calls)
+}
+
+func (suite *MerchantWebhooksTestSuite) TestMerchantWebhooks_Delete_Ok() {
+	sub := suite.createWebhook("https://example.com/paysuper")
+
+	res, err := suite.caller.Builder().
+		Method(http.MethodDelete).
+		Params(":"+common.RequestParameterId, suite.merchantId, ":"+common.RequestParameterWebhookId, sub.Id).
+		Path(common.AuthUserGroupPath + merchantsWebhooksIdPath).
+		Init(test.ReqInitJSON()).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusNoContent, res.Code)
+
+	_, err = suite.router.dispatch.Webhooks.Storage().GetSubscription(suite.merchantId, sub.Id)
+	assert.Equal(suite.T(), webhook.ErrNotFound, err)
+}
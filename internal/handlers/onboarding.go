@@ -12,6 +12,7 @@ import (
 	"github.com/paysuper/paysuper-billing-server/pkg/proto/billing"
 	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
 	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
+	"github.com/paysuper/paysuper-management-api/pkg/webhook"
 	"io"
 	"mime/multipart"
 	"net/http"
@@ -64,6 +65,7 @@ type OnboardingRoute struct {
 	dispatch   common.HandlerSet
 	awsManager awsWrapper.AwsManagerInterface
 	cfg        common.Config
+	s3Check    common.HealthCheck
 	provider.LMT
 }
 
@@ -74,37 +76,52 @@ func NewOnboardingRoute(set common.HandlerSet, initial config.Initial, awsManage
 		LMT:        &set.AwareSet,
 		cfg:        *globalCfg,
 		awsManager: awsManager,
+		s3Check: common.NewS3BucketHealthCheck(
+			"s3_agreement",
+			globalCfg.AwsAccessKeyIdAgreement,
+			globalCfg.AwsSecretAccessKeyAgreement,
+			globalCfg.AwsRegionAgreement,
+			globalCfg.AwsBucketAgreement,
+		),
 	}
 }
 
+// HealthChecks
+func (h *OnboardingRoute) HealthChecks() []common.HealthCheck {
+	return []common.HealthCheck{h.s3Check}
+}
+
 func (h *OnboardingRoute) Route(groups *common.Groups) {
+	merchantAccess := common.MerchantAccessMiddleware(h.dispatch, h.cfg, common.RequestParameterId)
+	notificationsAccess := common.MerchantAccessMiddleware(h.dispatch, h.cfg, common.RequestParameterMerchantId)
+
 	groups.AuthUser.GET(merchantsPath, h.listMerchants)
-	groups.AuthUser.GET(merchantsIdPath, h.getMerchant)
+	groups.AuthUser.GET(merchantsIdPath, h.getMerchant, merchantAccess)
 	groups.AuthUser.GET(merchantsUserPath, h.getMerchantByUser)
 
 	groups.AuthUser.PUT(merchantsCompanyPath, h.setMerchantCompany)
 	groups.AuthUser.PUT(merchantsContactsPath, h.setMerchantContacts)
 	groups.AuthUser.PUT(merchantsBankingPath, h.setMerchantBanking)
-	groups.AuthUser.PUT(merchantsIdCompanyPath, h.setMerchantCompany)
-	groups.AuthUser.PUT(merchantsIdContactsPath, h.setMerchantContacts)
-	groups.AuthUser.PUT(merchantsIdBankingPath, h.setMerchantBanking)
-	groups.AuthUser.GET(merchantsIdStatusCompanyPath, h.getMerchantStatus)
+	groups.AuthUser.PUT(merchantsIdCompanyPath, h.setMerchantCompany, merchantAccess)
+	groups.AuthUser.PUT(merchantsIdContactsPath, h.setMerchantContacts, merchantAccess)
+	groups.AuthUser.PUT(merchantsIdBankingPath, h.setMerchantBanking, merchantAccess)
+	groups.AuthUser.GET(merchantsIdStatusCompanyPath, h.getMerchantStatus, merchantAccess)
 
-	groups.AuthUser.PUT(merchantsIdChangeStatusCompanyPath, h.changeMerchantStatus)
-	groups.AuthUser.PATCH(merchantsIdPath, h.changeAgreement)
+	groups.AuthUser.PUT(merchantsIdChangeStatusCompanyPath, h.changeMerchantStatus, merchantAccess)
+	groups.AuthUser.PATCH(merchantsIdPath, h.changeAgreement, merchantAccess)
 
-	groups.AuthUser.GET(merchantsIdAgreementPath, h.getAgreementData)
-	groups.AuthUser.GET(merchantsAgreementDocumentPath, h.getAgreementDocument)
-	groups.AuthUser.POST(merchantsAgreementDocumentPath, h.uploadAgreementDocument)
-	groups.AuthUser.PUT(merchantsAgreementSignaturePath, h.createAgreementSignature)
+	groups.AuthUser.GET(merchantsIdAgreementPath, h.getAgreementData, merchantAccess)
+	groups.AuthUser.GET(merchantsAgreementDocumentPath, h.getAgreementDocument, merchantAccess)
+	groups.AuthUser.POST(merchantsAgreementDocumentPath, h.uploadAgreementDocument, merchantAccess)
+	groups.AuthUser.PUT(merchantsAgreementSignaturePath, h.createAgreementSignature, merchantAccess)
 
-	groups.AuthUser.POST(merchantsNotificationsPath, h.createNotification)
-	groups.AuthUser.GET(merchantsNotificationsIdPath, h.getNotification)
-	groups.AuthUser.GET(merchantsNotificationsPath, h.listNotifications)
-	groups.AuthUser.PUT(merchantsNotificationsMarkReadPath, h.markAsReadNotification)
+	groups.AuthUser.POST(merchantsNotificationsPath, h.createNotification, notificationsAccess)
+	groups.AuthUser.GET(merchantsNotificationsIdPath, h.getNotification, notificationsAccess)
+	groups.AuthUser.GET(merchantsNotificationsPath, h.listNotifications, notificationsAccess)
+	groups.AuthUser.PUT(merchantsNotificationsMarkReadPath, h.markAsReadNotification, notificationsAccess)
 
 	groups.AuthUser.GET(merchantsTariffsPath, h.getTariffRates)
-	groups.AuthUser.POST(merchantsIdTariffsPath, h.setTariffRates)
+	groups.AuthUser.POST(merchantsIdTariffsPath, h.setTariffRates, merchantAccess)
 }
 
 func (h *OnboardingRoute) getMerchant(ctx echo.Context) error {
@@ -156,8 +173,7 @@ func (h *OnboardingRoute) getMerchantByUser(ctx echo.Context) error {
 func (h *OnboardingRoute) listMerchants(ctx echo.Context) error {
 	req := &grpc.MerchantListingRequest{}
 	err := (&common.OnboardingMerchantListingBinder{
-		LimitDefault:  h.cfg.LimitDefault,
-		OffsetDefault: h.cfg.OffsetDefault,
+		Pagination: common.NewPaginationBinder(h.cfg),
 	}).Bind(req, ctx)
 
 	if err != nil {
@@ -207,6 +223,11 @@ func (h *OnboardingRoute) changeMerchantStatus(ctx echo.Context) error {
 		return echo.NewHTTPError(int(res.Status), res.Message)
 	}
 
+	h.dispatch.Webhooks.Publish(res.Item.Id, webhook.EventMerchantStatusChanged, map[string]interface{}{
+		"merchant_id": res.Item.Id,
+		"status":      res.Item.Status,
+	})
+
 	return ctx.JSON(http.StatusOK, res.Item)
 }
 
@@ -269,8 +290,7 @@ func (h *OnboardingRoute) getNotification(ctx echo.Context) error {
 func (h *OnboardingRoute) listNotifications(ctx echo.Context) error {
 	req := &grpc.ListingNotificationRequest{}
 	err := (&common.OnboardingNotificationsListBinder{
-		LimitDefault:  h.cfg.LimitDefault,
-		OffsetDefault: h.cfg.OffsetDefault,
+		Pagination: common.NewPaginationBinder(h.cfg),
 	}).Bind(req, ctx)
 
 	if err != nil {
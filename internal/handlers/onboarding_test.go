@@ -1065,6 +1065,53 @@ func (suite *OnboardingTestSuite) TestOnboarding_SetMerchantCompany_WithMerchant
 	assert.Equal(suite.T(), merchant.Company, company)
 }
 
+func (suite *OnboardingTestSuite) TestOnboarding_SetMerchantCompany_WithoutZip() {
+	company := &billing.MerchantCompanyInfo{
+		Name:               mock.OnboardingMerchantMock.Company.Name,
+		AlternativeName:    mock.OnboardingMerchantMock.Company.Name,
+		Website:            "http://localhost",
+		Country:            "AE",
+		State:              "Dubai",
+		City:               "Dubai",
+		Address:            "Sheikh Zayed rd. 1",
+		RegistrationNumber: "1234567890",
+	}
+	b, err := json.Marshal(company)
+	assert.NoError(suite.T(), err)
+
+	res, err := suite.caller.Builder().
+		Method(http.MethodPut).
+		Params(":"+common.RequestParameterId, mock.SomeMerchantId).
+		Path(common.AuthUserGroupPath + merchantsIdCompanyPath).
+		Init(test.ReqInitJSON()).
+		BodyBytes(b).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+
+	// the zip is required for the countries with the known format
+	company.Country = "RU"
+	company.State = "St.Petersburg"
+	company.City = "St.Petersburg"
+	company.Address = "Nevskiy st. 1"
+	b, err = json.Marshal(company)
+	assert.NoError(suite.T(), err)
+
+	_, err = suite.caller.Builder().
+		Method(http.MethodPut).
+		Params(":"+common.RequestParameterId, mock.SomeMerchantId).
+		Path(common.AuthUserGroupPath + merchantsIdCompanyPath).
+		Init(test.ReqInitJSON()).
+		BodyBytes(b).
+		Exec(suite.T())
+
+	assert.Error(suite.T(), err)
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
+}
+
 func (suite *OnboardingTestSuite) TestOnboarding_SetMerchantCompany_BindError() {
 	b := `{"name": 123}`
 
@@ -1099,7 +1146,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_SetMerchantCompany_ValidationEr
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), common.ErrorMessageIncorrectCompanyName.Code, msg.Code)
 	assert.Equal(suite.T(), common.ErrorMessageIncorrectCompanyName.Message, msg.Message)
@@ -1122,7 +1169,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_SetMerchantCompany_ValidationEr
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), common.ErrorMessageIncorrectAlternativeName.Code, msg.Code)
 	assert.Equal(suite.T(), common.ErrorMessageIncorrectAlternativeName.Message, msg.Message)
@@ -1145,7 +1192,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_SetMerchantCompany_ValidationEr
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), common.ErrorMessageIncorrectWebsite.Code, msg.Code)
 	assert.Equal(suite.T(), common.ErrorMessageIncorrectWebsite.Message, msg.Message)
@@ -1168,7 +1215,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_SetMerchantCompany_ValidationEr
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), common.ErrorIncorrectCountryIdentifier.Code, msg.Code)
 	assert.Equal(suite.T(), common.ErrorIncorrectCountryIdentifier.Message, msg.Message)
@@ -1191,7 +1238,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_SetMerchantCompany_ValidationEr
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), common.ErrorMessageIncorrectState.Code, msg.Code)
 	assert.Equal(suite.T(), common.ErrorMessageIncorrectState.Message, msg.Message)
@@ -1220,7 +1267,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_SetMerchantCompany_ValidationEr
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), common.ErrorMessageIncorrectZip.Code, msg.Code)
 	assert.Equal(suite.T(), common.ErrorMessageIncorrectZip.Message, msg.Message)
@@ -1250,7 +1297,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_SetMerchantCompany_ValidationEr
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), common.ErrorMessageIncorrectCity.Code, msg.Code)
 	assert.Equal(suite.T(), common.ErrorMessageIncorrectCity.Message, msg.Message)
@@ -1281,7 +1328,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_SetMerchantCompany_ValidationEr
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), common.ErrorMessageIncorrectAddress.Code, msg.Code)
 	assert.Equal(suite.T(), common.ErrorMessageIncorrectAddress.Message, msg.Message)
@@ -1456,7 +1503,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_SetMerchantContacts_ValidationE
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), common.ErrorMessageRequiredContactAuthorized.Code, msg.Code)
 	assert.Equal(suite.T(), common.ErrorMessageRequiredContactAuthorized.Message, msg.Message)
@@ -1479,7 +1526,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_SetMerchantContacts_ValidationE
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), common.ErrorMessageRequiredContactTechnical.Code, msg.Code)
 	assert.Equal(suite.T(), common.ErrorMessageRequiredContactTechnical.Message, msg.Message)
@@ -1505,7 +1552,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_SetMerchantContacts_ValidationE
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), common.ErrorMessageIncorrectName.Code, msg.Code)
 	assert.Equal(suite.T(), common.ErrorMessageIncorrectName.Message, msg.Message)
@@ -1531,7 +1578,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_SetMerchantContacts_ValidationE
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), common.ErrorMessageIncorrectName.Code, msg.Code)
 	assert.Equal(suite.T(), common.ErrorMessageIncorrectName.Message, msg.Message)
@@ -1557,7 +1604,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_SetMerchantContacts_ValidationE
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), common.ErrorEmailFieldIncorrect.Code, msg.Code)
 	assert.Equal(suite.T(), common.ErrorEmailFieldIncorrect.Message, msg.Message)
@@ -1583,7 +1630,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_SetMerchantContacts_ValidationE
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), common.ErrorEmailFieldIncorrect.Code, msg.Code)
 	assert.Equal(suite.T(), common.ErrorEmailFieldIncorrect.Message, msg.Message)
@@ -1609,7 +1656,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_SetMerchantContacts_ValidationE
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), common.ErrorMessageIncorrectPhone.Code, msg.Code)
 	assert.Equal(suite.T(), common.ErrorMessageIncorrectPhone.Message, msg.Message)
@@ -1635,7 +1682,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_SetMerchantContacts_ValidationE
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), common.ErrorMessageIncorrectPhone.Code, msg.Code)
 	assert.Equal(suite.T(), common.ErrorMessageIncorrectPhone.Message, msg.Message)
@@ -1661,7 +1708,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_SetMerchantContacts_ValidationE
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), common.ErrorMessageIncorrectPosition.Code, msg.Code)
 	assert.Equal(suite.T(), common.ErrorMessageIncorrectPosition.Message, msg.Message)
@@ -1818,7 +1865,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_SetMerchantBanking_ValidationEr
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), common.ErrorMessageIncorrectBankName.Code, msg.Code)
 	assert.Equal(suite.T(), common.ErrorMessageIncorrectBankName.Message, msg.Message)
@@ -1847,7 +1894,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_SetMerchantBanking_ValidationEr
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), common.ErrorMessageIncorrectBankAddress.Code, msg.Code)
 	assert.Equal(suite.T(), common.ErrorMessageIncorrectBankAddress.Message, msg.Message)
@@ -1876,7 +1923,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_SetMerchantBanking_ValidationEr
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), common.ErrorMessageIncorrectBankAccountNumber.Code, msg.Code)
 	assert.Equal(suite.T(), common.ErrorMessageIncorrectBankAccountNumber.Message, msg.Message)
@@ -1905,7 +1952,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_SetMerchantBanking_ValidationEr
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), common.ErrorMessageIncorrectBankSwift.Code, msg.Code)
 	assert.Equal(suite.T(), common.ErrorMessageIncorrectBankSwift.Message, msg.Message)
@@ -1935,7 +1982,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_SetMerchantBanking_ValidationEr
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), common.ErrorMessageIncorrectBankCorrespondentAccount.Code, msg.Code)
 	assert.Equal(suite.T(), common.ErrorMessageIncorrectBankCorrespondentAccount.Message, msg.Message)
@@ -2030,7 +2077,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_GetMerchantStatus_ValidateError
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Regexp(suite.T(), "MerchantId", msg.Details)
 }
@@ -2223,7 +2270,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_GetTariffRates_ValidateError()
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Regexp(suite.T(), "Region", msg.Details)
 }
@@ -2247,7 +2294,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_GetTariffRates_ValidateAmountRa
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Regexp(suite.T(), "AmountFrom", msg.Details)
 }
@@ -2356,7 +2403,7 @@ func (suite *OnboardingTestSuite) TestOnboarding_SetTariffRates_ValidationError(
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Regexp(suite.T(), "AmountFrom", msg.Details)
 }
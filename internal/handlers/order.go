@@ -10,17 +10,23 @@ import (
 	"github.com/paysuper/paysuper-billing-server/pkg/proto/billing"
 	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
 	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
+	"github.com/paysuper/paysuper-management-api/pkg/export"
 	paylinkServiceConst "github.com/paysuper/paysuper-payment-link/pkg"
 	"github.com/paysuper/paysuper-payment-link/proto"
 	"net/http"
+	"net/url"
+	"sort"
+	"strings"
 	"time"
 )
 
 const (
 	orderIdPath              = "/order/:id"
+	orderFallbackFormPath    = "/order/:id/form"
 	paylinkIdPath            = "/paylink/:id"
 	orderCreatePath          = "/order/create"
 	orderPath                = "/order"
+	orderExportPath          = "/order/export"
 	paymentPath              = "/payment"
 	orderRefundsPath         = "/order/:order_id/refunds"
 	orderRefundsIdsPath      = "/order/:order_id/refunds/:refund_id"
@@ -36,9 +42,19 @@ const (
 )
 
 const (
-	orderFormTemplateName  = "order.html"
-	orderInlineFormUrlMask = "%s://%s/order/%s"
-	errorTemplateName      = "error.html"
+	orderFormTemplateName    = "order.html"
+	orderInlineFormUrlMask   = "%s://%s/order/%s"
+	orderFallbackFormUrlMask = "/order/%s/form?method=%s"
+	orderFallbackActionMask  = "/order/%s/form"
+	errorTemplateName        = "error.html"
+	paymentResultTemplate    = "payment_result.html"
+)
+
+var (
+	orderExportDefaultColumns = []string{
+		"id", "uuid", "transaction", "status", "total_payment_amount", "currency", "country_code",
+		"merchant_id", "project.id", "project.name", "payment_method.name", "created_at", "transaction_date",
+	}
 )
 
 type CreateOrderJsonProjectResponse struct {
@@ -61,13 +77,15 @@ func (b *OrderListRefundsBinder) Bind(i interface{}, ctx echo.Context) error {
 		return err
 	}
 
-	structure := i.(*grpc.ListRefundsRequest)
-	structure.OrderId = ctx.Param(common.RequestParameterOrderId)
+	err = common.NewPaginationBinder(b.cfg).Apply(i, ctx)
 
-	if structure.Limit <= 0 {
-		structure.Limit = b.cfg.LimitDefault
+	if err != nil {
+		return err
 	}
 
+	structure := i.(*grpc.ListRefundsRequest)
+	structure.OrderId = ctx.Param(common.RequestParameterOrderId)
+
 	return nil
 }
 
@@ -81,23 +99,27 @@ func NewOrderListRefundsBinde(set common.HandlerSet, cfg common.Config) *OrderLi
 }
 
 type OrderRoute struct {
-	dispatch common.HandlerSet
-	cfg      common.Config
+	dispatch       common.HandlerSet
+	cfg            common.Config
+	frameAncestors *common.FrameAncestors
 	provider.LMT
 }
 
 func NewOrderRoute(set common.HandlerSet, cfg *common.Config) *OrderRoute {
 	set.AwareSet.Logger = set.AwareSet.Logger.WithFields(logger.Fields{"router": "OrderRoute"})
 	return &OrderRoute{
-		dispatch: set,
-		LMT:      &set.AwareSet,
-		cfg:      *cfg,
+		dispatch:       set,
+		LMT:            &set.AwareSet,
+		cfg:            *cfg,
+		frameAncestors: common.NewFrameAncestors(cfg.SecurityFrameAncestorsTtl),
 	}
 }
 
 func (h *OrderRoute) Route(groups *common.Groups) {
 
 	groups.Common.GET(orderIdPath, h.getOrderForm)
+	groups.Common.GET(orderFallbackFormPath, h.getOrderFallbackForm)
+	groups.Common.POST(orderFallbackFormPath, h.processOrderFallbackForm)
 	groups.Common.GET(paylinkIdPath, h.getOrderForPaylink)       // TODO: Need a test
 	groups.Common.GET(orderCreatePath, h.createFromFormData)     // TODO: Need a test
 	groups.Common.POST(orderCreatePath, h.createFromFormData)    // TODO: Need a test
@@ -105,6 +127,7 @@ func (h *OrderRoute) Route(groups *common.Groups) {
 	groups.AuthProject.POST(paymentPath, h.processCreatePayment) // TODO: Need a test
 
 	groups.AuthUser.GET(orderPath, h.listOrdersPublic)
+	groups.AuthUser.GET(orderExportPath, h.exportOrdersPublic)
 	groups.AuthUser.GET(orderIdPath, h.getOrderPublic) // TODO: Need a test
 
 	groups.AuthUser.GET(orderRefundsPath, h.listRefunds)
@@ -286,6 +309,180 @@ func (h *OrderRoute) getOrderForm(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, common.ErrorRequestParamsIncorrect)
 	}
 
+	res, err := h.getPaymentFormData(ctx, id)
+
+	if err != nil {
+		return err
+	}
+
+	h.setPaymentFormFrameAncestors(ctx, res)
+
+	return ctx.Render(
+		http.StatusOK,
+		orderFormTemplateName,
+		map[string]interface{}{
+			"Order":                   res,
+			"WebSocketUrl":            h.cfg.WebsocketUrl,
+			"PaymentFormJsLibraryUrl": h.cfg.PaymentFormJsLibraryUrl,
+			"Theme":                   h.getPaymentFormTheme(res),
+		},
+	)
+}
+
+// Render server side payment form of the payment method for clients without javascript
+// GET /order/5ced34d689fce60bf4440829/form?method=5be2d0b4b0b30d0007383ce6
+func (h *OrderRoute) getOrderFallbackForm(ctx echo.Context) error {
+	id := ctx.Param(common.RequestParameterId)
+
+	if id == "" {
+		return ctx.Render(http.StatusBadRequest, errorTemplateName, map[string]interface{}{})
+	}
+
+	res, err := h.getPaymentFormData(ctx, id)
+
+	if err != nil {
+		return err
+	}
+
+	h.setPaymentFormFrameAncestors(ctx, res)
+
+	form := &common.PaymentForm{
+		OrderId:   res.Item.Id,
+		Amount:    res.Item.Amount,
+		Currency:  res.Item.Currency,
+		ActionUrl: fmt.Sprintf(orderFallbackActionMask, url.PathEscape(id)),
+		Theme:     h.getPaymentFormTheme(res),
+	}
+
+	if res.Item.Project != nil {
+		form.ProjectName = res.Item.Project.Name
+	}
+
+	methodId := ctx.QueryParam(common.RequestParameterMethod)
+
+	for _, pm := range res.Item.PaymentMethods {
+		// payment methods without the form template are available in the javascript form only
+		if _, ok := common.PaymentFormTemplates[pm.Group]; !ok {
+			continue
+		}
+
+		method := &common.PaymentFormMethod{
+			Id:    pm.Id,
+			Name:  pm.Name,
+			Group: pm.Group,
+			Url:   fmt.Sprintf(orderFallbackFormUrlMask, url.PathEscape(id), url.QueryEscape(pm.Id)),
+		}
+		form.Methods = append(form.Methods, method)
+
+		if form.Method == nil && (methodId == "" || methodId == pm.Id) {
+			form.Method = method
+		}
+	}
+
+	if form.Method == nil {
+		h.L().Error("payment method form not found", logger.PairArgs("order_id", id, "method", methodId))
+		return ctx.Render(http.StatusNotFound, errorTemplateName, map[string]interface{}{})
+	}
+
+	return ctx.Render(http.StatusOK, common.PaymentFormTemplateName(form.Method.Group), form)
+}
+
+// Create payment by the server side payment form posted without javascript, the customer is redirected
+// to the payment provider and the errors are rendered as the error page
+// POST /order/5ced34d689fce60bf4440829/form
+func (h *OrderRoute) processOrderFallbackForm(ctx echo.Context) error {
+	id := ctx.Param(common.RequestParameterId)
+	params, err := ctx.FormParams()
+
+	if id == "" || err != nil {
+		return ctx.Render(http.StatusBadRequest, errorTemplateName, map[string]interface{}{})
+	}
+
+	data := make(map[string]string, len(params))
+
+	for k, v := range params {
+		if len(v) > 0 {
+			data[k] = v[0]
+		}
+	}
+
+	// the order is taken from the path of the form, not from its fields
+	data[common.RequestParameterOrderId] = id
+
+	req := &grpc.PaymentCreateRequest{
+		Data:           data,
+		AcceptLanguage: ctx.Request().Header.Get(common.HeaderAcceptLanguage),
+		UserAgent:      ctx.Request().Header.Get(common.HeaderUserAgent),
+		Ip:             ctx.RealIP(),
+	}
+	res, err := h.dispatch.Services.Billing.PaymentCreateProcess(ctx.Request().Context(), req)
+
+	if err != nil {
+		common.LogSrvCallFailedGRPC(h.L(), err, pkg.ServiceName, "PaymentCreateProcess", common.MaskPaymentCreateRequest(req))
+		return ctx.Render(http.StatusInternalServerError, errorTemplateName, map[string]interface{}{})
+	}
+
+	if res.Status != pkg.ResponseStatusOk {
+		return ctx.Render(int(res.Status), errorTemplateName, map[string]interface{}{"Message": res.Message.GetMessage()})
+	}
+
+	if res.RedirectUrl != "" {
+		return ctx.Redirect(http.StatusFound, res.RedirectUrl)
+	}
+
+	return ctx.Render(http.StatusOK, paymentResultTemplate, map[string]interface{}{"OrderId": id})
+}
+
+// getPaymentFormTheme returns theme of the project of the order, payment form is rendered with default theme
+// if the theme isn't available
+func (h *OrderRoute) getPaymentFormTheme(res *grpc.PaymentFormJsonDataResponse) *common.ProjectTheme {
+	if res.Item.Project == nil {
+		return nil
+	}
+
+	theme, err := h.dispatch.Themes.GetTheme(res.Item.Project.Id)
+
+	if err != nil {
+		h.L().Error(common.InternalErrorTemplate, logger.PairArgs("err", err.Error(), "project_id", res.Item.Project.Id))
+		return nil
+	}
+
+	return theme
+}
+
+// setPaymentFormFrameAncestors allows the sites of the project of the order to embed the payment form,
+// the sites are known by the urls the project is allowed to create orders from, other sites can't embed the form
+func (h *OrderRoute) setPaymentFormFrameAncestors(ctx echo.Context, res *grpc.PaymentFormJsonDataResponse) {
+	if res.Item.Project == nil || res.Item.Project.Id == "" {
+		return
+	}
+
+	if origins, ok := h.frameAncestors.Get(res.Item.Project.Id); ok {
+		common.SetFrameAncestorsContext(ctx, origins)
+		return
+	}
+
+	req := &grpc.GetProjectRequest{ProjectId: res.Item.Project.Id}
+	rsp, err := h.dispatch.Services.Billing.GetProject(ctx.Request().Context(), req)
+
+	if err != nil {
+		common.LogSrvCallFailedGRPC(h.L(), err, pkg.ServiceName, "GetProject", req)
+		return
+	}
+
+	if rsp.Status != pkg.ResponseStatusOk || rsp.Item == nil {
+		h.L().Error("project of the payment form not found", logger.PairArgs("project_id", req.ProjectId))
+		return
+	}
+
+	origins := common.AllowedUrlsOrigins(rsp.Item.CreateOrderAllowedUrls)
+	h.frameAncestors.Set(req.ProjectId, origins)
+
+	common.SetFrameAncestorsContext(ctx, origins)
+}
+
+// getPaymentFormData returns data of the payment form of the order and sets the customer token cookie
+func (h *OrderRoute) getPaymentFormData(ctx echo.Context, id string) (*grpc.PaymentFormJsonDataResponse, error) {
 	cookie, err := ctx.Cookie(common.CustomerTokenCookiesName)
 
 	req := &grpc.PaymentFormJsonDataRequest{
@@ -304,11 +501,11 @@ func (h *OrderRoute) getOrderForm(ctx echo.Context) error {
 
 	if err != nil {
 		common.LogSrvCallFailedGRPC(h.L(), err, pkg.ServiceName, "PaymentFormJsonDataProcess", req)
-		return echo.NewHTTPError(http.StatusInternalServerError, common.ErrorUnknown)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, common.ErrorUnknown)
 	}
 
 	if res.Status != http.StatusOK {
-		return echo.NewHTTPError(int(res.Status), res.Message)
+		return nil, echo.NewHTTPError(int(res.Status), res.Message)
 	}
 
 	if res.Item.Cookie != "" {
@@ -318,17 +515,65 @@ func (h *OrderRoute) getOrderForm(ctx echo.Context) error {
 		cookie.Expires = time.Now().Add(h.cfg.CustomerTokenCookiesLifetime)
 		cookie.HttpOnly = true
 		ctx.SetCookie(cookie)
+
+		h.saveFormCustomerToken(ctx, id, res.Item, cookie.Value)
 	}
 
-	return ctx.Render(
-		http.StatusOK,
-		orderFormTemplateName,
-		map[string]interface{}{
-			"Order":                   res,
-			"WebSocketUrl":            h.cfg.WebsocketUrl,
-			"PaymentFormJsLibraryUrl": h.cfg.PaymentFormJsLibraryUrl,
-		},
-	)
+	return res, nil
+}
+
+// saveFormCustomerToken binds the customer token cookie of the payment form to the customer of the order,
+// so the browser of the customer is authorized by the cookie in the saved cards and the order events api
+func (h *OrderRoute) saveFormCustomerToken(ctx echo.Context, orderId string, form *grpc.PaymentFormJsonData, value string) {
+	lifetime := h.cfg.CustomerTokenCookiesLifetime
+	if lifetime <= 0 {
+		lifetime = h.cfg.CustomerTokenLifetime
+	}
+
+	token, err := h.dispatch.Customers.GetToken(value)
+	if err != nil {
+		h.L().Error(common.InternalErrorTemplate, logger.PairArgs("err", err.Error()))
+		return
+	}
+
+	// the order is requested once for the cookie in the project, the customer of the cookie doesn't change
+	bound := token != nil && form.Project != nil && token.ProjectId == form.Project.Id
+
+	if !bound {
+		req := &grpc.GetOrderRequest{Id: orderId}
+		rsp, err := h.dispatch.Services.Billing.GetOrderPrivate(ctx.Request().Context(), req)
+
+		if err != nil {
+			common.LogSrvCallFailedGRPC(h.L(), err, pkg.ServiceName, "GetOrderPrivate", req)
+			return
+		}
+
+		if rsp.Status != pkg.ResponseStatusOk || rsp.Item == nil || rsp.Item.Project == nil || rsp.Item.User == nil {
+			return
+		}
+
+		token = &common.CustomerToken{
+			Token:      value,
+			ProjectId:  rsp.Item.Project.Id,
+			CustomerId: rsp.Item.User.ExternalId,
+			UserId:     rsp.Item.User.Id,
+		}
+	}
+
+	token.ExpiresAt = time.Now().Add(lifetime)
+
+	if err = h.dispatch.Customers.SaveToken(token); err != nil {
+		h.L().Error(common.InternalErrorTemplate, logger.PairArgs("err", err.Error()))
+		return
+	}
+
+	if bound || token.CustomerId == "" || token.UserId == "" {
+		return
+	}
+
+	if err = h.dispatch.Customers.AddCustomerUser(token.ProjectId, token.CustomerId, token.UserId); err != nil {
+		h.L().Error(common.InternalErrorTemplate, logger.PairArgs("err", err.Error()))
+	}
 }
 
 // Create order from payment link and redirect to order payment form
@@ -435,18 +680,73 @@ func (h *OrderRoute) getOrderPublic(ctx echo.Context) error {
 func (h *OrderRoute) listOrdersPublic(ctx echo.Context) error {
 
 	req := &grpc.ListOrdersRequest{}
-	err := ctx.Bind(req)
+	err := common.NewPaginationBinder(h.cfg).Bind(req, ctx)
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, common.ErrorRequestParamsIncorrect)
 	}
 
-	if req.Limit <= 0 {
-		req.Limit = h.cfg.LimitDefault
+	err = h.dispatch.Validate.Struct(req)
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, common.GetValidationError(err))
+	}
+
+	res, err := h.dispatch.Services.Billing.FindAllOrdersPublic(ctx.Request().Context(), req)
+
+	if err != nil {
+		common.LogSrvCallFailedGRPC(h.L(), err, pkg.ServiceName, "FindAllOrdersPublic", req)
+		return echo.NewHTTPError(http.StatusInternalServerError, common.ErrorUnknown)
+	}
+
+	if res.Status != pkg.ResponseStatusOk {
+		return echo.NewHTTPError(int(res.Status), res.Message)
+	}
+
+	if res.Item != nil {
+		page := common.NewPage(common.ExtractCursorContext(ctx), int64(res.Item.Count), res.Item.Items)
+		common.SetPaginationLinks(ctx, h.cfg.PaginationSecret(), page)
+	}
+
+	return ctx.JSON(http.StatusOK, res.Item)
+}
+
+// Export all orders matched by the filters of the list, orders are requested from the billing service
+// page by page and streamed to the client, the export is stopped when the client disconnects
+// GET /admin/api/v1/order/export?format=csv&columns=id,status,project.name,created_at
+func (h *OrderRoute) exportOrdersPublic(ctx echo.Context) error {
+	format := ctx.QueryParam(common.RequestParameterFormat)
+	if format == "" {
+		format = export.FormatCsv
+	}
+
+	contentType := export.ContentType(format)
+	if contentType == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, common.ErrorMessageExportFormatUnsupported)
 	}
 
-	if req.Offset <= 0 {
-		req.Offset = h.cfg.OffsetDefault
+	columns := orderExportDefaultColumns
+	if param := ctx.QueryParam(common.RequestParameterColumns); param != "" {
+		columns = nil
+		for _, column := range strings.Split(param, ",") {
+			if column = strings.TrimSpace(column); column != "" {
+				columns = append(columns, column)
+			}
+		}
+	}
+
+	req := &grpc.ListOrdersRequest{}
+	err := ctx.Bind(req)
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, common.ErrorRequestParamsIncorrect)
+	}
+
+	req.Limit = h.cfg.LimitMax
+	req.Offset = 0
+
+	if err = h.restrictOrdersExportMerchants(ctx, req); err != nil {
+		return err
 	}
 
 	err = h.dispatch.Validate.Struct(req)
@@ -455,18 +755,151 @@ func (h *OrderRoute) listOrdersPublic(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, common.GetValidationError(err))
 	}
 
+	// first page is requested before the response is started, so its errors are returned with the status code
+	items, count, err := h.findOrdersPublicPage(ctx, req)
+
+	if err != nil {
+		return err
+	}
+
+	rsp := ctx.Response()
+	rsp.Header().Set(echo.HeaderContentType, contentType)
+	rsp.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=\"orders.%s\"", format))
+	rsp.Header().Set(common.HeaderTrailer, common.HeaderExportStatus)
+	rsp.WriteHeader(http.StatusOK)
+
+	w, err := export.NewWriter(format, rsp, columns)
+
+	if err != nil {
+		h.failOrdersExport(ctx, err, req.Offset)
+		return nil
+	}
+
+	for {
+		for _, order := range items {
+			values, err := export.Columns(order, columns)
+
+			if err == nil {
+				err = w.WriteRow(values)
+			}
+
+			if err != nil {
+				h.failOrdersExport(ctx, err, req.Offset)
+				return nil
+			}
+		}
+
+		if err = w.Flush(); err != nil {
+			h.failOrdersExport(ctx, err, req.Offset)
+			return nil
+		}
+		rsp.Flush()
+
+		req.Offset += int32(len(items))
+
+		if len(items) < int(req.Limit) || int64(req.Offset) >= int64(count) {
+			break
+		}
+
+		if err = ctx.Request().Context().Err(); err != nil {
+			h.L().Info("order export cancelled by the client", logger.PairArgs("offset", req.Offset))
+			return nil
+		}
+
+		items, count, err = h.findOrdersPublicPage(ctx, req)
+
+		if err != nil {
+			h.failOrdersExport(ctx, err, req.Offset)
+			return nil
+		}
+	}
+
+	if err = w.Close(); err != nil {
+		h.failOrdersExport(ctx, err, req.Offset)
+		return nil
+	}
+
+	rsp.Header().Set(common.HeaderExportStatus, common.ExportStatusComplete)
+	return nil
+}
+
+// restrictOrdersExportMerchants limits the export of the non staff user to the orders of its merchants,
+// the requested merchants the user has no access to are dropped
+func (h *OrderRoute) restrictOrdersExportMerchants(ctx echo.Context, req *grpc.ListOrdersRequest) error {
+	if common.ExtractUserContext(ctx).IsStaff() {
+		return nil
+	}
+
+	if _, err := common.ExtractUserMerchant(h.dispatch.Services, h.L(), ctx); err != nil {
+		return err
+	}
+
+	user := common.ExtractUserContext(ctx)
+	merchants := make([]string, 0)
+
+	if len(req.Merchant) > 0 {
+		for _, id := range req.Merchant {
+			if user.HasMerchant(id) {
+				merchants = append(merchants, id)
+			}
+		}
+	} else {
+		for id := range user.Merchants {
+			merchants = append(merchants, id)
+		}
+		sort.Strings(merchants)
+	}
+
+	if len(merchants) == 0 {
+		return echo.NewHTTPError(http.StatusForbidden, common.ErrorMessageAccessDenied)
+	}
+
+	req.Merchant = merchants
+	return nil
+}
+
+// failOrdersExport reports the failed export after the response is started, the status trailer is set to failed
+// and the connection is closed before the end of the body, so the client can't take the part of the export for the whole
+func (h *OrderRoute) failOrdersExport(ctx echo.Context, err error, offset int32) {
+	h.L().Error("order export failed", logger.PairArgs("err", err.Error(), "offset", offset))
+
+	rsp := ctx.Response()
+	rsp.Header().Set(common.HeaderExportStatus, common.ExportStatusFailed)
+	rsp.Flush()
+
+	hijacker, ok := rsp.Writer.(http.Hijacker)
+
+	if !ok {
+		return
+	}
+
+	conn, _, err := hijacker.Hijack()
+
+	if err != nil {
+		h.L().Error("order export connection not closed", logger.PairArgs("err", err.Error()))
+		return
+	}
+
+	_ = conn.Close()
+}
+
+func (h *OrderRoute) findOrdersPublicPage(ctx echo.Context, req *grpc.ListOrdersRequest) ([]*billing.OrderViewPublic, int32, error) {
 	res, err := h.dispatch.Services.Billing.FindAllOrdersPublic(ctx.Request().Context(), req)
 
 	if err != nil {
 		common.LogSrvCallFailedGRPC(h.L(), err, pkg.ServiceName, "FindAllOrdersPublic", req)
-		return echo.NewHTTPError(http.StatusInternalServerError, common.ErrorUnknown)
+		return nil, 0, echo.NewHTTPError(http.StatusInternalServerError, common.ErrorUnknown)
 	}
 
 	if res.Status != pkg.ResponseStatusOk {
-		return echo.NewHTTPError(int(res.Status), res.Message)
+		return nil, 0, echo.NewHTTPError(int(res.Status), res.Message)
 	}
 
-	return ctx.JSON(http.StatusOK, res.Item)
+	if res.Item == nil {
+		return nil, 0, nil
+	}
+
+	return res.Item.Items, res.Item.Count, nil
 }
 
 // Create payment by order
@@ -488,7 +921,7 @@ func (h *OrderRoute) processCreatePayment(ctx echo.Context) error {
 	res, err := h.dispatch.Services.Billing.PaymentCreateProcess(ctx.Request().Context(), req)
 
 	if err != nil {
-		common.LogSrvCallFailedGRPC(h.L(), err, pkg.ServiceName, "PaymentCreateProcess", req)
+		common.LogSrvCallFailedGRPC(h.L(), err, pkg.ServiceName, "PaymentCreateProcess", common.MaskPaymentCreateRequest(req))
 		return echo.NewHTTPError(http.StatusBadRequest, common.ErrorUnknown)
 	}
 
@@ -702,12 +1135,19 @@ func (h *OrderRoute) processBillingAddress(ctx echo.Context) error {
 	}
 
 	req.OrderId = orderId
+	req.Zip = common.NormalizeZip(req.Country, req.Zip)
 	err = h.dispatch.Validate.Struct(req)
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, common.GetValidationError(err))
 	}
 
+	err = h.dispatch.Validate.Struct(&common.BillingAddress{Country: req.Country, Zip: req.Zip})
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, common.GetValidationError(err))
+	}
+
 	res, err := h.dispatch.Services.Billing.ProcessBillingAddress(ctx.Request().Context(), req)
 
 	if err != nil {
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ProtocolONE/go-core/v2/pkg/logger"
+	"github.com/ProtocolONE/go-core/v2/pkg/provider"
+	"github.com/labstack/echo/v4"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/billing"
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
+	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
+	"github.com/paysuper/paysuper-management-api/pkg/event"
+	"net/http"
+	"time"
+)
+
+const (
+	orderEventsPath = common.OrderEventsPath
+
+	orderEventsDefaultHeartbeat = 15 * time.Second
+	orderEventsHeartbeat        = ": heartbeat\n\n"
+)
+
+type OrderEventsRoute struct {
+	dispatch common.HandlerSet
+	cfg      common.Config
+	provider.LMT
+}
+
+func NewOrderEventsRoute(set common.HandlerSet, cfg *common.Config) *OrderEventsRoute {
+	set.AwareSet.Logger = set.AwareSet.Logger.WithFields(logger.Fields{"router": "OrderEventsRoute"})
+	return &OrderEventsRoute{
+		dispatch: set,
+		LMT:      &set.AwareSet,
+		cfg:      *cfg,
+	}
+}
+
+func (h *OrderEventsRoute) Route(groups *common.Groups) {
+	groups.AuthProject.GET(orderEventsPath, h.streamOrderEvents)
+}
+
+// Stream status changes of the order as server-sent events, the current status of the order is sent first
+// GET /api/v1/orders/:order_id/events
+//
+// @Example curl -N -X GET -H "X-Customer-Token: %customer_token_here%" \
+//      https://api.paysuper.online/api/v1/orders/%order_id_here%/events
+func (h *OrderEventsRoute) streamOrderEvents(ctx echo.Context) error {
+	order, err := h.getOrder(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err = h.authorize(ctx, order); err != nil {
+		return err
+	}
+
+	// subscription is made before the current status is sent, so no change is lost in between
+	sub := h.dispatch.OrderEvents.Subscribe(order.Uuid)
+	defer sub.Close()
+
+	res := ctx.Response()
+	res.Header().Set(echo.HeaderContentType, common.MIMETextEventStream)
+	res.Header().Set(common.HeaderCacheControl, "no-cache")
+	res.Header().Set(common.HeaderXAccelBuffering, "no")
+	res.WriteHeader(http.StatusOK)
+
+	current := &event.Event{
+		Topic: order.Uuid,
+		Type:  common.OrderEventTypeStatus,
+		Data:  &common.OrderStatusEvent{OrderId: order.Uuid, Status: order.Status},
+	}
+
+	if err = h.writeEvent(res, current); err != nil {
+		return nil
+	}
+
+	heartbeat := h.cfg.OrderEventsHeartbeat
+	if heartbeat <= 0 {
+		heartbeat = orderEventsDefaultHeartbeat
+	}
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Request().Context().Done():
+			return nil
+		case e, ok := <-sub.C():
+			// channel is closed by the broker if the client doesn't keep up, the client reconnects and gets actual status
+			if !ok {
+				return nil
+			}
+
+			if err = h.writeEvent(res, e); err != nil {
+				return nil
+			}
+		case <-ticker.C:
+			if _, err = fmt.Fprint(res, orderEventsHeartbeat); err != nil {
+				return nil
+			}
+			res.Flush()
+		}
+	}
+}
+
+func (h *OrderEventsRoute) getOrder(ctx echo.Context) (*billing.Order, error) {
+	req := &grpc.GetOrderRequest{Id: ctx.Param(common.RequestParameterOrderId)}
+
+	if err := h.dispatch.Validate.Struct(req); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, common.GetValidationError(err))
+	}
+
+	// the private order is requested since the public view has no customer of the order
+	res, err := h.dispatch.Services.Billing.GetOrderPrivate(ctx.Request().Context(), req)
+
+	if err != nil {
+		common.LogSrvCallFailedGRPC(h.L(), err, pkg.ServiceName, "GetOrderPrivate", req)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, common.ErrorUnknown)
+	}
+
+	if res.Status != pkg.ResponseStatusOk {
+		return nil, echo.NewHTTPError(int(res.Status), res.Message)
+	}
+
+	if res.Item == nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, common.ErrorMessageOrdersNotFound)
+	}
+
+	return res.Item, nil
+}
+
+// authorize checks the project signature if it's sent, otherwise the customer token of the customer of the order,
+// the browser of the customer is authorized by the cookie of the payment form since EventSource can't send headers
+func (h *OrderEventsRoute) authorize(ctx echo.Context, order *billing.Order) error {
+	projectId := ""
+	if order.Project != nil {
+		projectId = order.Project.Id
+	}
+
+	if ctx.Request().Header.Get(common.HeaderXApiSignatureHeader) != "" {
+		return common.CheckProjectAuthRequestSignature(h.dispatch, ctx, projectId)
+	}
+
+	value := ctx.Request().Header.Get(common.HeaderCustomerToken)
+	if value == "" {
+		if cookie, err := ctx.Cookie(common.CustomerTokenCookiesName); err == nil && cookie != nil {
+			value = cookie.Value
+		}
+	}
+
+	if value == "" {
+		return echo.NewHTTPError(http.StatusUnauthorized, common.ErrorMessageOrderEventsUnauthorized)
+	}
+
+	token, err := h.dispatch.Customers.GetToken(value)
+	if err != nil {
+		h.L().Error(common.InternalErrorTemplate, logger.PairArgs("err", err.Error()))
+		return echo.NewHTTPError(http.StatusInternalServerError, common.ErrorInternal)
+	}
+
+	if token == nil || token.ProjectId != projectId {
+		return echo.NewHTTPError(http.StatusUnauthorized, common.ErrorMessageCustomerTokenInvalid)
+	}
+
+	if order.User == nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, common.ErrorMessageCustomerTokenInvalid)
+	}
+
+	// the cookie of the payment form is bound to the billing customer, so the anonymous customer is authorized too
+	if token.UserId != "" && token.UserId == order.User.Id {
+		return nil
+	}
+
+	// the token of the project keeps identifier of the customer in the project, it's the external identifier of the order user
+	if order.User.ExternalId == "" || token.CustomerId != order.User.ExternalId {
+		return echo.NewHTTPError(http.StatusUnauthorized, common.ErrorMessageCustomerTokenInvalid)
+	}
+
+	return nil
+}
+
+func (h *OrderEventsRoute) writeEvent(res *echo.Response, e *event.Event) error {
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		h.L().Error(common.InternalErrorTemplate, logger.PairArgs("err", err.Error()))
+		return err
+	}
+
+	if e.Id > 0 {
+		if _, err = fmt.Fprintf(res, "id: %d\n", e.Id); err != nil {
+			return err
+		}
+	}
+
+	if _, err = fmt.Fprintf(res, "event: %s\ndata: %s\n\n", e.Type, data); err != nil {
+		return err
+	}
+
+	res.Flush()
+	return nil
+}
@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"github.com/globalsign/mgo/bson"
+	"github.com/labstack/echo/v4"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	billMock "github.com/paysuper/paysuper-billing-server/pkg/mocks"
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/billing"
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
+	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
+	"github.com/paysuper/paysuper-management-api/internal/mock"
+	"github.com/paysuper/paysuper-management-api/internal/test"
+	"github.com/paysuper/paysuper-management-api/pkg/event"
+	"github.com/stretchr/testify/assert"
+	mock2 "github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// orderEventsBrokerMock delivers the prepared events to the subscriber and closes the subscription after them
+type orderEventsBrokerMock struct {
+	events    []*event.Event
+	topics    []string
+	published []*event.Event
+}
+
+type orderEventsSubscriptionMock struct {
+	ch chan *event.Event
+}
+
+func (m *orderEventsBrokerMock) Subscribe(topic string) event.Subscription {
+	m.topics = append(m.topics, topic)
+
+	ch := make(chan *event.Event, len(m.events))
+	for _, e := range m.events {
+		ch <- e
+	}
+	close(ch)
+
+	return &orderEventsSubscriptionMock{ch: ch}
+}
+
+func (m *orderEventsBrokerMock) Publish(topic, typ string, data interface{}) *event.Event {
+	e := &event.Event{Id: uint64(len(m.published) + 1), Topic: topic, Type: typ, Data: data}
+	m.published = append(m.published, e)
+	return e
+}
+
+func (s *orderEventsSubscriptionMock) C() <-chan *event.Event {
+	return s.ch
+}
+
+func (s *orderEventsSubscriptionMock) Close() {}
+
+type OrderEventsTestSuite struct {
+	suite.Suite
+	router *OrderEventsRoute
+	orders *OrderRoute
+	caller *test.EchoReqResCaller
+	broker *orderEventsBrokerMock
+	token  *common.CustomerToken
+	order  *billing.Order
+}
+
+func Test_OrderEvents(t *testing.T) {
+	suite.Run(t, new(OrderEventsTestSuite))
+}
+
+func (suite *OrderEventsTestSuite) SetupTest() {
+	var e error
+	settings := test.DefaultSettings()
+	srv := common.Services{
+		Billing: mock.NewBillingServerOkMock(),
+	}
+	suite.caller, e = test.SetUp(settings, srv, func(set *test.TestSet, mw test.Middleware) common.Handlers {
+		suite.router = NewOrderEventsRoute(set.HandlerSet, set.GlobalConfig)
+		suite.orders = NewOrderRoute(set.HandlerSet, set.GlobalConfig)
+		return common.Handlers{
+			suite.router,
+			suite.orders,
+		}
+	})
+	if e != nil {
+		panic(e)
+	}
+
+	suite.token = &common.CustomerToken{
+		Token:      bson.NewObjectId().Hex(),
+		ProjectId:  bson.NewObjectId().Hex(),
+		CustomerId: "customer_1",
+		ExpiresAt:  time.Now().Add(time.Hour),
+	}
+	assert.NoError(suite.T(), suite.router.dispatch.Customers.SaveToken(suite.token))
+
+	suite.order = &billing.Order{
+		Uuid:    "2b6f8b4a-7a8e-4d4c-9c1f-0d0c1b6d2a11",
+		Status:  "created",
+		Project: &billing.ProjectOrder{Id: suite.token.ProjectId},
+		User:    &billing.OrderUser{Id: bson.NewObjectId().Hex(), ExternalId: suite.token.CustomerId},
+	}
+
+	bs := &billMock.BillingService{}
+	bs.On("GetOrderPrivate", mock2.Anything, mock2.MatchedBy(func(req *grpc.GetOrderRequest) bool {
+		return req.Id == suite.order.Uuid
+	}), mock2.Anything).
+		Return(&grpc.GetOrderPrivateResponse{Status: pkg.ResponseStatusOk, Item: suite.order}, nil)
+	bs.On("GetOrderPrivate", mock2.Anything, mock2.Anything, mock2.Anything).
+		Return(&grpc.GetOrderPrivateResponse{Status: pkg.ResponseStatusNotFound, Message: common.ErrorMessageOrdersNotFound}, nil)
+	bs.On("PaymentFormJsonDataProcess", mock2.Anything, mock2.Anything, mock2.Anything).
+		Return(&grpc.PaymentFormJsonDataResponse{
+			Status: pkg.ResponseStatusOk,
+			Item:   &grpc.PaymentFormJsonData{Id: suite.order.Uuid, Cookie: bson.NewObjectId().Hex()},
+		}, nil)
+	suite.router.dispatch.Services.Billing = bs
+	suite.orders.dispatch.Services.Billing = bs
+
+	suite.broker = &orderEventsBrokerMock{
+		events: []*event.Event{
+			{
+				Id:    7,
+				Topic: suite.order.Uuid,
+				Type:  common.OrderEventTypeStatus,
+				Data:  &common.OrderStatusEvent{OrderId: suite.order.Uuid, Status: "processed"},
+			},
+		},
+	}
+	suite.router.dispatch.OrderEvents = suite.broker
+}
+
+func (suite *OrderEventsTestSuite) TearDownTest() {}
+
+func (suite *OrderEventsTestSuite) TestOrderEvents_Stream_Ok() {
+	res, err := suite.caller.Builder().
+		Path(common.AuthProjectGroupPath+orderEventsPath).
+		Params(":"+common.RequestParameterOrderId, suite.order.Uuid).
+		Init(func(request *http.Request, middleware test.Middleware) {
+			request.Header.Set(common.HeaderCustomerToken, suite.token.Token)
+		}).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+	assert.Equal(suite.T(), common.MIMETextEventStream, res.Header().Get(echo.HeaderContentType))
+	assert.Equal(suite.T(), "no-cache", res.Header().Get(common.HeaderCacheControl))
+	assert.Equal(suite.T(), []string{suite.order.Uuid}, suite.broker.topics)
+
+	expected := "event: order.status\n" +
+		"data: {\"order_id\":\"" + suite.order.Uuid + "\",\"status\":\"created\"}\n\n" +
+		"id: 7\n" +
+		"event: order.status\n" +
+		"data: {\"order_id\":\"" + suite.order.Uuid + "\",\"status\":\"processed\"}\n\n"
+	assert.Equal(suite.T(), expected, res.Body.String())
+}
+
+func (suite *OrderEventsTestSuite) TestOrderEvents_Stream_Cookie() {
+	res, err := suite.caller.Builder().
+		Path(common.AuthProjectGroupPath+orderEventsPath).
+		Params(":"+common.RequestParameterOrderId, suite.order.Uuid).
+		AddCookie(&http.Cookie{Name: common.CustomerTokenCookiesName, Value: suite.token.Token}).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+	assert.True(suite.T(), strings.HasPrefix(res.Body.String(), "event: order.status\n"))
+}
+
+// formCookie renders the payment form of the order and returns the customer token cookie set by it
+func (suite *OrderEventsTestSuite) formCookie() *http.Cookie {
+	res, err := suite.caller.Builder().
+		Params(":"+common.RequestParameterId, suite.order.Uuid).
+		Path(orderIdPath).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+
+	for _, cookie := range res.Result().Cookies() {
+		if cookie.Name == common.CustomerTokenCookiesName {
+			return &http.Cookie{Name: cookie.Name, Value: cookie.Value}
+		}
+	}
+
+	suite.FailNow("payment form has no customer token cookie")
+	return nil
+}
+
+func (suite *OrderEventsTestSuite) TestOrderEvents_Stream_FormCookie() {
+	res, err := suite.caller.Builder().
+		Path(common.AuthProjectGroupPath+orderEventsPath).
+		Params(":"+common.RequestParameterOrderId, suite.order.Uuid).
+		AddCookie(suite.formCookie()).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+	assert.True(suite.T(), strings.HasPrefix(res.Body.String(), "event: order.status\n"))
+}
+
+func (suite *OrderEventsTestSuite) TestOrderEvents_Stream_FormCookie_AnonymousCustomer() {
+	suite.order.User.ExternalId = ""
+
+	res, err := suite.caller.Builder().
+		Path(common.AuthProjectGroupPath+orderEventsPath).
+		Params(":"+common.RequestParameterOrderId, suite.order.Uuid).
+		AddCookie(suite.formCookie()).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+	assert.Equal(suite.T(), []string{suite.order.Uuid}, suite.broker.topics)
+}
+
+func (suite *OrderEventsTestSuite) TestOrderEvents_Stream_Unauthorized() {
+	_, err := suite.caller.Builder().
+		Path(common.AuthProjectGroupPath+orderEventsPath).
+		Params(":"+common.RequestParameterOrderId, suite.order.Uuid).
+		Exec(suite.T())
+
+	assert.Error(suite.T(), err)
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusUnauthorized, httpErr.Code)
+	assert.Equal(suite.T(), common.ErrorMessageOrderEventsUnauthorized, httpErr.Message)
+	assert.Empty(suite.T(), suite.broker.topics)
+}
+
+func (suite *OrderEventsTestSuite) TestOrderEvents_Stream_TokenOfOtherProject() {
+	token := &common.CustomerToken{
+		Token:      bson.NewObjectId().Hex(),
+		ProjectId:  bson.NewObjectId().Hex(),
+		CustomerId: "customer_1",
+		ExpiresAt:  time.Now().Add(time.Hour),
+	}
+	assert.NoError(suite.T(), suite.router.dispatch.Customers.SaveToken(token))
+
+	_, err := suite.caller.Builder().
+		Path(common.AuthProjectGroupPath+orderEventsPath).
+		Params(":"+common.RequestParameterOrderId, suite.order.Uuid).
+		Init(func(request *http.Request, middleware test.Middleware) {
+			request.Header.Set(common.HeaderCustomerToken, token.Token)
+		}).
+		Exec(suite.T())
+
+	assert.Error(suite.T(), err)
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusUnauthorized, httpErr.Code)
+	assert.Equal(suite.T(), common.ErrorMessageCustomerTokenInvalid, httpErr.Message)
+}
+
+func (suite *OrderEventsTestSuite) TestOrderEvents_Stream_TokenOfOtherCustomer() {
+	token := &common.CustomerToken{
+		Token:      bson.NewObjectId().Hex(),
+		ProjectId:  suite.token.ProjectId,
+		CustomerId: "customer_2",
+		ExpiresAt:  time.Now().Add(time.Hour),
+	}
+	assert.NoError(suite.T(), suite.router.dispatch.Customers.SaveToken(token))
+
+	_, err := suite.caller.Builder().
+		Path(common.AuthProjectGroupPath+orderEventsPath).
+		Params(":"+common.RequestParameterOrderId, suite.order.Uuid).
+		Init(func(request *http.Request, middleware test.Middleware) {
+			request.Header.Set(common.HeaderCustomerToken, token.Token)
+		}).
+		Exec(suite.T())
+
+	assert.Error(suite.T(), err)
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusUnauthorized, httpErr.Code)
+	assert.Equal(suite.T(), common.ErrorMessageCustomerTokenInvalid, httpErr.Message)
+	assert.Empty(suite.T(), suite.broker.topics)
+}
+
+func (suite *OrderEventsTestSuite) TestOrderEvents_Stream_OrderNotFound() {
+	_, err := suite.caller.Builder().
+		Path(common.AuthProjectGroupPath+orderEventsPath).
+		Params(":"+common.RequestParameterOrderId, "9a1d0b0e-3c2f-4e7a-8a2b-5f6c7d8e9f00").
+		Init(func(request *http.Request, middleware test.Middleware) {
+			request.Header.Set(common.HeaderCustomerToken, suite.token.Token)
+		}).
+		Exec(suite.T())
+
+	assert.Error(suite.T(), err)
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusNotFound, httpErr.Code)
+}
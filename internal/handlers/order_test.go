@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"archive/zip"
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -14,12 +16,16 @@ import (
 	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
 	"github.com/paysuper/paysuper-management-api/internal/mock"
 	"github.com/paysuper/paysuper-management-api/internal/test"
+	"github.com/paysuper/paysuper-management-api/pkg/export"
 	"github.com/stretchr/testify/assert"
 	mock2 "github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	"io/ioutil"
 	"net/http"
 	"net/url"
+	"regexp"
+	"strings"
 	"testing"
 	"time"
 )
@@ -629,9 +635,10 @@ func (suite *OrderTestSuite) TestOrder_CalculateAmounts_ValidationZipError() {
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
-	assert.Equal(suite.T(), common.ErrorMessageIncorrectZip, msg)
+	assert.Equal(suite.T(), common.ErrorMessageIncorrectZip.Code, msg.Code)
+	assert.Equal(suite.T(), common.ErrorMessageIncorrectZip.Message, msg.Message)
 	assert.Regexp(suite.T(), "Zip", msg.Details)
 }
 
@@ -1048,6 +1055,45 @@ func (suite *OrderTestSuite) TestOrder_GetOrderForm_TokenCookieExist_Ok() {
 	assert.Equal(suite.T(), cookie.Value, cookies[0].Value)
 }
 
+func (suite *OrderTestSuite) TestOrder_GetOrderForm_CustomerTokenSaved() {
+	cookie := bson.NewObjectId().Hex()
+	order := &billing.Order{
+		Uuid:    uuid.New().String(),
+		Project: &billing.ProjectOrder{Id: bson.NewObjectId().Hex()},
+		User:    &billing.OrderUser{Id: bson.NewObjectId().Hex(), ExternalId: "customer_1"},
+	}
+
+	bs := &billMock.BillingService{}
+	bs.On("PaymentFormJsonDataProcess", mock2.Anything, mock2.Anything).
+		Return(&grpc.PaymentFormJsonDataResponse{
+			Status: pkg.ResponseStatusOk,
+			Item:   &grpc.PaymentFormJsonData{Id: order.Uuid, Cookie: cookie},
+		}, nil)
+	bs.On("GetOrderPrivate", mock2.Anything, mock2.Anything).
+		Return(&grpc.GetOrderPrivateResponse{Status: pkg.ResponseStatusOk, Item: order}, nil)
+	suite.router.dispatch.Services.Billing = bs
+
+	res, err := suite.caller.Builder().
+		Params(":"+common.RequestParameterId, order.Uuid).
+		Path(orderIdPath).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+
+	token, err := suite.router.dispatch.Customers.GetToken(cookie)
+	assert.NoError(suite.T(), err)
+	if assert.NotNil(suite.T(), token) {
+		assert.Equal(suite.T(), order.Project.Id, token.ProjectId)
+		assert.Equal(suite.T(), order.User.ExternalId, token.CustomerId)
+		assert.Equal(suite.T(), order.User.Id, token.UserId)
+	}
+
+	users, err := suite.router.dispatch.Customers.GetCustomerUsers(order.Project.Id, order.User.ExternalId)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []string{order.User.Id}, users)
+}
+
 func (suite *OrderTestSuite) TestOrder_GetOrderForm_ParameterIdNotFound_Error() {
 
 	_, err := suite.caller.Builder().
@@ -1083,6 +1129,294 @@ func (suite *OrderTestSuite) TestOrder_GetOrderForm_BillingServerSystemError() {
 	assert.Equal(suite.T(), common.ErrorUnknown, httpErr.Message)
 }
 
+func (suite *OrderTestSuite) TestOrder_GetOrderForm_SecurityHeaders_Ok() {
+	projectId := bson.NewObjectId().Hex()
+
+	bs := &billMock.BillingService{}
+	bs.On("PaymentFormJsonDataProcess", mock2.Anything, mock2.Anything).
+		Return(&grpc.PaymentFormJsonDataResponse{
+			Status: pkg.ResponseStatusOk,
+			Item: &grpc.PaymentFormJsonData{
+				Id:      "5ced34d689fce60bf4440829",
+				Project: &grpc.PaymentFormJsonDataProject{Id: projectId},
+			},
+		}, nil)
+	bs.On("GetProject", mock2.Anything, mock2.Anything).
+		Return(&grpc.ChangeProjectResponse{
+			Status: pkg.ResponseStatusOk,
+			Item: &billing.Project{
+				Id:                     projectId,
+				UrlRedirectSuccess:     "https://redirect.example.com/success",
+				CreateOrderAllowedUrls: []string{"https://shop.example.com/buy", "https://shop.example.com/cart", "http://insecure.example.com"},
+			},
+		}, nil)
+	suite.router.dispatch.Services.Billing = bs
+
+	res, err := suite.caller.Builder().
+		Params(":"+common.RequestParameterId, "5ced34d689fce60bf4440829").
+		Path(orderIdPath).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+
+	// the policy is enforced by default
+	assert.Empty(suite.T(), res.Header().Get(common.HeaderContentSecurityPolicyReportOnly))
+	csp := res.Header().Get(common.HeaderContentSecurityPolicy)
+	assert.Contains(suite.T(), csp, "frame-ancestors https://shop.example.com;")
+	assert.NotContains(suite.T(), csp, "insecure.example.com")
+	assert.NotContains(suite.T(), csp, "redirect.example.com")
+	assert.Contains(suite.T(), csp, common.CspDirectiveReportUri+" "+common.CspReportPath)
+	assert.Empty(suite.T(), res.Header().Get(common.HeaderXFrameOptions))
+	assert.Equal(suite.T(), "nosniff", res.Header().Get(common.HeaderXContentTypeOptions))
+	assert.NotEmpty(suite.T(), res.Header().Get(common.HeaderReferrerPolicy))
+
+	nonce := regexp.MustCompile(`'nonce-([\w-]+)'`).FindStringSubmatch(csp)
+	require.Len(suite.T(), nonce, 2)
+	assert.Contains(suite.T(), res.Body.String(), `<script nonce="`+nonce[1]+`">`)
+
+	// the origins of the project are cached for the next renders of the form
+	res, err = suite.caller.Builder().
+		Params(":"+common.RequestParameterId, "5ced34d689fce60bf4440829").
+		Path(orderIdPath).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Contains(suite.T(), res.Header().Get(common.HeaderContentSecurityPolicy), "frame-ancestors https://shop.example.com;")
+	bs.AssertNumberOfCalls(suite.T(), "GetProject", 1)
+}
+
+func (suite *OrderTestSuite) TestOrder_GetOrderFallbackForm_SecurityHeaders_WithoutAncestors() {
+	suite.mockPaymentFormMethods()
+
+	res, err := suite.caller.Builder().
+		Params(":"+common.RequestParameterId, "5ced34d689fce60bf4440829").
+		Path(orderFallbackFormPath).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+	assert.Contains(suite.T(), res.Header().Get(common.HeaderContentSecurityPolicy), "frame-ancestors 'none'")
+	// X-Frame-Options can't list the origins of the project, so it isn't sent on the framed routes
+	assert.Empty(suite.T(), res.Header().Get(common.HeaderXFrameOptions))
+}
+
+func (suite *OrderTestSuite) TestOrder_GetOrderFallbackForm_SecurityHeaders_ReportOnly() {
+	settings := test.DefaultSettings()
+	settings["dispatcher"].(map[string]interface{})["global"].(map[string]interface{})["security"] = map[string]interface{}{
+		"securityCspReportOnly": true,
+	}
+	srv := common.Services{
+		Billing: mock.NewBillingServerOkMock(),
+		PayLink: mock.NewPaymentLinkOkMock(),
+	}
+	caller, err := test.SetUp(settings, srv, func(set *test.TestSet, mw test.Middleware) common.Handlers {
+		suite.router = NewOrderRoute(set.HandlerSet, set.GlobalConfig)
+		return common.Handlers{
+			suite.router,
+		}
+	})
+	require.NoError(suite.T(), err)
+	suite.mockPaymentFormMethods()
+
+	res, err := caller.Builder().
+		Params(":"+common.RequestParameterId, "5ced34d689fce60bf4440829").
+		Path(orderFallbackFormPath).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+
+	// the rest of the policy is reported only, but the embedding is restricted anyway
+	assert.Contains(suite.T(), res.Header().Get(common.HeaderContentSecurityPolicyReportOnly), "script-src")
+	assert.Equal(
+		suite.T(),
+		"frame-ancestors 'none'; "+common.CspDirectiveReportUri+" "+common.CspReportPath,
+		res.Header().Get(common.HeaderContentSecurityPolicy),
+	)
+}
+
+func (suite *OrderTestSuite) mockPaymentFormMethods() {
+	bs := &billMock.BillingService{}
+	bs.On("PaymentFormJsonDataProcess", mock2.Anything, mock2.Anything).
+		Return(&grpc.PaymentFormJsonDataResponse{
+			Status: pkg.ResponseStatusOk,
+			Item: &grpc.PaymentFormJsonData{
+				Id:       "5ced34d689fce60bf4440829",
+				Amount:   10.5,
+				Currency: "USD",
+				PaymentMethods: []*billing.PaymentFormPaymentMethod{
+					{Id: "5be2d0b4b0b30d0007383ce6", Name: "Bank card", Group: common.PaymentMethodGroupBankCard},
+					{Id: "5be2d0b4b0b30d0007383ce7", Name: "QIWI", Group: common.PaymentMethodGroupQiwi},
+					{Id: "5be2d0b4b0b30d0007383ce8", Name: "WebMoney", Group: common.PaymentMethodGroupWebMoney},
+					{Id: "5be2d0b4b0b30d0007383ce9", Name: "Bitcoin", Group: common.PaymentMethodGroupBitcoin},
+					{Id: "5be2d0b4b0b30d0007383cea", Name: "Alipay", Group: common.PaymentMethodGroupAlipay},
+					{Id: "5be2d0b4b0b30d0007383ceb", Name: "Unknown", Group: "UNKNOWN"},
+				},
+			},
+		}, nil)
+	suite.router.dispatch.Services.Billing = bs
+}
+
+func (suite *OrderTestSuite) TestOrder_GetOrderFallbackForm_Ok() {
+	suite.mockPaymentFormMethods()
+
+	methods := map[string]string{
+		"5be2d0b4b0b30d0007383ce6": `id="bank_card"`,
+		"5be2d0b4b0b30d0007383ce7": `id="qiwi"`,
+		"5be2d0b4b0b30d0007383ce8": `id="webmoney"`,
+		"5be2d0b4b0b30d0007383ce9": `id="bitcoin"`,
+		"5be2d0b4b0b30d0007383cea": `id="alipay"`,
+	}
+
+	for methodId, form := range methods {
+		res, err := suite.caller.Builder().
+			Params(":"+common.RequestParameterId, "5ced34d689fce60bf4440829").
+			SetQueryParam(common.RequestParameterMethod, methodId).
+			Path(orderFallbackFormPath).
+			Exec(suite.T())
+
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), http.StatusOK, res.Code)
+		assert.Equal(suite.T(), echo.MIMETextHTMLCharsetUTF8, res.Header().Get(echo.HeaderContentType))
+
+		body := res.Body.String()
+		assert.Contains(suite.T(), body, form)
+		assert.Contains(suite.T(), body, `name="payment_method_id" value="`+methodId+`"`)
+		assert.Contains(suite.T(), body, `action="/order/5ced34d689fce60bf4440829/form"`)
+		assert.Contains(suite.T(), body, "10.5 USD")
+		assert.NotContains(suite.T(), body, "Unknown")
+	}
+}
+
+func (suite *OrderTestSuite) TestOrder_GetOrderFallbackForm_DefaultMethod_Ok() {
+	suite.mockPaymentFormMethods()
+
+	res, err := suite.caller.Builder().
+		Params(":"+common.RequestParameterId, "5ced34d689fce60bf4440829").
+		Path(orderFallbackFormPath).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+	assert.Contains(suite.T(), res.Body.String(), `id="bank_card"`)
+}
+
+func (suite *OrderTestSuite) TestOrder_GetOrderFallbackForm_MethodNotFound() {
+	suite.mockPaymentFormMethods()
+
+	res, err := suite.caller.Builder().
+		Params(":"+common.RequestParameterId, "5ced34d689fce60bf4440829").
+		SetQueryParam(common.RequestParameterMethod, "5be2d0b4b0b30d0007383ceb").
+		Path(orderFallbackFormPath).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusNotFound, res.Code)
+}
+
+func (suite *OrderTestSuite) TestOrder_ProcessOrderFallbackForm_Ok() {
+	suite.mockPaymentFormMethods()
+
+	form, err := suite.caller.Builder().
+		Params(":"+common.RequestParameterId, "5ced34d689fce60bf4440829").
+		Path(orderFallbackFormPath).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, form.Code)
+
+	// the fields are submitted by the names rendered in the form
+	values := url.Values{}
+	for _, name := range []string{"payment_method_id", "pan", "month", "year", "cvv", "card_holder", "email"} {
+		assert.Contains(suite.T(), form.Body.String(), `name="`+name+`"`)
+	}
+	values.Set("order_id", "5ced34d689fce60bf4440829")
+	values.Set("payment_method_id", "5be2d0b4b0b30d0007383ce6")
+	values.Set("pan", "4000000000000002")
+	values.Set("month", "12")
+	values.Set("year", "2030")
+	values.Set("cvv", "123")
+	values.Set("card_holder", "MR. CARD HOLDER")
+	values.Set("email", "customer@example.com")
+
+	bs := suite.router.dispatch.Services.Billing.(*billMock.BillingService)
+	bs.On("PaymentCreateProcess", mock2.Anything, mock2.Anything).
+		Return(&grpc.PaymentCreateResponse{
+			Status:       pkg.ResponseStatusOk,
+			RedirectUrl:  "https://provider.example.com/3ds",
+			NeedRedirect: true,
+		}, nil)
+
+	res, err := suite.caller.Builder().
+		Method(http.MethodPost).
+		Params(":"+common.RequestParameterId, "5ced34d689fce60bf4440829").
+		Path(orderFallbackFormPath).
+		Init(func(request *http.Request, middleware test.Middleware) {
+			request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		}).
+		BodyString(values.Encode()).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusFound, res.Code)
+	assert.Equal(suite.T(), "https://provider.example.com/3ds", res.Header().Get(echo.HeaderLocation))
+	bs.AssertCalled(suite.T(), "PaymentCreateProcess", mock2.Anything, mock2.MatchedBy(func(req *grpc.PaymentCreateRequest) bool {
+		return req.Data["order_id"] == "5ced34d689fce60bf4440829" &&
+			req.Data["payment_method_id"] == "5be2d0b4b0b30d0007383ce6" &&
+			req.Data["pan"] == "4000000000000002" &&
+			req.Data["card_holder"] == "MR. CARD HOLDER" &&
+			req.Data["email"] == "customer@example.com"
+	}))
+}
+
+func (suite *OrderTestSuite) TestOrder_ProcessOrderFallbackForm_WithoutRedirect_Ok() {
+	bs := &billMock.BillingService{}
+	bs.On("PaymentCreateProcess", mock2.Anything, mock2.Anything).
+		Return(&grpc.PaymentCreateResponse{Status: pkg.ResponseStatusOk}, nil)
+	suite.router.dispatch.Services.Billing = bs
+
+	res, err := suite.caller.Builder().
+		Method(http.MethodPost).
+		Params(":"+common.RequestParameterId, "5ced34d689fce60bf4440829").
+		Path(orderFallbackFormPath).
+		Init(func(request *http.Request, middleware test.Middleware) {
+			request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		}).
+		BodyString("payment_method_id=5be2d0b4b0b30d0007383ce7&phone=79000000000").
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+	assert.Equal(suite.T(), echo.MIMETextHTMLCharsetUTF8, res.Header().Get(echo.HeaderContentType))
+	assert.Contains(suite.T(), res.Body.String(), "5ced34d689fce60bf4440829")
+}
+
+func (suite *OrderTestSuite) TestOrder_ProcessOrderFallbackForm_BillingServerResultError() {
+	bs := &billMock.BillingService{}
+	bs.On("PaymentCreateProcess", mock2.Anything, mock2.Anything).
+		Return(&grpc.PaymentCreateResponse{
+			Status:  pkg.ResponseStatusBadData,
+			Message: &grpc.ResponseErrorMessage{Message: "card number is invalid"},
+		}, nil)
+	suite.router.dispatch.Services.Billing = bs
+
+	res, err := suite.caller.Builder().
+		Method(http.MethodPost).
+		Params(":"+common.RequestParameterId, "5ced34d689fce60bf4440829").
+		Path(orderFallbackFormPath).
+		Init(func(request *http.Request, middleware test.Middleware) {
+			request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		}).
+		BodyString("payment_method_id=5be2d0b4b0b30d0007383ce6&pan=1").
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusBadRequest, res.Code)
+	assert.Equal(suite.T(), echo.MIMETextHTMLCharsetUTF8, res.Header().Get(echo.HeaderContentType))
+	assert.Contains(suite.T(), res.Body.String(), "card number is invalid")
+}
+
 func (suite *OrderTestSuite) TestOrder_GetOrders_Ok() {
 
 	bs := &billMock.BillingService{}
@@ -1131,6 +1465,253 @@ func (suite *OrderTestSuite) TestOrder_GetOrders_BillingServerError() {
 	assert.Equal(suite.T(), common.ErrorUnknown, httpErr.Message)
 }
 
+func (suite *OrderTestSuite) TestOrder_GetOrders_Cursor_Ok() {
+	items := []*billing.OrderViewPublic{{Id: "order_1"}, {Id: "order_2"}}
+
+	bs := &billMock.BillingService{}
+	bs.On("FindAllOrdersPublic", mock2.Anything, mock2.MatchedBy(func(req *grpc.ListOrdersRequest) bool {
+		return req.Offset == 0 && req.Limit == 2
+	}), mock2.Anything).
+		Return(&grpc.ListOrdersPublicResponse{
+			Status: pkg.ResponseStatusOk,
+			Item:   &grpc.ListOrdersPublicResponseItem{Count: 3, Items: items},
+		}, nil)
+	bs.On("FindAllOrdersPublic", mock2.Anything, mock2.MatchedBy(func(req *grpc.ListOrdersRequest) bool {
+		return req.Offset == 2 && req.Limit == 2
+	}), mock2.Anything).
+		Return(&grpc.ListOrdersPublicResponse{
+			Status: pkg.ResponseStatusOk,
+			Item:   &grpc.ListOrdersPublicResponseItem{Count: 3, Items: items[:1]},
+		}, nil)
+	suite.router.dispatch.Services.Billing = bs
+
+	res, err := suite.caller.Builder().
+		Method(http.MethodGet).
+		SetQueryParams(url.Values{common.RequestParameterLimit: []string{"2"}}).
+		Path(common.AuthUserGroupPath + orderPath).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+	assert.Contains(suite.T(), res.Header().Get(common.HeaderLink), `rel="next"`)
+	assert.Empty(suite.T(), res.Header().Get(common.HeaderPrevCursor))
+
+	next := res.Header().Get(common.HeaderNextCursor)
+	cursor, err := common.DecodeCursor(suite.router.cfg.PaginationSecret(), next)
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), 2, cursor.Offset)
+
+	res, err = suite.caller.Builder().
+		Method(http.MethodGet).
+		SetQueryParams(url.Values{common.QueryParameterNameCursor: []string{next}}).
+		Path(common.AuthUserGroupPath + orderPath).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+	assert.Empty(suite.T(), res.Header().Get(common.HeaderNextCursor))
+	assert.Contains(suite.T(), res.Header().Get(common.HeaderLink), `rel="prev"`)
+}
+
+func (suite *OrderTestSuite) TestOrder_GetOrders_CursorInvalid() {
+	token := common.EncodeCursor("another secret", &common.PageCursor{Limit: 2, Offset: 2})
+
+	_, err := suite.caller.Builder().
+		Method(http.MethodGet).
+		SetQueryParams(url.Values{common.QueryParameterNameCursor: []string{token}}).
+		Path(common.AuthUserGroupPath + orderPath).
+		Exec(suite.T())
+
+	assert.Error(suite.T(), err)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
+	assert.Equal(suite.T(), common.ErrorMessagePaginationCursorInvalid, httpErr.Message)
+}
+
+const orderExportMerchantId = "5ced34d689fce60bf4440829"
+
+func orderExportPage(ids ...string) *grpc.ListOrdersPublicResponse {
+	items := make([]*billing.OrderViewPublic, len(ids))
+	for i, id := range ids {
+		items[i] = &billing.OrderViewPublic{Id: id, Status: "processed"}
+	}
+	return &grpc.ListOrdersPublicResponse{
+		Status: pkg.ResponseStatusOk,
+		Item:   &grpc.ListOrdersPublicResponseItem{Count: 3, Items: items},
+	}
+}
+
+// mockExportOrdersFirstPage mocks the merchant of the user and the first page of two orders of three
+func (suite *OrderTestSuite) mockExportOrdersFirstPage() *billMock.BillingService {
+	suite.router.cfg.LimitMax = 2
+
+	bs := &billMock.BillingService{}
+	bs.On("GetMerchantBy", mock2.Anything, mock2.Anything).Return(&grpc.GetMerchantResponse{
+		Status: pkg.ResponseStatusOk,
+		Item:   &billing.Merchant{Id: orderExportMerchantId},
+	}, nil)
+	bs.On("FindAllOrdersPublic", mock2.Anything, mock2.Anything, mock2.Anything).
+		Return(orderExportPage("order_1", "order_2"), nil).Once()
+	suite.router.dispatch.Services.Billing = bs
+	return bs
+}
+
+func (suite *OrderTestSuite) mockExportOrdersPages() *billMock.BillingService {
+	bs := suite.mockExportOrdersFirstPage()
+	bs.On("FindAllOrdersPublic", mock2.Anything, mock2.Anything, mock2.Anything).
+		Return(orderExportPage("order_3"), nil).Once()
+	return bs
+}
+
+func (suite *OrderTestSuite) TestOrder_ExportOrders_Csv_Ok() {
+	suite.mockExportOrdersPages()
+
+	q := url.Values{common.RequestParameterColumns: []string{"id,status"}}
+	res, err := suite.caller.Builder().
+		Method(http.MethodGet).
+		SetQueryParams(q).
+		Path(common.AuthUserGroupPath + orderExportPath).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+	assert.Contains(suite.T(), res.Header().Get(echo.HeaderContentDisposition), "orders.csv")
+	assert.Equal(suite.T(), "id,status\norder_1,processed\norder_2,processed\norder_3,processed\n", res.Body.String())
+	assert.Equal(suite.T(), common.ExportStatusComplete, res.Result().Trailer.Get(common.HeaderExportStatus))
+}
+
+func (suite *OrderTestSuite) TestOrder_ExportOrders_Xlsx_Ok() {
+	suite.mockExportOrdersPages()
+
+	q := url.Values{
+		common.RequestParameterFormat:  []string{"xlsx"},
+		common.RequestParameterColumns: []string{"id,status"},
+	}
+	res, err := suite.caller.Builder().
+		Method(http.MethodGet).
+		SetQueryParams(q).
+		Path(common.AuthUserGroupPath + orderExportPath).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+	assert.Equal(suite.T(), export.ContentType("xlsx"), res.Header().Get(echo.HeaderContentType))
+	assert.Contains(suite.T(), res.Header().Get(echo.HeaderContentDisposition), "orders.xlsx")
+
+	body := res.Body.Bytes()
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	require.NoError(suite.T(), err)
+
+	var sheet []byte
+	for _, f := range archive.File {
+		if f.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+		r, err := f.Open()
+		require.NoError(suite.T(), err)
+		sheet, err = ioutil.ReadAll(r)
+		require.NoError(suite.T(), err)
+		_ = r.Close()
+	}
+
+	require.NotEmpty(suite.T(), sheet)
+	assert.Equal(suite.T(), 4, strings.Count(string(sheet), "<row "))
+	for _, value := range []string{"id", "status", "order_1", "order_2", "order_3", "processed"} {
+		assert.Contains(suite.T(), string(sheet), "<t xml:space=\"preserve\">"+value+"</t>")
+	}
+}
+
+func (suite *OrderTestSuite) TestOrder_ExportOrders_NextPageFailed() {
+	bs := suite.mockExportOrdersFirstPage()
+	bs.On("FindAllOrdersPublic", mock2.Anything, mock2.Anything, mock2.Anything).
+		Return(nil, errors.New("some error")).Once()
+
+	q := url.Values{common.RequestParameterColumns: []string{"id"}}
+	res, err := suite.caller.Builder().
+		Method(http.MethodGet).
+		SetQueryParams(q).
+		Path(common.AuthUserGroupPath + orderExportPath).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+	assert.Equal(suite.T(), "id\norder_1\norder_2\n", res.Body.String())
+	assert.Equal(suite.T(), common.ExportStatusFailed, res.Result().Trailer.Get(common.HeaderExportStatus))
+	bs.AssertNumberOfCalls(suite.T(), "FindAllOrdersPublic", 2)
+}
+
+func (suite *OrderTestSuite) TestOrder_ExportOrders_MerchantForced() {
+	bs := suite.mockExportOrdersPages()
+
+	q := url.Values{common.RequestParameterColumns: []string{"id"}}
+	res, err := suite.caller.Builder().
+		Method(http.MethodGet).
+		SetQueryParams(q).
+		Path(common.AuthUserGroupPath + orderExportPath).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+	bs.AssertCalled(suite.T(), "FindAllOrdersPublic", mock2.Anything, mock2.MatchedBy(func(req *grpc.ListOrdersRequest) bool {
+		return len(req.Merchant) == 1 && req.Merchant[0] == orderExportMerchantId
+	}), mock2.Anything)
+}
+
+func (suite *OrderTestSuite) TestOrder_ExportOrders_ForeignMerchant() {
+	bs := suite.mockExportOrdersPages()
+
+	q := url.Values{"merchant": []string{bson.NewObjectId().Hex()}}
+	_, err := suite.caller.Builder().
+		Method(http.MethodGet).
+		SetQueryParams(q).
+		Path(common.AuthUserGroupPath + orderExportPath).
+		Exec(suite.T())
+
+	assert.Error(suite.T(), err)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusForbidden, httpErr.Code)
+	assert.Equal(suite.T(), common.ErrorMessageAccessDenied, httpErr.Message)
+	bs.AssertNotCalled(suite.T(), "FindAllOrdersPublic", mock2.Anything, mock2.Anything, mock2.Anything)
+}
+
+func (suite *OrderTestSuite) TestOrder_ExportOrders_Ndjson_Ok() {
+	suite.mockExportOrdersPages()
+
+	q := url.Values{
+		common.RequestParameterFormat:  []string{"ndjson"},
+		common.RequestParameterColumns: []string{"id"},
+	}
+	res, err := suite.caller.Builder().
+		Method(http.MethodGet).
+		SetQueryParams(q).
+		Path(common.AuthUserGroupPath + orderExportPath).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+	assert.Equal(suite.T(), "{\"id\":\"order_1\"}\n{\"id\":\"order_2\"}\n{\"id\":\"order_3\"}\n", res.Body.String())
+}
+
+func (suite *OrderTestSuite) TestOrder_ExportOrders_FormatUnsupported() {
+	q := url.Values{common.RequestParameterFormat: []string{"pdf"}}
+	_, err := suite.caller.Builder().
+		Method(http.MethodGet).
+		SetQueryParams(q).
+		Path(common.AuthUserGroupPath + orderExportPath).
+		Exec(suite.T())
+
+	assert.Error(suite.T(), err)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
+	assert.Equal(suite.T(), common.ErrorMessageExportFormatUnsupported, httpErr.Message)
+}
+
 func (suite *OrderTestSuite) TestOrder_GetOrders_BindError_Id() {
 	q := make(url.Values)
 	q.Set(common.RequestParameterId, bson.NewObjectId().Hex())
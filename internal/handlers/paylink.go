@@ -49,8 +49,7 @@ func (h *PayLinkRoute) getPaylinksList(ctx echo.Context) error {
 	authUser := common.ExtractUserContext(ctx)
 	req := &paylink.GetPaylinksRequest{}
 	err := (&common.PaylinksListBinder{
-		LimitDefault:  h.cfg.LimitDefault,
-		OffsetDefault: h.cfg.OffsetDefault,
+		Pagination: common.NewPaginationBinder(h.cfg),
 	}).Bind(req, ctx)
 
 	if err != nil {
@@ -36,20 +36,25 @@ const (
 	paymentCostsMoneyBackAllPath         = "/payment_costs/money_back/system/all"
 	paymentCostsMoneyBackMerchantPath    = "/payment_costs/money_back/merchant/:id"
 	paymentCostsMoneyBackMerchantAllPath = "/payment_costs/money_back/merchant/:id/all"
+	paymentCostsMoneyBackMerchantIdsPath = "/payment_costs/money_back/merchant/:merchant_id/:rate_id"
 	paymentCostsMoneyBackSystemPath      = "/payment_costs/money_back/system"
 	paymentCostsMoneyBackSystemIdPath    = "/payment_costs/money_back/system/:id"
 )
 
 func (h *PaymentCostRoute) Route(groups *common.Groups) {
+	merchantAccess := common.MerchantAccessMiddleware(h.dispatch, h.cfg, common.RequestParameterId)
+	// the rates are updated on the paths with the merchant and the rate identifiers
+	merchantIdAccess := common.MerchantAccessMiddleware(h.dispatch, h.cfg, common.RequestParameterMerchantId)
+
 	groups.AuthUser.GET(paymentCostsChannelSystemAllPath, h.getAllPaymentChannelCostSystem)
-	groups.AuthUser.GET(paymentCostsChannelMerchantAllPath, h.getAllPaymentChannelCostMerchant) //надо править
+	groups.AuthUser.GET(paymentCostsChannelMerchantAllPath, h.getAllPaymentChannelCostMerchant, merchantAccess) //надо править
 	groups.AuthUser.GET(paymentCostsMoneyBackAllPath, h.getAllMoneyBackCostSystem)
-	groups.AuthUser.GET(paymentCostsMoneyBackMerchantAllPath, h.getAllMoneyBackCostMerchant) //надо править
+	groups.AuthUser.GET(paymentCostsMoneyBackMerchantAllPath, h.getAllMoneyBackCostMerchant, merchantAccess) //надо править
 
 	groups.AuthUser.GET(paymentCostsChannelSystemPath, h.getPaymentChannelCostSystem)
-	groups.AuthUser.GET(paymentCostsChannelMerchantPath, h.getPaymentChannelCostMerchant)
+	groups.AuthUser.GET(paymentCostsChannelMerchantPath, h.getPaymentChannelCostMerchant, merchantAccess)
 	groups.AuthUser.GET(paymentCostsMoneyBackSystemPath, h.getMoneyBackCostSystem)
-	groups.AuthUser.GET(paymentCostsMoneyBackMerchantPath, h.getMoneyBackCostMerchant)
+	groups.AuthUser.GET(paymentCostsMoneyBackMerchantPath, h.getMoneyBackCostMerchant, merchantAccess)
 
 	groups.AuthUser.DELETE(paymentCostsChannelSystemIdPath, h.deletePaymentChannelCostSystem)
 	groups.AuthUser.DELETE(paymentCostsChannelMerchantPath, h.deletePaymentChannelCostMerchant)
@@ -57,14 +62,14 @@ func (h *PaymentCostRoute) Route(groups *common.Groups) {
 	groups.AuthUser.DELETE(paymentCostsMoneyBackMerchantPath, h.deleteMoneyBackCostMerchant)
 
 	groups.AuthUser.POST(paymentCostsChannelSystemPath, h.setPaymentChannelCostSystem)
-	groups.AuthUser.POST(paymentCostsChannelMerchantPath, h.setPaymentChannelCostMerchant)
+	groups.AuthUser.POST(paymentCostsChannelMerchantPath, h.setPaymentChannelCostMerchant, merchantAccess)
 	groups.AuthUser.POST(paymentCostsMoneyBackSystemPath, h.setMoneyBackCostSystem)
-	groups.AuthUser.POST(paymentCostsMoneyBackMerchantPath, h.setMoneyBackCostMerchant)
+	groups.AuthUser.POST(paymentCostsMoneyBackMerchantPath, h.setMoneyBackCostMerchant, merchantAccess)
 
 	groups.AuthUser.PUT(paymentCostsChannelSystemIdPath, h.setPaymentChannelCostSystem)
-	groups.AuthUser.PUT(paymentCostsChannelMerchantIdsPath, h.setPaymentChannelCostMerchant)
+	groups.AuthUser.PUT(paymentCostsChannelMerchantIdsPath, h.setPaymentChannelCostMerchant, merchantIdAccess)
 	groups.AuthUser.PUT(paymentCostsMoneyBackSystemIdPath, h.setMoneyBackCostSystem)
-	groups.AuthUser.PUT(paymentCostsChannelMerchantIdsPath, h.setMoneyBackCostMerchant)
+	groups.AuthUser.PUT(paymentCostsMoneyBackMerchantIdsPath, h.setMoneyBackCostMerchant, merchantIdAccess)
 }
 
 // @Description Get system costs for payments operations
@@ -359,6 +364,7 @@ func (h *PaymentCostRoute) setPaymentChannelCostMerchant(ctx echo.Context) error
 	req.MerchantId = ctx.Param(common.RequestParameterId)
 
 	if ctx.Request().Method == http.MethodPut {
+		req.MerchantId = ctx.Param(common.RequestParameterMerchantId)
 		req.Id = ctx.Param(common.RequestParameterRateId)
 	}
 
@@ -447,6 +453,7 @@ func (h *PaymentCostRoute) setMoneyBackCostMerchant(ctx echo.Context) error {
 	req.MerchantId = ctx.Param(common.RequestParameterId)
 
 	if ctx.Request().Method == http.MethodPut {
+		req.MerchantId = ctx.Param(common.RequestParameterMerchantId)
 		req.Id = ctx.Param(common.RequestParameterRateId)
 	}
 
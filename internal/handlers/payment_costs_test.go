@@ -2,10 +2,16 @@ package handlers
 
 import (
 	"github.com/globalsign/mgo/bson"
+	"github.com/labstack/echo/v4"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	billMock "github.com/paysuper/paysuper-billing-server/pkg/mocks"
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/billing"
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
 	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
 	"github.com/paysuper/paysuper-management-api/internal/mock"
 	"github.com/paysuper/paysuper-management-api/internal/test"
 	"github.com/stretchr/testify/assert"
+	mock2 "github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 	"net/http"
 	"testing"
@@ -308,3 +314,115 @@ func (suite *PaymentCostTestSuite) TestPaymentCosts_MoneyBackCostMerchant_Delete
 		assert.Empty(suite.T(), res.Body.String())
 	}
 }
+
+// PaymentCostMerchantAccessTestSuite checks the merchant access to the rates of the merchant
+type PaymentCostMerchantAccessTestSuite struct {
+	suite.Suite
+	router     *PaymentCostRoute
+	caller     *test.EchoReqResCaller
+	billing    *billMock.BillingService
+	merchantId string
+	rateId     string
+}
+
+func Test_PaymentCostMerchantAccess(t *testing.T) {
+	suite.Run(t, new(PaymentCostMerchantAccessTestSuite))
+}
+
+func (suite *PaymentCostMerchantAccessTestSuite) SetupTest() {
+	suite.merchantId = bson.NewObjectId().Hex()
+	suite.rateId = bson.NewObjectId().Hex()
+
+	suite.billing = &billMock.BillingService{}
+	suite.billing.On("GetMerchantBy", mock2.Anything, mock2.Anything).Return(&grpc.GetMerchantResponse{
+		Status: pkg.ResponseStatusOk,
+		Item:   &billing.Merchant{Id: suite.merchantId},
+	}, nil)
+	suite.billing.On("SetPaymentChannelCostMerchant", mock2.Anything, mock2.Anything).Return(&grpc.PaymentChannelCostMerchantResponse{
+		Status: pkg.ResponseStatusOk,
+		Item:   &billing.PaymentChannelCostMerchant{},
+	}, nil)
+	suite.billing.On("SetMoneyBackCostMerchant", mock2.Anything, mock2.Anything).Return(&grpc.MoneyBackCostMerchantResponse{
+		Status: pkg.ResponseStatusOk,
+		Item:   &billing.MoneyBackCostMerchant{},
+	}, nil)
+
+	var e error
+	settings := test.DefaultSettings()
+	srv := common.Services{
+		Billing: suite.billing,
+	}
+	suite.caller, e = test.SetUp(settings, srv, func(set *test.TestSet, mw test.Middleware) common.Handlers {
+		mw.Pre(test.PreAuthUserMiddleware(&common.AuthUser{Id: "ffffffffffffffffffffffff"}))
+		suite.router = NewPaymentCostRoute(set.HandlerSet, set.GlobalConfig)
+		// the merchant access is checked by the middlewares of the routes
+		suite.router.cfg.DisableAuthMiddleware = false
+		return common.Handlers{
+			suite.router,
+		}
+	})
+	if e != nil {
+		panic(e)
+	}
+}
+
+func (suite *PaymentCostMerchantAccessTestSuite) TearDownTest() {}
+
+func (suite *PaymentCostMerchantAccessTestSuite) update(path, merchantId, body string) error {
+	res, err := suite.caller.Builder().
+		Method(http.MethodPut).
+		Params(":"+common.RequestParameterMerchantId, merchantId, ":"+common.RequestParameterRateId, suite.rateId).
+		Path(common.AuthUserGroupPath + path).
+		Init(test.ReqInitJSON()).
+		BodyString(body).
+		Exec(suite.T())
+
+	if err == nil {
+		assert.Equal(suite.T(), http.StatusOK, res.Code)
+	}
+	return err
+}
+
+func (suite *PaymentCostMerchantAccessTestSuite) TestPaymentCosts_PaymentChannelCostMerchant_Update_Ok() {
+	err := suite.update(paymentCostsChannelMerchantIdsPath, suite.merchantId, `{"name": "VISA", "region": "CIS",
+		"country": "AZ", "min_amount": 0.75, "method_percent": 0.0101, "method_fix_amount": 2.34, "ps_percent": 0.00035,
+		"ps_fixed_fee": 2, "ps_fixed_fee_currency": "EUR", "payout_currency": "USD", "method_fix_amount_currency": "EUR"}`)
+
+	assert.NoError(suite.T(), err)
+	suite.billing.AssertCalled(suite.T(), "SetPaymentChannelCostMerchant", mock2.Anything, mock2.MatchedBy(func(req *billing.PaymentChannelCostMerchant) bool {
+		return req.MerchantId == suite.merchantId && req.Id == suite.rateId
+	}))
+}
+
+func (suite *PaymentCostMerchantAccessTestSuite) TestPaymentCosts_PaymentChannelCostMerchant_Update_AccessDenied() {
+	err := suite.update(paymentCostsChannelMerchantIdsPath, bson.NewObjectId().Hex(), `{"name": "VISA"}`)
+
+	assert.Error(suite.T(), err)
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusForbidden, httpErr.Code)
+	assert.Equal(suite.T(), common.ErrorMessageMerchantAccessDenied, httpErr.Message)
+	suite.billing.AssertNotCalled(suite.T(), "SetPaymentChannelCostMerchant", mock2.Anything, mock2.Anything)
+}
+
+func (suite *PaymentCostMerchantAccessTestSuite) TestPaymentCosts_MoneyBackCostMerchant_Update_Ok() {
+	err := suite.update(paymentCostsMoneyBackMerchantIdsPath, suite.merchantId, `{"name": "VISA", "region": "CIS",
+		"country": "AZ", "percent": 0.0101, "fix_amount": 2.34, "fix_amount_currency": "USD", "payout_currency": "USD",
+		"undo_reason": "chargeback", "days_from": 0, "payment_stage": 1, "is_paid_by_merchant": true}`)
+
+	assert.NoError(suite.T(), err)
+	suite.billing.AssertCalled(suite.T(), "SetMoneyBackCostMerchant", mock2.Anything, mock2.MatchedBy(func(req *billing.MoneyBackCostMerchant) bool {
+		return req.MerchantId == suite.merchantId && req.Id == suite.rateId
+	}))
+}
+
+func (suite *PaymentCostMerchantAccessTestSuite) TestPaymentCosts_MoneyBackCostMerchant_Update_AccessDenied() {
+	err := suite.update(paymentCostsMoneyBackMerchantIdsPath, bson.NewObjectId().Hex(), `{"name": "VISA"}`)
+
+	assert.Error(suite.T(), err)
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusForbidden, httpErr.Code)
+	assert.Equal(suite.T(), common.ErrorMessageMerchantAccessDenied, httpErr.Message)
+	suite.billing.AssertNotCalled(suite.T(), "SetMoneyBackCostMerchant", mock2.Anything, mock2.Anything)
+}
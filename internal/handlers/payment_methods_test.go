@@ -60,7 +60,7 @@ func (suite *PaymentMethodTestSuite) TestPaymentMethod_create_BindError_Required
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, res.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Regexp(suite.T(), "Name", msg.Details)
 }
@@ -81,7 +81,7 @@ func (suite *PaymentMethodTestSuite) TestPaymentMethod_create_BindError_Required
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Regexp(suite.T(), "PaymentSystemId", msg.Details)
 }
@@ -102,7 +102,7 @@ func (suite *PaymentMethodTestSuite) TestPaymentMethod_create_BindError_Name() {
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Regexp(suite.T(), "field validation for 'Name' failed on the 'alphanum' tag", msg.Details)
 }
@@ -123,7 +123,7 @@ func (suite *PaymentMethodTestSuite) TestPaymentMethod_create_BindError_PaymentS
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Regexp(suite.T(), "field validation for 'PaymentSystemId' failed on the 'len' tag", msg.Details)
 }
@@ -196,7 +196,7 @@ func (suite *PaymentMethodTestSuite) TestPaymentMethod_getProductionSettings_Bin
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Regexp(suite.T(), "field validation for 'PaymentMethodId' failed on the 'required' tag", msg.Details)
 }
@@ -257,7 +257,7 @@ func (suite *PaymentMethodTestSuite) TestPaymentMethod_getTestSettings_BindError
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Regexp(suite.T(), "field validation for 'PaymentMethodId' failed on the 'required' tag", msg.Details)
 }
@@ -319,7 +319,7 @@ func (suite *PaymentMethodTestSuite) TestPaymentMethod_createProductionSettings_
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Regexp(suite.T(), "field validation for 'Params' failed on the 'required' tag", msg.Details)
 }
@@ -341,7 +341,7 @@ func (suite *PaymentMethodTestSuite) TestPaymentMethod_createProductionSettings_
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Regexp(suite.T(), "field validation for 'PaymentMethodId' failed on the 'required' tag", msg.Details)
 }
@@ -362,7 +362,7 @@ func (suite *PaymentMethodTestSuite) TestPaymentMethod_updateProductionSettings_
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Regexp(suite.T(), "field validation for 'Params' failed on the 'required' tag", msg.Details)
 }
@@ -384,7 +384,7 @@ func (suite *PaymentMethodTestSuite) TestPaymentMethod_updateProductionSettings_
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Regexp(suite.T(), "field validation for 'PaymentMethodId' failed on the 'required' tag", msg.Details)
 }
@@ -406,7 +406,7 @@ func (suite *PaymentMethodTestSuite) TestPaymentMethod_createTestSettings_BindEr
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Regexp(suite.T(), "field validation for 'Params' failed on the 'required' tag", msg.Details)
 }
@@ -428,7 +428,7 @@ func (suite *PaymentMethodTestSuite) TestPaymentMethod_createTestSettings_BindEr
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Regexp(suite.T(), "field validation for 'PaymentMethodId' failed on the 'required' tag", msg.Details)
 }
@@ -450,7 +450,7 @@ func (suite *PaymentMethodTestSuite) TestPaymentMethod_updateTestSettings_BindEr
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Regexp(suite.T(), "field validation for 'Params' failed on the 'required' tag", msg.Details)
 }
@@ -472,7 +472,7 @@ func (suite *PaymentMethodTestSuite) TestPaymentMethod_updateTestSettings_BindEr
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Regexp(suite.T(), "field validation for 'PaymentMethodId' failed on the 'required' tag", msg.Details)
 }
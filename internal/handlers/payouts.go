@@ -7,6 +7,7 @@ import (
 	"github.com/paysuper/paysuper-billing-server/pkg"
 	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
 	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
+	"github.com/paysuper/paysuper-management-api/pkg/webhook"
 	"net/http"
 )
 
@@ -160,6 +161,13 @@ func (h *PayoutDocumentsRoute) updatePayoutDocument(ctx echo.Context) error {
 	if res.Status != http.StatusOK {
 		return echo.NewHTTPError(int(res.Status), res.Message)
 	}
+	if req.Status != "" && res.Item != nil {
+		h.dispatch.Webhooks.Publish(res.Item.MerchantId, webhook.EventPayoutDocumentStatusChanged, map[string]interface{}{
+			"payout_document_id": res.Item.Id,
+			"status":             res.Item.Status,
+		})
+	}
+
 	return ctx.JSON(http.StatusOK, res.Item)
 }
 
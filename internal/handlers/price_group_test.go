@@ -61,7 +61,7 @@ func (suite *PriceGroupTestSuite) TestPriceGroup_getPriceGroupByCountry_BindErro
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Regexp(suite.T(), "field validation for 'Country' failed on the 'required' tag", msg.Details)
 }
@@ -152,7 +152,7 @@ func (suite *PriceGroupTestSuite) TestPriceGroup_getCurrencyByRegion_BindError_R
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Regexp(suite.T(), "field validation for 'Region' failed on the 'required' tag", msg.Details)
 }
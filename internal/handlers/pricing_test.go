@@ -60,7 +60,7 @@ func (suite *PricingTestSuite) TestPricing_getRecommendedPrice_BindError_Require
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Regexp(suite.T(), "field validation for 'Amount' failed on the 'required' tag", msg.Details)
 }
@@ -35,7 +35,11 @@ func NewProductRoute(set common.HandlerSet, cfg *common.Config) *ProductRoute {
 
 func (h *ProductRoute) Route(groups *common.Groups) {
 	groups.AuthUser.GET(productsPath, h.getProductsList)
-	groups.AuthUser.GET(productsMerchantPath, h.getProductsList)
+	groups.AuthUser.GET(
+		productsMerchantPath,
+		h.getProductsList,
+		common.MerchantAccessMiddleware(h.dispatch, h.cfg, common.RequestParameterId),
+	)
 	groups.AuthUser.POST(productsPath, h.createProduct)
 	groups.AuthUser.GET(productsIdPath, h.getProduct)
 	groups.AuthUser.PUT(productsIdPath, h.updateProduct)
@@ -50,8 +54,7 @@ func (h *ProductRoute) getProductsList(ctx echo.Context) error {
 	authUser := common.ExtractUserContext(ctx)
 	req := &grpc.ListProductsRequest{}
 	err := (&common.ProductsGetProductsListBinder{
-		LimitDefault:  h.cfg.LimitDefault,
-		OffsetDefault: h.cfg.OffsetDefault,
+		Pagination: common.NewPaginationBinder(h.cfg),
 	}).Bind(req, ctx)
 
 	if err != nil {
@@ -125,16 +125,12 @@ func (h *ProjectRoute) getProject(ctx echo.Context) error {
 
 func (h *ProjectRoute) listProjects(ctx echo.Context) error {
 	req := &grpc.ListProjectsRequest{}
-	err := ctx.Bind(req)
+	err := common.NewPaginationBinder(h.cfg).Bind(req, ctx)
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, common.ErrorRequestParamsIncorrect)
 	}
 
-	if req.Limit <= 0 {
-		req.Limit = h.cfg.LimitDefault
-	}
-
 	err = h.dispatch.Validate.Struct(req)
 
 	if err != nil {
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"github.com/ProtocolONE/go-core/v2/pkg/logger"
+	"github.com/ProtocolONE/go-core/v2/pkg/provider"
+	"github.com/labstack/echo/v4"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
+	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	projectsThemePath        = "/projects/:id/theme"
+	projectsThemePreviewPath = "/projects/:id/theme/preview"
+)
+
+const (
+	projectThemePreviewOrderId  = "preview"
+	projectThemePreviewAmount   = 10
+	projectThemePreviewCurrency = "USD"
+)
+
+type ProjectThemeRoute struct {
+	dispatch common.HandlerSet
+	cfg      common.Config
+	provider.LMT
+}
+
+func NewProjectThemeRoute(set common.HandlerSet, cfg *common.Config) *ProjectThemeRoute {
+	set.AwareSet.Logger = set.AwareSet.Logger.WithFields(logger.Fields{"router": "ProjectThemeRoute"})
+	return &ProjectThemeRoute{
+		dispatch: set,
+		LMT:      &set.AwareSet,
+		cfg:      *cfg,
+	}
+}
+
+func (h *ProjectThemeRoute) Route(groups *common.Groups) {
+	groups.AuthUser.GET(projectsThemePath, h.getTheme)
+	groups.AuthUser.PUT(projectsThemePath, h.setTheme)
+	groups.AuthUser.DELETE(projectsThemePath, h.deleteTheme)
+	groups.AuthUser.GET(projectsThemePreviewPath, h.previewTheme)
+	groups.AuthUser.POST(projectsThemePreviewPath, h.previewTheme)
+}
+
+// Get theme of the payment form of the project, project without theme has the empty one
+// GET /admin/api/v1/projects/5ced34d689fce60bf4440829/theme
+func (h *ProjectThemeRoute) getTheme(ctx echo.Context) error {
+	theme, err := h.getProjectTheme(ctx)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, theme)
+}
+
+// Replace theme of the payment form of the project
+// PUT /admin/api/v1/projects/5ced34d689fce60bf4440829/theme
+func (h *ProjectThemeRoute) setTheme(ctx echo.Context) error {
+	theme, err := h.bindTheme(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err = h.checkProject(ctx, theme.ProjectId); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	theme.UpdatedAt = &now
+
+	if err = h.dispatch.Themes.SaveTheme(theme); err != nil {
+		h.L().Error(common.InternalErrorTemplate, logger.PairArgs("err", err.Error()))
+		return echo.NewHTTPError(http.StatusInternalServerError, common.ErrorInternal)
+	}
+
+	return ctx.JSON(http.StatusOK, theme)
+}
+
+// Reset theme of the payment form of the project to default
+// DELETE /admin/api/v1/projects/5ced34d689fce60bf4440829/theme
+func (h *ProjectThemeRoute) deleteTheme(ctx echo.Context) error {
+	projectId := ctx.Param(common.RequestParameterId)
+
+	if err := h.checkProject(ctx, projectId); err != nil {
+		return err
+	}
+
+	if err := h.dispatch.Themes.DeleteTheme(projectId); err != nil {
+		h.L().Error(common.InternalErrorTemplate, logger.PairArgs("err", err.Error()))
+		return echo.NewHTTPError(http.StatusInternalServerError, common.ErrorInternal)
+	}
+
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// Render payment form of the fake order with the saved theme or with the theme from the request body
+// GET /admin/api/v1/projects/5ced34d689fce60bf4440829/theme/preview
+// POST /admin/api/v1/projects/5ced34d689fce60bf4440829/theme/preview
+func (h *ProjectThemeRoute) previewTheme(ctx echo.Context) error {
+	var (
+		theme *common.ProjectTheme
+		err   error
+	)
+
+	if ctx.Request().Method == http.MethodPost {
+		if err = h.checkProject(ctx, ctx.Param(common.RequestParameterId)); err != nil {
+			return err
+		}
+		theme, err = h.bindTheme(ctx)
+	} else {
+		theme, err = h.getProjectTheme(ctx)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	order := &grpc.PaymentFormJsonDataResponse{
+		Status: pkg.ResponseStatusOk,
+		Item: &grpc.PaymentFormJsonData{
+			Id:       projectThemePreviewOrderId,
+			Amount:   projectThemePreviewAmount,
+			Currency: projectThemePreviewCurrency,
+		},
+	}
+
+	return ctx.Render(
+		http.StatusOK,
+		orderFormTemplateName,
+		map[string]interface{}{
+			"Order":                   order,
+			"WebSocketUrl":            h.cfg.WebsocketUrl,
+			"PaymentFormJsLibraryUrl": h.cfg.PaymentFormJsLibraryUrl,
+			"Theme":                   theme,
+		},
+	)
+}
+
+func (h *ProjectThemeRoute) getProjectTheme(ctx echo.Context) (*common.ProjectTheme, error) {
+	projectId := ctx.Param(common.RequestParameterId)
+
+	if err := h.checkProject(ctx, projectId); err != nil {
+		return nil, err
+	}
+
+	theme, err := h.dispatch.Themes.GetTheme(projectId)
+
+	if err != nil {
+		h.L().Error(common.InternalErrorTemplate, logger.PairArgs("err", err.Error()))
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, common.ErrorInternal)
+	}
+
+	if theme == nil {
+		theme = &common.ProjectTheme{ProjectId: projectId, FooterLinks: []*common.ProjectThemeLink{}}
+	}
+
+	return theme, nil
+}
+
+func (h *ProjectThemeRoute) bindTheme(ctx echo.Context) (*common.ProjectTheme, error) {
+	theme := &common.ProjectTheme{}
+
+	if err := ctx.Bind(theme); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, common.ErrorRequestParamsIncorrect)
+	}
+
+	theme.ProjectId = ctx.Param(common.RequestParameterId)
+	theme.UpdatedAt = nil
+
+	if theme.FooterLinks == nil {
+		theme.FooterLinks = []*common.ProjectThemeLink{}
+	}
+
+	if err := h.dispatch.Validate.Struct(theme); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, common.GetValidationError(err))
+	}
+
+	// payment form is the page with card data, so it loads resources by secure urls only
+	for _, u := range theme.Urls() {
+		if u != "" && !strings.HasPrefix(strings.ToLower(u), "https://") {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, common.ErrorMessageProjectThemeUrlInsecure)
+		}
+	}
+
+	return theme, nil
+}
+
+// checkProject checks the project exists and belongs to the merchant of the user,
+// the theme is rendered into the payment form with the card data, so it can't be changed by the other merchants
+func (h *ProjectThemeRoute) checkProject(ctx echo.Context, projectId string) error {
+	req := &grpc.GetProjectRequest{ProjectId: projectId}
+
+	if err := h.dispatch.Validate.Struct(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, common.GetValidationError(err))
+	}
+
+	res, err := h.dispatch.Services.Billing.GetProject(ctx.Request().Context(), req)
+
+	if err != nil {
+		common.LogSrvCallFailedGRPC(h.L(), err, pkg.ServiceName, "GetProject", req)
+		return echo.NewHTTPError(http.StatusInternalServerError, common.ErrorUnknown)
+	}
+
+	if res.Status != pkg.ResponseStatusOk {
+		return echo.NewHTTPError(int(res.Status), res.Message)
+	}
+
+	return common.CheckUserMerchantAccess(h.dispatch, ctx, res.Item.MerchantId)
+}
@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/globalsign/mgo/bson"
+	"github.com/labstack/echo/v4"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	billMock "github.com/paysuper/paysuper-billing-server/pkg/mocks"
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/billing"
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
+	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
+	"github.com/paysuper/paysuper-management-api/internal/test"
+	"github.com/stretchr/testify/assert"
+	mock2 "github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"testing"
+)
+
+type ProjectThemeTestSuite struct {
+	suite.Suite
+	router    *ProjectThemeRoute
+	caller    *test.EchoReqResCaller
+	projectId string
+	// foreignProjectId is a project of the other merchant
+	foreignProjectId string
+}
+
+func Test_ProjectTheme(t *testing.T) {
+	suite.Run(t, new(ProjectThemeTestSuite))
+}
+
+func (suite *ProjectThemeTestSuite) SetupTest() {
+	suite.projectId = bson.NewObjectId().Hex()
+	suite.foreignProjectId = bson.NewObjectId().Hex()
+	merchantId := bson.NewObjectId().Hex()
+
+	billingService := &billMock.BillingService{}
+	billingService.On("GetMerchantBy", mock2.Anything, mock2.Anything).Return(&grpc.GetMerchantResponse{
+		Status: pkg.ResponseStatusOk,
+		Item:   &billing.Merchant{Id: merchantId},
+	}, nil)
+	billingService.On("GetProject", mock2.Anything, mock2.MatchedBy(func(req *grpc.GetProjectRequest) bool {
+		return req.ProjectId == suite.foreignProjectId
+	})).Return(&grpc.ChangeProjectResponse{
+		Status: pkg.ResponseStatusOk,
+		Item:   &billing.Project{Id: suite.foreignProjectId, MerchantId: bson.NewObjectId().Hex()},
+	}, nil)
+	billingService.On("GetProject", mock2.Anything, mock2.Anything).Return(&grpc.ChangeProjectResponse{
+		Status: pkg.ResponseStatusOk,
+		Item:   &billing.Project{Id: suite.projectId, MerchantId: merchantId},
+	}, nil)
+
+	var e error
+	settings := test.DefaultSettings()
+	srv := common.Services{
+		Billing: billingService,
+	}
+	suite.caller, e = test.SetUp(settings, srv, func(set *test.TestSet, mw test.Middleware) common.Handlers {
+		mw.Pre(test.PreAuthUserMiddleware(&common.AuthUser{Id: "ffffffffffffffffffffffff"}))
+		suite.router = NewProjectThemeRoute(set.HandlerSet, set.GlobalConfig)
+		return common.Handlers{
+			suite.router,
+		}
+	})
+	if e != nil {
+		panic(e)
+	}
+}
+
+func (suite *ProjectThemeTestSuite) TearDownTest() {}
+
+func (suite *ProjectThemeTestSuite) setTheme(body string) (*common.ProjectTheme, error) {
+	res, err := suite.caller.Builder().
+		Method(http.MethodPut).
+		Params(":"+common.RequestParameterId, suite.projectId).
+		Path(common.AuthUserGroupPath + projectsThemePath).
+		Init(test.ReqInitJSON()).
+		BodyString(body).
+		Exec(suite.T())
+
+	if err != nil {
+		return nil, err
+	}
+
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+
+	theme := &common.ProjectTheme{}
+	assert.NoError(suite.T(), json.Unmarshal(res.Body.Bytes(), theme))
+	return theme, nil
+}
+
+func (suite *ProjectThemeTestSuite) TestProjectTheme_Get_Default() {
+	res, err := suite.caller.Builder().
+		Params(":"+common.RequestParameterId, suite.projectId).
+		Path(common.AuthUserGroupPath + projectsThemePath).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+
+	theme := &common.ProjectTheme{}
+	assert.NoError(suite.T(), json.Unmarshal(res.Body.Bytes(), theme))
+	assert.Equal(suite.T(), suite.projectId, theme.ProjectId)
+	assert.Empty(suite.T(), theme.LogoUrl)
+	assert.Nil(suite.T(), theme.UpdatedAt)
+}
+
+func (suite *ProjectThemeTestSuite) TestProjectTheme_Set_Ok() {
+	theme, err := suite.setTheme(`{"logo_url": "https://cdn.example.com/logo.png", "primary_color": "#ff6600",
+		"font_family": "Roboto, sans-serif", "custom_css": ".form { border-radius: 4px; }",
+		"footer_links": [{"title": "Terms", "url": "https://example.com/terms"}]}`)
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), suite.projectId, theme.ProjectId)
+	assert.NotNil(suite.T(), theme.UpdatedAt)
+
+	saved, err := suite.router.dispatch.Themes.GetTheme(suite.projectId)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "#ff6600", saved.PrimaryColor)
+	assert.Len(suite.T(), saved.FooterLinks, 1)
+}
+
+func (suite *ProjectThemeTestSuite) TestProjectTheme_Set_ValidationError() {
+	bodies := []string{
+		`{"primary_color": "red"}`,
+		`{"logo_url": "javascript:alert(1)"}`,
+		`{"custom_css": "</style><script>alert(1)</script>"}`,
+		`{"font_family": "x;}body{display:none"}`,
+		`{"footer_links": [{"title": "Terms"}]}`,
+	}
+
+	for _, body := range bodies {
+		_, err := suite.setTheme(body)
+
+		assert.Error(suite.T(), err, body)
+		httpErr, ok := err.(*echo.HTTPError)
+		assert.True(suite.T(), ok)
+		assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
+	}
+}
+
+func (suite *ProjectThemeTestSuite) TestProjectTheme_Delete_Ok() {
+	_, err := suite.setTheme(`{"primary_color": "#ff6600"}`)
+	assert.NoError(suite.T(), err)
+
+	res, err := suite.caller.Builder().
+		Method(http.MethodDelete).
+		Params(":"+common.RequestParameterId, suite.projectId).
+		Path(common.AuthUserGroupPath + projectsThemePath).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusNoContent, res.Code)
+
+	theme, err := suite.router.dispatch.Themes.GetTheme(suite.projectId)
+	assert.NoError(suite.T(), err)
+	assert.Nil(suite.T(), theme)
+}
+
+func (suite *ProjectThemeTestSuite) TestProjectTheme_Preview_Saved_Ok() {
+	_, err := suite.setTheme(`{"logo_url": "https://cdn.example.com/logo.png",
+		"footer_links": [{"title": "Terms", "url": "https://example.com/terms"}]}`)
+	assert.NoError(suite.T(), err)
+
+	res, err := suite.caller.Builder().
+		Params(":"+common.RequestParameterId, suite.projectId).
+		Path(common.AuthUserGroupPath + projectsThemePreviewPath).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+	assert.Equal(suite.T(), echo.MIMETextHTMLCharsetUTF8, res.Header().Get(echo.HeaderContentType))
+	assert.Contains(suite.T(), res.Body.String(), `src="https://cdn.example.com/logo.png"`)
+	assert.Contains(suite.T(), res.Body.String(), `href="https://example.com/terms"`)
+	assert.Contains(suite.T(), res.Body.String(), projectThemePreviewOrderId)
+	assert.Contains(suite.T(), res.Header().Get(common.HeaderContentSecurityPolicy), "frame-ancestors 'none'")
+	assert.Equal(suite.T(), "DENY", res.Header().Get(common.HeaderXFrameOptions))
+}
+
+func (suite *ProjectThemeTestSuite) TestProjectTheme_Preview_Unsaved_Ok() {
+	res, err := suite.caller.Builder().
+		Method(http.MethodPost).
+		Params(":"+common.RequestParameterId, suite.projectId).
+		Path(common.AuthUserGroupPath + projectsThemePreviewPath).
+		Init(test.ReqInitJSON()).
+		BodyString(`{"background_color": "#123456", "custom_css": ".form { margin: 0; }"}`).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+	assert.Contains(suite.T(), res.Body.String(), "background-color: #123456")
+	assert.Contains(suite.T(), res.Body.String(), ".form { margin: 0; }")
+
+	theme, err := suite.router.dispatch.Themes.GetTheme(suite.projectId)
+	assert.NoError(suite.T(), err)
+	assert.Nil(suite.T(), theme)
+}
+
+func (suite *ProjectThemeTestSuite) TestProjectTheme_ForeignMerchant_Forbidden() {
+	_, err := suite.caller.Builder().
+		Method(http.MethodPut).
+		Params(":"+common.RequestParameterId, suite.foreignProjectId).
+		Path(common.AuthUserGroupPath + projectsThemePath).
+		Init(test.ReqInitJSON()).
+		BodyString(`{"custom_css": "body { display: none; }"}`).
+		Exec(suite.T())
+
+	assert.Error(suite.T(), err)
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusForbidden, httpErr.Code)
+	assert.Equal(suite.T(), common.ErrorMessageMerchantAccessDenied, httpErr.Message)
+
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		_, err = suite.caller.Builder().
+			Method(method).
+			Params(":"+common.RequestParameterId, suite.foreignProjectId).
+			Path(common.AuthUserGroupPath + projectsThemePath).
+			Exec(suite.T())
+
+		assert.Error(suite.T(), err, method)
+		httpErr, ok = err.(*echo.HTTPError)
+		assert.True(suite.T(), ok)
+		assert.Equal(suite.T(), http.StatusForbidden, httpErr.Code)
+	}
+
+	theme, err := suite.router.dispatch.Themes.GetTheme(suite.foreignProjectId)
+	assert.NoError(suite.T(), err)
+	assert.Nil(suite.T(), theme)
+}
@@ -5,15 +5,74 @@ import (
 	"github.com/ProtocolONE/go-core/v2/pkg/provider"
 	awsWrapper "github.com/paysuper/paysuper-aws-manager"
 	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
+	"github.com/paysuper/paysuper-management-api/pkg/webhook"
 	"gopkg.in/go-playground/validator.v9"
 )
 
 // ProviderHandlers
-func ProviderHandlers(initial config.Initial, srv common.Services, validator *validator.Validate, set provider.AwareSet, cfg *common.Config) (common.Handlers, func(), error) {
+func ProviderHandlers(initial config.Initial, srv common.Services, validator *validator.Validate, set provider.AwareSet, cfg *common.Config, webhookVerifier *common.WebhookVerifier) (common.Handlers, func(), error) {
+	session, err := cfg.DialMongo()
+	if err != nil {
+		return nil, func() {}, err
+	}
+	closeSession := func() {
+		if session != nil {
+			session.Close()
+		}
+	}
+
+	themes, err := common.NewProjectThemeStorage(session)
+	if err != nil {
+		closeSession()
+		return nil, func() {}, err
+	}
+
+	inboundWebhooks, err := common.NewInboundWebhookStorage(session)
+	if err != nil {
+		closeSession()
+		return nil, func() {}, err
+	}
+
+	webhookStorage, err := webhook.NewStorage(session)
+	if err != nil {
+		closeSession()
+		return nil, func() {}, err
+	}
+
+	replays, err := common.NewWebhookReplayStorage(session)
+	if err != nil {
+		closeSession()
+		return nil, func() {}, err
+	}
+	webhookVerifier.SetReplayStorage(replays)
+
+	keyStocks, err := common.NewKeyStockStorage(session)
+	if err != nil {
+		closeSession()
+		return nil, func() {}, err
+	}
+
+	customers, err := common.NewCustomerStorage(session)
+	if err != nil {
+		closeSession()
+		return nil, func() {}, err
+	}
+
+	webhooks := webhook.NewSender(webhookStorage, cfg.SenderConfig(), set.Logger)
+	jobs := cfg.NewJobManager(set.Logger)
 	hSet := common.HandlerSet{
-		Services: srv,
-		Validate: validator,
-		AwareSet: set,
+		Services:         srv,
+		Validate:         validator,
+		AwareSet:         set,
+		Webhooks:         webhooks,
+		Customers:        customers,
+		Jobs:             jobs,
+		KeyStocks:        keyStocks,
+		Themes:           themes,
+		OrderEvents:      cfg.NewOrderEventBroker(),
+		InboundWebhooks:  inboundWebhooks,
+		ProviderWebhooks: cfg.NewProviderWebhookRegistry(),
+		WebhookVerifier:  webhookVerifier,
 	}
 	copyCfg := *cfg
 
@@ -26,6 +85,7 @@ func ProviderHandlers(initial config.Initial, srv common.Services, validator *va
 	}
 	awsManagerAgreement, err := awsWrapper.New(awsOptions...)
 	if err != nil {
+		closeSession()
 		return nil, func() {}, err
 	}
 
@@ -38,11 +98,14 @@ func ProviderHandlers(initial config.Initial, srv common.Services, validator *va
 	}
 	awsManagerReporter, err := awsWrapper.New(awsOptions...)
 	if err != nil {
+		closeSession()
 		return nil, func() {}, err
 	}
 
+	webhooks.Start()
+
 	return []common.Handler{
-		NewCardPayWebHook(hSet, &copyCfg),
+		NewProviderWebhooksRoute(hSet, &copyCfg),
 		NewCountryApiV1(hSet, &copyCfg),
 		NewDashboardRoute(hSet, &copyCfg),
 		NewKeyRoute(hSet, &copyCfg),
@@ -65,5 +128,16 @@ func ProviderHandlers(initial config.Initial, srv common.Services, validator *va
 		NewBalanceRoute(hSet, &copyCfg),
 		NewPayoutDocumentsRoute(hSet, &copyCfg),
 		NewPricingRoute(hSet, &copyCfg),
-	}, func() {}, nil
+		NewMerchantWebhooksRoute(hSet, &copyCfg),
+		NewSavedCardsRoute(hSet, &copyCfg),
+		NewRefundBatchRoute(hSet, &copyCfg),
+		NewJobsRoute(hSet, &copyCfg),
+		NewProjectThemeRoute(hSet, &copyCfg),
+		NewOrderEventsRoute(hSet, &copyCfg),
+		NewInboundWebhooksRoute(hSet, &copyCfg),
+	}, func() {
+		webhooks.Stop()
+		jobs.Stop()
+		closeSession()
+	}, nil
 }
@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"context"
+	"github.com/ProtocolONE/go-core/v2/pkg/logger"
+	"github.com/ProtocolONE/go-core/v2/pkg/provider"
+	"github.com/globalsign/mgo/bson"
+	"github.com/labstack/echo/v4"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
+	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
+	"net/http"
+)
+
+// ProviderWebhooksRoute receives callbacks of the payment providers registered in the provider webhook registry
+type ProviderWebhooksRoute struct {
+	dispatch common.HandlerSet
+	cfg      common.Config
+	provider.LMT
+}
+
+func NewProviderWebhooksRoute(set common.HandlerSet, cfg *common.Config) *ProviderWebhooksRoute {
+	set.AwareSet.Logger = set.AwareSet.Logger.WithFields(logger.Fields{"router": "ProviderWebhooksRoute"})
+	return &ProviderWebhooksRoute{
+		dispatch: set,
+		LMT:      &set.AwareSet,
+		cfg:      *cfg,
+	}
+}
+
+func (h *ProviderWebhooksRoute) Route(groups *common.Groups) {
+	for _, adapter := range h.dispatch.ProviderWebhooks.Adapters() {
+		if !h.dispatch.WebhookVerifier.Configured(adapter.Provider()) {
+			h.L().Error(common.ErrWebhookNotConfigured.Error()+", its callbacks are rejected", logger.PairArgs("provider", adapter.Provider()))
+		}
+
+		for _, route := range adapter.Routes() {
+			groups.WebHooks.POST(
+				route.Path,
+				h.callback(adapter, route.Type),
+				h.allowWebhookIp(adapter),
+				h.verifyWebhook(adapter, route.Type),
+				h.storeWebhook(adapter, route.Type),
+			)
+		}
+	}
+}
+
+// allowWebhookIp rejects callbacks sent from the addresses which aren't in the allow list of the provider
+func (h *ProviderWebhooksRoute) allowWebhookIp(adapter common.ProviderWebhookAdapter) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			if err := h.dispatch.WebhookVerifier.AllowIp(adapter.Provider(), ctx.RealIP()); err != nil {
+				h.L().Error(err.Error(), logger.PairArgs("provider", adapter.Provider(), "ip", ctx.RealIP()))
+				return echo.NewHTTPError(http.StatusForbidden, common.ErrorMessageWebhookIpNotAllowed)
+			}
+
+			return next(ctx)
+		}
+	}
+}
+
+// storeWebhook saves the callback with the result of its processing, so it can be inspected and replayed later,
+// the callbacks rejected by the verification are logged only, otherwise anyone could fill the storage
+func (h *ProviderWebhooksRoute) storeWebhook(adapter common.ProviderWebhookAdapter, typ string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			w := common.NewInboundWebhook(ctx, bson.NewObjectId().Hex(), adapter.Provider(), typ, adapter.SignatureHeader())
+			common.SetInboundWebhookContext(ctx, w)
+
+			err := next(ctx)
+
+			w.Result = common.NewInboundWebhookResult(ctx, err)
+
+			if e := h.dispatch.InboundWebhooks.SaveWebhook(w); e != nil {
+				h.L().Error(common.InternalErrorTemplate, logger.PairArgs("err", e.Error(), "webhook_id", w.Id))
+			}
+
+			return err
+		}
+	}
+}
+
+// verifyWebhook checks signature of the callback before its body is parsed,
+// the successfully processed callback isn't sent to the billing server again if it's repeated within the replay window
+func (h *ProviderWebhooksRoute) verifyWebhook(adapter common.ProviderWebhookAdapter, typ string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			s, err := adapter.Signature(typ, ctx.Request().Header, common.ExtractRawBodyContext(ctx))
+
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, common.ErrorRequestParamsIncorrect)
+			}
+
+			err = h.dispatch.WebhookVerifier.Verify(adapter.Provider(), s)
+
+			switch err {
+			case nil:
+			case common.ErrWebhookReplayed:
+				// the provider repeats the callback until it gets the successful response,
+				// so the duplicate of the processed callback is answered as processed
+				h.L().Info(err.Error(), logger.PairArgs("provider", adapter.Provider(), "ip", ctx.RealIP()))
+				return ctx.NoContent(http.StatusOK)
+			case common.ErrWebhookTimestampExpired:
+				h.L().Error(err.Error(), logger.PairArgs("provider", adapter.Provider(), "ip", ctx.RealIP()))
+				return echo.NewHTTPError(http.StatusBadRequest, common.ErrorMessageWebhookTimestampExpired)
+			case common.ErrWebhookSignatureInvalid, common.ErrWebhookAlgorithmUnknown:
+				h.L().Error(err.Error(), logger.PairArgs("provider", adapter.Provider(), "ip", ctx.RealIP()))
+				return echo.NewHTTPError(http.StatusUnauthorized, common.ErrorMessageWebhookSignatureInvalid)
+			default:
+				h.L().Error(common.InternalErrorTemplate, logger.PairArgs("err", err.Error(), "provider", adapter.Provider()))
+				return echo.NewHTTPError(http.StatusInternalServerError, common.ErrorInternal)
+			}
+
+			if err = next(ctx); err != nil {
+				return err
+			}
+
+			if ctx.Response().Status != http.StatusOK {
+				return nil
+			}
+
+			if err = h.dispatch.WebhookVerifier.Processed(adapter.Provider(), s); err != nil {
+				h.L().Error(common.InternalErrorTemplate, logger.PairArgs("err", err.Error(), "provider", adapter.Provider()))
+			}
+
+			return nil
+		}
+	}
+}
+
+func (h *ProviderWebhooksRoute) callback(adapter common.ProviderWebhookAdapter, typ string) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		body := common.ExtractRawBodyContext(ctx)
+
+		w, err := adapter.Parse(typ, body)
+
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, common.ErrorRequestParamsIncorrect)
+		}
+
+		if err = h.dispatch.Validate.Struct(w.Payload); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, common.GetValidationError(err))
+		}
+
+		signature := w.Signature
+		if signature == "" && adapter.SignatureHeader() != "" {
+			signature = ctx.Request().Header.Get(adapter.SignatureHeader())
+		}
+
+		stored := common.ExtractInboundWebhookContext(ctx)
+		stored.OrderId = w.OrderId
+		stored.Signature = signature
+
+		req := adapter.Request(w, body, signature)
+		httpStatus, msg, err := processProviderWebhook(ctx.Request().Context(), h.dispatch.Services.Billing, typ, w.OrderId, req)
+
+		if err != nil {
+			h.L().Error(common.InternalErrorTemplate, logger.PairArgs("err", err.Error(), "provider", adapter.Provider()))
+			return echo.NewHTTPError(http.StatusInternalServerError, common.ErrorUnknown)
+		}
+
+		// payment callbacks are answered by the message in any case, others by the error of the billing server
+		if typ == common.InboundWebhookTypePayment {
+			message := map[string]string{"message": msg}
+
+			if httpStatus == http.StatusOK {
+				message["message"] = "Payment successfully complete"
+				publishOrderStatus(ctx, h.dispatch, h.L(), w.OrderId)
+				submitKeyStockSale(h.dispatch, h.L(), h.cfg.KeyStockLowThreshold, w.OrderId)
+			}
+
+			return ctx.JSON(httpStatus, message)
+		}
+
+		if httpStatus != http.StatusOK {
+			return echo.NewHTTPError(httpStatus, msg)
+		}
+
+		if w.OrderId != "" {
+			publishOrderStatus(ctx, h.dispatch, h.L(), w.OrderId)
+		}
+
+		if msg != "" {
+			return ctx.JSON(http.StatusOK, map[string]string{"message": msg})
+		}
+
+		return ctx.NoContent(http.StatusOK)
+	}
+}
+
+// processProviderWebhook sends the callback to the billing server,
+// it returns response code of the callback and error message of the billing server
+func processProviderWebhook(ctx context.Context, bs grpc.BillingService, typ, orderId string, req *grpc.CallbackRequest) (int, string, error) {
+	switch typ {
+	case common.InboundWebhookTypePayment:
+		// PaymentNotifyRequest has no handler, the billing server parses the body by the handler of the payment system
+		// of the order, so the callback is sent only if the order is paid by the provider of the callback
+		order, err := bs.GetOrderPrivate(ctx, &grpc.GetOrderRequest{Id: orderId})
+
+		if err != nil {
+			return 0, "", err
+		}
+
+		if order.Status != pkg.ResponseStatusOk || order.Item == nil {
+			return http.StatusNotFound, common.ErrorMessageWebhookOrderNotFound.Message, nil
+		}
+
+		if order.Item.PaymentMethod == nil || order.Item.PaymentMethod.Handler != req.Handler {
+			return http.StatusBadRequest, common.ErrorMessageWebhookHandlerMismatch.Message, nil
+		}
+
+		res, err := bs.PaymentCallbackProcess(ctx, &grpc.PaymentNotifyRequest{
+			OrderId:   orderId,
+			Request:   req.Body,
+			Signature: req.Signature,
+		})
+
+		if err != nil {
+			return 0, "", err
+		}
+
+		switch res.Status {
+		case pkg.StatusErrorValidation:
+			return http.StatusBadRequest, res.Error, nil
+		case pkg.StatusErrorSystem:
+			return http.StatusInternalServerError, res.Error, nil
+		case pkg.StatusTemporary:
+			return http.StatusGone, res.Error, nil
+		}
+
+		return http.StatusOK, res.Error, nil
+	case common.InboundWebhookTypeRefund:
+		res, err := bs.ProcessRefundCallback(ctx, req)
+
+		if err != nil {
+			return 0, "", err
+		}
+
+		if res.Status != pkg.ResponseStatusOk {
+			return int(res.Status), res.Error, nil
+		}
+
+		return http.StatusOK, res.Error, nil
+	}
+
+	return 0, "", common.ErrProviderWebhookTypeUnknown
+}
+
+// publishOrderStatus sends actual status of the order processed by the callback to the order events subscribers,
+// failure doesn't affect the response of the callback since the subscribers get the status on reconnect
+func publishOrderStatus(ctx echo.Context, set common.HandlerSet, log logger.Logger, orderId string) {
+	req := &grpc.GetOrderRequest{Id: orderId}
+	res, err := set.Services.Billing.GetOrderPublic(ctx.Request().Context(), req)
+
+	if err != nil {
+		common.LogSrvCallFailedGRPC(log, err, pkg.ServiceName, "GetOrderPublic", req)
+		return
+	}
+
+	if res.Status != pkg.ResponseStatusOk || res.Item == nil {
+		return
+	}
+
+	data := &common.OrderStatusEvent{OrderId: res.Item.Uuid, Status: res.Item.Status}
+	set.OrderEvents.Publish(res.Item.Uuid, common.OrderEventTypeStatus, data)
+}
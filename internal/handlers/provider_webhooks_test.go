@@ -0,0 +1,648 @@
+package handlers
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"github.com/globalsign/mgo/bson"
+	"github.com/labstack/echo/v4"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	billMock "github.com/paysuper/paysuper-billing-server/pkg/mocks"
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/billing"
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
+	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
+	"github.com/paysuper/paysuper-management-api/internal/test"
+	"github.com/stretchr/testify/assert"
+	mock2 "github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	providerWebhookFixtureOrderId = "5d8a3b2c9f1e4a0001c3d4e5"
+	providerWebhookSecret         = "callback_secret"
+	// the billing server has no handlers of qiwi and webmoney, the tests configure them
+	providerWebhookQiwiHandler     = "qiwi"
+	providerWebhookWebMoneyHandler = "webmoney"
+	// providerWebhookQiwiPaymentSignature is HMAC-SHA256 of "RUB|150.00|5d8a3b2c9f1e4a0001c3d4e5|270305|PAID"
+	// with providerWebhookSecret, the fields of qiwi_payment.json
+	providerWebhookQiwiPaymentSignature = "5fe96ec4fd3e36775e41f8c76f3209f677b82285e0b36191928af59ae0679ac0"
+	// providerWebhookQiwiRefundSignature is HMAC-SHA256 of "RUB|50.00|5d8a3b2c9f1e4a0001c3d4e5|refund_5d8a3b2c|PARTIAL"
+	// with providerWebhookSecret, the fields of qiwi_refund.json
+	providerWebhookQiwiRefundSignature = "033172efd8e2b329cbea950a2bed16b8e48d9a842bbd3a21ea61df3ad2e0669e"
+	// providerWebhookWebMoneyPaymentSignature is SHA-256 of the fields of webmoney_payment.txt with providerWebhookSecret
+	providerWebhookWebMoneyPaymentSignature = "B877FB88B8AFF59939E2B1CF80818CBDD0F768A0ECC795F44EE1789F8F409A4B"
+)
+
+type ProviderWebhooksTestSuite struct {
+	suite.Suite
+	router  *ProviderWebhooksRoute
+	caller  *test.EchoReqResCaller
+	billing *billMock.BillingService
+	// order is returned by the billing server for the callbacks, the tests change its payment system
+	order   *grpc.GetOrderPrivateResponse
+	workDir string
+}
+
+func Test_ProviderWebhooks(t *testing.T) {
+	suite.Run(t, new(ProviderWebhooksTestSuite))
+}
+
+func (suite *ProviderWebhooksTestSuite) SetupTest() {
+	suite.order = &grpc.GetOrderPrivateResponse{
+		Status: pkg.ResponseStatusOk,
+		Item: &billing.Order{
+			Uuid:          providerWebhookFixtureOrderId,
+			PaymentMethod: &billing.PaymentMethodOrder{Handler: providerWebhookQiwiHandler},
+		},
+	}
+
+	suite.billing = &billMock.BillingService{}
+	suite.billing.On("GetOrderPrivate", mock2.Anything, mock2.Anything, mock2.Anything).
+		Return(suite.order, nil)
+	suite.billing.On("PaymentCallbackProcess", mock2.Anything, mock2.Anything, mock2.Anything).
+		Return(&grpc.PaymentNotifyResponse{}, nil)
+	suite.billing.On("ProcessRefundCallback", mock2.Anything, mock2.Anything, mock2.Anything).
+		Return(&grpc.PaymentNotifyResponse{Status: pkg.ResponseStatusOk}, nil)
+	suite.billing.On("GetOrderPublic", mock2.Anything, mock2.Anything, mock2.Anything).
+		Return(&grpc.GetOrderPublicResponse{Status: pkg.ResponseStatusNotFound}, nil)
+
+	var e error
+	settings := test.DefaultSettings()
+	settings["dispatcher"].(map[string]interface{})["global"].(map[string]interface{})["providerWebhooks"] = map[string]interface{}{
+		"webhookHandlers": map[string]interface{}{
+			common.ProviderQiwi:     providerWebhookQiwiHandler,
+			common.ProviderWebMoney: providerWebhookWebMoneyHandler,
+		},
+	}
+	srv := common.Services{
+		Billing: suite.billing,
+	}
+	suite.caller, e = test.SetUp(settings, srv, func(set *test.TestSet, mw test.Middleware) common.Handlers {
+		suite.workDir = set.Initial.WorkDir
+		suite.router = NewProviderWebhooksRoute(set.HandlerSet, set.GlobalConfig)
+		return common.Handlers{
+			suite.router,
+		}
+	})
+	if e != nil {
+		panic(e)
+	}
+
+	suite.setVerification(common.WebhookVerification{
+		WebhookSecrets: map[string]string{
+			common.ProviderCardPay:  providerWebhookSecret,
+			common.ProviderQiwi:     providerWebhookSecret,
+			common.ProviderWebMoney: providerWebhookSecret,
+		},
+	})
+}
+
+func (suite *ProviderWebhooksTestSuite) TearDownTest() {}
+
+func (suite *ProviderWebhooksTestSuite) TestProviderWebhooks_Fixtures_Ok() {
+	cases := []struct {
+		name        string
+		path        string
+		fixture     string
+		contentType string
+		header      string
+		signature   string
+		typ         string
+		handler     string
+	}{
+		{
+			name:        "qiwi payment",
+			path:        common.QiwiWebhookPaymentPath,
+			fixture:     "qiwi_payment.json",
+			contentType: echo.MIMEApplicationJSON,
+			header:      common.QiwiWebhookSignatureHeader,
+			signature:   providerWebhookQiwiPaymentSignature,
+			typ:         common.InboundWebhookTypePayment,
+			handler:     providerWebhookQiwiHandler,
+		},
+		{
+			name:        "qiwi refund",
+			path:        common.QiwiWebhookRefundPath,
+			fixture:     "qiwi_refund.json",
+			contentType: echo.MIMEApplicationJSON,
+			header:      common.QiwiWebhookSignatureHeader,
+			signature:   providerWebhookQiwiRefundSignature,
+			typ:         common.InboundWebhookTypeRefund,
+			handler:     providerWebhookQiwiHandler,
+		},
+		{
+			name:        "webmoney payment",
+			path:        common.WebMoneyWebhookPaymentPath,
+			fixture:     "webmoney_payment.txt",
+			contentType: echo.MIMEApplicationForm,
+			signature:   providerWebhookWebMoneyPaymentSignature,
+			typ:         common.InboundWebhookTypePayment,
+			handler:     providerWebhookWebMoneyHandler,
+		},
+	}
+
+	for _, c := range cases {
+		body, err := ioutil.ReadFile(suite.workDir + "/test/webhooks/" + c.fixture)
+		assert.NoError(suite.T(), err, c.name)
+		suite.order.Item.PaymentMethod.Handler = c.handler
+
+		res, err := suite.caller.Builder().
+			Method(http.MethodPost).
+			Path(common.WebHookGroupPath + c.path).
+			Init(func(request *http.Request, middleware test.Middleware) {
+				request.Header.Set(echo.HeaderContentType, c.contentType)
+				if c.header != "" {
+					request.Header.Set(c.header, c.signature)
+				}
+			}).
+			BodyBytes(body).
+			Exec(suite.T())
+
+		assert.NoError(suite.T(), err, c.name)
+		assert.Equal(suite.T(), http.StatusOK, res.Code, c.name)
+
+		if c.typ == common.InboundWebhookTypePayment {
+			suite.billing.AssertCalled(suite.T(), "PaymentCallbackProcess", mock2.Anything, mock2.MatchedBy(func(req *grpc.PaymentNotifyRequest) bool {
+				return req.OrderId == providerWebhookFixtureOrderId && string(req.Request) == string(body) && req.Signature == c.signature
+			}), mock2.Anything)
+		} else {
+			suite.billing.AssertCalled(suite.T(), "ProcessRefundCallback", mock2.Anything, mock2.MatchedBy(func(req *grpc.CallbackRequest) bool {
+				return req.Handler == c.handler && string(req.Body) == string(body) && req.Signature == c.signature
+			}), mock2.Anything)
+		}
+
+		list, err := suite.router.dispatch.InboundWebhooks.ListWebhooks(&common.InboundWebhookFilter{Type: c.typ}, 1, 0, nil)
+		assert.NoError(suite.T(), err, c.name)
+		assert.Equal(suite.T(), providerWebhookFixtureOrderId, list.Items[0].OrderId, c.name)
+		assert.Equal(suite.T(), c.signature, list.Items[0].Signature, c.name)
+	}
+}
+
+func (suite *ProviderWebhooksTestSuite) TestProviderWebhooks_Payment_HandlerMismatch() {
+	suite.order.Item.PaymentMethod.Handler = providerWebhookWebMoneyHandler
+
+	body, err := ioutil.ReadFile(suite.workDir + "/test/webhooks/qiwi_payment.json")
+	assert.NoError(suite.T(), err)
+
+	res, err := suite.postQiwi(common.QiwiWebhookPaymentPath, body, providerWebhookQiwiPaymentSignature)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusBadRequest, res.Code)
+	assert.Contains(suite.T(), res.Body.String(), common.ErrorMessageWebhookHandlerMismatch.Message)
+	suite.billing.AssertCalled(suite.T(), "GetOrderPrivate", mock2.Anything, mock2.MatchedBy(func(req *grpc.GetOrderRequest) bool {
+		return req.Id == providerWebhookFixtureOrderId
+	}), mock2.Anything)
+	suite.billing.AssertNotCalled(suite.T(), "PaymentCallbackProcess", mock2.Anything, mock2.Anything, mock2.Anything)
+}
+
+func (suite *ProviderWebhooksTestSuite) TestProviderWebhooks_Payment_OrderNotFound() {
+	suite.order.Status = pkg.ResponseStatusNotFound
+	suite.order.Item = nil
+
+	body, err := ioutil.ReadFile(suite.workDir + "/test/webhooks/qiwi_payment.json")
+	assert.NoError(suite.T(), err)
+
+	res, err := suite.postQiwi(common.QiwiWebhookPaymentPath, body, providerWebhookQiwiPaymentSignature)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusNotFound, res.Code)
+	assert.Contains(suite.T(), res.Body.String(), common.ErrorMessageWebhookOrderNotFound.Message)
+	suite.billing.AssertNotCalled(suite.T(), "PaymentCallbackProcess", mock2.Anything, mock2.Anything, mock2.Anything)
+}
+
+func (suite *ProviderWebhooksTestSuite) TestProviderWebhooks_Payment_KeyStockSold() {
+	suite.order.Item.Id = "5dbac8ecd9ab0b0001ce9d11"
+	suite.order.Item.Status = common.OrderStatusProcessed
+	suite.order.Item.PlatformId = "steam"
+	suite.order.Item.Products = []string{"5ced34d689fce60bf4440829"}
+	suite.order.Item.Project = &billing.ProjectOrder{MerchantId: "ffffffffffffffffffffffff"}
+
+	suite.billing.On("GetAvailableKeysCount", mock2.Anything, mock2.Anything).
+		Return(&grpc.GetPlatformKeyCountResponse{Status: pkg.ResponseStatusOk, Count: 3}, nil)
+	suite.billing.On("GetMerchantBy", mock2.Anything, mock2.Anything).Return(&grpc.GetMerchantResponse{
+		Status: pkg.ResponseStatusOk,
+		Item: &billing.Merchant{
+			Id:   "ffffffffffffffffffffffff",
+			User: &billing.MerchantUser{Id: "5cd5620f06ae110001509185"},
+		},
+	}, nil)
+	suite.billing.On("CreateNotification", mock2.Anything, mock2.Anything).
+		Return(&grpc.CreateNotificationResponse{Status: pkg.ResponseStatusOk}, nil)
+
+	body, err := ioutil.ReadFile(suite.workDir + "/test/webhooks/qiwi_payment.json")
+	assert.NoError(suite.T(), err)
+
+	// the order may be processed by several callbacks, its keys are counted once
+	for i := 0; i < 2; i++ {
+		res, err := suite.postQiwi(common.QiwiWebhookPaymentPath, body, providerWebhookQiwiPaymentSignature)
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), http.StatusOK, res.Code)
+	}
+
+	// the keys are counted by the background jobs
+	suite.router.dispatch.Jobs.Wait()
+
+	stock, err := suite.router.dispatch.KeyStocks.GetKeyStock("ffffffffffffffffffffffff", "5ced34d689fce60bf4440829", "steam")
+	assert.NoError(suite.T(), err)
+	assert.EqualValues(suite.T(), 1, stock.Sold)
+	assert.True(suite.T(), stock.LowAlerted)
+	suite.billing.AssertNumberOfCalls(suite.T(), "CreateNotification", 1)
+}
+
+func (suite *ProviderWebhooksTestSuite) TestProviderWebhooks_Qiwi_ValidationError() {
+	body := `{"bill": {"siteId": "270305", "billId": "` + providerWebhookFixtureOrderId + `"}, "version": "1"}`
+	_, err := suite.postQiwi(common.QiwiWebhookPaymentPath, []byte(body), suite.qiwiSignature("", "", providerWebhookFixtureOrderId, "270305", ""))
+
+	assert.Error(suite.T(), err)
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
+	assert.Regexp(suite.T(), common.NewValidationError("Amount"), httpErr.Message)
+	suite.billing.AssertNotCalled(suite.T(), "PaymentCallbackProcess", mock2.Anything, mock2.Anything, mock2.Anything)
+}
+
+func (suite *ProviderWebhooksTestSuite) TestProviderWebhooks_WebMoney_ValidationError() {
+	digest := sha256.Sum256([]byte("Z145179295679" + "12.08" + providerWebhookSecret))
+	body := "LMI_PREREQUEST=1&LMI_PAYEE_PURSE=Z145179295679&LMI_PAYMENT_AMOUNT=12.08&LMI_HASH=" + hex.EncodeToString(digest[:])
+
+	_, err := suite.caller.Builder().
+		Method(http.MethodPost).
+		Path(common.WebHookGroupPath + common.WebMoneyWebhookPaymentPath).
+		Init(func(request *http.Request, middleware test.Middleware) {
+			request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		}).
+		BodyString(body).
+		Exec(suite.T())
+
+	assert.Error(suite.T(), err)
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
+	suite.billing.AssertNotCalled(suite.T(), "PaymentCallbackProcess", mock2.Anything, mock2.Anything, mock2.Anything)
+}
+
+func (suite *ProviderWebhooksTestSuite) TestProviderWebhooks_Registry_Duplicate() {
+	r := common.NewProviderWebhookRegistry()
+	assert.NoError(suite.T(), r.Register(&common.CardPayWebhookAdapter{}))
+	assert.Error(suite.T(), r.Register(&common.CardPayWebhookAdapter{}))
+	assert.Len(suite.T(), r.Adapters(), 1)
+	assert.Nil(suite.T(), r.Get(common.ProviderQiwi))
+
+	// the providers without handlers of the billing server aren't registered
+	r = common.NewDefaultProviderWebhookRegistry(nil)
+	assert.NotNil(suite.T(), r.Get(common.ProviderCardPay))
+	assert.Nil(suite.T(), r.Get(common.ProviderQiwi))
+	assert.Nil(suite.T(), r.Get(common.ProviderWebMoney))
+
+	r = common.NewDefaultProviderWebhookRegistry(map[string]string{
+		common.ProviderQiwi:     providerWebhookQiwiHandler,
+		common.ProviderWebMoney: providerWebhookWebMoneyHandler,
+	})
+	assert.NotNil(suite.T(), r.Get(common.ProviderQiwi))
+	assert.NotNil(suite.T(), r.Get(common.ProviderWebMoney))
+}
+
+func (suite *ProviderWebhooksTestSuite) TestProviderWebhooks_Verification_Ok() {
+	suite.setVerification(common.WebhookVerification{
+		WebhookSecrets:      map[string]string{common.ProviderCardPay: providerWebhookSecret},
+		WebhookReplayWindow: time.Minute,
+	})
+
+	body := suite.cardPayRefundBody()
+	res, err := suite.postCardPayRefund(body, suite.cardPaySignature(body, providerWebhookSecret), "")
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+	suite.billing.AssertCalled(suite.T(), "ProcessRefundCallback", mock2.Anything, mock2.Anything, mock2.Anything)
+}
+
+func (suite *ProviderWebhooksTestSuite) TestProviderWebhooks_Verification_SignatureInvalid() {
+	suite.setVerification(common.WebhookVerification{
+		WebhookSecrets: map[string]string{common.ProviderCardPay: providerWebhookSecret},
+	})
+
+	body := suite.cardPayRefundBody()
+	_, err := suite.postCardPayRefund(body, suite.cardPaySignature(body, "forged_secret"), "")
+
+	assert.Error(suite.T(), err)
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusUnauthorized, httpErr.Code)
+	assert.Equal(suite.T(), common.ErrorMessageWebhookSignatureInvalid, httpErr.Message)
+	suite.billing.AssertNotCalled(suite.T(), "ProcessRefundCallback", mock2.Anything, mock2.Anything, mock2.Anything)
+
+	// the rejected callbacks aren't stored
+	list, err := suite.router.dispatch.InboundWebhooks.ListWebhooks(&common.InboundWebhookFilter{}, 1, 0, nil)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 0, list.Count)
+}
+
+func (suite *ProviderWebhooksTestSuite) TestProviderWebhooks_Verification_SecretRotation() {
+	body := suite.cardPayRefundBody()
+	signature := suite.cardPaySignature(body, providerWebhookSecret)
+
+	suite.setVerification(common.WebhookVerification{
+		WebhookSecrets: map[string]string{common.ProviderCardPay: "new_secret;" + providerWebhookSecret},
+	})
+	res, err := suite.postCardPayRefund(body, signature, "")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+
+	suite.setVerification(common.WebhookVerification{
+		WebhookSecrets: map[string]string{common.ProviderCardPay: "new_secret"},
+	})
+	_, err = suite.postCardPayRefund(body, signature, "")
+	assert.Error(suite.T(), err)
+
+	// malformed settings don't replace the current ones
+	assert.Error(suite.T(), suite.router.dispatch.WebhookVerifier.SetConfig(common.WebhookVerification{
+		WebhookAllowedIps: map[string]string{common.ProviderCardPay: "not_ip"},
+	}))
+	_, err = suite.postCardPayRefund(body, suite.cardPaySignature(body, "new_secret"), "")
+	assert.NoError(suite.T(), err)
+}
+
+func (suite *ProviderWebhooksTestSuite) TestProviderWebhooks_Verification_Replayed() {
+	suite.setVerification(common.WebhookVerification{
+		WebhookSecrets:      map[string]string{common.ProviderCardPay: providerWebhookSecret},
+		WebhookReplayWindow: time.Minute,
+	})
+
+	body := suite.cardPayRefundBody()
+	signature := suite.cardPaySignature(body, providerWebhookSecret)
+
+	_, err := suite.postCardPayRefund(body, signature, "")
+	assert.NoError(suite.T(), err)
+
+	// the duplicate of the processed callback is answered as processed without sending to the billing server
+	res, err := suite.postCardPayRefund(body, signature, "")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+	suite.billing.AssertNumberOfCalls(suite.T(), "ProcessRefundCallback", 1)
+}
+
+func (suite *ProviderWebhooksTestSuite) TestProviderWebhooks_Verification_ReplayedSignatureInvalid() {
+	suite.setVerification(common.WebhookVerification{
+		WebhookSecrets:      map[string]string{common.ProviderCardPay: providerWebhookSecret},
+		WebhookReplayWindow: time.Minute,
+	})
+
+	body := suite.cardPayRefundBody()
+	signature := suite.cardPaySignature(body, providerWebhookSecret)
+
+	_, err := suite.postCardPayRefund(body, signature, "")
+	assert.NoError(suite.T(), err)
+
+	// the signature is checked before the replay, so the processed callback can't be probed by the forged body
+	_, err = suite.postCardPayRefund(suite.cardPayRefundBody(), signature, "")
+	assert.Error(suite.T(), err)
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusUnauthorized, httpErr.Code)
+	suite.billing.AssertNumberOfCalls(suite.T(), "ProcessRefundCallback", 1)
+}
+
+func (suite *ProviderWebhooksTestSuite) TestProviderWebhooks_Verification_NotConfigured() {
+	suite.setVerification(common.WebhookVerification{
+		WebhookSecrets: map[string]string{common.ProviderQiwi: providerWebhookSecret},
+	})
+	assert.False(suite.T(), suite.router.dispatch.WebhookVerifier.Configured(common.ProviderCardPay))
+
+	body := suite.cardPayRefundBody()
+	_, err := suite.postCardPayRefund(body, suite.cardPaySignature(body, providerWebhookSecret), "")
+
+	assert.Error(suite.T(), err)
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusInternalServerError, httpErr.Code)
+	suite.billing.AssertNotCalled(suite.T(), "ProcessRefundCallback", mock2.Anything, mock2.Anything, mock2.Anything)
+}
+
+func (suite *ProviderWebhooksTestSuite) TestProviderWebhooks_Verification_ReplayedOnOtherReplica() {
+	cfg := common.WebhookVerification{
+		WebhookSecrets:      map[string]string{common.ProviderCardPay: providerWebhookSecret},
+		WebhookReplayWindow: time.Minute,
+	}
+	suite.setVerification(cfg)
+
+	replays := common.NewWebhookReplayMemoryStorage()
+	suite.router.dispatch.WebhookVerifier.SetReplayStorage(replays)
+
+	other, err := common.NewWebhookVerifier(cfg)
+	assert.NoError(suite.T(), err)
+	other.SetReplayStorage(replays)
+
+	body := suite.cardPayRefundBody()
+	signature := suite.cardPaySignature(body, providerWebhookSecret)
+
+	// the callback is processed by the other replica
+	assert.NoError(suite.T(), other.Processed(common.ProviderCardPay, &common.ProviderWebhookSignature{Value: signature}))
+
+	res, err := suite.postCardPayRefund(body, signature, "")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+	suite.billing.AssertNotCalled(suite.T(), "ProcessRefundCallback", mock2.Anything, mock2.Anything, mock2.Anything)
+}
+
+func (suite *ProviderWebhooksTestSuite) TestProviderWebhooks_Verification_TimestampExpired() {
+	suite.setVerification(common.WebhookVerification{
+		WebhookSecrets:      map[string]string{common.ProviderWebMoney: providerWebhookSecret},
+		WebhookReplayWindow: time.Minute,
+	})
+
+	body, err := ioutil.ReadFile(suite.workDir + "/test/webhooks/webmoney_payment.txt")
+	assert.NoError(suite.T(), err)
+
+	_, err = suite.caller.Builder().
+		Method(http.MethodPost).
+		Path(common.WebHookGroupPath + common.WebMoneyWebhookPaymentPath).
+		Init(func(request *http.Request, middleware test.Middleware) {
+			request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		}).
+		BodyBytes(body).
+		Exec(suite.T())
+
+	assert.Error(suite.T(), err)
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
+	assert.Equal(suite.T(), common.ErrorMessageWebhookTimestampExpired, httpErr.Message)
+	suite.billing.AssertNotCalled(suite.T(), "PaymentCallbackProcess", mock2.Anything, mock2.Anything, mock2.Anything)
+}
+
+func (suite *ProviderWebhooksTestSuite) TestProviderWebhooks_Verification_Hmac() {
+	suite.setVerification(common.WebhookVerification{
+		WebhookSecrets: map[string]string{common.ProviderQiwi: providerWebhookSecret},
+	})
+
+	cases := []struct {
+		path      string
+		fixture   string
+		signature string
+	}{
+		{common.QiwiWebhookPaymentPath, "qiwi_payment.json", providerWebhookQiwiPaymentSignature},
+		{common.QiwiWebhookRefundPath, "qiwi_refund.json", providerWebhookQiwiRefundSignature},
+	}
+
+	for _, c := range cases {
+		body, err := ioutil.ReadFile(suite.workDir + "/test/webhooks/" + c.fixture)
+		assert.NoError(suite.T(), err)
+
+		res, err := suite.postQiwi(c.path, body, c.signature)
+		assert.NoError(suite.T(), err, c.fixture)
+		assert.Equal(suite.T(), http.StatusOK, res.Code, c.fixture)
+
+		// the signature covers the fields of the notification, not the whole body
+		mac := hmac.New(sha256.New, []byte(providerWebhookSecret))
+		mac.Write(body)
+
+		_, err = suite.postQiwi(c.path, body, hex.EncodeToString(mac.Sum(nil)))
+		assert.Error(suite.T(), err, c.fixture)
+		httpErr, ok := err.(*echo.HTTPError)
+		assert.True(suite.T(), ok)
+		assert.Equal(suite.T(), http.StatusUnauthorized, httpErr.Code)
+	}
+
+	// the signed fields can't be changed
+	body, err := ioutil.ReadFile(suite.workDir + "/test/webhooks/qiwi_payment.json")
+	assert.NoError(suite.T(), err)
+
+	_, err = suite.postQiwi(common.QiwiWebhookPaymentPath, []byte(strings.Replace(string(body), "150.00", "1500.00", 1)), providerWebhookQiwiPaymentSignature)
+	assert.Error(suite.T(), err)
+}
+
+func (suite *ProviderWebhooksTestSuite) TestProviderWebhooks_Verification_Rsa() {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(suite.T(), err)
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	assert.NoError(suite.T(), err)
+
+	file, err := ioutil.TempFile("", "webhook_public_key")
+	assert.NoError(suite.T(), err)
+	defer os.Remove(file.Name())
+
+	assert.NoError(suite.T(), pem.Encode(file, &pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	assert.NoError(suite.T(), file.Close())
+
+	suite.setVerification(common.WebhookVerification{
+		WebhookPublicKeys: map[string]string{common.ProviderCardPay: file.Name()},
+		WebhookAlgorithms: map[string]string{common.ProviderCardPay: common.WebhookSignatureRsaSha256},
+	})
+
+	body := suite.cardPayRefundBody()
+	hashed := sha256.Sum256([]byte(body))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+	assert.NoError(suite.T(), err)
+
+	res, err := suite.postCardPayRefund(body, base64.StdEncoding.EncodeToString(signature), "")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+
+	_, err = suite.postCardPayRefund(body, suite.cardPaySignature(body, providerWebhookSecret), "")
+	assert.Error(suite.T(), err)
+}
+
+func (suite *ProviderWebhooksTestSuite) TestProviderWebhooks_Verification_IpNotAllowed() {
+	suite.setVerification(common.WebhookVerification{
+		WebhookSecrets:    map[string]string{common.ProviderCardPay: providerWebhookSecret},
+		WebhookAllowedIps: map[string]string{common.ProviderCardPay: "192.168.1.0/24;10.0.0.1"},
+	})
+
+	body := suite.cardPayRefundBody()
+
+	signature := suite.cardPaySignature(body, providerWebhookSecret)
+
+	res, err := suite.postCardPayRefund(body, signature, "10.0.0.1")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+
+	_, err = suite.postCardPayRefund(body, signature, "10.0.0.2")
+	assert.Error(suite.T(), err)
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusForbidden, httpErr.Code)
+	assert.Equal(suite.T(), common.ErrorMessageWebhookIpNotAllowed, httpErr.Message)
+	suite.billing.AssertNumberOfCalls(suite.T(), "ProcessRefundCallback", 1)
+}
+
+func (suite *ProviderWebhooksTestSuite) setVerification(cfg common.WebhookVerification) {
+	assert.NoError(suite.T(), suite.router.dispatch.WebhookVerifier.SetConfig(cfg))
+}
+
+func (suite *ProviderWebhooksTestSuite) cardPayRefundBody() string {
+	body, err := json.Marshal(&billing.CardPayRefundCallback{
+		MerchantOrder: &billing.CardPayMerchantOrder{Id: providerWebhookFixtureOrderId},
+		PaymentMethod: "BANKCARD",
+		PaymentData: &billing.CardPayRefundCallbackPaymentData{
+			Id: bson.NewObjectId().Hex(),
+		},
+		RefundData: &billing.CardPayRefundCallbackRefundData{
+			Amount:   100,
+			Created:  time.Now().Format("2006-01-02T15:04:05Z"),
+			Id:       bson.NewObjectId().Hex(),
+			Currency: "RUB",
+			Status:   pkg.CardPayPaymentResponseStatusCompleted,
+			AuthCode: bson.NewObjectId().Hex(),
+			Rrn:      bson.NewObjectId().Hex(),
+		},
+		CallbackTime: time.Now().Format("2006-01-02T15:04:05Z"),
+		Customer: &billing.CardPayCustomer{
+			Email: "test@unit.test",
+			Id:    "test@unit.test",
+		},
+	})
+	assert.NoError(suite.T(), err)
+	return string(body)
+}
+
+func (suite *ProviderWebhooksTestSuite) postQiwi(path string, body []byte, signature string) (*httptest.ResponseRecorder, error) {
+	return suite.caller.Builder().
+		Method(http.MethodPost).
+		Path(common.WebHookGroupPath + path).
+		Init(func(request *http.Request, middleware test.Middleware) {
+			request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			request.Header.Set(common.QiwiWebhookSignatureHeader, signature)
+		}).
+		BodyBytes(body).
+		Exec(suite.T())
+}
+
+func (suite *ProviderWebhooksTestSuite) qiwiSignature(fields ...string) string {
+	mac := hmac.New(sha256.New, []byte(providerWebhookSecret))
+	mac.Write([]byte(strings.Join(fields, "|")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (suite *ProviderWebhooksTestSuite) cardPaySignature(body, secret string) string {
+	digest := sha512.Sum512([]byte(body + secret))
+	return hex.EncodeToString(digest[:])
+}
+
+func (suite *ProviderWebhooksTestSuite) postCardPayRefund(body, signature, ip string) (*httptest.ResponseRecorder, error) {
+	return suite.caller.Builder().
+		Method(http.MethodPost).
+		Path(common.WebHookGroupPath + common.CardPayWebhookRefundPath).
+		Init(func(request *http.Request, middleware test.Middleware) {
+			request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			request.Header.Set(common.CardPayPaymentResponseHeaderSignature, signature)
+			if ip != "" {
+				request.Header.Set(echo.HeaderXRealIP, ip)
+			}
+		}).
+		BodyString(body).
+		Exec(suite.T())
+}
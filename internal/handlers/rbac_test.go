@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"context"
+	"github.com/labstack/echo/v4"
+	awsWrapperMocks "github.com/paysuper/paysuper-aws-manager/pkg/mocks"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	billMock "github.com/paysuper/paysuper-billing-server/pkg/mocks"
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/billing"
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
+	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
+	"github.com/paysuper/paysuper-management-api/internal/mock"
+	"github.com/paysuper/paysuper-management-api/internal/test"
+	"github.com/stretchr/testify/assert"
+	mock2 "github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type RbacTestSuite struct {
+	suite.Suite
+	set    *test.TestSet
+	routes []*echo.Route
+}
+
+func Test_Rbac(t *testing.T) {
+	suite.Run(t, new(RbacTestSuite))
+}
+
+// SetupTest registers routes of all handlers the same way as the provider does
+func (suite *RbacTestSuite) SetupTest() {
+	srv := common.Services{
+		Billing: mock.NewBillingServerOkMock(),
+		PayLink: mock.NewPaymentLinkOkMock(),
+	}
+	set, _, err := test.BuildTestSet(context.Background(), test.DefaultSettings(), srv, nil)
+	require.NoError(suite.T(), err)
+	suite.set = set
+
+	awsManager := &awsWrapperMocks.AwsManagerInterface{}
+	handlers := common.Handlers{
+		NewProviderWebhooksRoute(set.HandlerSet, set.GlobalConfig),
+		NewCountryApiV1(set.HandlerSet, set.GlobalConfig),
+		NewDashboardRoute(set.HandlerSet, set.GlobalConfig),
+		NewKeyRoute(set.HandlerSet, set.GlobalConfig),
+		NewKeyProductRoute(set.HandlerSet, set.GlobalConfig),
+		NewOnboardingRoute(set.HandlerSet, set.Initial, awsManager, set.GlobalConfig),
+		NewOrderRoute(set.HandlerSet, set.GlobalConfig),
+		NewPayLinkRoute(set.HandlerSet, set.GlobalConfig),
+		NewPaymentCostRoute(set.HandlerSet, set.GlobalConfig),
+		NewPaymentMethodApiV1(set.HandlerSet, set.GlobalConfig),
+		NewPriceGroupRoute(set.HandlerSet, set.GlobalConfig),
+		NewProductRoute(set.HandlerSet, set.GlobalConfig),
+		NewProjectRoute(set.HandlerSet, set.GlobalConfig),
+		NewReportFileRoute(set.HandlerSet, awsManager, set.GlobalConfig),
+		NewRoyaltyReportsRoute(set.HandlerSet, set.GlobalConfig),
+		NewTaxesRoute(set.HandlerSet, set.GlobalConfig),
+		NewTokenRoute(set.HandlerSet, set.GlobalConfig),
+		NewUserProfileRoute(set.HandlerSet, set.GlobalConfig),
+		NewVatReportsRoute(set.HandlerSet, set.GlobalConfig),
+		NewZipCodeRoute(set.HandlerSet, set.GlobalConfig),
+		NewBalanceRoute(set.HandlerSet, set.GlobalConfig),
+		NewPayoutDocumentsRoute(set.HandlerSet, set.GlobalConfig),
+		NewPricingRoute(set.HandlerSet, set.GlobalConfig),
+		NewMerchantWebhooksRoute(set.HandlerSet, set.GlobalConfig),
+		NewSavedCardsRoute(set.HandlerSet, set.GlobalConfig),
+		NewRefundBatchRoute(set.HandlerSet, set.GlobalConfig),
+		NewJobsRoute(set.HandlerSet, set.GlobalConfig),
+		NewProjectThemeRoute(set.HandlerSet, set.GlobalConfig),
+		NewOrderEventsRoute(set.HandlerSet, set.GlobalConfig),
+		NewInboundWebhooksRoute(set.HandlerSet, set.GlobalConfig),
+	}
+
+	e := echo.New()
+	groups := &common.Groups{
+		AuthProject: e.Group(common.AuthProjectGroupPath),
+		AuthUser:    e.Group(common.AuthUserGroupPath),
+		WebHooks:    e.Group(common.WebHookGroupPath),
+		Common:      e,
+	}
+	for _, handler := range handlers {
+		handler.Route(groups)
+	}
+
+	suite.routes = nil
+	for _, route := range e.Routes() {
+		if strings.HasPrefix(route.Path, common.AuthUserGroupPath+"/") {
+			suite.routes = append(suite.routes, route)
+		}
+	}
+	require.NotEmpty(suite.T(), suite.routes)
+}
+
+func (suite *RbacTestSuite) TearDownTest() {}
+
+func (suite *RbacTestSuite) TestRbac_AuthUserRoutes_HavePolicy() {
+	for _, route := range suite.routes {
+		assert.NotNil(suite.T(), common.FindAccessPolicy(route.Method, route.Path), "route %s %s has no access policy", route.Method, route.Path)
+	}
+}
+
+func (suite *RbacTestSuite) TestRbac_Policies_MatchRoutes() {
+	registered := make(map[string]bool)
+	for _, route := range suite.routes {
+		registered[common.AccessPolicyKey(route.Method, route.Path)] = true
+	}
+
+	for key, policy := range common.AccessPolicies {
+		assert.True(suite.T(), registered[key], "policy %s has no route", key)
+
+		if policy.MerchantParam != "" {
+			assert.Contains(suite.T(), key, "/:"+policy.MerchantParam, "policy %s has no merchant parameter in the path", key)
+		}
+	}
+}
+
+func (suite *RbacTestSuite) TestRbac_Policies_Roles() {
+	tests := []struct {
+		method string
+		path   string
+		roles  []string
+	}{
+		{http.MethodPut, "/merchants/:id/change-status", []string{common.RoleAdmin}},
+		{http.MethodPost, "/taxes", []string{common.RoleAdmin, common.RoleAccountant}},
+		{http.MethodPost, "/vat_reports/status/:id", []string{common.RoleAdmin, common.RoleAccountant}},
+		{http.MethodPost, "/payment_costs/channel/system", []string{common.RoleAdmin}},
+		{http.MethodGet, "/key-products/inventory", []string{common.RoleAdmin, common.RoleSupport, common.RoleMerchantOwner}},
+		{http.MethodPut, "/key-products/:key_product_id/platforms/:platform_id/threshold", []string{common.RoleAdmin, common.RoleSupport, common.RoleMerchantOwner}},
+		{http.MethodGet, "/jobs/:id", nil},
+		{http.MethodDelete, "/jobs/:id", nil},
+	}
+
+	for _, tt := range tests {
+		policy := common.FindAccessPolicy(tt.method, common.AuthUserGroupPath+tt.path)
+		if assert.NotNil(suite.T(), policy, "%s %s", tt.method, tt.path) {
+			assert.ElementsMatch(suite.T(), tt.roles, policy.Roles, "%s %s", tt.method, tt.path)
+		}
+	}
+}
+
+func (suite *RbacTestSuite) TestRbac_CheckAccess() {
+	const (
+		merchantId      = "5ced34d689fce60bf4440829"
+		otherMerchantId = "ffffffffffffffffffffffff"
+	)
+
+	user := func(roles ...string) *common.AuthUser {
+		u := &common.AuthUser{Id: "5cd5620f06ae110001509185", Roles: map[string]bool{}, Merchants: map[string]bool{}}
+		for _, role := range roles {
+			u.Roles[role] = true
+			if role == common.RoleMerchantOwner {
+				u.Merchants[merchantId] = true
+			}
+		}
+		return u
+	}
+
+	adminOnly := &common.AccessPolicy{Roles: []string{common.RoleAdmin}}
+	merchantScoped := &common.AccessPolicy{
+		Roles:         []string{common.RoleAdmin, common.RoleSupport, common.RoleMerchantOwner},
+		MerchantParam: common.RequestParameterId,
+	}
+	open := &common.AccessPolicy{}
+
+	tests := []struct {
+		name       string
+		policy     *common.AccessPolicy
+		user       *common.AuthUser
+		merchantId string
+		err        error
+	}{
+		{"admin on admin route", adminOnly, user(common.RoleAdmin), "", nil},
+		{"accountant on admin route", adminOnly, user(common.RoleAccountant), "", common.ErrorMessageInsufficientRole},
+		{"owner on admin route", adminOnly, user(common.RoleMerchantOwner), "", common.ErrorMessageInsufficientRole},
+		{"user without roles on admin route", adminOnly, user(), "", common.ErrorMessageInsufficientRole},
+		{"owner of the merchant", merchantScoped, user(common.RoleMerchantOwner), merchantId, nil},
+		{"owner of the other merchant", merchantScoped, user(common.RoleMerchantOwner), otherMerchantId, common.ErrorMessageMerchantAccessDenied},
+		{"owner without merchant parameter", merchantScoped, user(common.RoleMerchantOwner), "", common.ErrorMessageMerchantAccessDenied},
+		{"support of any merchant", merchantScoped, user(common.RoleSupport), otherMerchantId, nil},
+		{"accountant without role", merchantScoped, user(common.RoleAccountant), merchantId, common.ErrorMessageInsufficientRole},
+		{"user without roles on open route", open, user(), "", nil},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(suite.T(), tt.err, tt.policy.CheckAccess(tt.user, tt.merchantId), tt.name)
+	}
+}
+
+func (suite *RbacTestSuite) TestRbac_CheckRouteAccess() {
+	const (
+		userId          = "5cd5620f06ae110001509185"
+		merchantId      = "5ced34d689fce60bf4440829"
+		otherMerchantId = "ffffffffffffffffffffffff"
+		orderId         = "5d8a3b2c9f1e4a0001c3d4e5"
+		otherOrderId    = "5d8a3b2c9f1e4a0001c3d4e6"
+	)
+
+	bs := &billMock.BillingService{}
+	bs.On("GetMerchantBy", mock2.Anything, mock2.Anything).
+		Return(&grpc.GetMerchantResponse{Status: pkg.ResponseStatusOk, Item: &billing.Merchant{Id: merchantId}}, nil)
+	bs.On("GetOrderPrivate", mock2.Anything, mock2.MatchedBy(func(req *grpc.GetOrderRequest) bool {
+		return req.Id == orderId
+	})).Return(&grpc.GetOrderPrivateResponse{
+		Status: pkg.ResponseStatusOk,
+		Item:   &billing.Order{Uuid: orderId, Project: &billing.ProjectOrder{MerchantId: merchantId}},
+	}, nil)
+	bs.On("GetOrderPrivate", mock2.Anything, mock2.MatchedBy(func(req *grpc.GetOrderRequest) bool {
+		return req.Id == otherOrderId
+	})).Return(&grpc.GetOrderPrivateResponse{
+		Status: pkg.ResponseStatusOk,
+		Item:   &billing.Order{Uuid: otherOrderId, Project: &billing.ProjectOrder{MerchantId: otherMerchantId}},
+	}, nil)
+	srv := common.Services{Billing: bs}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		param  string
+		value  string
+		roles  []string
+		userId string
+		code   int
+	}{
+		{"route without policy", http.MethodGet, "/unknown", "", "", nil, userId, http.StatusForbidden},
+		{"unauthorized user", http.MethodGet, "/user/profile", "", "", nil, "", http.StatusUnauthorized},
+		{"open route", http.MethodGet, "/user/profile", "", "", nil, userId, 0},
+		{"order of the merchant", http.MethodGet, "/order/:id", common.RequestParameterId, orderId, nil, userId, 0},
+		{"order of the other merchant", http.MethodGet, "/order/:id", common.RequestParameterId, otherOrderId, nil, userId, http.StatusForbidden},
+		{"refund of the merchant order", http.MethodPost, "/order/:order_id/refunds", common.RequestParameterOrderId, orderId, nil, userId, 0},
+		{"refund of the other merchant order", http.MethodPost, "/order/:order_id/refunds", common.RequestParameterOrderId, otherOrderId, nil, userId, http.StatusForbidden},
+		{"refund of the support", http.MethodPost, "/order/:order_id/refunds", common.RequestParameterOrderId, otherOrderId, []string{common.RoleSupport}, userId, 0},
+	}
+
+	for _, tt := range tests {
+		e := echo.New()
+		ctx := e.NewContext(httptest.NewRequest(tt.method, "/", nil), httptest.NewRecorder())
+		ctx.SetPath(common.AuthUserGroupPath + tt.path)
+		if tt.param != "" {
+			ctx.SetParamNames(tt.param)
+			ctx.SetParamValues(tt.value)
+		}
+
+		user := &common.AuthUser{Id: tt.userId, Roles: map[string]bool{}, Merchants: map[string]bool{}}
+		for _, role := range tt.roles {
+			user.Roles[role] = true
+		}
+		common.SetUserContext(ctx, user)
+
+		err := common.CheckRouteAccess(srv, suite.set.AwareSet.Logger, ctx)
+		if tt.code == 0 {
+			assert.NoError(suite.T(), err, tt.name)
+			continue
+		}
+
+		if assert.Error(suite.T(), err, tt.name) {
+			httpErr, ok := err.(*echo.HTTPError)
+			assert.True(suite.T(), ok, tt.name)
+			assert.Equal(suite.T(), tt.code, httpErr.Code, tt.name)
+		}
+	}
+
+	// the staff users aren't limited by the merchant of the order
+	bs.AssertNumberOfCalls(suite.T(), "GetOrderPrivate", 4)
+}
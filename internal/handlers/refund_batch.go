@@ -0,0 +1,355 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"github.com/ProtocolONE/go-core/v2/pkg/logger"
+	"github.com/ProtocolONE/go-core/v2/pkg/provider"
+	"github.com/labstack/echo/v4"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
+	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
+	"github.com/paysuper/paysuper-management-api/pkg/job"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	refundsBatchPath   = "/refunds/batch"
+	refundsBatchIdPath = "/refunds/batch/:id"
+)
+
+// RefundBatchRequest
+type RefundBatchRequest struct {
+	Items []*RefundBatchRequestItem `json:"items"`
+}
+
+// RefundBatchRequestItem
+type RefundBatchRequestItem struct {
+	OrderId string  `json:"order_id"`
+	Amount  float64 `json:"amount"`
+	Reason  string  `json:"reason"`
+}
+
+type RefundBatchRoute struct {
+	dispatch common.HandlerSet
+	cfg      common.Config
+	provider.LMT
+}
+
+func NewRefundBatchRoute(set common.HandlerSet, cfg *common.Config) *RefundBatchRoute {
+	set.AwareSet.Logger = set.AwareSet.Logger.WithFields(logger.Fields{"router": "RefundBatchRoute"})
+	return &RefundBatchRoute{
+		dispatch: set,
+		LMT:      &set.AwareSet,
+		cfg:      *cfg,
+	}
+}
+
+func (h *RefundBatchRoute) Route(groups *common.Groups) {
+	groups.AuthUser.POST(refundsBatchPath, h.createRefundBatch)
+	groups.AuthUser.GET(refundsBatchIdPath, h.getRefundBatch)
+}
+
+// Create refunds for the list of orders, items are sent as json or as csv file with order_id, amount
+// and reason columns. Small batches are processed in the request and the report is returned immediately,
+// large batches are processed in background and the report is available by the batch identifier
+// POST /admin/api/v1/refunds/batch
+//
+// @Example curl -X POST -H 'Authorization: Bearer %access_token_here%' -H 'Content-Type: application/json' \
+//      -d '{"items": [{"order_id": "%order_uuid_here%", "amount": 10, "reason": "chargeback"}]}' \
+//      https://api.paysuper.online/admin/api/v1/refunds/batch
+func (h *RefundBatchRoute) createRefundBatch(ctx echo.Context) error {
+	items, err := h.bindRefundBatchItems(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(items) == 0 || len(items) > h.cfg.RefundBatchMaxItems {
+		return echo.NewHTTPError(http.StatusBadRequest, common.ErrorMessageRefundBatchSizeIncorrect)
+	}
+
+	batch := &common.RefundBatch{
+		CreatorId: common.ExtractUserContext(ctx).Id,
+		Status:    common.RefundBatchStatusProcessing,
+		Total:     len(items),
+		Items:     make([]*common.RefundBatchItem, len(items)),
+		CreatedAt: time.Now(),
+	}
+
+	for i, item := range items {
+		if item == nil {
+			item = &RefundBatchRequestItem{}
+		}
+		batch.Items[i] = &common.RefundBatchItem{
+			Index:   i,
+			OrderId: item.OrderId,
+			Amount:  item.Amount,
+			Reason:  item.Reason,
+			Status:  common.RefundBatchItemStatusPending,
+		}
+	}
+
+	if len(items) <= h.cfg.RefundBatchSyncItems {
+		if err = h.processRefundBatch(ctx.Request().Context(), batch, nil); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, common.ErrorUnknown)
+		}
+		return ctx.JSON(http.StatusOK, batch)
+	}
+
+	j, err := h.dispatch.Jobs.Submit(jobTypeRefundBatch, batch.CreatorId, func(ctx context.Context, progress job.Progress) (interface{}, error) {
+		err := h.processRefundBatch(ctx, batch, progress)
+		return batch, err
+	})
+
+	if err != nil {
+		h.L().Error(common.InternalErrorTemplate, logger.PairArgs("err", err.Error()))
+		return echo.NewHTTPError(http.StatusInternalServerError, common.ErrorInternal)
+	}
+
+	return jobAccepted(ctx, j)
+}
+
+// Get report of the batch refund processed in background, identifier of the batch is the identifier of its job
+// GET /admin/api/v1/refunds/batch/5ced34d689fce60bf4440829
+func (h *RefundBatchRoute) getRefundBatch(ctx echo.Context) error {
+	j, err := getUserJob(ctx, h.dispatch.Jobs, ctx.Param(common.RequestParameterId), jobTypeRefundBatch)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, common.ErrorMessageRefundBatchNotFound)
+	}
+
+	batch := &common.RefundBatch{
+		CreatorId: j.OwnerId,
+		Status:    common.RefundBatchStatusProcessing,
+		CreatedAt: j.CreatedAt,
+	}
+
+	if err = j.DecodeResult(batch); err != nil {
+		h.L().Error(common.InternalErrorTemplate, logger.PairArgs("err", err.Error()))
+		return echo.NewHTTPError(http.StatusInternalServerError, common.ErrorInternal)
+	}
+
+	batch.Id = j.Id
+
+	if j.Status == job.StatusCancelled {
+		batch.Status = common.RefundBatchStatusCancelled
+	}
+
+	return ctx.JSON(http.StatusOK, batch)
+}
+
+func (h *RefundBatchRoute) bindRefundBatchItems(ctx echo.Context) ([]*RefundBatchRequestItem, error) {
+	if !strings.HasPrefix(ctx.Request().Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+		req := &RefundBatchRequest{}
+		if err := ctx.Bind(req); err != nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, common.ErrorRequestParamsIncorrect)
+		}
+		return req.Items, nil
+	}
+
+	file, err := ctx.FormFile(common.RequestParameterFile)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, common.ErrorMessageFileNotFound)
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		h.L().Error(common.ErrorMessageCantReadFile.String(), logger.PairArgs("err", err.Error()))
+		return nil, echo.NewHTTPError(http.StatusBadRequest, common.ErrorMessageCantReadFile)
+	}
+	defer src.Close()
+
+	items, err := parseRefundBatchCsv(src, h.cfg.RefundBatchMaxItems)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, common.ErrorMessageRefundBatchFileIncorrect)
+	}
+
+	return items, nil
+}
+
+// parseRefundBatchCsv reads items of the csv file, the first line is a header with the names of the columns.
+// Lines with the incorrect amount are kept with zero amount to be reported as invalid items of the batch
+func parseRefundBatchCsv(src io.Reader, max int) ([]*RefundBatchRequestItem, error) {
+	r := csv.NewReader(src)
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+
+	orderIdx, ok := columns["order_id"]
+	if !ok {
+		return nil, common.ErrorMessageRefundBatchFileIncorrect
+	}
+	amountIdx, ok := columns["amount"]
+	if !ok {
+		return nil, common.ErrorMessageRefundBatchFileIncorrect
+	}
+	reasonIdx, hasReason := columns["reason"]
+
+	var items []*RefundBatchRequestItem
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// items over the limit are counted only to reject the batch
+		if len(items) >= max {
+			items = append(items, nil)
+			continue
+		}
+
+		item := &RefundBatchRequestItem{OrderId: record[orderIdx]}
+		item.Amount, _ = strconv.ParseFloat(strings.TrimSpace(record[amountIdx]), 64)
+		if hasReason {
+			item.Reason = record[reasonIdx]
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// processRefundBatch creates refunds of the batch with the limited number of the concurrent calls
+// to the billing service, the progress of the background batch is reported after each processed item.
+// Cancelled batch doesn't start new refunds, unprocessed items stay pending
+func (h *RefundBatchRoute) processRefundBatch(ctx context.Context, batch *common.RefundBatch, progress job.Progress) error {
+	concurrency := h.cfg.RefundBatchConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	var (
+		mx  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, concurrency)
+	)
+
+	if progress != nil {
+		mx.Lock()
+		progress.SetTotal(batch.Total)
+		progress.SetResult(batch)
+		mx.Unlock()
+	}
+
+	// refunds of the same order are created one by one, so the billing server checks the refunded amount
+	// of the order against the previous refunds, the orders are refunded concurrently
+	for _, items := range groupRefundBatchItems(batch.Items) {
+		if ctx.Err() != nil {
+			break
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+
+		go func(items []*common.RefundBatchItem) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			for _, item := range items {
+				if ctx.Err() != nil {
+					return
+				}
+
+				status, refundId, itemErr := h.createRefund(ctx, batch.CreatorId, item)
+
+				mx.Lock()
+				item.Status, item.RefundId, item.Error = status, refundId, itemErr
+				batch.Processed++
+				if status == common.RefundBatchItemStatusSucceeded {
+					batch.Succeeded++
+				} else {
+					batch.Failed++
+				}
+
+				if progress != nil {
+					progress.Add(1)
+					progress.SetResult(batch)
+				}
+				mx.Unlock()
+			}
+		}(items)
+	}
+
+	wg.Wait()
+
+	now := time.Now()
+	batch.Status = common.RefundBatchStatusCompleted
+	batch.FinishedAt = &now
+
+	if err := ctx.Err(); err != nil {
+		batch.Status = common.RefundBatchStatusCancelled
+		return err
+	}
+
+	h.L().Info(
+		"refund batch completed",
+		logger.PairArgs("creator_id", batch.CreatorId, "succeeded", batch.Succeeded, "failed", batch.Failed),
+	)
+
+	return nil
+}
+
+// groupRefundBatchItems groups items by the order keeping order of the items in the batch
+func groupRefundBatchItems(items []*common.RefundBatchItem) [][]*common.RefundBatchItem {
+	groups := make([][]*common.RefundBatchItem, 0, len(items))
+	index := make(map[string]int)
+
+	for _, item := range items {
+		i, ok := index[item.OrderId]
+		if !ok {
+			i = len(groups)
+			index[item.OrderId] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], item)
+	}
+
+	return groups
+}
+
+func (h *RefundBatchRoute) createRefund(ctx context.Context, creatorId string, item *common.RefundBatchItem) (string, string, interface{}) {
+	req := &grpc.CreateRefundRequest{
+		OrderId:   item.OrderId,
+		Amount:    item.Amount,
+		Reason:    item.Reason,
+		CreatorId: creatorId,
+	}
+
+	if err := h.dispatch.Validate.Struct(req); err != nil {
+		return common.RefundBatchItemStatusInvalid, "", common.GetValidationError(err)
+	}
+
+	res, err := h.dispatch.Services.Billing.CreateRefund(ctx, req)
+
+	if err != nil {
+		common.LogSrvCallFailedGRPC(h.L(), err, pkg.ServiceName, "CreateRefund", req)
+		return common.RefundBatchItemStatusFailed, "", common.ErrorUnknown
+	}
+
+	if res.Status != pkg.ResponseStatusOk {
+		return common.RefundBatchItemStatusFailed, "", res.Message
+	}
+
+	if res.Item == nil {
+		return common.RefundBatchItemStatusSucceeded, "", nil
+	}
+
+	return common.RefundBatchItemStatusSucceeded, res.Item.Id, nil
+}
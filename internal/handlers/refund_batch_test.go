@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/globalsign/mgo/bson"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/paysuper/paysuper-billing-server/pkg"
+	billMock "github.com/paysuper/paysuper-billing-server/pkg/mocks"
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/billing"
+	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
+	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
+	"github.com/paysuper/paysuper-management-api/internal/mock"
+	"github.com/paysuper/paysuper-management-api/internal/test"
+	"github.com/paysuper/paysuper-management-api/pkg/job"
+	"github.com/stretchr/testify/assert"
+	mock2 "github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+type RefundBatchTestSuite struct {
+	suite.Suite
+	router  *RefundBatchRoute
+	caller  *test.EchoReqResCaller
+	orderOk string
+	orderKo string
+}
+
+func Test_RefundBatch(t *testing.T) {
+	suite.Run(t, new(RefundBatchTestSuite))
+}
+
+func (suite *RefundBatchTestSuite) SetupTest() {
+	var e error
+	settings := test.DefaultSettings()
+	srv := common.Services{
+		Billing: mock.NewBillingServerOkMock(),
+	}
+	suite.caller, e = test.SetUp(settings, srv, func(set *test.TestSet, mw test.Middleware) common.Handlers {
+		suite.router = NewRefundBatchRoute(set.HandlerSet, set.GlobalConfig)
+		return common.Handlers{
+			suite.router,
+		}
+	})
+	if e != nil {
+		panic(e)
+	}
+
+	suite.orderOk = uuid.New().String()
+	suite.orderKo = uuid.New().String()
+
+	bs := &billMock.BillingService{}
+	bs.On("CreateRefund", mock2.Anything, mock2.MatchedBy(func(req *grpc.CreateRefundRequest) bool {
+		return req.OrderId == suite.orderOk
+	}), mock2.Anything).
+		Return(&grpc.CreateRefundResponse{Status: pkg.ResponseStatusOk, Item: &billing.Refund{Id: "refund_1"}}, nil)
+	bs.On("CreateRefund", mock2.Anything, mock2.MatchedBy(func(req *grpc.CreateRefundRequest) bool {
+		return req.OrderId == suite.orderKo
+	}), mock2.Anything).
+		Return(&grpc.CreateRefundResponse{
+			Status:  pkg.ResponseStatusBadData,
+			Message: &grpc.ResponseErrorMessage{Message: "refund amount exceeds order amount"},
+		}, nil)
+	suite.router.dispatch.Services.Billing = bs
+}
+
+func (suite *RefundBatchTestSuite) TearDownTest() {}
+
+func (suite *RefundBatchTestSuite) TestRefundBatch_Sync_Ok() {
+	data := `{"items": [
+		{"order_id": "` + suite.orderOk + `", "amount": 10, "reason": "chargeback"},
+		{"order_id": "` + suite.orderKo + `", "amount": 1000, "reason": "chargeback"},
+		{"order_id": "` + suite.orderOk + `", "amount": -10, "reason": "chargeback"}
+	]}`
+
+	res, err := suite.caller.Builder().
+		Method(http.MethodPost).
+		Path(common.AuthUserGroupPath + refundsBatchPath).
+		Init(test.ReqInitJSON()).
+		BodyString(data).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+
+	batch := &common.RefundBatch{}
+	assert.NoError(suite.T(), json.Unmarshal(res.Body.Bytes(), batch))
+	assert.Equal(suite.T(), common.RefundBatchStatusCompleted, batch.Status)
+	assert.Equal(suite.T(), 3, batch.Processed)
+	assert.Equal(suite.T(), 1, batch.Succeeded)
+	assert.Equal(suite.T(), 2, batch.Failed)
+	assert.Equal(suite.T(), common.RefundBatchItemStatusSucceeded, batch.Items[0].Status)
+	assert.Equal(suite.T(), "refund_1", batch.Items[0].RefundId)
+	assert.Equal(suite.T(), common.RefundBatchItemStatusFailed, batch.Items[1].Status)
+	assert.NotNil(suite.T(), batch.Items[1].Error)
+	assert.Equal(suite.T(), common.RefundBatchItemStatusInvalid, batch.Items[2].Status)
+}
+
+func (suite *RefundBatchTestSuite) TestRefundBatch_Async_Ok() {
+	suite.router.cfg.RefundBatchSyncItems = 1
+	data := `{"items": [
+		{"order_id": "` + suite.orderOk + `", "amount": 10, "reason": "chargeback"},
+		{"order_id": "` + suite.orderOk + `", "amount": 20, "reason": "chargeback"}
+	]}`
+
+	res, err := suite.caller.Builder().
+		Method(http.MethodPost).
+		Path(common.AuthUserGroupPath + refundsBatchPath).
+		Init(test.ReqInitJSON()).
+		BodyString(data).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusAccepted, res.Code)
+
+	j := &job.Job{}
+	assert.NoError(suite.T(), json.Unmarshal(res.Body.Bytes(), j))
+	assert.Equal(suite.T(), jobTypeRefundBatch, j.Type)
+	assert.Equal(suite.T(), common.AuthUserGroupPath+"/jobs/"+j.Id, res.Header().Get(echo.HeaderLocation))
+
+	batch := &common.RefundBatch{Id: j.Id}
+
+	for i := 0; i < 250 && batch.Status != common.RefundBatchStatusCompleted; i++ {
+		time.Sleep(time.Millisecond * 20)
+
+		res, err = suite.caller.Builder().
+			Params(":"+common.RequestParameterId, batch.Id).
+			Path(common.AuthUserGroupPath + refundsBatchIdPath).
+			Exec(suite.T())
+
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), http.StatusOK, res.Code)
+		assert.NoError(suite.T(), json.Unmarshal(res.Body.Bytes(), batch))
+	}
+
+	assert.Equal(suite.T(), common.RefundBatchStatusCompleted, batch.Status)
+	assert.Equal(suite.T(), 2, batch.Succeeded)
+}
+
+func (suite *RefundBatchTestSuite) TestRefundBatch_SameOrder_Sequential() {
+	suite.router.cfg.RefundBatchConcurrency = 5
+
+	var (
+		mx          sync.Mutex
+		inFlight    int
+		maxInFlight int
+		amounts     []float64
+	)
+
+	bs := &billMock.BillingService{}
+	bs.On("CreateRefund", mock2.Anything, mock2.Anything, mock2.Anything).
+		Run(func(args mock2.Arguments) {
+			mx.Lock()
+			inFlight++
+			if inFlight > maxInFlight {
+				maxInFlight = inFlight
+			}
+			amounts = append(amounts, args.Get(1).(*grpc.CreateRefundRequest).Amount)
+			mx.Unlock()
+
+			time.Sleep(time.Millisecond * 10)
+
+			mx.Lock()
+			inFlight--
+			mx.Unlock()
+		}).
+		Return(&grpc.CreateRefundResponse{Status: pkg.ResponseStatusOk, Item: &billing.Refund{Id: "refund_1"}}, nil)
+	suite.router.dispatch.Services.Billing = bs
+
+	data := `{"items": [
+		{"order_id": "` + suite.orderOk + `", "amount": 10, "reason": "chargeback"},
+		{"order_id": "` + suite.orderOk + `", "amount": 20, "reason": "chargeback"},
+		{"order_id": "` + suite.orderOk + `", "amount": 30, "reason": "chargeback"}
+	]}`
+
+	res, err := suite.caller.Builder().
+		Method(http.MethodPost).
+		Path(common.AuthUserGroupPath + refundsBatchPath).
+		Init(test.ReqInitJSON()).
+		BodyString(data).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+
+	batch := &common.RefundBatch{}
+	assert.NoError(suite.T(), json.Unmarshal(res.Body.Bytes(), batch))
+	assert.Equal(suite.T(), 3, batch.Succeeded)
+	assert.Equal(suite.T(), 1, maxInFlight)
+	assert.Equal(suite.T(), []float64{10, 20, 30}, amounts)
+}
+
+func (suite *RefundBatchTestSuite) TestRefundBatch_Csv_Ok() {
+	filePath := os.TempDir() + string(os.PathSeparator) + bson.NewObjectId().Hex() + ".csv"
+	content := "order_id,amount,reason\n" + suite.orderOk + ",10,chargeback\n" + suite.orderKo + ",10,chargeback\n"
+	assert.NoError(suite.T(), ioutil.WriteFile(filePath, []byte(content), 0666))
+	defer os.Remove(filePath)
+
+	res, err := suite.caller.Builder().
+		Path(common.AuthUserGroupPath+refundsBatchPath).
+		ExecFileUpload(suite.T(), nil, common.RequestParameterFile, filePath)
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+
+	batch := &common.RefundBatch{}
+	assert.NoError(suite.T(), json.Unmarshal(res.Body.Bytes(), batch))
+	assert.Equal(suite.T(), 2, batch.Total)
+	assert.Equal(suite.T(), 1, batch.Succeeded)
+}
+
+func (suite *RefundBatchTestSuite) TestRefundBatch_TooManyItems() {
+	suite.router.cfg.RefundBatchMaxItems = 1
+	data := `{"items": [{"order_id": "` + suite.orderOk + `", "amount": 10}, {"order_id": "` + suite.orderOk + `", "amount": 10}]}`
+
+	_, err := suite.caller.Builder().
+		Method(http.MethodPost).
+		Path(common.AuthUserGroupPath + refundsBatchPath).
+		Init(test.ReqInitJSON()).
+		BodyString(data).
+		Exec(suite.T())
+
+	assert.Error(suite.T(), err)
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
+	assert.Equal(suite.T(), common.ErrorMessageRefundBatchSizeIncorrect, httpErr.Message)
+}
+
+func (suite *RefundBatchTestSuite) TestRefundBatch_Get_NotFound() {
+	_, err := suite.caller.Builder().
+		Params(":"+common.RequestParameterId, bson.NewObjectId().Hex()).
+		Path(common.AuthUserGroupPath + refundsBatchIdPath).
+		Exec(suite.T())
+
+	assert.Error(suite.T(), err)
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusNotFound, httpErr.Code)
+	assert.Equal(suite.T(), common.ErrorMessageRefundBatchNotFound, httpErr.Message)
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/ProtocolONE/go-core/v2/pkg/logger"
@@ -8,6 +9,7 @@ import (
 	"github.com/labstack/echo/v4"
 	awsWrapper "github.com/paysuper/paysuper-aws-manager"
 	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
+	"github.com/paysuper/paysuper-management-api/pkg/job"
 	reporterPkg "github.com/paysuper/paysuper-reporter/pkg"
 	reporterProto "github.com/paysuper/paysuper-reporter/pkg/proto"
 	"net/http"
@@ -32,6 +34,7 @@ type ReportFileRoute struct {
 	dispatch   common.HandlerSet
 	awsManager awsWrapper.AwsManagerInterface
 	cfg        common.Config
+	s3Check    common.HealthCheck
 	provider.LMT
 }
 
@@ -42,15 +45,27 @@ func NewReportFileRoute(set common.HandlerSet, awsManager awsWrapper.AwsManagerI
 		LMT:        &set.AwareSet,
 		cfg:        *cfg,
 		awsManager: awsManager,
+		s3Check: common.NewS3BucketHealthCheck(
+			"s3_reporter",
+			cfg.AwsAccessKeyIdReporter,
+			cfg.AwsSecretAccessKeyReporter,
+			cfg.AwsRegionReporter,
+			cfg.AwsBucketReporter,
+		),
 	}
 }
 
+// HealthChecks
+func (h *ReportFileRoute) HealthChecks() []common.HealthCheck {
+	return []common.HealthCheck{h.s3Check}
+}
+
 func (h *ReportFileRoute) Route(groups *common.Groups) {
 	groups.AuthUser.POST(reportFilePath, h.create)
 	groups.AuthUser.GET(reportFileDownloadPath, h.download)
 }
 
-// Send a request to create a report for download.
+// Send a request to create a report for download, the request is sent in background job.
 // POST /admin/api/v1/report_file
 //
 // @Example curl -X POST -H "Accept: application/json" -H "Content-Type: application/json" \
@@ -86,13 +101,21 @@ func (h *ReportFileRoute) create(ctx echo.Context) error {
 		SendNotification: true,
 	}
 
-	res, err := h.dispatch.Services.Reporter.CreateFile(ctx.Request().Context(), req)
+	j, err := h.dispatch.Jobs.Submit(jobTypeReportFile, authUser.Id, func(ctx context.Context, progress job.Progress) (interface{}, error) {
+		res, err := h.dispatch.Services.Reporter.CreateFile(ctx, req)
+		if err != nil {
+			common.LogSrvCallFailedGRPC(h.L(), err, reporterPkg.ServiceName, "CreateFile", req)
+			return nil, common.ErrorMessageCreateReportFile
+		}
+		return res, nil
+	})
+
 	if err != nil {
-		common.LogSrvCallFailedGRPC(h.L(), err, reporterPkg.ServiceName, "CreateFile", req)
-		return echo.NewHTTPError(http.StatusInternalServerError, common.ErrorMessageCreateReportFile)
+		h.L().Error(common.InternalErrorTemplate, logger.PairArgs("err", err.Error()))
+		return echo.NewHTTPError(http.StatusInternalServerError, common.ErrorInternal)
 	}
 
-	return ctx.JSON(http.StatusOK, res)
+	return jobAccepted(ctx, j)
 }
 
 // Send a request to create a report for download.
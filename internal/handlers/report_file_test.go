@@ -11,6 +11,7 @@ import (
 	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
 	"github.com/paysuper/paysuper-management-api/internal/mock"
 	"github.com/paysuper/paysuper-management-api/internal/test"
+	"github.com/paysuper/paysuper-management-api/pkg/job"
 	reporterMocks "github.com/paysuper/paysuper-reporter/pkg/mocks"
 	reporterProto "github.com/paysuper/paysuper-reporter/pkg/proto"
 	"github.com/stretchr/testify/assert"
@@ -104,17 +105,20 @@ func (suite *ReportFileTestSuite) TestReportFile_create_Error_CreateFile() {
 		Return(nil, errors.New("error"))
 	suite.router.dispatch.Services.Reporter = reporterService
 
-	_, err := suite.caller.Builder().
+	res, err := suite.caller.Builder().
 		Method(http.MethodPost).
 		Path(common.AuthUserGroupPath + reportFilePath).
 		Init(test.ReqInitJSON()).
 		BodyString(data).
 		Exec(suite.T())
 
-	httpErr, ok := err.(*echo.HTTPError)
-	assert.True(suite.T(), ok)
-	assert.Equal(suite.T(), http.StatusInternalServerError, httpErr.Code)
-	assert.Regexp(suite.T(), common.ErrorMessageCreateReportFile.Message, httpErr.Message)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusAccepted, res.Code)
+
+	j := waitJobFinished(suite.T(), suite.router.dispatch.Jobs, res)
+	assert.Equal(suite.T(), job.StatusFailed, j.Status)
+	assert.Len(suite.T(), j.Errors, 1)
+	assert.Regexp(suite.T(), common.ErrorMessageCreateReportFile.Message, j.Errors[0])
 }
 
 func (suite *ReportFileTestSuite) TestReportFile_create_Ok() {
@@ -126,7 +130,7 @@ func (suite *ReportFileTestSuite) TestReportFile_create_Ok() {
 		Return(&reporterProto.CreateFileResponse{FileId: bson.NewObjectId().Hex()}, nil)
 	suite.router.dispatch.Services.Reporter = reporterService
 
-	_, err := suite.caller.Builder().
+	res, err := suite.caller.Builder().
 		Method(http.MethodPost).
 		Path(common.AuthUserGroupPath + reportFilePath).
 		Init(test.ReqInitJSON()).
@@ -134,6 +138,12 @@ func (suite *ReportFileTestSuite) TestReportFile_create_Ok() {
 		Exec(suite.T())
 
 	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusAccepted, res.Code)
+
+	j := waitJobFinished(suite.T(), suite.router.dispatch.Jobs, res)
+	assert.Equal(suite.T(), job.StatusSucceeded, j.Status)
+	assert.Equal(suite.T(), "ffffffffffffffffffffffff", j.OwnerId)
+	assert.NotEmpty(suite.T(), j.Result)
 }
 
 func (suite *ReportFileTestSuite) TestReportFile_download_Error_EmptyId() {
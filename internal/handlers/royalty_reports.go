@@ -7,6 +7,7 @@ import (
 	"github.com/paysuper/paysuper-billing-server/pkg"
 	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
 	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
+	"github.com/paysuper/paysuper-management-api/pkg/webhook"
 	"net/http"
 )
 
@@ -36,6 +37,7 @@ func NewRoyaltyReportsRoute(set common.HandlerSet, cfg *common.Config) *RoyaltyR
 
 func (h *RoyaltyReportsRoute) Route(groups *common.Groups) {
 	groups.AuthUser.GET(royaltyReportsPath, h.getRoyaltyReportsList)
+	groups.AuthUser.POST(royaltyReportsPath, h.createRoyaltyReports)
 	groups.AuthUser.GET(royaltyReportsIdPath, h.getRoyaltyReport)
 	groups.AuthUser.GET(royaltyReportsTransactionsPath, h.listRoyaltyReportOrders)
 	groups.AuthUser.POST(royaltyReportsAcceptPath, h.merchantReviewRoyaltyReport)
@@ -47,7 +49,7 @@ func (h *RoyaltyReportsRoute) Route(groups *common.Groups) {
 // GET /admin/api/v1/royalty_reports
 func (h *RoyaltyReportsRoute) getRoyaltyReportsList(ctx echo.Context) error {
 	req := &grpc.ListRoyaltyReportsRequest{}
-	err := ctx.Bind(req)
+	err := common.NewPaginationBinder(h.cfg).Bind(req, ctx)
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, common.NewValidationError(err.Error()))
@@ -68,6 +70,10 @@ func (h *RoyaltyReportsRoute) getRoyaltyReportsList(ctx echo.Context) error {
 	if res.Status != http.StatusOK {
 		return echo.NewHTTPError(int(res.Status), res.Message)
 	}
+	if res.Data != nil {
+		page := common.NewPage(common.ExtractCursorContext(ctx), int64(res.Data.Count), res.Data.Items)
+		common.SetPaginationLinks(ctx, h.cfg.PaginationSecret(), page)
+	}
 	return ctx.JSON(http.StatusOK, res.Data)
 }
 
@@ -98,7 +104,7 @@ func (h *RoyaltyReportsRoute) getRoyaltyReport(ctx echo.Context) error {
 // GET /admin/api/v1/royalty_reports/5ced34d689fce60bf4440829/transactions
 func (h *RoyaltyReportsRoute) listRoyaltyReportOrders(ctx echo.Context) error {
 	req := &grpc.ListRoyaltyReportOrdersRequest{}
-	err := ctx.Bind(req)
+	err := common.NewPaginationBinder(h.cfg).Bind(req, ctx)
 
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, common.NewValidationError(err.Error()))
@@ -119,6 +125,10 @@ func (h *RoyaltyReportsRoute) listRoyaltyReportOrders(ctx echo.Context) error {
 	if res.Status != http.StatusOK {
 		return echo.NewHTTPError(int(res.Status), res.Message)
 	}
+	if res.Data != nil {
+		page := common.NewPage(common.ExtractCursorContext(ctx), int64(res.Data.Count), res.Data.Items)
+		common.SetPaginationLinks(ctx, h.cfg.PaginationSecret(), page)
+	}
 	return ctx.JSON(http.StatusOK, res.Data)
 }
 
@@ -140,6 +150,7 @@ func (h *RoyaltyReportsRoute) merchantReviewRoyaltyReport(ctx echo.Context) erro
 	if res.Status != http.StatusOK {
 		return echo.NewHTTPError(int(res.Status), res.Message)
 	}
+	h.publishReportEvent(ctx, req.ReportId, webhook.EventRoyaltyReportAccepted)
 	return ctx.NoContent(http.StatusNoContent)
 }
 
@@ -170,6 +181,7 @@ func (h *RoyaltyReportsRoute) merchantDeclineRoyaltyReport(ctx echo.Context) err
 	if res.Status != http.StatusOK {
 		return echo.NewHTTPError(int(res.Status), res.Message)
 	}
+	h.publishReportEvent(ctx, req.ReportId, webhook.EventRoyaltyReportStatusChanged)
 	return ctx.NoContent(http.StatusNoContent)
 }
 
@@ -204,5 +216,79 @@ func (h *RoyaltyReportsRoute) changeRoyaltyReport(ctx echo.Context) error {
 	if res.Status != http.StatusOK {
 		return echo.NewHTTPError(int(res.Status), res.Message)
 	}
+	h.publishReportEvent(ctx, req.ReportId, webhook.EventRoyaltyReportStatusChanged)
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// Generate royalty reports of the merchants for the last period, reports of all merchants are generated if the list is empty
+// POST /admin/api/v1/royalty_reports
+//
+// @Example curl -X POST -H "Accept: application/json" -H "Content-Type: application/json" \
+//      -H "Authorization: Bearer %access_token_here%" \
+//      -d '{"merchants": ["5ced34d689fce60bf4440829"]}' \
+//      https://api.paysuper.online/admin/api/v1/royalty_reports
+func (h *RoyaltyReportsRoute) createRoyaltyReports(ctx echo.Context) error {
+	req := &grpc.CreateRoyaltyReportRequest{}
+	err := ctx.Bind(req)
+
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, common.NewValidationError(err.Error()))
+	}
+
+	res, err := h.dispatch.Services.Billing.CreateRoyaltyReport(ctx.Request().Context(), req)
+	if err != nil {
+		common.LogSrvCallFailedGRPC(h.L(), err, pkg.ServiceName, "CreateRoyaltyReport", req)
+		return echo.NewHTTPError(http.StatusInternalServerError, common.ErrorUnknown)
+	}
+
+	merchants := req.Merchants
+	if res != nil && len(res.Merchants) > 0 {
+		merchants = res.Merchants
+	}
+	for _, merchantId := range merchants {
+		h.publishGeneratedEvent(ctx, merchantId)
+	}
+
 	return ctx.NoContent(http.StatusNoContent)
 }
+
+// publishGeneratedEvent sends the webhook event with the newest pending report of the merchant
+func (h *RoyaltyReportsRoute) publishGeneratedEvent(ctx echo.Context, merchantId string) {
+	req := &grpc.ListRoyaltyReportsRequest{
+		MerchantId: merchantId,
+		Status:     []string{pkg.RoyaltyReportStatusPending},
+		Limit:      1,
+	}
+	res, err := h.dispatch.Services.Billing.ListRoyaltyReports(ctx.Request().Context(), req)
+	if err != nil {
+		common.LogSrvCallFailedGRPC(h.L(), err, pkg.ServiceName, "ListRoyaltyReports", req)
+		return
+	}
+	if res.Status != http.StatusOK || res.Data == nil || len(res.Data.Items) == 0 {
+		return
+	}
+
+	report := res.Data.Items[0]
+	h.dispatch.Webhooks.Publish(merchantId, webhook.EventRoyaltyReportGenerated, map[string]interface{}{
+		"report_id": report.Id,
+		"status":    report.Status,
+	})
+}
+
+// publishReportEvent sends the webhook event with the current state of the report to the merchant
+func (h *RoyaltyReportsRoute) publishReportEvent(ctx echo.Context, reportId, event string) {
+	req := &grpc.GetRoyaltyReportRequest{ReportId: reportId}
+	res, err := h.dispatch.Services.Billing.GetRoyaltyReport(ctx.Request().Context(), req)
+	if err != nil {
+		common.LogSrvCallFailedGRPC(h.L(), err, pkg.ServiceName, "GetRoyaltyReport", req)
+		return
+	}
+	if res.Status != http.StatusOK || res.Item == nil || res.Item.MerchantId == "" {
+		return
+	}
+
+	h.dispatch.Webhooks.Publish(res.Item.MerchantId, event, map[string]interface{}{
+		"report_id": res.Item.Id,
+		"status":    res.Item.Status,
+	})
+}
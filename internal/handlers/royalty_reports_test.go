@@ -111,6 +111,21 @@ func (suite *RoyaltyReportsTestSuite) TestRoyaltyReports_listRoyaltyReportOrders
 	}
 }
 
+func (suite *RoyaltyReportsTestSuite) TestRoyaltyReports_createRoyaltyReports() {
+	bodyJson := `{"merchants": ["5ced34d689fce60bf444082b"]}`
+
+	res, err := suite.caller.Builder().
+		Method(http.MethodPost).
+		Path(common.AuthUserGroupPath + royaltyReportsPath).
+		Init(test.ReqInitJSON()).
+		BodyString(bodyJson).
+		Exec(suite.T())
+
+	if assert.NoError(suite.T(), err) {
+		assert.Equal(suite.T(), http.StatusNoContent, res.Code)
+	}
+}
+
 func (suite *RoyaltyReportsTestSuite) TestRoyaltyReports_MerchantReviewRoyaltyReport() {
 
 	res, err := suite.caller.Builder().
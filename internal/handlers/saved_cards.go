@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"context"
+	"github.com/ProtocolONE/go-core/v2/pkg/logger"
+	"github.com/ProtocolONE/go-core/v2/pkg/provider"
+	"github.com/labstack/echo/v4"
+	"github.com/micro/go-micro/client"
+	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
+	"github.com/paysuper/paysuper-recurring-repository/pkg/constant"
+	"github.com/paysuper/paysuper-recurring-repository/pkg/proto/entity"
+	"github.com/paysuper/paysuper-recurring-repository/pkg/proto/repository"
+	"net/http"
+)
+
+const (
+	savedCardsPath              = "/saved_cards"
+	savedCardsIdPath            = "/saved_cards/:id"
+	savedCardsIdDefaultPath     = "/saved_cards/:id/default"
+	customerSavedCardsAdminPath = "/projects/:project_id/customers/:customer_id/saved_cards"
+)
+
+// SavedCardExpire
+type SavedCardExpire struct {
+	Month string `json:"month"`
+	Year  string `json:"year"`
+}
+
+// SavedCard is a saved payment instrument of the customer, the full card data is never returned
+type SavedCard struct {
+	Id        string           `json:"id"`
+	MaskedPan string           `json:"masked_pan"`
+	Expire    *SavedCardExpire `json:"expire"`
+	IsDefault bool             `json:"is_default"`
+}
+
+// savedCardRepository is a part of the recurring repository service used by the saved cards api
+type savedCardRepository interface {
+	FindSavedCards(ctx context.Context, in *repository.SavedCardRequest, opts ...client.CallOption) (*repository.SavedCardList, error)
+	DeleteSavedCard(ctx context.Context, in *repository.DeleteSavedCardRequest, opts ...client.CallOption) (*repository.DeleteSavedCardResponse, error)
+}
+
+type SavedCardsRoute struct {
+	dispatch   common.HandlerSet
+	cfg        common.Config
+	repository savedCardRepository
+	provider.LMT
+}
+
+func NewSavedCardsRoute(set common.HandlerSet, cfg *common.Config) *SavedCardsRoute {
+	set.AwareSet.Logger = set.AwareSet.Logger.WithFields(logger.Fields{"router": "SavedCardsRoute"})
+	return &SavedCardsRoute{
+		dispatch:   set,
+		LMT:        &set.AwareSet,
+		cfg:        *cfg,
+		repository: set.Services.Repository,
+	}
+}
+
+func (h *SavedCardsRoute) Route(groups *common.Groups) {
+	groups.AuthProject.GET(savedCardsPath, h.listSavedCards)
+	groups.AuthProject.DELETE(savedCardsIdPath, h.deleteSavedCard)
+	groups.AuthProject.PUT(savedCardsIdDefaultPath, h.setDefaultSavedCard)
+
+	groups.AuthUser.DELETE(customerSavedCardsAdminPath, h.revokeCustomerSavedCards)
+}
+
+// Get saved cards of the customer, customer is identified by the token
+// GET /api/v1/saved_cards
+//
+// @Example curl -X GET -H "X-Customer-Token: %customer_token_here%" \
+//      https://api.paysuper.online/api/v1/saved_cards
+func (h *SavedCardsRoute) listSavedCards(ctx echo.Context) error {
+	token, err := h.getCustomerToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	cards, err := h.findCustomerSavedCards(ctx, token.ProjectId, token.CustomerId)
+	if err != nil {
+		return err
+	}
+
+	defaultId, err := h.dispatch.Customers.GetDefaultSavedCard(token.ProjectId, token.CustomerId)
+	if err != nil {
+		h.L().Error(common.InternalErrorTemplate, logger.PairArgs("err", err.Error()))
+		return echo.NewHTTPError(http.StatusInternalServerError, common.ErrorInternal)
+	}
+
+	items := make([]*SavedCard, len(cards))
+	for i, card := range cards {
+		items[i] = &SavedCard{
+			Id:        card.Id,
+			MaskedPan: card.MaskedPan,
+			IsDefault: card.Id == defaultId,
+		}
+		if card.Expire != nil {
+			items[i].Expire = &SavedCardExpire{Month: card.Expire.Month, Year: card.Expire.Year}
+		}
+	}
+
+	return ctx.JSON(http.StatusOK, items)
+}
+
+// Delete saved card of the customer
+// DELETE /api/v1/saved_cards/5ced34d689fce60bf4440829
+func (h *SavedCardsRoute) deleteSavedCard(ctx echo.Context) error {
+	token, err := h.getCustomerToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	card, err := h.findSavedCard(ctx, token, ctx.Param(common.RequestParameterId))
+	if err != nil {
+		return err
+	}
+
+	if err = h.removeSavedCard(ctx, card); err != nil {
+		return err
+	}
+
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// Set saved card as default payment instrument of the customer
+// PUT /api/v1/saved_cards/5ced34d689fce60bf4440829/default
+func (h *SavedCardsRoute) setDefaultSavedCard(ctx echo.Context) error {
+	token, err := h.getCustomerToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	card, err := h.findSavedCard(ctx, token, ctx.Param(common.RequestParameterId))
+	if err != nil {
+		return err
+	}
+
+	if err = h.dispatch.Customers.SetDefaultSavedCard(token.ProjectId, token.CustomerId, card.Id); err != nil {
+		h.L().Error(common.InternalErrorTemplate, logger.PairArgs("err", err.Error()))
+		return echo.NewHTTPError(http.StatusInternalServerError, common.ErrorInternal)
+	}
+
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// Revoke all saved cards of the project customer by support, responds not found if the customer has no saved cards
+// DELETE /admin/api/v1/projects/5bdc39a95d1e1100019fb7df/customers/user_1/saved_cards
+func (h *SavedCardsRoute) revokeCustomerSavedCards(ctx echo.Context) error {
+	projectId := ctx.Param(common.RequestParameterProjectId)
+	customerId := ctx.Param(common.RequestParameterCustomerId)
+
+	if projectId == "" || customerId == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, common.ErrorIdIsEmpty)
+	}
+
+	cards, err := h.findCustomerSavedCards(ctx, projectId, customerId)
+	if err != nil {
+		return err
+	}
+
+	if len(cards) == 0 {
+		return echo.NewHTTPError(http.StatusNotFound, common.ErrorMessageSavedCardNotFound)
+	}
+
+	for _, card := range cards {
+		if err = h.removeSavedCard(ctx, card); err != nil {
+			return err
+		}
+	}
+
+	if err = h.dispatch.Customers.SetDefaultSavedCard(projectId, customerId, ""); err != nil {
+		h.L().Error(common.InternalErrorTemplate, logger.PairArgs("err", err.Error()))
+	}
+
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// getCustomerToken returns customer by the token from the header or the customer cookie
+func (h *SavedCardsRoute) getCustomerToken(ctx echo.Context) (*common.CustomerToken, error) {
+	value := ctx.Request().Header.Get(common.HeaderCustomerToken)
+	if value == "" {
+		if cookie, err := ctx.Cookie(common.CustomerTokenCookiesName); err == nil && cookie != nil {
+			value = cookie.Value
+		}
+	}
+
+	if value == "" {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, common.ErrorMessageCustomerTokenInvalid)
+	}
+
+	token, err := h.dispatch.Customers.GetToken(value)
+	if err != nil {
+		h.L().Error(common.InternalErrorTemplate, logger.PairArgs("err", err.Error()))
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, common.ErrorInternal)
+	}
+
+	// the saved cards are available to the customers identified by the project only
+	if token == nil || token.CustomerId == "" {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, common.ErrorMessageCustomerTokenInvalid)
+	}
+
+	return token, nil
+}
+
+// findCustomerSavedCards returns cards of the project customer saved under any billing customer bound to it
+func (h *SavedCardsRoute) findCustomerSavedCards(ctx echo.Context, projectId, customerId string) ([]*entity.SavedCard, error) {
+	users, err := h.dispatch.Customers.GetCustomerUsers(projectId, customerId)
+	if err != nil {
+		h.L().Error(common.InternalErrorTemplate, logger.PairArgs("err", err.Error()))
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, common.ErrorInternal)
+	}
+
+	var cards []*entity.SavedCard
+	found := make(map[string]bool)
+
+	for _, userId := range users {
+		userCards, err := h.findSavedCards(ctx, projectId, userId)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, card := range userCards {
+			if !found[card.Id] {
+				found[card.Id] = true
+				cards = append(cards, card)
+			}
+		}
+	}
+
+	return cards, nil
+}
+
+// findSavedCards returns cards of the project saved by the recurring repository under the billing customer
+func (h *SavedCardsRoute) findSavedCards(ctx echo.Context, projectId, userId string) ([]*entity.SavedCard, error) {
+	req := &repository.SavedCardRequest{Token: userId}
+	res, err := h.repository.FindSavedCards(ctx.Request().Context(), req)
+
+	if err != nil {
+		common.LogSrvCallFailedGRPC(h.L(), err, constant.PayOneRepositoryServiceName, "FindSavedCards", req)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, common.ErrorUnknown)
+	}
+
+	cards := make([]*entity.SavedCard, 0, len(res.SavedCards))
+	for _, card := range res.SavedCards {
+		if card.ProjectId == projectId {
+			cards = append(cards, card)
+		}
+	}
+
+	return cards, nil
+}
+
+func (h *SavedCardsRoute) findSavedCard(ctx echo.Context, token *common.CustomerToken, id string) (*entity.SavedCard, error) {
+	cards, err := h.findCustomerSavedCards(ctx, token.ProjectId, token.CustomerId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, card := range cards {
+		if card.Id == id {
+			return card, nil
+		}
+	}
+
+	return nil, echo.NewHTTPError(http.StatusNotFound, common.ErrorMessageSavedCardNotFound)
+}
+
+func (h *SavedCardsRoute) removeSavedCard(ctx echo.Context, card *entity.SavedCard) error {
+	req := &repository.DeleteSavedCardRequest{Id: card.Id, Token: card.Token}
+	res, err := h.repository.DeleteSavedCard(ctx.Request().Context(), req)
+
+	if err != nil {
+		common.LogSrvCallFailedGRPC(h.L(), err, constant.PayOneRepositoryServiceName, "DeleteSavedCard", req)
+		return echo.NewHTTPError(http.StatusInternalServerError, common.ErrorUnknown)
+	}
+
+	if res.Status != http.StatusOK {
+		return echo.NewHTTPError(int(res.Status), res.Message)
+	}
+
+	return nil
+}
@@ -0,0 +1,301 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/globalsign/mgo/bson"
+	"github.com/labstack/echo/v4"
+	"github.com/micro/go-micro/client"
+	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
+	"github.com/paysuper/paysuper-management-api/internal/mock"
+	"github.com/paysuper/paysuper-management-api/internal/test"
+	"github.com/paysuper/paysuper-recurring-repository/pkg/proto/entity"
+	"github.com/paysuper/paysuper-recurring-repository/pkg/proto/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type savedCardRepositoryMock struct {
+	cards   []*entity.SavedCard
+	deleted []string
+}
+
+func (m *savedCardRepositoryMock) FindSavedCards(ctx context.Context, in *repository.SavedCardRequest, opts ...client.CallOption) (*repository.SavedCardList, error) {
+	list := &repository.SavedCardList{}
+	for _, card := range m.cards {
+		if card.Token == in.Token {
+			list.SavedCards = append(list.SavedCards, card)
+		}
+	}
+	return list, nil
+}
+
+func (m *savedCardRepositoryMock) DeleteSavedCard(ctx context.Context, in *repository.DeleteSavedCardRequest, opts ...client.CallOption) (*repository.DeleteSavedCardResponse, error) {
+	m.deleted = append(m.deleted, in.Id)
+	return &repository.DeleteSavedCardResponse{Status: http.StatusOK}, nil
+}
+
+type SavedCardsTestSuite struct {
+	suite.Suite
+	router     *SavedCardsRoute
+	caller     *test.EchoReqResCaller
+	repository *savedCardRepositoryMock
+	token      *common.CustomerToken
+	userId     string
+}
+
+func Test_SavedCards(t *testing.T) {
+	suite.Run(t, new(SavedCardsTestSuite))
+}
+
+func (suite *SavedCardsTestSuite) SetupTest() {
+	var e error
+	settings := test.DefaultSettings()
+	srv := common.Services{
+		Billing: mock.NewBillingServerOkMock(),
+	}
+	suite.caller, e = test.SetUp(settings, srv, func(set *test.TestSet, mw test.Middleware) common.Handlers {
+		suite.router = NewSavedCardsRoute(set.HandlerSet, set.GlobalConfig)
+		return common.Handlers{
+			suite.router,
+		}
+	})
+	if e != nil {
+		panic(e)
+	}
+
+	suite.token = &common.CustomerToken{
+		Token:      bson.NewObjectId().Hex(),
+		ProjectId:  bson.NewObjectId().Hex(),
+		CustomerId: "customer_1",
+		ExpiresAt:  time.Now().Add(time.Hour),
+	}
+	assert.NoError(suite.T(), suite.router.dispatch.Customers.SaveToken(suite.token))
+
+	// the cards are saved by the recurring repository under the billing customer learned from the payment form
+	suite.userId = bson.NewObjectId().Hex()
+	assert.NoError(suite.T(), suite.router.dispatch.Customers.AddCustomerUser(suite.token.ProjectId, suite.token.CustomerId, suite.userId))
+
+	suite.repository = &savedCardRepositoryMock{
+		cards: []*entity.SavedCard{
+			{
+				Id:          bson.NewObjectId().Hex(),
+				Token:       suite.userId,
+				ProjectId:   suite.token.ProjectId,
+				MaskedPan:   "400000******0002",
+				RecurringId: "recurring_1",
+				Expire:      &entity.CardExpire{Month: "12", Year: "2030"},
+			},
+			{
+				Id:        bson.NewObjectId().Hex(),
+				Token:     suite.userId,
+				ProjectId: bson.NewObjectId().Hex(),
+				MaskedPan: "555555******4444",
+				Expire:    &entity.CardExpire{Month: "01", Year: "2031"},
+			},
+		},
+	}
+	suite.router.repository = suite.repository
+}
+
+func (suite *SavedCardsTestSuite) TearDownTest() {}
+
+func (suite *SavedCardsTestSuite) TestSavedCards_List_Ok() {
+	res, err := suite.caller.Builder().
+		Path(common.AuthProjectGroupPath + savedCardsPath).
+		Init(func(request *http.Request, middleware test.Middleware) {
+			request.Header.Set(common.HeaderCustomerToken, suite.token.Token)
+		}).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+	assert.NotContains(suite.T(), res.Body.String(), "recurring_1")
+
+	var cards []*SavedCard
+	assert.NoError(suite.T(), json.Unmarshal(res.Body.Bytes(), &cards))
+	assert.Len(suite.T(), cards, 1)
+	assert.Equal(suite.T(), "400000******0002", cards[0].MaskedPan)
+	assert.Equal(suite.T(), "12", cards[0].Expire.Month)
+	assert.False(suite.T(), cards[0].IsDefault)
+}
+
+func (suite *SavedCardsTestSuite) TestSavedCards_List_Cookie() {
+	res, err := suite.caller.Builder().
+		Path(common.AuthProjectGroupPath + savedCardsPath).
+		AddCookie(&http.Cookie{Name: common.CustomerTokenCookiesName, Value: suite.token.Token}).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+}
+
+func (suite *SavedCardsTestSuite) TestSavedCards_List_TokenInvalid() {
+	_, err := suite.caller.Builder().
+		Path(common.AuthProjectGroupPath + savedCardsPath).
+		Init(func(request *http.Request, middleware test.Middleware) {
+			request.Header.Set(common.HeaderCustomerToken, "unknown")
+		}).
+		Exec(suite.T())
+
+	assert.Error(suite.T(), err)
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusUnauthorized, httpErr.Code)
+	assert.Equal(suite.T(), common.ErrorMessageCustomerTokenInvalid, httpErr.Message)
+}
+
+func (suite *SavedCardsTestSuite) TestSavedCards_SetDefault_Ok() {
+	cardId := suite.repository.cards[0].Id
+
+	res, err := suite.caller.Builder().
+		Method(http.MethodPut).
+		Params(":"+common.RequestParameterId, cardId).
+		Path(common.AuthProjectGroupPath + savedCardsIdDefaultPath).
+		Init(func(request *http.Request, middleware test.Middleware) {
+			request.Header.Set(common.HeaderCustomerToken, suite.token.Token)
+		}).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusNoContent, res.Code)
+
+	defaultId, err := suite.router.dispatch.Customers.GetDefaultSavedCard(suite.token.ProjectId, suite.token.CustomerId)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), cardId, defaultId)
+}
+
+func (suite *SavedCardsTestSuite) TestSavedCards_Delete_ForeignProject() {
+	_, err := suite.caller.Builder().
+		Method(http.MethodDelete).
+		Params(":"+common.RequestParameterId, suite.repository.cards[1].Id).
+		Path(common.AuthProjectGroupPath + savedCardsIdPath).
+		Init(func(request *http.Request, middleware test.Middleware) {
+			request.Header.Set(common.HeaderCustomerToken, suite.token.Token)
+		}).
+		Exec(suite.T())
+
+	assert.Error(suite.T(), err)
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusNotFound, httpErr.Code)
+	assert.Equal(suite.T(), common.ErrorMessageSavedCardNotFound, httpErr.Message)
+	assert.Empty(suite.T(), suite.repository.deleted)
+}
+
+func (suite *SavedCardsTestSuite) TestSavedCards_Delete_Ok() {
+	cardId := suite.repository.cards[0].Id
+
+	res, err := suite.caller.Builder().
+		Method(http.MethodDelete).
+		Params(":"+common.RequestParameterId, cardId).
+		Path(common.AuthProjectGroupPath + savedCardsIdPath).
+		Init(func(request *http.Request, middleware test.Middleware) {
+			request.Header.Set(common.HeaderCustomerToken, suite.token.Token)
+		}).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusNoContent, res.Code)
+	assert.Equal(suite.T(), []string{cardId}, suite.repository.deleted)
+}
+
+func (suite *SavedCardsTestSuite) TestSavedCards_Revoke_Ok() {
+	res, err := suite.caller.Builder().
+		Method(http.MethodDelete).
+		Params(
+			":"+common.RequestParameterProjectId, suite.token.ProjectId,
+			":"+common.RequestParameterCustomerId, suite.token.CustomerId,
+		).
+		Path(common.AuthUserGroupPath + customerSavedCardsAdminPath).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusNoContent, res.Code)
+	assert.Equal(suite.T(), []string{suite.repository.cards[0].Id}, suite.repository.deleted)
+}
+
+func (suite *SavedCardsTestSuite) TestSavedCards_List_UnboundUser() {
+	suite.repository.cards[0].Token = bson.NewObjectId().Hex()
+
+	res, err := suite.caller.Builder().
+		Path(common.AuthProjectGroupPath + savedCardsPath).
+		Init(func(request *http.Request, middleware test.Middleware) {
+			request.Header.Set(common.HeaderCustomerToken, suite.token.Token)
+		}).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+
+	var cards []*SavedCard
+	assert.NoError(suite.T(), json.Unmarshal(res.Body.Bytes(), &cards))
+	assert.Empty(suite.T(), cards)
+}
+
+func (suite *SavedCardsTestSuite) TestSavedCards_List_AnonymousFormToken() {
+	token := &common.CustomerToken{
+		Token:     bson.NewObjectId().Hex(),
+		ProjectId: suite.token.ProjectId,
+		UserId:    suite.userId,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	assert.NoError(suite.T(), suite.router.dispatch.Customers.SaveToken(token))
+
+	_, err := suite.caller.Builder().
+		Path(common.AuthProjectGroupPath + savedCardsPath).
+		AddCookie(&http.Cookie{Name: common.CustomerTokenCookiesName, Value: token.Token}).
+		Exec(suite.T())
+
+	assert.Error(suite.T(), err)
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusUnauthorized, httpErr.Code)
+}
+
+func (suite *SavedCardsTestSuite) TestSavedCards_Revoke_AllUsers() {
+	userId := bson.NewObjectId().Hex()
+	assert.NoError(suite.T(), suite.router.dispatch.Customers.AddCustomerUser(suite.token.ProjectId, suite.token.CustomerId, userId))
+
+	card := &entity.SavedCard{
+		Id:        bson.NewObjectId().Hex(),
+		Token:     userId,
+		ProjectId: suite.token.ProjectId,
+		MaskedPan: "510000******0008",
+	}
+	suite.repository.cards = append(suite.repository.cards, card)
+
+	res, err := suite.caller.Builder().
+		Method(http.MethodDelete).
+		Params(
+			":"+common.RequestParameterProjectId, suite.token.ProjectId,
+			":"+common.RequestParameterCustomerId, suite.token.CustomerId,
+		).
+		Path(common.AuthUserGroupPath + customerSavedCardsAdminPath).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusNoContent, res.Code)
+	assert.ElementsMatch(suite.T(), []string{suite.repository.cards[0].Id, card.Id}, suite.repository.deleted)
+}
+
+func (suite *SavedCardsTestSuite) TestSavedCards_Revoke_NotFound() {
+	_, err := suite.caller.Builder().
+		Method(http.MethodDelete).
+		Params(
+			":"+common.RequestParameterProjectId, suite.token.ProjectId,
+			":"+common.RequestParameterCustomerId, "customer_2",
+		).
+		Path(common.AuthUserGroupPath + customerSavedCardsAdminPath).
+		Exec(suite.T())
+
+	assert.Error(suite.T(), err)
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusNotFound, httpErr.Code)
+	assert.Equal(suite.T(), common.ErrorMessageSavedCardNotFound, httpErr.Message)
+	assert.Empty(suite.T(), suite.repository.deleted)
+}
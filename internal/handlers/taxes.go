@@ -37,7 +37,11 @@ func (h *TaxesRoute) Route(groups *common.Groups) {
 }
 
 func (h *TaxesRoute) getTaxes(ctx echo.Context) error {
-	req := h.bindGetTaxes(ctx)
+	req, err := h.bindGetTaxes(ctx)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, common.ErrorRequestParamsIncorrect)
+	}
+
 	res, err := h.dispatch.Services.Tax.GetRates(ctx.Request().Context(), req)
 
 	if err != nil {
@@ -48,7 +52,7 @@ func (h *TaxesRoute) getTaxes(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, res.Rates)
 }
 
-func (h *TaxesRoute) bindGetTaxes(ctx echo.Context) *tax_service.GetRatesRequest {
+func (h *TaxesRoute) bindGetTaxes(ctx echo.Context) (*tax_service.GetRatesRequest, error) {
 	structure := &tax_service.GetRatesRequest{}
 
 	params := ctx.QueryParams()
@@ -69,23 +73,11 @@ func (h *TaxesRoute) bindGetTaxes(ctx echo.Context) *tax_service.GetRatesRequest
 		structure.Zip = string(v[0])
 	}
 
-	if v, ok := params[common.RequestParameterLimit]; ok {
-		if i, err := strconv.ParseInt(v[0], 10, 32); err == nil {
-			structure.Limit = int32(i)
-		}
-	} else {
-		structure.Limit = h.cfg.LimitDefault
-	}
-
-	if v, ok := params[common.RequestParameterOffset]; ok {
-		if i, err := strconv.ParseInt(v[0], 10, 32); err == nil {
-			structure.Offset = int32(i)
-		}
-	} else {
-		structure.Offset = h.cfg.OffsetDefault
+	if err := common.NewPaginationBinder(h.cfg).Apply(structure, ctx); err != nil {
+		return nil, err
 	}
 
-	return structure
+	return structure, nil
 }
 
 func (h *TaxesRoute) setTax(ctx echo.Context) error {
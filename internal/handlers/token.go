@@ -8,6 +8,7 @@ import (
 	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
 	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
 	"net/http"
+	"time"
 )
 
 const (
@@ -64,5 +65,18 @@ func (h *TokenRoute) createToken(ctx echo.Context) error {
 		return echo.NewHTTPError(int(res.Status), res.Message)
 	}
 
+	if req.User != nil && req.User.Id != "" {
+		err = h.dispatch.Customers.SaveToken(&common.CustomerToken{
+			Token:      res.Token,
+			ProjectId:  req.Settings.ProjectId,
+			CustomerId: req.User.Id,
+			ExpiresAt:  time.Now().Add(h.cfg.CustomerTokenLifetime),
+		})
+
+		if err != nil {
+			h.L().Error(common.InternalErrorTemplate, logger.WithFields(logger.Fields{"err": err.Error()}))
+		}
+	}
+
 	return ctx.JSON(http.StatusOK, map[string]string{"token": res.Token})
 }
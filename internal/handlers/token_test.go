@@ -1,9 +1,12 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"github.com/globalsign/mgo/bson"
 	"github.com/labstack/echo/v4"
+	"github.com/micro/go-micro/client"
+	"github.com/paysuper/paysuper-billing-server/pkg"
 	"github.com/paysuper/paysuper-billing-server/pkg/proto/billing"
 	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
 	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
@@ -21,6 +24,25 @@ type TokenTestSuite struct {
 	caller *test.EchoReqResCaller
 }
 
+// signatureBillingMock accepts the requests signed by the "signature" only
+type signatureBillingMock struct {
+	grpc.BillingService
+}
+
+func (m *signatureBillingMock) CheckProjectRequestSignature(
+	ctx context.Context,
+	in *grpc.CheckProjectRequestSignatureRequest,
+	opts ...client.CallOption,
+) (*grpc.CheckProjectRequestSignatureResponse, error) {
+	if in.Signature != "signature" {
+		return &grpc.CheckProjectRequestSignatureResponse{
+			Status:  pkg.ResponseStatusBadData,
+			Message: &grpc.ResponseErrorMessage{Message: "request signature is invalid"},
+		}, nil
+	}
+	return m.BillingService.CheckProjectRequestSignature(ctx, in, opts...)
+}
+
 func Test_Customer(t *testing.T) {
 	suite.Run(t, new(TokenTestSuite))
 }
@@ -29,7 +51,7 @@ func (suite *TokenTestSuite) SetupTest() {
 	var e error
 	settings := test.DefaultSettings()
 	srv := common.Services{
-		Billing: mock.NewBillingServerOkMock(),
+		Billing: &signatureBillingMock{BillingService: mock.NewBillingServerOkMock()},
 	}
 	suite.caller, e = test.SetUp(settings, srv, func(set *test.TestSet, mw test.Middleware) common.Handlers {
 		suite.router = NewTokenRoute(set.HandlerSet, set.GlobalConfig)
@@ -99,6 +121,181 @@ func (suite *TokenTestSuite) TestToken_CreateToken_Ok() {
 	assert.NotEmpty(suite.T(), res.Body.String())
 }
 
+func (suite *TokenTestSuite) TestToken_CreateToken_IdempotencyKey_Replay() {
+	b, err := json.Marshal(suite.getTokenRequest(bson.NewObjectId().Hex(), 100))
+	assert.NoError(suite.T(), err)
+
+	reqInit := suite.getIdempotentReqInit(bson.NewObjectId().Hex())
+
+	res1, err := suite.caller.Builder().
+		Method(http.MethodPost).
+		Path(common.AuthProjectGroupPath + tokenPath).
+		Init(reqInit).
+		BodyBytes(b).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res1.Code)
+	assert.Empty(suite.T(), res1.Header().Get(common.HeaderIdempotentReplayed))
+
+	res2, err := suite.caller.Builder().
+		Method(http.MethodPost).
+		Path(common.AuthProjectGroupPath + tokenPath).
+		Init(reqInit).
+		BodyBytes(b).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res2.Code)
+	assert.Equal(suite.T(), "true", res2.Header().Get(common.HeaderIdempotentReplayed))
+	assert.Equal(suite.T(), res1.Body.String(), res2.Body.String())
+}
+
+func (suite *TokenTestSuite) TestToken_CreateToken_IdempotencyKey_Conflict() {
+	projectId := bson.NewObjectId().Hex()
+	reqInit := suite.getIdempotentReqInit(bson.NewObjectId().Hex())
+
+	b, err := json.Marshal(suite.getTokenRequest(projectId, 100))
+	assert.NoError(suite.T(), err)
+
+	res, err := suite.caller.Builder().
+		Method(http.MethodPost).
+		Path(common.AuthProjectGroupPath + tokenPath).
+		Init(reqInit).
+		BodyBytes(b).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+
+	b, err = json.Marshal(suite.getTokenRequest(projectId, 200))
+	assert.NoError(suite.T(), err)
+
+	_, err = suite.caller.Builder().
+		Method(http.MethodPost).
+		Path(common.AuthProjectGroupPath + tokenPath).
+		Init(reqInit).
+		BodyBytes(b).
+		Exec(suite.T())
+
+	assert.Error(suite.T(), err)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusUnprocessableEntity, httpErr.Code)
+	assert.Equal(suite.T(), common.ErrorMessageIdempotencyKeyConflict, httpErr.Message)
+}
+
+func (suite *TokenTestSuite) TestToken_CreateToken_IdempotencyKey_ForgedSignature() {
+	b, err := json.Marshal(suite.getTokenRequest(bson.NewObjectId().Hex(), 100))
+	assert.NoError(suite.T(), err)
+
+	key := bson.NewObjectId().Hex()
+
+	res, err := suite.caller.Builder().
+		Method(http.MethodPost).
+		Path(common.AuthProjectGroupPath + tokenPath).
+		Init(suite.getIdempotentReqInit(key)).
+		BodyBytes(b).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+
+	// saved response isn't returned to the request knowing the project and the key but not the secret of the project
+	res, err = suite.caller.Builder().
+		Method(http.MethodPost).
+		Path(common.AuthProjectGroupPath + tokenPath).
+		Init(func(request *http.Request, middleware test.Middleware) {
+			suite.getIdempotentReqInit(key)(request, middleware)
+			request.Header.Set(common.HeaderXApiSignatureHeader, "forged")
+		}).
+		BodyBytes(b).
+		Exec(suite.T())
+
+	assert.Error(suite.T(), err)
+	assert.Empty(suite.T(), res.Header().Get(common.HeaderIdempotentReplayed))
+
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
+}
+
+func (suite *TokenTestSuite) TestToken_CreateToken_RateLimit_ForgedProject() {
+	settings := test.DefaultSettings()
+	global := settings["dispatcher"].(map[string]interface{})["global"].(map[string]interface{})
+	global["rateLimit"] = map[string]interface{}{
+		"authProjectRate":  0.01,
+		"authProjectBurst": 1,
+	}
+	srv := common.Services{
+		Billing: &signatureBillingMock{BillingService: mock.NewBillingServerOkMock()},
+	}
+	caller, err := test.SetUp(settings, srv, func(set *test.TestSet, mw test.Middleware) common.Handlers {
+		return common.Handlers{
+			NewTokenRoute(set.HandlerSet, set.GlobalConfig),
+		}
+	})
+	assert.NoError(suite.T(), err)
+
+	b, err := json.Marshal(suite.getTokenRequest(bson.NewObjectId().Hex(), 100))
+	assert.NoError(suite.T(), err)
+
+	post := func(ip, signature string) error {
+		_, err := caller.Builder().
+			Method(http.MethodPost).
+			Path(common.AuthProjectGroupPath + tokenPath).
+			Init(func(request *http.Request, middleware test.Middleware) {
+				request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				request.Header.Set(echo.HeaderXRealIP, ip)
+				request.Header.Set(common.HeaderXApiSignatureHeader, signature)
+			}).
+			BodyBytes(b).
+			Exec(suite.T())
+		return err
+	}
+
+	// forged request of the project is throttled by the address only
+	err = post("10.0.0.1", "forged")
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusBadRequest, err.(*echo.HTTPError).Code)
+
+	assert.NoError(suite.T(), post("10.0.0.1", "signature"))
+	assert.NoError(suite.T(), post("10.0.0.2", "signature"))
+
+	err = post("10.0.0.1", "forged")
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusTooManyRequests, err.(*echo.HTTPError).Code)
+}
+
+func (suite *TokenTestSuite) getIdempotentReqInit(key string) func(*http.Request, test.Middleware) {
+	return func(request *http.Request, middleware test.Middleware) {
+		request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		request.Header.Set(common.HeaderXApiSignatureHeader, "signature")
+		request.Header.Set(common.HeaderIdempotencyKey, key)
+	}
+}
+
+func (suite *TokenTestSuite) getTokenRequest(projectId string, amount float64) *grpc.TokenRequest {
+	return &grpc.TokenRequest{
+		User: &billing.TokenUser{
+			Id: bson.NewObjectId().Hex(),
+			Email: &billing.TokenUserEmailValue{
+				Value: "test@unit.test",
+			},
+			Locale: &billing.TokenUserLocaleValue{
+				Value: "ru",
+			},
+		},
+		Settings: &billing.TokenSettings{
+			ProjectId:   projectId,
+			Currency:    "RUB",
+			Amount:      amount,
+			Description: "test payment",
+		},
+	}
+}
+
 func (suite *TokenTestSuite) TestToken_CreateToken_BindError() {
 	body := `{"user": "qwerty", "metadata": "qwerty"}`
 
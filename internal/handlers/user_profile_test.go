@@ -2,7 +2,6 @@ package handlers
 
 import (
 	"github.com/labstack/echo/v4"
-	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
 	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
 	"github.com/paysuper/paysuper-management-api/internal/mock"
 	"github.com/paysuper/paysuper-management-api/internal/test"
@@ -72,7 +71,7 @@ func (suite *UserProfileTestSuite) TestUserProfile_GetUserProfile_ValidationErro
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	msg, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	msg, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 	assert.Regexp(suite.T(), "ProfileId", msg.Details)
 }
@@ -172,7 +171,7 @@ func (suite *UserProfileTestSuite) TestUserProfile_SetUserProfile_ValidationErro
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	err1, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	err1, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
 
 	assert.Regexp(suite.T(), "UserId", err1.Details)
@@ -194,9 +193,10 @@ func (suite *UserProfileTestSuite) TestUserProfile_SetUserProfile_ValidationUser
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	err1, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	err1, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
-	assert.Equal(suite.T(), common.ErrorMessageIncorrectFirstName, err1)
+	assert.Equal(suite.T(), common.ErrorMessageIncorrectFirstName.Code, err1.Code)
+	assert.Equal(suite.T(), common.ErrorMessageIncorrectFirstName.Message, err1.Message)
 	assert.Regexp(suite.T(), "Name", err1.Details)
 }
 
@@ -216,9 +216,10 @@ func (suite *UserProfileTestSuite) TestUserProfile_SetUserProfile_ValidationUser
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	err1, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	err1, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
-	assert.Equal(suite.T(), common.ErrorMessageIncorrectPosition, err1)
+	assert.Equal(suite.T(), common.ErrorMessageIncorrectPosition.Code, err1.Code)
+	assert.Equal(suite.T(), common.ErrorMessageIncorrectPosition.Message, err1.Message)
 	assert.Regexp(suite.T(), "Position", err1.Details)
 }
 
@@ -245,9 +246,10 @@ func (suite *UserProfileTestSuite) TestUserProfile_SetUserProfile_ValidationAnnu
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	err1, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	err1, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
-	assert.Equal(suite.T(), common.ErrorMessageIncorrectAnnualIncome, err1)
+	assert.Equal(suite.T(), common.ErrorMessageIncorrectAnnualIncome.Code, err1.Code)
+	assert.Equal(suite.T(), common.ErrorMessageIncorrectAnnualIncome.Message, err1.Message)
 	assert.Regexp(suite.T(), "AnnualIncome", err1.Details)
 }
 
@@ -274,9 +276,10 @@ func (suite *UserProfileTestSuite) TestUserProfile_SetUserProfile_ValidationNumb
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	err1, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	err1, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
-	assert.Equal(suite.T(), common.ErrorMessageIncorrectNumberOfEmployees, err1)
+	assert.Equal(suite.T(), common.ErrorMessageIncorrectNumberOfEmployees.Code, err1.Code)
+	assert.Equal(suite.T(), common.ErrorMessageIncorrectNumberOfEmployees.Message, err1.Message)
 	assert.Regexp(suite.T(), "NumberOfEmployees", err1.Details)
 }
 
@@ -303,9 +306,10 @@ func (suite *UserProfileTestSuite) TestUserProfile_SetUserProfile_ValidationComp
 	assert.True(suite.T(), ok)
 	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
 
-	err1, ok := httpErr.Message.(*grpc.ResponseErrorMessage)
+	err1, ok := httpErr.Message.(*common.ResponseError)
 	assert.True(suite.T(), ok)
-	assert.Equal(suite.T(), common.ErrorMessageIncorrectCompanyName, err1)
+	assert.Equal(suite.T(), common.ErrorMessageIncorrectCompanyName.Code, err1.Code)
+	assert.Equal(suite.T(), common.ErrorMessageIncorrectCompanyName.Message, err1.Message)
 	assert.Regexp(suite.T(), "CompanyName", err1.Details)
 }
 
@@ -6,6 +6,7 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
 	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
+	"github.com/paysuper/paysuper-management-api/pkg/webhook"
 	"net/http"
 	"strings"
 )
@@ -138,5 +139,10 @@ func (h *VatReportsRoute) updateVatReportStatus(ctx echo.Context) error {
 	if res.Status != http.StatusOK {
 		return echo.NewHTTPError(int(res.Status), res.Message)
 	}
+	// vat reports are not bound to the merchant, the event is sent to the subscriptions receiving it explicitly
+	h.dispatch.Webhooks.Broadcast(webhook.EventVatReportStatusChanged, map[string]interface{}{
+		"report_id": req.Id,
+		"status":    req.Status,
+	})
 	return ctx.NoContent(http.StatusNoContent)
 }
@@ -7,10 +7,12 @@ import (
 	"github.com/paysuper/paysuper-billing-server/pkg/proto/grpc"
 	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
 	"net/http"
+	"strings"
 )
 
 const (
-	zipCodePath = "/zip"
+	zipCodePath         = "/zip"
+	zipCodeValidatePath = "/zip/validate"
 )
 
 type ZipCodeRoute struct {
@@ -30,19 +32,16 @@ func NewZipCodeRoute(set common.HandlerSet, cfg *common.Config) *ZipCodeRoute {
 
 func (h *ZipCodeRoute) Route(groups *common.Groups) {
 	groups.AuthProject.GET(zipCodePath, h.checkZip)
+	groups.AuthProject.GET(zipCodeValidatePath, h.validateZip)
 }
 
 func (h *ZipCodeRoute) checkZip(ctx echo.Context) error {
 	req := &grpc.FindByZipCodeRequest{}
 
-	if err := ctx.Bind(req); err != nil {
+	if err := common.NewPaginationBinder(h.cfg).Bind(req, ctx); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, common.ErrorRequestParamsIncorrect)
 	}
 
-	if req.Limit <= 0 {
-		req.Limit = h.cfg.LimitDefault
-	}
-
 	if err := h.dispatch.Validate.Struct(req); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, common.GetValidationError(err))
 	}
@@ -56,3 +55,23 @@ func (h *ZipCodeRoute) checkZip(ctx echo.Context) error {
 
 	return ctx.JSON(http.StatusOK, res)
 }
+
+// Validate zip code by the postal code format of the country, invalid zip code isn't an error of the request
+// GET /api/v1/zip/validate?country=GB&zip=sw1a1aa
+func (h *ZipCodeRoute) validateZip(ctx echo.Context) error {
+	req := &common.ZipValidateRequest{}
+
+	if err := ctx.Bind(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, common.ErrorRequestParamsIncorrect)
+	}
+
+	req.Country = strings.ToUpper(req.Country)
+
+	if err := h.dispatch.Validate.Struct(req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, common.GetValidationError(err))
+	}
+
+	zip, valid := common.ValidateZip(req.Country, req.Zip)
+
+	return ctx.JSON(http.StatusOK, &common.ZipValidateResponse{Country: req.Country, Zip: zip, Valid: valid})
+}
@@ -14,6 +14,197 @@ import (
 	"testing"
 )
 
+// zipCountries contains valid zip code as it's typed by the customer, its normalized form
+// and invalid zip code of each country of common.ZipRegexp
+var zipCountries = []struct {
+	country    string
+	zip        string
+	normalized string
+	invalid    string
+}{
+	{"AF", "1234", "1234", "12345"},
+	{"AX", "12345", "12345", "123456"},
+	{"AL", "1234", "1234", "12345"},
+	{"DZ", "12345", "12345", "123456"},
+	{"AS", "96799-1234", "96799-1234", "96799-12"},
+	{"AD", "ad500", "AD500", "AD5000"},
+	{"AI", "ai-2640", "AI-2640", "AI-2641"},
+	{"AR", "c1425dka", "C1425DKA", "C1425DK"},
+	{"AM", "1234", "1234", "12345"},
+	{"AC", "ascn1zz", "ASCN 1ZZ", "ASCN 2ZZ"},
+	{"AU", "1234", "1234", "12345"},
+	{"AT", "1234", "1234", "12345"},
+	{"AZ", "az1000", "AZ1000", "AZ100"},
+	{"BH", "317", "317", "31700"},
+	{"BD", "1234", "1234", "12345"},
+	{"BB", "bb11000", "BB11000", "BA11000"},
+	{"BY", "123456", "123456", "1234567"},
+	{"BE", "1234", "1234", "12345"},
+	{"BM", "hm02", "HM 02", "HM 0A"},
+	{"BT", "12345", "12345", "123456"},
+	{"BO", "1234", "1234", "12345"},
+	{"BA", "12345", "12345", "123456"},
+	{"BR", "01310-100", "01310-100", "01310-10"},
+	{"IO", "bbnd 1zz", "BBND 1ZZ", "BBND 2ZZ"},
+	{"VG", "vg1110", "VG1110", "VG111"},
+	{"BN", "bs8811", "BS8811", "BS881"},
+	{"BG", "1234", "1234", "12345"},
+	{"KH", "12345", "12345", "123456"},
+	{"CA", "k1a0b1", "K1A 0B1", "K1A 0BB"},
+	{"CV", "1234", "1234", "12345"},
+	{"KY", "ky1-1100", "KY1-1100", "KY1-110"},
+	{"TD", "12345", "12345", "123456"},
+	{"CL", "1234567", "1234567", "12345678"},
+	{"CN", "123456", "123456", "1234567"},
+	{"CX", "1234", "1234", "12345"},
+	{"CC", "1234", "1234", "12345"},
+	{"CO", "123456", "123456", "1234567"},
+	{"CR", "10101", "10101", "101010"},
+	{"HR", "12345", "12345", "123456"},
+	{"CU", "12345", "12345", "123456"},
+	{"CY", "1234", "1234", "12345"},
+	{"CZ", "11000", "110 00", "110 0A"},
+	{"DK", "1234", "1234", "12345"},
+	{"DO", "12345", "12345", "123456"},
+	{"EC", "123456", "123456", "1234567"},
+	{"SV", "1101", "1101", "1102"},
+	{"EG", "12345", "12345", "123456"},
+	{"EE", "12345", "12345", "123456"},
+	{"ET", "1234", "1234", "12345"},
+	{"FK", "fiqq1zz", "FIQQ 1ZZ", "FIQQ 2ZZ"},
+	{"FO", "123", "123", "1234"},
+	{"FI", "12345", "12345", "123456"},
+	{"FR", "12345", "12345", "123456"},
+	{"GF", "97300", "97300", "97400"},
+	{"PF", "98709", "98709", "97709"},
+	{"GA", "01  libreville  01", "01 LIBREVILLE 01", "01 LIBREVILLE"},
+	{"GE", "1234", "1234", "12345"},
+	{"DE", "12345", "12345", "123456"},
+	{"GI", "gx111aa", "GX11 1AA", "GX11 1AB"},
+	{"GR", "10431", "104 31", "104 3A"},
+	{"GL", "1234", "1234", "12345"},
+	{"GP", "97100", "97100", "97200"},
+	{"GU", "12345", "12345", "123456"},
+	{"GT", "12345", "12345", "123456"},
+	{"GG", "gy11aa", "GY1 1AA", "JE1 1AA"},
+	{"GW", "1234", "1234", "12345"},
+	{"HT", "1234", "1234", "12345"},
+	{"HM", "1234", "1234", "12345"},
+	{"HN", "12345", "12345", "123456"},
+	{"HU", "1234", "1234", "12345"},
+	{"IS", "123", "123", "1234"},
+	{"IN", "123456", "123456", "1234567"},
+	{"ID", "12345", "12345", "123456"},
+	{"IR", "11369-14411", "11369-14411", "11369-1441"},
+	{"IQ", "12345", "12345", "123456"},
+	{"IM", "im11aa", "IM1 1AA", "IM1 1A"},
+	{"IL", "6100000", "6100000", "610000"},
+	{"IT", "12345", "12345", "123456"},
+	{"JM", "10", "10", "100"},
+	{"JP", "100-0001", "100-0001", "100-00011"},
+	{"JE", "je24wd", "JE2 4WD", "JE2 4W"},
+	{"JO", "12345", "12345", "123456"},
+	{"KZ", "123456", "123456", "1234567"},
+	{"KE", "12345", "12345", "123456"},
+	{"KR", "12345", "12345", "123456"},
+	{"XK", "12345", "12345", "123456"},
+	{"KW", "12345", "12345", "123456"},
+	{"KG", "123456", "123456", "1234567"},
+	{"LV", "lv-1050", "LV-1050", "LV-105"},
+	{"LA", "12345", "12345", "123456"},
+	{"LB", "2038 3054", "2038 3054", "2038 305"},
+	{"LS", "123", "123", "1234"},
+	{"LR", "1234", "1234", "12345"},
+	{"LY", "12345", "12345", "123456"},
+	{"LI", "1234", "1234", "12345"},
+	{"LT", "lt-01100", "LT-01100", "LT-0110"},
+	{"LU", "1234", "1234", "12345"},
+	{"MK", "1234", "1234", "12345"},
+	{"MG", "123", "123", "1234"},
+	{"MV", "20026", "20026", "200260"},
+	{"MY", "12345", "12345", "123456"},
+	{"MT", "vlt1117", "VLT 1117", "VLT 111"},
+	{"MH", "12345", "12345", "123456"},
+	{"MQ", "97200", "97200", "97100"},
+	{"YT", "97600", "97600", "97500"},
+	{"MX", "12345", "12345", "123456"},
+	{"FM", "12345", "12345", "123456"},
+	{"MD", "md-2001", "MD-2001", "MD-200"},
+	{"MC", "98000", "98000", "99000"},
+	{"MN", "12345", "12345", "123456"},
+	{"ME", "12345", "12345", "123456"},
+	{"MS", "msr1110", "MSR 1110", "MSR 111"},
+	{"MA", "12345", "12345", "123456"},
+	{"MZ", "1234", "1234", "12345"},
+	{"MM", "12345", "12345", "123456"},
+	{"NA", "12345", "12345", "123456"},
+	{"NP", "12345", "12345", "123456"},
+	{"NL", "1012ab", "1012 AB", "1012 A"},
+	{"NC", "98800", "98800", "98900"},
+	{"NZ", "1234", "1234", "12345"},
+	{"NI", "12345", "12345", "123456"},
+	{"NE", "1234", "1234", "12345"},
+	{"NG", "123456", "123456", "1234567"},
+	{"NF", "1234", "1234", "12345"},
+	{"MP", "12345", "12345", "123456"},
+	{"NO", "1234", "1234", "12345"},
+	{"OM", "123", "123", "1234"},
+	{"PK", "12345", "12345", "123456"},
+	{"PW", "12345", "12345", "123456"},
+	{"PA", "123456", "123456", "1234567"},
+	{"PG", "123", "123", "1234"},
+	{"PY", "1234", "1234", "12345"},
+	{"PE", "12345", "12345", "123456"},
+	{"PH", "1234", "1234", "12345"},
+	{"PN", "pcrn1zz", "PCRN 1ZZ", "PCRN 2ZZ"},
+	{"PL", "00-950", "00-950", "00-95"},
+	{"PT", "1234", "1234", "12345"},
+	{"PR", "12345", "12345", "123456"},
+	{"RE", "97400", "97400", "97300"},
+	{"RO", "123456", "123456", "1234567"},
+	{"RU", "123456", "123456", "1234567"},
+	{"BL", "97133", "97133", "97134"},
+	{"SH", "stHl1zz", "STHL 1ZZ", "STHL 2ZZ"},
+	{"MF", "97150", "97150", "97151"},
+	{"PM", "97500", "97500", "97501"},
+	{"VC", "vc0100", "VC0100", "VC010"},
+	{"SM", "47890", "47890", "47900"},
+	{"SA", "11564-2345", "11564-2345", "11564-234"},
+	{"SN", "12345", "12345", "123456"},
+	{"RS", "12345", "12345", "123456"},
+	{"SG", "123456", "123456", "1234567"},
+	{"SK", "81101", "811 01", "811 0A"},
+	{"SI", "si-1000", "SI-1000", "SI-100"},
+	{"ZA", "1234", "1234", "12345"},
+	{"GS", "siqq1zz", "SIQQ 1ZZ", "SIQQ 2ZZ"},
+	{"ES", "12345", "12345", "123456"},
+	{"LK", "12345", "12345", "123456"},
+	{"SD", "12345", "12345", "123456"},
+	{"SZ", "h100", "H100", "H1000"},
+	{"SE", "11455", "114 55", "114 5A"},
+	{"CH", "1234", "1234", "12345"},
+	{"SJ", "1234", "1234", "12345"},
+	{"TW", "12345", "12345", "123456"},
+	{"TJ", "123456", "123456", "1234567"},
+	{"TH", "12345", "12345", "123456"},
+	{"TT", "123456", "123456", "1234567"},
+	{"TN", "1234", "1234", "12345"},
+	{"TR", "12345", "12345", "123456"},
+	{"TM", "123456", "123456", "1234567"},
+	{"TC", "tkca1zz", "TKCA 1ZZ", "TKCA 2ZZ"},
+	{"UA", "12345", "12345", "123456"},
+	{"GB", "sw1a1aa", "SW1A 1AA", "SW1A 1CA"},
+	{"US", "94105-1234", "94105-1234", "94105-12"},
+	{"UY", "12345", "12345", "123456"},
+	{"VI", "12345", "12345", "123456"},
+	{"UZ", "100000", "100 000", "100 00A"},
+	{"VA", "00120", "00120", "00121"},
+	{"VE", "1010 a", "1010 A", "1010 AB"},
+	{"VN", "123456", "123456", "1234567"},
+	{"WF", "98600", "98600", "98700"},
+	{"ZM", "12345", "12345", "123456"},
+}
+
 type ZipCodeTestSuite struct {
 	suite.Suite
 	router *ZipCodeRoute
@@ -98,6 +289,31 @@ func (suite *ZipCodeTestSuite) TestCheckZip_ValidateError() {
 	assert.Regexp(suite.T(), common.NewValidationError("Country"), httpErr.Message)
 }
 
+func (suite *ZipCodeTestSuite) TestCheckZip_ValidateErrorLocalized() {
+	q := make(url.Values)
+	q.Set("zip", "98")
+
+	res, err := suite.caller.Builder().
+		Path(common.AuthProjectGroupPath + zipCodePath).
+		SetQueryParams(q).
+		Init(func(request *http.Request, middleware test.Middleware) {
+			request.Header.Set(common.HeaderAcceptLanguage, "ru-RU,ru;q=0.9,en;q=0.8")
+		}).
+		Exec(suite.T())
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusBadRequest, res.Code)
+
+	rsp := make(map[string]interface{})
+	assert.NoError(suite.T(), json.Unmarshal(res.Body.Bytes(), &rsp))
+	assert.Equal(suite.T(), common.ErrorValidationFailed.Code, rsp["code"])
+	assert.Equal(suite.T(), "ошибка проверки данных", rsp["message"])
+
+	errs, ok := rsp["errors"].([]interface{})
+	assert.True(suite.T(), ok)
+	assert.Len(suite.T(), errs, 1)
+	assert.Equal(suite.T(), "Country", errs[0].(map[string]interface{})["field"])
+}
+
 func (suite *ZipCodeTestSuite) TestCheckZip_BillingServerError() {
 	q := make(url.Values)
 	q.Set("country", "US")
@@ -115,3 +331,102 @@ func (suite *ZipCodeTestSuite) TestCheckZip_BillingServerError() {
 	assert.Equal(suite.T(), http.StatusInternalServerError, httpErr.Code)
 	assert.Equal(suite.T(), common.ErrorUnknown, httpErr.Message)
 }
+
+func (suite *ZipCodeTestSuite) TestValidateZip_Countries() {
+	assert.Len(suite.T(), zipCountries, len(common.ZipRegexp))
+
+	for _, c := range zipCountries {
+		_, ok := common.ZipRegexp[c.country]
+		assert.True(suite.T(), ok, c.country)
+
+		zip, valid := common.ValidateZip(c.country, c.zip)
+		assert.True(suite.T(), valid, c.country)
+		assert.Equal(suite.T(), c.normalized, zip, c.country)
+
+		_, valid = common.ValidateZip(c.country, c.invalid)
+		assert.False(suite.T(), valid, c.country)
+	}
+}
+
+func (suite *ZipCodeTestSuite) TestValidateZip_Ok() {
+	q := make(url.Values)
+	q.Set("country", "gb")
+	q.Set("zip", " sw1a   1aa ")
+
+	res, err := suite.caller.Builder().
+		Path(common.AuthProjectGroupPath + zipCodeValidatePath).
+		SetQueryParams(q).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+
+	data := &common.ZipValidateResponse{}
+	assert.NoError(suite.T(), json.Unmarshal(res.Body.Bytes(), data))
+	assert.Equal(suite.T(), "GB", data.Country)
+	assert.Equal(suite.T(), "SW1A 1AA", data.Zip)
+	assert.True(suite.T(), data.Valid)
+}
+
+func (suite *ZipCodeTestSuite) TestValidateZip_Invalid() {
+	q := make(url.Values)
+	q.Set("country", "US")
+	q.Set("zip", "9810")
+
+	res, err := suite.caller.Builder().
+		Path(common.AuthProjectGroupPath + zipCodeValidatePath).
+		SetQueryParams(q).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+
+	data := &common.ZipValidateResponse{}
+	assert.NoError(suite.T(), json.Unmarshal(res.Body.Bytes(), data))
+	assert.False(suite.T(), data.Valid)
+}
+
+func (suite *ZipCodeTestSuite) TestValidateZip_UnknownCountry_Ok() {
+	q := make(url.Values)
+	q.Set("country", "CD")
+	q.Set("zip", "1234")
+
+	res, err := suite.caller.Builder().
+		Path(common.AuthProjectGroupPath + zipCodeValidatePath).
+		SetQueryParams(q).
+		Exec(suite.T())
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusOK, res.Code)
+	assert.Contains(suite.T(), res.Body.String(), `"valid":true`)
+}
+
+func (suite *ZipCodeTestSuite) TestValidateZip_ValidateError() {
+	q := make(url.Values)
+	q.Set("country", "USA")
+	q.Set("zip", "98101")
+
+	_, err := suite.caller.Builder().
+		Path(common.AuthProjectGroupPath + zipCodeValidatePath).
+		SetQueryParams(q).
+		Exec(suite.T())
+	assert.Error(suite.T(), err)
+
+	httpErr, ok := err.(*echo.HTTPError)
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), http.StatusBadRequest, httpErr.Code)
+	assert.Regexp(suite.T(), common.NewValidationError("Country"), httpErr.Message)
+}
+
+func (suite *ZipCodeTestSuite) TestValidateZip_Tag() {
+	addr := &common.BillingAddress{Country: "NL", Zip: common.NormalizeZip("NL", "1012ab")}
+	assert.NoError(suite.T(), suite.router.dispatch.Validate.Struct(addr))
+
+	addr.Zip = "1012"
+	err := suite.router.dispatch.Validate.Struct(addr)
+	assert.Error(suite.T(), err)
+
+	msg := common.GetValidationError(err)
+	assert.Equal(suite.T(), common.ErrorMessageIncorrectZip.Code, msg.Code)
+	assert.Regexp(suite.T(), "Zip", msg.Details)
+}
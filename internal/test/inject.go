@@ -14,6 +14,7 @@ import (
 	"github.com/paysuper/paysuper-management-api/internal/dispatcher"
 	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
 	"github.com/paysuper/paysuper-management-api/internal/validators"
+	"github.com/paysuper/paysuper-management-api/pkg/webhook"
 	"gopkg.in/go-playground/validator.v9"
 	"os"
 )
@@ -27,15 +28,24 @@ type TestSet struct {
 }
 
 // ProviderTestSet
-func ProviderTestSet(initial config.Initial, awareSet provider.AwareSet, srv common.Services, configurator config.Configurator, globalConfig *common.Config, validate *validator.Validate) (*TestSet, func(), error) {
+func ProviderTestSet(initial config.Initial, awareSet provider.AwareSet, srv common.Services, configurator config.Configurator, globalConfig *common.Config, validate *validator.Validate, webhookVerifier *common.WebhookVerifier) (*TestSet, func(), error) {
 	t := &TestSet{
 		AwareSet:     awareSet,
 		Configurator: configurator,
 		GlobalConfig: globalConfig,
 		HandlerSet: common.HandlerSet{
-			AwareSet: awareSet,
-			Validate: validate,
-			Services: srv,
+			AwareSet:         awareSet,
+			Validate:         validate,
+			Services:         srv,
+			Webhooks:         webhook.NewSender(webhook.NewMemoryStorage(), globalConfig.SenderConfig(), awareSet.Logger),
+			Customers:        common.NewCustomerMemoryStorage(),
+			Jobs:             globalConfig.NewJobManager(awareSet.Logger),
+			KeyStocks:        common.NewKeyStockMemoryStorage(),
+			Themes:           common.NewProjectThemeMemoryStorage(),
+			OrderEvents:      globalConfig.NewOrderEventBroker(),
+			InboundWebhooks:  common.NewInboundWebhookMemoryStorage(),
+			ProviderWebhooks: globalConfig.NewProviderWebhookRegistry(),
+			WebhookVerifier:  webhookVerifier,
 		},
 		Initial: initial,
 	}
@@ -64,6 +74,7 @@ func BuildTestSet(ctx context.Context, settings config.Settings, srv common.Serv
 			wire.Struct(new(provider.AwareSet), "*"),
 			validators.WireSet,
 			dispatcher.ProviderGlobalCfg,
+			dispatcher.ProviderWebhookVerifier,
 			dispatcher.ProviderValidators,
 		),
 	)
@@ -227,9 +227,10 @@ func (c *EchoReqResCaller) Request(method, target string, body io.Reader, init f
 		return
 	}
 	//
+	errorHandler := he.HTTPErrorHandler
 	he.HTTPErrorHandler = func(e error, context echo.Context) {
 		err = e
-		he.DefaultHTTPErrorHandler(e, context)
+		errorHandler(e, context)
 	}
 	//
 	he.ServeHTTP(resRec, req)
@@ -269,11 +270,15 @@ func DefaultSettings() map[string]interface{} {
 				"awsRegionReporter":            "eu-west-1",
 				"awsBucketReporterr":           "eu-west-1",
 				"customerTokenCookiesLifetime": "2592000s",
+				"paginationCursorSecret":       "unknown",
 				"auth1": map[string]interface{}{
 					"clientId":     "unknown",
 					"clientSecret": "unknown",
 					"redirectUrl":  "unknown",
 				},
+				"rateLimit": map[string]interface{}{
+					"disabled": true,
+				},
 			},
 		},
 	}
@@ -16,6 +16,7 @@ import (
 	"github.com/paysuper/paysuper-management-api/internal/dispatcher"
 	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
 	"github.com/paysuper/paysuper-management-api/internal/validators"
+	"github.com/paysuper/paysuper-management-api/pkg/webhook"
 	"gopkg.in/go-playground/validator.v9"
 	"os"
 )
@@ -68,7 +69,7 @@ func BuildTestSet(ctx context.Context, settings config.Settings, srv common.Serv
 		cleanup()
 		return nil, nil, err
 	}
-	validatorSet, cleanup7, err := validators.Provider(srv, awareSet)
+	webhookVerifier, cleanup7, err := dispatcher.ProviderWebhookVerifier(commonConfig)
 	if err != nil {
 		cleanup6()
 		cleanup5()
@@ -78,7 +79,7 @@ func BuildTestSet(ctx context.Context, settings config.Settings, srv common.Serv
 		cleanup()
 		return nil, nil, err
 	}
-	validate, cleanup8, err := dispatcher.ProviderValidators(validatorSet)
+	validatorSet, cleanup8, err := validators.Provider(srv, awareSet)
 	if err != nil {
 		cleanup7()
 		cleanup6()
@@ -89,7 +90,7 @@ func BuildTestSet(ctx context.Context, settings config.Settings, srv common.Serv
 		cleanup()
 		return nil, nil, err
 	}
-	testSet, cleanup9, err := ProviderTestSet(initial, awareSet, srv, configurator, commonConfig, validate)
+	validate, cleanup9, err := dispatcher.ProviderValidators(validatorSet)
 	if err != nil {
 		cleanup8()
 		cleanup7()
@@ -101,7 +102,21 @@ func BuildTestSet(ctx context.Context, settings config.Settings, srv common.Serv
 		cleanup()
 		return nil, nil, err
 	}
+	testSet, cleanup10, err := ProviderTestSet(initial, awareSet, srv, configurator, commonConfig, validate, webhookVerifier)
+	if err != nil {
+		cleanup9()
+		cleanup8()
+		cleanup7()
+		cleanup6()
+		cleanup5()
+		cleanup4()
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
 	return testSet, func() {
+		cleanup10()
 		cleanup9()
 		cleanup8()
 		cleanup7()
@@ -160,14 +175,26 @@ func BuildDispatcher(ctx context.Context, settings config.Settings, srv common.S
 		cleanup()
 		return nil, nil, err
 	}
+	webhookVerifier, cleanup7, err := dispatcher.ProviderWebhookVerifier(commonConfig)
+	if err != nil {
+		cleanup6()
+		cleanup5()
+		cleanup4()
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
 	jwtVerifier := dispatcher.ProviderJwtVerifier(commonConfig)
 	appSet := dispatcher.AppSet{
-		Handlers:    handlers,
-		Services:    srv,
-		JwtVerifier: jwtVerifier,
+		Handlers:        handlers,
+		Services:        srv,
+		JwtVerifier:     jwtVerifier,
+		WebhookVerifier: webhookVerifier,
 	}
-	dispatcherConfig, cleanup7, err := dispatcher.ProviderCfg(configurator)
+	dispatcherConfig, cleanup8, err := dispatcher.ProviderCfg(configurator)
 	if err != nil {
+		cleanup7()
 		cleanup6()
 		cleanup5()
 		cleanup4()
@@ -176,8 +203,9 @@ func BuildDispatcher(ctx context.Context, settings config.Settings, srv common.S
 		cleanup()
 		return nil, nil, err
 	}
-	dispatcherDispatcher, cleanup8, err := dispatcher.ProviderDispatcher(ctx, awareSet, appSet, dispatcherConfig, commonConfig)
+	dispatcherDispatcher, cleanup9, err := dispatcher.ProviderDispatcher(ctx, awareSet, appSet, dispatcherConfig, commonConfig)
 	if err != nil {
+		cleanup8()
 		cleanup7()
 		cleanup6()
 		cleanup5()
@@ -188,6 +216,7 @@ func BuildDispatcher(ctx context.Context, settings config.Settings, srv common.S
 		return nil, nil, err
 	}
 	return dispatcherDispatcher, func() {
+		cleanup9()
 		cleanup8()
 		cleanup7()
 		cleanup6()
@@ -210,15 +239,24 @@ type TestSet struct {
 }
 
 // ProviderTestSet
-func ProviderTestSet(initial config.Initial, awareSet provider.AwareSet, srv common.Services, configurator config.Configurator, globalConfig *common.Config, validate *validator.Validate) (*TestSet, func(), error) {
+func ProviderTestSet(initial config.Initial, awareSet provider.AwareSet, srv common.Services, configurator config.Configurator, globalConfig *common.Config, validate *validator.Validate, webhookVerifier *common.WebhookVerifier) (*TestSet, func(), error) {
 	t := &TestSet{
 		AwareSet:     awareSet,
 		Configurator: configurator,
 		GlobalConfig: globalConfig,
 		HandlerSet: common.HandlerSet{
-			AwareSet: awareSet,
-			Validate: validate,
-			Services: srv,
+			AwareSet:         awareSet,
+			Validate:         validate,
+			Services:         srv,
+			Webhooks:         webhook.NewSender(webhook.NewMemoryStorage(), globalConfig.SenderConfig(), awareSet.Logger),
+			Customers:        common.NewCustomerMemoryStorage(),
+			Jobs:             globalConfig.NewJobManager(awareSet.Logger),
+			KeyStocks:        common.NewKeyStockMemoryStorage(),
+			Themes:           common.NewProjectThemeMemoryStorage(),
+			OrderEvents:      globalConfig.NewOrderEventBroker(),
+			InboundWebhooks:  common.NewInboundWebhookMemoryStorage(),
+			ProviderWebhooks: globalConfig.NewProviderWebhookRegistry(),
+			WebhookVerifier:  webhookVerifier,
 		},
 		Initial: initial,
 	}
@@ -10,6 +10,7 @@ import (
 	"github.com/paysuper/paysuper-management-api/internal/dispatcher/common"
 	"github.com/ttacon/libphonenumber"
 	"gopkg.in/go-playground/validator.v9"
+	"reflect"
 	"regexp"
 )
 
@@ -53,7 +54,6 @@ var (
 	zipUsaRegexp      = regexp.MustCompile("^[0-9]{5}(?:-[0-9]{4})?$")
 	nameRegexp        = regexp.MustCompile("^[\\p{L}\\p{M} \\-\\']+$")
 	companyNameRegexp = regexp.MustCompile("^[\\p{L}\\p{M} \\-\\.0-9]+$")
-	zipGeneralRegexp  = regexp.MustCompile("^\\d{0,30}$")
 	swiftRegexp       = regexp.MustCompile("^[A-Z]{6}[A-Z0-9]{2}([A-Z0-9]{3})?$")
 	cityRegexp        = regexp.MustCompile("^[\\p{L}\\p{M} \\-\\.]+$")
 )
@@ -93,6 +93,28 @@ func (v *ValidatorSet) ZipUsaValidator(fl validator.FieldLevel) bool {
 	return zipUsaRegexp.MatchString(fl.Field().String())
 }
 
+// ZipValidator validates zip code by the format of the country from the sibling field named by the tag param,
+// e.g. zip=BillingCountry, the field is "Country" if the param is omitted
+func (v *ValidatorSet) ZipValidator(fl validator.FieldLevel) bool {
+	name := fl.Param()
+	if name == "" {
+		name = common.ZipCountryField
+	}
+
+	parent := reflect.Indirect(fl.Parent())
+	if parent.Kind() != reflect.Struct {
+		return false
+	}
+
+	country := parent.FieldByName(name)
+	if !country.IsValid() || country.Kind() != reflect.String {
+		return false
+	}
+
+	_, ok := common.ValidateZip(country.String(), fl.Field().String())
+	return ok
+}
+
 // NameValidator
 func (v *ValidatorSet) NameValidator(fl validator.FieldLevel) bool {
 	return nameRegexp.MatchString(fl.Field().String())
@@ -172,15 +194,11 @@ func (v *ValidatorSet) CompanyNameValidator(fl validator.FieldLevel) bool {
 func (v *ValidatorSet) MerchantCompanyValidator(sl validator.StructLevel) {
 	company := sl.Current().Interface().(billing.MerchantCompanyInfo)
 
-	reg := zipGeneralRegexp
-
-	if v, ok := common.ZipRegexp[company.Country]; ok {
-		reg = v
+	if company.Zip == "" && !common.IsZipRequired(company.Country) {
+		return
 	}
 
-	match := reg.MatchString(company.Zip)
-
-	if !match {
+	if _, ok := common.ValidateZip(company.Country, company.Zip); !ok {
 		sl.ReportError(company.Zip, "Zip", "zip", "zip", "")
 	}
 }
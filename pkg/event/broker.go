@@ -0,0 +1,106 @@
+package event
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	defaultBufferSize = 16
+)
+
+type subscription struct {
+	broker *memoryBroker
+	topic  string
+	ch     chan *Event
+	once   sync.Once
+}
+
+type memoryBroker struct {
+	mx     sync.Mutex
+	seq    uint64
+	buffer int
+	topics map[string]map[*subscription]struct{}
+}
+
+// NewMemoryBroker returns in-process broker, each subscriber buffers up to buffer events
+// and it's dropped if the buffer is full
+func NewMemoryBroker(buffer int) Broker {
+	if buffer <= 0 {
+		buffer = defaultBufferSize
+	}
+
+	return &memoryBroker{
+		buffer: buffer,
+		topics: make(map[string]map[*subscription]struct{}),
+	}
+}
+
+// Subscribe
+func (b *memoryBroker) Subscribe(topic string) Subscription {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	s := &subscription{broker: b, topic: topic, ch: make(chan *Event, b.buffer)}
+
+	subs, ok := b.topics[topic]
+	if !ok {
+		subs = make(map[*subscription]struct{})
+		b.topics[topic] = subs
+	}
+	subs[s] = struct{}{}
+
+	return s
+}
+
+// Publish
+func (b *memoryBroker) Publish(topic, typ string, data interface{}) *Event {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	b.seq++
+	e := &Event{Id: b.seq, Topic: topic, Type: typ, Data: data, CreatedAt: time.Now()}
+
+	for s := range b.topics[topic] {
+		select {
+		case s.ch <- e:
+		default:
+			// slow subscriber is dropped, so it's able to resubscribe and get the actual state
+			b.remove(s)
+		}
+	}
+
+	return e
+}
+
+// remove must be called under the lock of the broker
+func (b *memoryBroker) remove(s *subscription) {
+	subs, ok := b.topics[s.topic]
+	if !ok {
+		return
+	}
+
+	if _, ok := subs[s]; !ok {
+		return
+	}
+
+	delete(subs, s)
+	if len(subs) == 0 {
+		delete(b.topics, s.topic)
+	}
+	close(s.ch)
+}
+
+// C
+func (s *subscription) C() <-chan *Event {
+	return s.ch
+}
+
+// Close
+func (s *subscription) Close() {
+	s.once.Do(func() {
+		s.broker.mx.Lock()
+		defer s.broker.mx.Unlock()
+		s.broker.remove(s)
+	})
+}
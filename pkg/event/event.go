@@ -0,0 +1,42 @@
+package event
+
+import (
+	"time"
+)
+
+// Event is a message published to the subscribers of the topic
+type Event struct {
+	// Id is a sequence number of the event increasing within the broker
+	Id        uint64
+	Topic     string
+	Type      string
+	Data      interface{}
+	CreatedAt time.Time
+}
+
+// Subscription receives events of the topic until it's closed, the channel is closed when the subscription
+// is closed or dropped by the broker since the subscriber doesn't keep up with the events
+type Subscription interface {
+	// C returns channel of the events
+	C() <-chan *Event
+	// Close cancels the subscription
+	Close()
+}
+
+// Source delivers events of the topics to the subscribers
+type Source interface {
+	// Subscribe starts delivery of the events published after the call
+	Subscribe(topic string) Subscription
+}
+
+// Publisher publishes events to the subscribers of the topic
+type Publisher interface {
+	// Publish never blocks, it returns published event
+	Publish(topic, typ string, data interface{}) *Event
+}
+
+// Broker is a pluggable source and publisher of the events
+type Broker interface {
+	Source
+	Publisher
+}
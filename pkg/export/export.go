@@ -0,0 +1,186 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FormatCsv    = "csv"
+	FormatXlsx   = "xlsx"
+	FormatNdjson = "ndjson"
+)
+
+var ErrFormatUnsupported = errors.New("export format is not supported")
+
+// Writer writes table rows in the export format
+type Writer interface {
+	// WriteRow writes values of the row in the order of the columns
+	WriteRow(values []interface{}) error
+	// Flush writes buffered data to the underlying writer
+	Flush() error
+	// Close completes the document, it doesn't close the underlying writer
+	Close() error
+}
+
+// ContentType returns mime type of the format
+func ContentType(format string) string {
+	switch format {
+	case FormatCsv:
+		return "text/csv; charset=utf-8"
+	case FormatXlsx:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case FormatNdjson:
+		return "application/x-ndjson"
+	}
+	return ""
+}
+
+// NewWriter returns writer of the format, the header row is written immediately if the format has it
+func NewWriter(format string, w io.Writer, columns []string) (Writer, error) {
+	switch format {
+	case FormatCsv:
+		return newCsvWriter(w, columns)
+	case FormatXlsx:
+		return newXlsxWriter(w, columns)
+	case FormatNdjson:
+		return &ndjsonWriter{enc: json.NewEncoder(w), columns: columns}, nil
+	}
+	return nil, ErrFormatUnsupported
+}
+
+// Columns returns values of the columns from the object, column is a path of the json keys
+// separated by the dot (project.name), missing values are nil
+func Columns(object interface{}, columns []string) ([]interface{}, error) {
+	b, err := json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+
+	var data map[string]interface{}
+	if err = json.Unmarshal(b, &data); err != nil {
+		return nil, err
+	}
+
+	values := make([]interface{}, len(columns))
+	for i, column := range columns {
+		values[i] = lookup(data, strings.Split(column, "."))
+	}
+	return values, nil
+}
+
+func lookup(data map[string]interface{}, path []string) interface{} {
+	value, ok := data[path[0]]
+	if !ok {
+		return nil
+	}
+	if len(path) == 1 {
+		return value
+	}
+	if nested, ok := value.(map[string]interface{}); ok {
+		return lookup(nested, path[1:])
+	}
+	return nil
+}
+
+// FormatValue returns text representation of the value for the table formats,
+// protobuf timestamps are formatted as RFC3339
+func FormatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case map[string]interface{}:
+		if ts, ok := timestamp(v); ok {
+			return ts
+		}
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(b)
+}
+
+// timestamp formats the json representation of the protobuf timestamp
+func timestamp(v map[string]interface{}) (string, bool) {
+	seconds, ok := v["seconds"].(float64)
+	if !ok || len(v) > 2 {
+		return "", false
+	}
+	nanos, _ := v["nanos"].(float64)
+	return time.Unix(int64(seconds), int64(nanos)).UTC().Format(time.RFC3339), true
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCsvWriter(w io.Writer, columns []string) (*csvWriter, error) {
+	cw := &csvWriter{w: csv.NewWriter(w)}
+	if err := cw.w.Write(columns); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+// WriteRow
+func (c *csvWriter) WriteRow(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, value := range values {
+		record[i] = FormatValue(value)
+	}
+	return c.w.Write(record)
+}
+
+// Flush
+func (c *csvWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// Close
+func (c *csvWriter) Close() error {
+	return c.Flush()
+}
+
+type ndjsonWriter struct {
+	enc     *json.Encoder
+	columns []string
+}
+
+// WriteRow
+func (n *ndjsonWriter) WriteRow(values []interface{}) error {
+	row := make(map[string]interface{}, len(values))
+	for i, value := range values {
+		if m, ok := value.(map[string]interface{}); ok {
+			if ts, ok := timestamp(m); ok {
+				value = ts
+			}
+		}
+		row[n.columns[i]] = value
+	}
+	return n.enc.Encode(row)
+}
+
+// Flush
+func (n *ndjsonWriter) Flush() error {
+	return nil
+}
+
+// Close
+func (n *ndjsonWriter) Close() error {
+	return nil
+}
@@ -12,3 +12,9 @@ const (
 type Dispatcher interface {
 	Dispatch(http *echo.Echo) error
 }
+
+// Drainer is implemented by the dispatcher reporting readiness of the service,
+// it's marked as not ready before the shutdown, so the load balancer stops sending new requests
+type Drainer interface {
+	Drain()
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/ProtocolONE/go-core/v2/pkg/provider"
 	"github.com/labstack/echo/v4"
 	"net/http"
+	"time"
 )
 
 // HTTP
@@ -34,6 +35,16 @@ func (h *HTTP) ListenAndServe() (err error) {
 	go func() {
 		<-h.ctx.Done()
 		h.L().Info("context cancelled, shutdown is raised")
+
+		// in-flight and already routed requests are served during the drain period before the listener is closed
+		if drainer, ok := h.dispatcher.(Drainer); ok {
+			drainer.Drain()
+		}
+		if h.cfg.ShutdownDrain > 0 {
+			h.L().Info("draining for %v before shutdown", logger.Args(h.cfg.ShutdownDrain))
+			time.Sleep(h.cfg.ShutdownDrain)
+		}
+
 		if e := server.Shutdown(context.Background()); e != nil {
 			h.L().Error("graceful shutdown error, %v", logger.Args(e))
 		}
@@ -53,9 +64,12 @@ func (h *HTTP) ListenAndServe() (err error) {
 
 // Config
 type Config struct {
-	Debug   bool   `fallback:"shared.debug"`
-	Bind    string `required:"true"`
-	invoker *invoker.Invoker
+	Debug bool   `fallback:"shared.debug"`
+	Bind  string `required:"true"`
+	// ShutdownDrain is a time between failing readiness and closing the listener,
+	// it should exceed the time the readiness probe needs to remove the instance from the load balancer
+	ShutdownDrain time.Duration `default:"15s"`
+	invoker       *invoker.Invoker
 }
 
 // OnReload
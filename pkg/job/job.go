@@ -0,0 +1,71 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+var (
+	ErrNotFound = errors.New("job not found")
+	ErrFinished = errors.New("job is already finished")
+)
+
+// Func is a body of the job, the context is cancelled when the job is cancelled or the manager is stopped.
+// Returned value is saved as the result of the job, returned error fails the job
+type Func func(ctx context.Context, progress Progress) (interface{}, error)
+
+// Progress reports state of the running job
+type Progress interface {
+	// SetTotal sets number of the units of work
+	SetTotal(total int)
+	// Add adds number of the done units of work
+	Add(done int)
+	// Error adds error of the unit of work, errors don't fail the job
+	Error(message string)
+	// SetResult saves intermediate result of the job
+	SetResult(result interface{})
+}
+
+// Job is a long-running operation started by the user
+type Job struct {
+	Id         string          `json:"id"`
+	Type       string          `json:"type"`
+	OwnerId    string          `json:"owner_id"`
+	Status     string          `json:"status"`
+	Total      int             `json:"total"`
+	Done       int             `json:"done"`
+	Result     json.RawMessage `json:"result,omitempty"`
+	Errors     []string        `json:"errors,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	StartedAt  *time.Time      `json:"started_at,omitempty"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty"`
+}
+
+// Finished
+func (j *Job) Finished() bool {
+	return j.Status == StatusSucceeded || j.Status == StatusFailed || j.Status == StatusCancelled
+}
+
+// DecodeResult unmarshals result of the job to the value
+func (j *Job) DecodeResult(v interface{}) error {
+	if len(j.Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(j.Result, v)
+}
+
+func copyJob(j *Job) *Job {
+	cp := *j
+	cp.Errors = append([]string(nil), j.Errors...)
+	return &cp
+}
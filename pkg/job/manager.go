@@ -0,0 +1,222 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/ProtocolONE/go-core/v2/pkg/logger"
+	"github.com/globalsign/mgo/bson"
+	"sync"
+	"time"
+)
+
+// Config
+type Config struct {
+	// Concurrency is a number of the jobs running at the same time, other jobs wait in the queue
+	Concurrency int
+}
+
+// Manager runs jobs in background with the limited concurrency and keeps their state in the storage
+type Manager struct {
+	storage Storage
+	log     logger.Logger
+	slots   chan struct{}
+	ctx     context.Context
+	stop    context.CancelFunc
+	mx      sync.Mutex
+	running map[string]*run
+	wg      sync.WaitGroup
+}
+
+type run struct {
+	job       *Job
+	cancel    context.CancelFunc
+	cancelled bool
+}
+
+// NewManager
+func NewManager(storage Storage, cfg Config, log logger.Logger) *Manager {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+
+	return &Manager{
+		storage: storage,
+		log:     log,
+		slots:   make(chan struct{}, cfg.Concurrency),
+		ctx:     ctx,
+		stop:    stop,
+		running: make(map[string]*run),
+	}
+}
+
+// Submit queues the job of the user and returns it immediately
+func (m *Manager) Submit(typ, ownerId string, fn Func) (*Job, error) {
+	j := &Job{
+		Id:        bson.NewObjectId().Hex(),
+		Type:      typ,
+		OwnerId:   ownerId,
+		Status:    StatusQueued,
+		CreatedAt: time.Now(),
+	}
+
+	if err := m.storage.Save(j); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(m.ctx)
+	r := &run{job: j, cancel: cancel}
+
+	m.mx.Lock()
+	m.running[j.Id] = r
+	m.mx.Unlock()
+
+	m.wg.Add(1)
+	go m.run(ctx, r, fn)
+
+	return copyJob(j), nil
+}
+
+// Get
+func (m *Manager) Get(id string) (*Job, error) {
+	return m.storage.Get(id)
+}
+
+// Cancel requests cancellation of the queued or running job, the job is cancelled
+// when its function returns
+func (m *Manager) Cancel(id string) (*Job, error) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	r, ok := m.running[id]
+	if !ok {
+		if _, err := m.storage.Get(id); err != nil {
+			return nil, err
+		}
+		return nil, ErrFinished
+	}
+
+	r.cancelled = true
+	r.cancel()
+
+	return copyJob(r.job), nil
+}
+
+// Stop cancels all jobs and waits for them to finish
+func (m *Manager) Stop() {
+	m.stop()
+	m.wg.Wait()
+}
+
+// Wait waits for the submitted jobs to finish without cancelling them
+func (m *Manager) Wait() {
+	m.wg.Wait()
+}
+
+func (m *Manager) run(ctx context.Context, r *run, fn Func) {
+	defer m.wg.Done()
+	defer r.cancel()
+
+	select {
+	case m.slots <- struct{}{}:
+		defer func() { <-m.slots }()
+	case <-ctx.Done():
+		m.finish(r, nil, ctx.Err())
+		return
+	}
+
+	now := time.Now()
+	m.update(r, func(j *Job) {
+		j.Status = StatusRunning
+		j.StartedAt = &now
+	})
+
+	result, err := m.call(ctx, r, fn)
+	m.finish(r, result, err)
+}
+
+func (m *Manager) call(ctx context.Context, r *run, fn Func) (result interface{}, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("job panic: %v", rec)
+		}
+	}()
+
+	return fn(ctx, &progress{m: m, r: r})
+}
+
+func (m *Manager) finish(r *run, result interface{}, err error) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	now := time.Now()
+	j := r.job
+	j.FinishedAt = &now
+
+	if result != nil {
+		m.setResult(j, result)
+	}
+
+	switch {
+	case err != nil && (r.cancelled || m.ctx.Err() != nil):
+		j.Status = StatusCancelled
+	case err != nil:
+		j.Status = StatusFailed
+		j.Errors = append(j.Errors, err.Error())
+	default:
+		j.Status = StatusSucceeded
+	}
+
+	m.save(j)
+	delete(m.running, j.Id)
+}
+
+func (m *Manager) update(r *run, fn func(j *Job)) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	fn(r.job)
+	m.save(r.job)
+}
+
+func (m *Manager) setResult(j *Job, result interface{}) {
+	b, err := json.Marshal(result)
+	if err != nil {
+		m.log.Error("unable to marshal job result", logger.PairArgs("err", err.Error(), "job_id", j.Id))
+		return
+	}
+	j.Result = b
+}
+
+func (m *Manager) save(j *Job) {
+	if err := m.storage.Save(j); err != nil {
+		m.log.Error("unable to save job", logger.PairArgs("err", err.Error(), "job_id", j.Id))
+	}
+}
+
+type progress struct {
+	m *Manager
+	r *run
+}
+
+// SetTotal
+func (p *progress) SetTotal(total int) {
+	p.m.update(p.r, func(j *Job) { j.Total = total })
+}
+
+// Add
+func (p *progress) Add(done int) {
+	p.m.update(p.r, func(j *Job) { j.Done += done })
+}
+
+// Error
+func (p *progress) Error(message string) {
+	p.m.update(p.r, func(j *Job) { j.Errors = append(j.Errors, message) })
+}
+
+// SetResult
+func (p *progress) SetResult(result interface{}) {
+	p.m.update(p.r, func(j *Job) { p.m.setResult(j, result) })
+}
@@ -0,0 +1,64 @@
+package job
+
+import (
+	"sync"
+	"time"
+)
+
+// Storage keeps jobs and their state
+type Storage interface {
+	// Save creates or replaces the job
+	Save(j *Job) error
+	// Get returns ErrNotFound if the job is unknown
+	Get(id string) (*Job, error)
+}
+
+type memoryStorage struct {
+	mx       sync.Mutex
+	jobs     map[string]*Job
+	lifetime time.Duration
+	purged   time.Time
+}
+
+// NewMemoryStorage returns storage keeping jobs in the process memory, finished jobs are removed after the lifetime
+func NewMemoryStorage(lifetime time.Duration) Storage {
+	return &memoryStorage{
+		jobs:     make(map[string]*Job),
+		lifetime: lifetime,
+		purged:   time.Now(),
+	}
+}
+
+// Save
+func (s *memoryStorage) Save(j *Job) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.purge(time.Now())
+	s.jobs[j.Id] = copyJob(j)
+	return nil
+}
+
+// Get
+func (s *memoryStorage) Get(id string) (*Job, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	j, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copyJob(j), nil
+}
+
+func (s *memoryStorage) purge(now time.Time) {
+	if now.Sub(s.purged) < time.Minute {
+		return
+	}
+	for id, j := range s.jobs {
+		if j.FinishedAt != nil && now.Sub(*j.FinishedAt) > s.lifetime {
+			delete(s.jobs, id)
+		}
+	}
+	s.purged = now
+}
@@ -0,0 +1,194 @@
+package keyfile
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+const (
+	EncodingUtf8    = "utf-8"
+	EncodingUtf16LE = "utf-16le"
+	EncodingUtf16BE = "utf-16be"
+
+	LineEndingsNone  = "none"
+	LineEndingsLf    = "lf"
+	LineEndingsCrLf  = "crlf"
+	LineEndingsCr    = "cr"
+	LineEndingsMixed = "mixed"
+
+	// DefaultFormat is a format of the keys for platforms without their own format,
+	// the keys may contain spaces but not at the ends since the lines are trimmed
+	DefaultFormat = `^[\x21-\x7E][\x20-\x7E]{2,253}[\x21-\x7E]$`
+
+	examplesMax = 5
+)
+
+var (
+	ErrEncoding = errors.New("keyfile: file must be utf-8 or utf-16 text")
+
+	bomUtf8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUtf16LE = []byte{0xFF, 0xFE}
+	bomUtf16BE = []byte{0xFE, 0xFF}
+)
+
+// DefaultFormats are formats of the keys by identifier of the platform
+var DefaultFormats = map[string]string{
+	"steam":  `^[A-Z0-9]{5}-[A-Z0-9]{5}-[A-Z0-9]{5}(-[A-Z0-9]{5}-[A-Z0-9]{5})?$`,
+	"origin": `^[A-Z0-9]{4}(-[A-Z0-9]{4}){4}$`,
+	"uplay":  `^[A-Z0-9]{3,4}(-[A-Z0-9]{4}){3}$`,
+}
+
+// Format is a format of the keys of the platform
+type Format struct {
+	Pattern *regexp.Regexp
+	// UpperCase makes keys upper case before they are matched, so keys of the platform are case insensitive
+	UpperCase bool
+}
+
+// Line is a key found in the line of the file
+type Line struct {
+	Number int    `json:"line"`
+	Key    string `json:"key"`
+}
+
+// Report describes content of the file
+type Report struct {
+	Encoding          string  `json:"encoding"`
+	LineEndings       string  `json:"line_endings"`
+	Lines             int     `json:"lines"`
+	Blank             int     `json:"blank"`
+	Valid             int     `json:"valid"`
+	Duplicate         int     `json:"duplicate"`
+	Invalid           int     `json:"invalid"`
+	DuplicateExamples []*Line `json:"duplicate_examples"`
+	InvalidExamples   []*Line `json:"invalid_examples"`
+}
+
+// Parse decodes the file, skips blank lines and returns unique keys matching the format
+// with the report about the file content, invalid keys are reported as they are written in the file
+func Parse(data []byte, format *Format) ([]string, *Report, error) {
+	text, encoding, err := decode(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	report := &Report{
+		Encoding:          encoding,
+		LineEndings:       lineEndings(text),
+		DuplicateExamples: []*Line{},
+		InvalidExamples:   []*Line{},
+	}
+
+	text = strings.Replace(text, "\r\n", "\n", -1)
+	text = strings.Replace(text, "\r", "\n", -1)
+	text = strings.TrimSuffix(text, "\n")
+
+	var keys []string
+	seen := make(map[string]bool)
+
+	for i, line := range strings.Split(text, "\n") {
+		raw := strings.TrimSpace(line)
+		key := raw
+		if format.UpperCase {
+			key = strings.ToUpper(key)
+		}
+		report.Lines++
+
+		switch {
+		case key == "":
+			report.Blank++
+		case !format.Pattern.MatchString(key):
+			report.Invalid++
+			report.InvalidExamples = appendExample(report.InvalidExamples, i+1, raw)
+		case seen[key]:
+			report.Duplicate++
+			report.DuplicateExamples = appendExample(report.DuplicateExamples, i+1, key)
+		default:
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	report.Valid = len(keys)
+
+	return keys, report, nil
+}
+
+// Bytes returns file with the keys separated by the line feed
+func Bytes(keys []string) []byte {
+	return []byte(strings.Join(keys, "\n"))
+}
+
+func decode(data []byte) (string, string, error) {
+	var (
+		order    func(b []byte) uint16
+		encoding string
+	)
+
+	switch {
+	case bytes.HasPrefix(data, bomUtf8):
+		data = data[len(bomUtf8):]
+	case bytes.HasPrefix(data, bomUtf16LE):
+		order = func(b []byte) uint16 { return uint16(b[0]) | uint16(b[1])<<8 }
+		encoding = EncodingUtf16LE
+	case bytes.HasPrefix(data, bomUtf16BE):
+		order = func(b []byte) uint16 { return uint16(b[0])<<8 | uint16(b[1]) }
+		encoding = EncodingUtf16BE
+	}
+
+	if order == nil {
+		// utf-16 without byte order mark is recognized by zero bytes, they never appear in the text file
+		if !utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0 {
+			return "", "", ErrEncoding
+		}
+		return string(data), EncodingUtf8, nil
+	}
+
+	data = data[2:]
+	if len(data)%2 != 0 {
+		return "", "", ErrEncoding
+	}
+
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order(data[i*2:])
+	}
+
+	return string(utf16.Decode(units)), encoding, nil
+}
+
+func lineEndings(text string) string {
+	crlf := strings.Count(text, "\r\n")
+	lf := strings.Count(text, "\n") - crlf
+	cr := strings.Count(text, "\r") - crlf
+
+	found := 0
+	endings := LineEndingsNone
+
+	for _, v := range []struct {
+		count  int
+		ending string
+	}{{lf, LineEndingsLf}, {crlf, LineEndingsCrLf}, {cr, LineEndingsCr}} {
+		if v.count > 0 {
+			found++
+			endings = v.ending
+		}
+	}
+
+	if found > 1 {
+		return LineEndingsMixed
+	}
+
+	return endings
+}
+
+func appendExample(examples []*Line, number int, key string) []*Line {
+	if len(examples) >= examplesMax {
+		return examples
+	}
+	return append(examples, &Line{Number: number, Key: key})
+}
@@ -0,0 +1,80 @@
+package keyset
+
+import (
+	"github.com/globalsign/mgo/bson"
+	"time"
+)
+
+const (
+	fieldCreatedAt = "created_at"
+	fieldId        = "_id"
+)
+
+// Position is a position in the list sorted by the creation time and the id from the newest item,
+// the page starts after the item of the position, so the new items don't shift the pages as the offset does
+type Position struct {
+	CreatedAt time.Time
+	Id        string
+	// Backward selects the items before the item of the position, i.e. the newer ones
+	Backward bool
+}
+
+// Item is an item of the list paged by the position
+type Item interface {
+	Position() Position
+}
+
+// Query returns the mongo query with the condition of the position added
+func (p *Position) Query(query bson.M) bson.M {
+	op := "$lt"
+	if p.Backward {
+		op = "$gt"
+	}
+
+	cp := bson.M{}
+	for k, v := range query {
+		cp[k] = v
+	}
+
+	cp["$or"] = []bson.M{
+		{fieldCreatedAt: bson.M{op: p.CreatedAt}},
+		{fieldCreatedAt: p.CreatedAt, fieldId: bson.M{op: p.Id}},
+	}
+
+	return cp
+}
+
+// Sort returns the mongo sort of the page, the backward page is requested from the oldest item
+// and must be reversed by Reverse
+func (p *Position) Sort() []string {
+	if p != nil && p.Backward {
+		return []string{fieldCreatedAt, fieldId}
+	}
+	return []string{"-" + fieldCreatedAt, "-" + fieldId}
+}
+
+// Match checks that the item created at the time with the id is on the page of the position
+func (p *Position) Match(createdAt time.Time, id string) bool {
+	if createdAt.Equal(p.CreatedAt) {
+		if p.Backward {
+			return id > p.Id
+		}
+		return id < p.Id
+	}
+
+	if p.Backward {
+		return createdAt.After(p.CreatedAt)
+	}
+	return createdAt.Before(p.CreatedAt)
+}
+
+// Reverse reverses the items of the backward page, so the newest item comes first as on the other pages
+func (p *Position) Reverse(n int, swap func(i, j int)) {
+	if p == nil || !p.Backward {
+		return
+	}
+
+	for i, j := 0, n-1; i < j; i, j = i+1, j-1 {
+		swap(i, j)
+	}
+}
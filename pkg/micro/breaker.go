@@ -0,0 +1,314 @@
+package micro
+
+import (
+	"context"
+	"github.com/ProtocolONE/go-core/v2/pkg/logger"
+	"github.com/micro/go-micro/client"
+	"github.com/micro/go-micro/errors"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	ErrorIdCircuitOpen = "go.micro.client.circuit_open"
+)
+
+// readOnlyMethodPrefixes are prefixes of the methods which don't change anything, only they are retried
+// since the failed call of the other methods may be already processed by the service, e.g. the refund is created
+var readOnlyMethodPrefixes = []string{"Get", "Find", "List", "Check"}
+
+const (
+	circuitClosed = iota
+	circuitHalfOpen
+	circuitOpen
+)
+
+// CallPolicy describes timeout, retries and circuit breaker settings of the outbound calls.
+// Policy without Service is applied to all services, policy without Method to all methods of the service.
+// Zero values are inherited from the less specific policy. Retries are made for the read only methods only.
+type CallPolicy struct {
+	Service          string
+	Method           string
+	Timeout          time.Duration
+	Retries          int
+	FailureThreshold int
+	OpenTimeout      time.Duration
+}
+
+func (p *CallPolicy) merge(o CallPolicy) {
+	if o.Timeout > 0 {
+		p.Timeout = o.Timeout
+	}
+	if o.Retries > 0 {
+		p.Retries = o.Retries
+	}
+	if o.FailureThreshold > 0 {
+		p.FailureThreshold = o.FailureThreshold
+	}
+	if o.OpenTimeout > 0 {
+		p.OpenTimeout = o.OpenTimeout
+	}
+}
+
+type circuit struct {
+	state    int
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// Breaker keeps circuits of the called services and applies call policies
+type Breaker struct {
+	mx        sync.Mutex
+	defaults  CallPolicy
+	builtin   []CallPolicy
+	policies  []CallPolicy
+	circuits  map[string]*circuit
+	logger    logger.Logger
+	timeNowFn func() time.Time
+}
+
+// NewBreaker
+func NewBreaker(defaults CallPolicy, policies []CallPolicy, log logger.Logger) *Breaker {
+	return &Breaker{
+		defaults:  defaults,
+		policies:  policies,
+		circuits:  make(map[string]*circuit),
+		logger:    log,
+		timeNowFn: time.Now,
+	}
+}
+
+// AddPolicies adds built-in policies, policies from the config take precedence over them
+func (b *Breaker) AddPolicies(policies ...CallPolicy) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	b.builtin = append(b.builtin, policies...)
+}
+
+// SetPolicies replaces policies from the config, it's called on the config reload
+func (b *Breaker) SetPolicies(defaults CallPolicy, policies []CallPolicy) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	b.defaults = defaults
+	b.policies = policies
+}
+
+// Policy returns effective policy of the service method
+func (b *Breaker) Policy(service, method string) CallPolicy {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	policy := b.defaults
+	all := append(append([]CallPolicy{}, b.builtin...), b.policies...)
+
+	for _, p := range all {
+		if p.Service == "" && p.Method == "" {
+			policy.merge(p)
+		}
+	}
+	for _, p := range all {
+		if p.Service == service && p.Method == "" {
+			policy.merge(p)
+		}
+	}
+	for _, p := range all {
+		if (p.Service == service || p.Service == "") && p.Method != "" && p.Method == method {
+			policy.merge(p)
+		}
+	}
+
+	policy.Service = service
+	policy.Method = method
+	return policy
+}
+
+// allow returns false if the circuit of the service is open
+func (b *Breaker) allow(service string, policy CallPolicy) bool {
+	if policy.FailureThreshold <= 0 {
+		return true
+	}
+
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	c := b.circuit(service)
+	switch c.state {
+	case circuitOpen:
+		if b.timeNowFn().Sub(c.openedAt) < policy.OpenTimeout {
+			return false
+		}
+		b.setState(service, c, circuitHalfOpen)
+		c.probing = true
+		return true
+	case circuitHalfOpen:
+		// Only one probe call is allowed until it's finished
+		if c.probing {
+			return false
+		}
+		c.probing = true
+		return true
+	}
+
+	return true
+}
+
+// done records result of the call to the circuit of the service
+func (b *Breaker) done(service string, policy CallPolicy, failed bool) {
+	if policy.FailureThreshold <= 0 {
+		return
+	}
+
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	c := b.circuit(service)
+	c.probing = false
+
+	if !failed {
+		c.failures = 0
+		b.setState(service, c, circuitClosed)
+		return
+	}
+
+	c.failures++
+	if c.state == circuitHalfOpen || c.failures >= policy.FailureThreshold {
+		c.openedAt = b.timeNowFn()
+		b.setState(service, c, circuitOpen)
+	}
+}
+
+// release frees the probe slot of the call without result, e.g. cancelled by the caller, the circuit stays as it is
+func (b *Breaker) release(service string, policy CallPolicy) {
+	if policy.FailureThreshold <= 0 {
+		return
+	}
+
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	b.circuit(service).probing = false
+}
+
+func (b *Breaker) circuit(service string) *circuit {
+	c, ok := b.circuits[service]
+	if !ok {
+		c = &circuit{}
+		b.circuits[service] = c
+	}
+	return c
+}
+
+func (b *Breaker) setState(service string, c *circuit, state int) {
+	if c.state == state {
+		return
+	}
+	c.state = state
+	breakerState.WithLabelValues(service).Set(float64(state))
+
+	if state == circuitOpen && b.logger != nil {
+		b.logger.Error("circuit breaker is open", logger.PairArgs("service", service, "failures", c.failures))
+	}
+}
+
+type breakerClient struct {
+	client.Client
+	breaker *Breaker
+}
+
+// Call
+func (c *breakerClient) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
+	policy := c.breaker.Policy(req.Service(), req.Endpoint())
+
+	retries := policy.Retries
+	if !IsReadOnlyMethod(req.Endpoint()) {
+		retries = 0
+	}
+
+	var err error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if !c.breaker.allow(req.Service(), policy) {
+			MarkCircuitOpen(ctx)
+			breakerRejections.WithLabelValues(req.Service()).Inc()
+			return errors.New(ErrorIdCircuitOpen, "circuit breaker is open for "+req.Service(), http.StatusServiceUnavailable)
+		}
+
+		err = c.call(ctx, policy, req, rsp, opts...)
+
+		// Cancellation by the caller is neither failure nor success of the service
+		if ctx.Err() != nil {
+			c.breaker.release(req.Service(), policy)
+			return err
+		}
+
+		c.breaker.done(req.Service(), policy, err != nil)
+		if err == nil {
+			return nil
+		}
+	}
+
+	return err
+}
+
+// IsReadOnlyMethod returns true if the endpoint, e.g. "BillingService.GetOrder", is a method without side effects
+func IsReadOnlyMethod(endpoint string) bool {
+	method := endpoint[strings.LastIndex(endpoint, ".")+1:]
+	for _, prefix := range readOnlyMethodPrefixes {
+		if strings.HasPrefix(method, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *breakerClient) call(ctx context.Context, policy CallPolicy, req client.Request, rsp interface{}, opts ...client.CallOption) error {
+	// Timeout passed by the caller takes precedence over the policy
+	callOpts := client.CallOptions{}
+	for _, opt := range opts {
+		opt(&callOpts)
+	}
+	if policy.Timeout > 0 && callOpts.RequestTimeout == 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, policy.Timeout)
+		defer cancel()
+		opts = append(opts, client.WithRequestTimeout(policy.Timeout))
+	}
+	return c.Client.Call(ctx, req, rsp, opts...)
+}
+
+// NewBreakerClientWrapper returns client wrapper applying call policies of the breaker
+func NewBreakerClientWrapper(breaker *Breaker) client.Wrapper {
+	return func(c client.Client) client.Client {
+		return &breakerClient{Client: c, breaker: breaker}
+	}
+}
+
+type circuitContextKey struct{}
+
+// NewCircuitContext returns context which registers calls rejected by the open circuit
+func NewCircuitContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, circuitContextKey{}, new(int32))
+}
+
+// CircuitOpened returns true if any call with the context was rejected by the open circuit
+func CircuitOpened(ctx context.Context) bool {
+	if flag, ok := ctx.Value(circuitContextKey{}).(*int32); ok {
+		return atomic.LoadInt32(flag) > 0
+	}
+	return false
+}
+
+// IsCircuitOpen returns true if the error is a rejection of the open circuit
+func IsCircuitOpen(err error) bool {
+	return err != nil && errors.Parse(err.Error()).Id == ErrorIdCircuitOpen
+}
+
+// MarkCircuitOpen registers the call rejected by the open circuit in the context
+func MarkCircuitOpen(ctx context.Context) {
+	if flag, ok := ctx.Value(circuitContextKey{}).(*int32); ok {
+		atomic.StoreInt32(flag, 1)
+	}
+}
@@ -0,0 +1,185 @@
+package micro
+
+import (
+	"context"
+	"errors"
+	"github.com/micro/go-micro/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+const breakerTestService = "p1payone.billing"
+
+type breakerTestRequest struct {
+	client.Request
+	endpoint string
+}
+
+func (r *breakerTestRequest) Service() string {
+	return breakerTestService
+}
+
+func (r *breakerTestRequest) Endpoint() string {
+	return r.endpoint
+}
+
+// breakerTestClient fails the calls while err is set
+type breakerTestClient struct {
+	client.Client
+	calls int
+	err   error
+}
+
+func (c *breakerTestClient) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
+	c.calls++
+	return c.err
+}
+
+type BreakerTestSuite struct {
+	suite.Suite
+	now     time.Time
+	breaker *Breaker
+	service *breakerTestClient
+	client  client.Client
+}
+
+func Test_Breaker(t *testing.T) {
+	suite.Run(t, new(BreakerTestSuite))
+}
+
+func (suite *BreakerTestSuite) SetupTest() {
+	suite.now = time.Now()
+	suite.breaker = NewBreaker(CallPolicy{FailureThreshold: 2, OpenTimeout: time.Minute}, nil, nil)
+	suite.breaker.timeNowFn = func() time.Time { return suite.now }
+	suite.service = &breakerTestClient{err: errors.New("service unavailable")}
+	suite.client = NewBreakerClientWrapper(suite.breaker)(suite.service)
+}
+
+func (suite *BreakerTestSuite) TearDownTest() {}
+
+func (suite *BreakerTestSuite) call(endpoint string) error {
+	return suite.client.Call(context.Background(), &breakerTestRequest{endpoint: endpoint}, nil)
+}
+
+func (suite *BreakerTestSuite) open() {
+	for i := 0; i < 2; i++ {
+		assert.Error(suite.T(), suite.call("BillingService.GetOrder"))
+	}
+	assert.Equal(suite.T(), circuitOpen, suite.breaker.circuit(breakerTestService).state)
+}
+
+func (suite *BreakerTestSuite) TestBreaker_Open() {
+	assert.Error(suite.T(), suite.call("BillingService.GetOrder"))
+	assert.Equal(suite.T(), circuitClosed, suite.breaker.circuit(breakerTestService).state)
+
+	suite.open()
+
+	err := suite.call("BillingService.GetOrder")
+	assert.True(suite.T(), IsCircuitOpen(err))
+	assert.Equal(suite.T(), 2, suite.service.calls)
+}
+
+func (suite *BreakerTestSuite) TestBreaker_Open_CircuitContext() {
+	suite.open()
+
+	ctx := NewCircuitContext(context.Background())
+	assert.False(suite.T(), CircuitOpened(ctx))
+
+	err := suite.client.Call(ctx, &breakerTestRequest{endpoint: "BillingService.GetOrder"}, nil)
+	assert.True(suite.T(), IsCircuitOpen(err))
+	assert.True(suite.T(), CircuitOpened(ctx))
+}
+
+func (suite *BreakerTestSuite) TestBreaker_HalfOpen_SingleProbe() {
+	suite.open()
+	suite.now = suite.now.Add(time.Minute)
+
+	policy := suite.breaker.Policy(breakerTestService, "BillingService.GetOrder")
+	assert.True(suite.T(), suite.breaker.allow(breakerTestService, policy))
+	assert.Equal(suite.T(), circuitHalfOpen, suite.breaker.circuit(breakerTestService).state)
+
+	// the other calls are rejected until the probe is finished
+	assert.False(suite.T(), suite.breaker.allow(breakerTestService, policy))
+}
+
+func (suite *BreakerTestSuite) TestBreaker_HalfOpen_ProbeFailed() {
+	suite.open()
+	suite.now = suite.now.Add(time.Minute)
+
+	assert.False(suite.T(), IsCircuitOpen(suite.call("BillingService.GetOrder")))
+	assert.Equal(suite.T(), circuitOpen, suite.breaker.circuit(breakerTestService).state)
+	assert.True(suite.T(), IsCircuitOpen(suite.call("BillingService.GetOrder")))
+	assert.Equal(suite.T(), 3, suite.service.calls)
+}
+
+func (suite *BreakerTestSuite) TestBreaker_Close() {
+	suite.open()
+	suite.now = suite.now.Add(time.Minute)
+	suite.service.err = nil
+
+	assert.NoError(suite.T(), suite.call("BillingService.GetOrder"))
+	assert.Equal(suite.T(), circuitClosed, suite.breaker.circuit(breakerTestService).state)
+	assert.Equal(suite.T(), 0, suite.breaker.circuit(breakerTestService).failures)
+	assert.NoError(suite.T(), suite.call("BillingService.GetOrder"))
+}
+
+func (suite *BreakerTestSuite) TestBreaker_Cancelled_NotFailure() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for i := 0; i < 3; i++ {
+		assert.Error(suite.T(), suite.client.Call(ctx, &breakerTestRequest{endpoint: "BillingService.GetOrder"}, nil))
+	}
+	assert.Equal(suite.T(), circuitClosed, suite.breaker.circuit(breakerTestService).state)
+}
+
+func (suite *BreakerTestSuite) TestBreaker_HalfOpen_ProbeCancelled() {
+	suite.open()
+	suite.now = suite.now.Add(time.Minute)
+	suite.service.err = nil
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.False(suite.T(), IsCircuitOpen(suite.client.Call(ctx, &breakerTestRequest{endpoint: "BillingService.GetOrder"}, nil)))
+	assert.Equal(suite.T(), circuitHalfOpen, suite.breaker.circuit(breakerTestService).state)
+	assert.False(suite.T(), suite.breaker.circuit(breakerTestService).probing)
+
+	// the next call takes the released probe slot
+	assert.NoError(suite.T(), suite.call("BillingService.GetOrder"))
+	assert.Equal(suite.T(), circuitClosed, suite.breaker.circuit(breakerTestService).state)
+}
+
+func (suite *BreakerTestSuite) TestBreaker_Retries_ReadOnly() {
+	suite.breaker.SetPolicies(CallPolicy{Retries: 2}, nil)
+
+	assert.Error(suite.T(), suite.call("BillingService.FindAllOrdersPublic"))
+	assert.Equal(suite.T(), 3, suite.service.calls)
+}
+
+func (suite *BreakerTestSuite) TestBreaker_Retries_NotReadOnly() {
+	suite.breaker.SetPolicies(CallPolicy{Retries: 2}, nil)
+
+	for _, endpoint := range []string{"BillingService.CreateRefund", "BillingService.PaymentCreateProcess"} {
+		suite.service.calls = 0
+		assert.Error(suite.T(), suite.call(endpoint))
+		assert.Equal(suite.T(), 1, suite.service.calls, endpoint)
+	}
+}
+
+func (suite *BreakerTestSuite) TestBreaker_Policy_Precedence() {
+	suite.breaker.AddPolicies(CallPolicy{Service: breakerTestService, Method: "BillingService.UploadKeysFile", Timeout: time.Minute})
+	suite.breaker.SetPolicies(
+		CallPolicy{Timeout: time.Second, FailureThreshold: 5},
+		[]CallPolicy{
+			{Service: breakerTestService, Timeout: 2 * time.Second},
+			{Method: "BillingService.UploadKeysFile", Timeout: 3 * time.Minute},
+		},
+	)
+
+	assert.Equal(suite.T(), 2*time.Second, suite.breaker.Policy(breakerTestService, "BillingService.GetOrder").Timeout)
+	assert.Equal(suite.T(), 3*time.Minute, suite.breaker.Policy(breakerTestService, "BillingService.UploadKeysFile").Timeout)
+	assert.Equal(suite.T(), 5, suite.breaker.Policy(breakerTestService, "BillingService.UploadKeysFile").FailureThreshold)
+}
@@ -0,0 +1,82 @@
+package micro
+
+import (
+	"context"
+	"github.com/micro/go-micro/client"
+	"github.com/prometheus/client_golang/prometheus"
+	"time"
+)
+
+const (
+	metricsNamespace = "management_api"
+
+	callStatusSuccess = "success"
+	callStatusError   = "error"
+)
+
+var (
+	clientCallsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "grpc_client_calls_total",
+			Help:      "Total number of outbound calls to the micro services.",
+		},
+		[]string{"service", "method", "status"},
+	)
+	clientCallDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "grpc_client_call_duration_seconds",
+			Help:      "Latency of outbound calls to the micro services.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"service", "method"},
+	)
+	breakerState = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "circuit_breaker_state",
+			Help:      "State of the service circuit breaker: 0 - closed, 1 - half-open, 2 - open.",
+		},
+		[]string{"service"},
+	)
+	breakerRejections = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "circuit_breaker_rejections_total",
+			Help:      "Total number of calls rejected by the open circuit breaker.",
+		},
+		[]string{"service"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(clientCallsTotal, clientCallDuration, breakerState, breakerRejections)
+}
+
+type metricsClient struct {
+	client.Client
+}
+
+// Call
+func (c *metricsClient) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
+	start := time.Now()
+	err := c.Client.Call(ctx, req, rsp, opts...)
+
+	status := callStatusSuccess
+	if err != nil {
+		status = callStatusError
+	}
+
+	clientCallsTotal.WithLabelValues(req.Service(), req.Endpoint(), status).Inc()
+	clientCallDuration.WithLabelValues(req.Service(), req.Endpoint()).Observe(time.Since(start).Seconds())
+
+	return err
+}
+
+// NewMetricsClientWrapper returns client wrapper collecting count and latency of the outbound calls
+func NewMetricsClientWrapper() client.Wrapper {
+	return func(c client.Client) client.Client {
+		return &metricsClient{Client: c}
+	}
+}
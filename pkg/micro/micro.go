@@ -9,13 +9,16 @@ import (
 	"github.com/micro/go-micro/client"
 	mlog "github.com/micro/go-micro/util/log"
 	"github.com/micro/go-plugins/client/selector/static"
+	"net"
+	"time"
 )
 
 // Micro
 type Micro struct {
-	ctx context.Context
-	cfg Config
-	srv micro.Service
+	ctx     context.Context
+	cfg     Config
+	srv     micro.Service
+	breaker *Breaker
 	provider.LMT
 }
 
@@ -24,6 +27,11 @@ func (m *Micro) Client() client.Client {
 	return m.srv.Client()
 }
 
+// AddCallPolicies adds built-in call policies of the services, policies from the config take precedence
+func (m *Micro) AddCallPolicies(policies ...CallPolicy) {
+	m.breaker.AddPolicies(policies...)
+}
+
 // ListenAndServe
 func (m *Micro) ListenAndServe() (err error) {
 
@@ -62,7 +70,24 @@ type Config struct {
 	Version  string `default:"latest"`
 	Selector string
 	Bind     string
-	invoker  *invoker.Invoker
+	// Defaults of the outbound calls, CallPolicies override them per service and per method,
+	// retries are made for the read only methods only
+	CallTimeout             time.Duration `default:"10s"`
+	CallRetries             int
+	BreakerFailureThreshold int           `default:"5"`
+	BreakerOpenTimeout      time.Duration `default:"30s"`
+	CallPolicies            []CallPolicy
+	invoker                 *invoker.Invoker
+}
+
+// DefaultCallPolicy
+func (c *Config) DefaultCallPolicy() CallPolicy {
+	return CallPolicy{
+		Timeout:          c.CallTimeout,
+		Retries:          c.CallRetries,
+		FailureThreshold: c.BreakerFailureThreshold,
+		OpenTimeout:      c.BreakerOpenTimeout,
+	}
 }
 
 // OnReload
@@ -78,17 +103,50 @@ func (c *Config) Reload(ctx context.Context) {
 // New
 func New(ctx context.Context, set provider.AwareSet, cfg *Config) *Micro {
 	set.Logger = set.Logger.WithFields(logger.Fields{"service": Prefix, "service_name": cfg.Name})
+	breaker := NewBreaker(cfg.DefaultCallPolicy(), cfg.CallPolicies, set.Logger)
+	cfg.OnReload(func(ctx context.Context) {
+		breaker.SetPolicies(cfg.DefaultCallPolicy(), cfg.CallPolicies)
+	})
 	options := []micro.Option{
 		micro.Name(cfg.Name),
 		micro.Version(cfg.Version),
+		micro.WrapClient(NewMetricsClientWrapper(), NewTracingClientWrapper(), NewBreakerClientWrapper(breaker)),
 	}
 	if cfg.Selector == "static" {
 		options = append(options, micro.Selector(static.NewSelector()))
 	}
 	return &Micro{
-		ctx: ctx,
-		cfg: *cfg,
-		LMT: &set,
-		srv: micro.NewService(options...),
+		ctx:     ctx,
+		cfg:     *cfg,
+		LMT:     &set,
+		srv:     micro.NewService(options...),
+		breaker: breaker,
 	}
 }
+
+// CheckRegistry checks availability of the services registry
+func (m *Micro) CheckRegistry(ctx context.Context) error {
+	_, err := m.srv.Options().Registry.ListServices()
+	return err
+}
+
+// CheckService checks that a node of the service accepts connections, the node is dialed since
+// the static selector returns the node for any service without checking it
+func (m *Micro) CheckService(ctx context.Context, name string) error {
+	next, err := m.srv.Client().Options().Selector.Select(name)
+	if err != nil {
+		return err
+	}
+
+	node, err := next()
+	if err != nil {
+		return err
+	}
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", node.Address)
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
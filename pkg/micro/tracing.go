@@ -0,0 +1,41 @@
+package micro
+
+import (
+	"context"
+	"github.com/micro/go-micro/client"
+	"github.com/micro/go-micro/metadata"
+	"github.com/paysuper/paysuper-management-api/pkg/trace"
+)
+
+type tracingClient struct {
+	client.Client
+}
+
+// Call
+func (c *tracingClient) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
+	ctx, span := trace.GetTracer().Start(ctx, req.Service()+"/"+req.Endpoint())
+	span.SetAttribute("rpc.service", req.Service())
+	span.SetAttribute("rpc.method", req.Endpoint())
+
+	md := metadata.Metadata{}
+	if incoming, ok := metadata.FromContext(ctx); ok {
+		for k, v := range incoming {
+			md[k] = v
+		}
+	}
+	md[trace.HeaderTraceparent] = span.Context.Traceparent()
+
+	err := c.Client.Call(metadata.NewContext(ctx, md), req, rsp, opts...)
+	span.SetError(err)
+	span.Finish()
+
+	return err
+}
+
+// NewTracingClientWrapper returns client wrapper creating span for each outbound call
+// and passing trace context to the called service
+func NewTracingClientWrapper() client.Wrapper {
+	return func(c client.Client) client.Client {
+		return &tracingClient{Client: c}
+	}
+}
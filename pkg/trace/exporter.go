@@ -0,0 +1,107 @@
+package trace
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+const (
+	ExporterStdout = "stdout"
+)
+
+// Exporter receives finished spans
+type Exporter interface {
+	Export(span *Span)
+}
+
+type noopExporter struct{}
+
+// NewNoopExporter returns exporter discarding all spans
+func NewNoopExporter() Exporter {
+	return &noopExporter{}
+}
+
+// Export
+func (e *noopExporter) Export(span *Span) {}
+
+type spanRecord struct {
+	Name       string                 `json:"name"`
+	TraceId    string                 `json:"trace_id"`
+	SpanId     string                 `json:"span_id"`
+	ParentId   string                 `json:"parent_id,omitempty"`
+	Start      int64                  `json:"start"`
+	Duration   int64                  `json:"duration"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+}
+
+// WriterExporter writes spans as json lines
+type WriterExporter struct {
+	mx sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterExporter returns exporter writing spans to w, it's used with os.Stdout
+func NewWriterExporter(w io.Writer) *WriterExporter {
+	return &WriterExporter{w: w}
+}
+
+// Export
+func (e *WriterExporter) Export(span *Span) {
+	span.mx.Lock()
+	rec := spanRecord{
+		Name:       span.Name,
+		TraceId:    span.Context.TraceId,
+		SpanId:     span.Context.SpanId,
+		ParentId:   span.ParentId,
+		Start:      span.Start.UnixNano(),
+		Duration:   span.End.Sub(span.Start).Nanoseconds(),
+		Attributes: span.Attributes,
+		Error:      span.Error,
+	}
+	b, err := json.Marshal(rec)
+	span.mx.Unlock()
+
+	if err != nil {
+		return
+	}
+
+	e.mx.Lock()
+	defer e.mx.Unlock()
+	_, _ = e.w.Write(append(b, '\n'))
+}
+
+// MemoryExporter keeps finished spans in memory, it's used in tests
+type MemoryExporter struct {
+	mx    sync.Mutex
+	spans []*Span
+}
+
+// NewMemoryExporter
+func NewMemoryExporter() *MemoryExporter {
+	return &MemoryExporter{}
+}
+
+// Export
+func (e *MemoryExporter) Export(span *Span) {
+	e.mx.Lock()
+	defer e.mx.Unlock()
+	e.spans = append(e.spans, span)
+}
+
+// Spans returns exported spans in order of finishing
+func (e *MemoryExporter) Spans() []*Span {
+	e.mx.Lock()
+	defer e.mx.Unlock()
+	spans := make([]*Span, len(e.spans))
+	copy(spans, e.spans)
+	return spans
+}
+
+// Reset
+func (e *MemoryExporter) Reset() {
+	e.mx.Lock()
+	defer e.mx.Unlock()
+	e.spans = nil
+}
@@ -0,0 +1,172 @@
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// HeaderTraceparent is a W3C trace context header, it's used for http and micro metadata propagation
+	HeaderTraceparent = "Traceparent"
+
+	traceparentVersion = "00"
+	traceparentSampled = "01"
+)
+
+type spanContextKey struct{}
+type remoteSpanContextKey struct{}
+
+// SpanContext identifies span in the trace
+type SpanContext struct {
+	TraceId string
+	SpanId  string
+}
+
+// IsValid
+func (sc SpanContext) IsValid() bool {
+	return len(sc.TraceId) == 32 && len(sc.SpanId) == 16
+}
+
+// Traceparent returns value of the traceparent header
+func (sc SpanContext) Traceparent() string {
+	return fmt.Sprintf("%s-%s-%s-%s", traceparentVersion, sc.TraceId, sc.SpanId, traceparentSampled)
+}
+
+// ParseTraceparent parses value of the traceparent header
+func ParseTraceparent(value string) (SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) != 4 || parts[0] != traceparentVersion {
+		return SpanContext{}, false
+	}
+	sc := SpanContext{TraceId: strings.ToLower(parts[1]), SpanId: strings.ToLower(parts[2])}
+	if !sc.IsValid() || !isHex(sc.TraceId) || !isHex(sc.SpanId) {
+		return SpanContext{}, false
+	}
+	return sc, true
+}
+
+// Span is a timed operation of the trace
+type Span struct {
+	mx         sync.Mutex
+	tracer     *Tracer
+	ended      bool
+	Name       string
+	Context    SpanContext
+	ParentId   string
+	Start      time.Time
+	End        time.Time
+	Attributes map[string]interface{}
+	Error      string
+}
+
+// SetAttribute
+func (s *Span) SetAttribute(key string, value interface{}) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.Attributes[key] = value
+}
+
+// SetError marks span as failed
+func (s *Span) SetError(err error) {
+	if err == nil {
+		return
+	}
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.Error = err.Error()
+}
+
+// Finish ends the span and passes it to the exporter, repeated calls are ignored
+func (s *Span) Finish() {
+	s.mx.Lock()
+	if s.ended {
+		s.mx.Unlock()
+		return
+	}
+	s.ended = true
+	s.End = time.Now()
+	s.mx.Unlock()
+
+	s.tracer.exporter.Export(s)
+}
+
+// Tracer creates spans and exports finished ones
+type Tracer struct {
+	exporter Exporter
+}
+
+// NewTracer
+func NewTracer(exporter Exporter) *Tracer {
+	if exporter == nil {
+		exporter = NewNoopExporter()
+	}
+	return &Tracer{exporter: exporter}
+}
+
+// Start creates span as a child of the span from the context or the remote parent,
+// returned context contains the new span
+func (t *Tracer) Start(ctx context.Context, name string) (context.Context, *Span) {
+	span := &Span{
+		tracer:     t,
+		Name:       name,
+		Start:      time.Now(),
+		Attributes: make(map[string]interface{}),
+	}
+
+	if parent := SpanFromContext(ctx); parent != nil {
+		span.Context.TraceId = parent.Context.TraceId
+		span.ParentId = parent.Context.SpanId
+	} else if remote, ok := ctx.Value(remoteSpanContextKey{}).(SpanContext); ok {
+		span.Context.TraceId = remote.TraceId
+		span.ParentId = remote.SpanId
+	} else {
+		span.Context.TraceId = newId(16)
+	}
+	span.Context.SpanId = newId(8)
+
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+// SpanFromContext returns current span of the context or nil
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanContextKey{}).(*Span)
+	return span
+}
+
+// ContextWithRemoteSpanContext sets span received from the caller as a parent of spans started with the context
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteSpanContextKey{}, sc)
+}
+
+var globalTracer atomic.Value
+
+func init() {
+	globalTracer.Store(NewTracer(nil))
+}
+
+// SetTracer sets tracer used by the http middleware and micro client wrapper
+func SetTracer(t *Tracer) {
+	globalTracer.Store(t)
+}
+
+// GetTracer
+func GetTracer() *Tracer {
+	return globalTracer.Load().(*Tracer)
+}
+
+func newId(size int) string {
+	b := make([]byte, size)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func isHex(s string) bool {
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
@@ -0,0 +1,210 @@
+package webhook
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/paysuper/paysuper-management-api/pkg/keyset"
+	"time"
+)
+
+const (
+	subscriptionCollection = "webhook_subscription"
+	deliveryCollection     = "webhook_delivery"
+
+	// deliveryLogTtl limits the delivery log kept in the database
+	deliveryLogTtl = 30 * 24 * time.Hour
+	// deliveryLease is a time the due delivery is hidden from the other replicas while it's being sent
+	deliveryLease = 5 * time.Minute
+)
+
+// NewStorage returns storage in the database of the session or in the process memory if session is nil
+func NewStorage(session *mgo.Session) (Storage, error) {
+	if session == nil {
+		return NewMemoryStorage(), nil
+	}
+
+	s := &mongoStorage{session: session}
+	indexes := map[string][]mgo.Index{
+		subscriptionCollection: {
+			{Key: []string{"merchant_id", "created_at"}},
+			{Key: []string{"events", "enabled"}},
+		},
+		deliveryCollection: {
+			{Key: []string{"subscription_id", "-created_at", "-_id"}},
+			{Key: []string{"status", "next_attempt_at"}},
+			{Key: []string{"created_at"}, ExpireAfter: deliveryLogTtl},
+		},
+	}
+
+	for name, list := range indexes {
+		for _, index := range list {
+			err := s.with(name, func(c *mgo.Collection) error { return c.EnsureIndex(index) })
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return s, nil
+}
+
+// mongoStorage keeps subscriptions and deliveries shared by all replicas,
+// the delivery log is removed after deliveryLogTtl
+type mongoStorage struct {
+	session *mgo.Session
+}
+
+// CreateSubscription
+func (s *mongoStorage) CreateSubscription(sub *Subscription) error {
+	return s.with(subscriptionCollection, func(c *mgo.Collection) error {
+		return c.Insert(sub)
+	})
+}
+
+// UpdateSubscription
+func (s *mongoStorage) UpdateSubscription(sub *Subscription) error {
+	return s.with(subscriptionCollection, func(c *mgo.Collection) error {
+		return notFound(c.Update(bson.M{"_id": sub.Id, "merchant_id": sub.MerchantId}, sub))
+	})
+}
+
+// DeleteSubscription
+func (s *mongoStorage) DeleteSubscription(merchantId, id string) error {
+	err := s.with(subscriptionCollection, func(c *mgo.Collection) error {
+		return notFound(c.Remove(bson.M{"_id": id, "merchant_id": merchantId}))
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.with(deliveryCollection, func(c *mgo.Collection) error {
+		_, err := c.RemoveAll(bson.M{"subscription_id": id})
+		return err
+	})
+}
+
+// GetSubscription
+func (s *mongoStorage) GetSubscription(merchantId, id string) (*Subscription, error) {
+	sub := &Subscription{}
+	err := s.with(subscriptionCollection, func(c *mgo.Collection) error {
+		return notFound(c.Find(bson.M{"_id": id, "merchant_id": merchantId}).One(sub))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// ListSubscriptions
+func (s *mongoStorage) ListSubscriptions(merchantId string) ([]*Subscription, error) {
+	list := make([]*Subscription, 0)
+	err := s.with(subscriptionCollection, func(c *mgo.Collection) error {
+		return c.Find(bson.M{"merchant_id": merchantId}).Sort("created_at").All(&list)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// ListEventSubscriptions
+func (s *mongoStorage) ListEventSubscriptions(event string) ([]*Subscription, error) {
+	list := make([]*Subscription, 0)
+	err := s.with(subscriptionCollection, func(c *mgo.Collection) error {
+		return c.Find(bson.M{"events": event, "enabled": true}).Sort("created_at").All(&list)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// SaveDelivery
+func (s *mongoStorage) SaveDelivery(d *Delivery) error {
+	err := s.with(subscriptionCollection, func(c *mgo.Collection) error {
+		n, err := c.FindId(d.SubscriptionId).Count()
+		if err == nil && n == 0 {
+			err = ErrNotFound
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.with(deliveryCollection, func(c *mgo.Collection) error {
+		_, err := c.UpsertId(d.Id, d)
+		return err
+	})
+}
+
+// ListDeliveries
+func (s *mongoStorage) ListDeliveries(merchantId, subscriptionId string, limit, offset int, pos *keyset.Position) (*DeliveryList, error) {
+	if _, err := s.GetSubscription(merchantId, subscriptionId); err != nil {
+		return nil, err
+	}
+
+	list := &DeliveryList{Items: make([]*Delivery, 0)}
+	err := s.with(deliveryCollection, func(c *mgo.Collection) error {
+		query := bson.M{"subscription_id": subscriptionId}
+
+		var err error
+		if list.Count, err = c.Find(query).Count(); err != nil {
+			return err
+		}
+
+		if pos == nil {
+			return c.Find(query).Sort(pos.Sort()...).Skip(offset).Limit(limit).All(&list.Items)
+		}
+		return c.Find(pos.Query(query)).Sort(pos.Sort()...).Limit(limit).All(&list.Items)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pos.Reverse(len(list.Items), func(i, j int) { list.Items[i], list.Items[j] = list.Items[j], list.Items[i] })
+	return list, nil
+}
+
+// DueDeliveries leases each returned delivery for deliveryLease, so the replicas don't send the same delivery
+// at the same time, the delivery is returned again after the lease if the replica failed to save the attempt
+func (s *mongoStorage) DueDeliveries(before time.Time, limit int) ([]*Delivery, error) {
+	list := make([]*Delivery, 0)
+	err := s.with(deliveryCollection, func(c *mgo.Collection) error {
+		query := bson.M{"status": DeliveryStatusPending, "next_attempt_at": bson.M{"$lte": before}}
+		change := mgo.Change{Update: bson.M{"$set": bson.M{"next_attempt_at": before.Add(deliveryLease)}}}
+
+		for len(list) < limit {
+			d := &Delivery{}
+			_, err := c.Find(query).Sort("next_attempt_at").Apply(change, d)
+			if err == mgo.ErrNotFound {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			list = append(list, d)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// with opens the collection in the copy of the shared session, so the concurrent operations don't wait
+// for each other on the single socket
+func (s *mongoStorage) with(name string, fn func(c *mgo.Collection) error) error {
+	session := s.session.Copy()
+	defer session.Close()
+
+	return fn(session.DB("").C(name))
+}
+
+func notFound(err error) error {
+	if err == mgo.ErrNotFound {
+		return ErrNotFound
+	}
+	return err
+}
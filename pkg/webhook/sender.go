@@ -0,0 +1,283 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/ProtocolONE/go-core/v2/pkg/logger"
+	"github.com/globalsign/mgo/bson"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	userAgent          = "PaySuper-Webhooks/1.0"
+	dueDeliveriesLimit = 100
+	responseBodyLimit  = 1024
+)
+
+// Config
+type Config struct {
+	// Timeout of the single delivery request
+	Timeout time.Duration
+	// MaxAttempts is a number of attempts after which the delivery is failed
+	MaxAttempts int
+	// RetryInterval is a delay before the first retry, each next delay is doubled
+	RetryInterval time.Duration
+	// MaxRetryInterval limits the delay between attempts
+	MaxRetryInterval time.Duration
+	// PollInterval is a period of the pending deliveries check
+	PollInterval time.Duration
+}
+
+// Sender signs and delivers events to the merchant subscriptions, failed deliveries
+// are retried with exponential backoff until the attempts are exhausted
+type Sender struct {
+	storage Storage
+	cfg     Config
+	client  *http.Client
+	log     logger.Logger
+	wake    chan struct{}
+	stop    chan struct{}
+	wg      sync.WaitGroup
+	once    sync.Once
+}
+
+// NewSender
+func NewSender(storage Storage, cfg Config, log logger.Logger) *Sender {
+	return &Sender{
+		storage: storage,
+		cfg:     cfg,
+		client:  newHttpClient(cfg.Timeout),
+		log:     log,
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+	}
+}
+
+// Storage
+func (s *Sender) Storage() Storage {
+	return s.storage
+}
+
+// SetHttpClient replaces client used for the deliveries, the client is responsible for refusing of the internal addresses
+func (s *Sender) SetHttpClient(client *http.Client) {
+	s.client = client
+}
+
+// Start runs background processing of the pending deliveries
+func (s *Sender) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.cfg.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			case <-s.wake:
+			}
+			s.ProcessDue()
+		}
+	}()
+}
+
+// Stop waits for the current processing and stops the background loop
+func (s *Sender) Stop() {
+	s.once.Do(func() {
+		close(s.stop)
+	})
+	s.wg.Wait()
+}
+
+// Publish creates deliveries of the event for each subscription of the merchant which receives it
+func (s *Sender) Publish(merchantId, event string, data interface{}) {
+	if merchantId == "" {
+		return
+	}
+
+	subs, err := s.storage.ListSubscriptions(merchantId)
+	if err != nil {
+		s.log.Error("unable to get webhook subscriptions", logger.PairArgs("err", err.Error(), "merchant_id", merchantId))
+		return
+	}
+
+	s.queue(subs, event, data)
+}
+
+// Broadcast creates deliveries of the event which isn't bound to the merchant, e.g. vat report,
+// for each subscription of any merchant which receives it, so the event is sent only to who explicitly subscribed to it
+func (s *Sender) Broadcast(event string, data interface{}) {
+	subs, err := s.storage.ListEventSubscriptions(event)
+	if err != nil {
+		s.log.Error("unable to get webhook subscriptions", logger.PairArgs("err", err.Error(), "event", event))
+		return
+	}
+
+	s.queue(subs, event, data)
+}
+
+func (s *Sender) queue(subs []*Subscription, event string, data interface{}) {
+	queued := false
+	for _, sub := range subs {
+		if !sub.Subscribed(event) {
+			continue
+		}
+
+		d, err := s.newDelivery(sub, event, data)
+		if err == nil {
+			err = s.storage.SaveDelivery(d)
+		}
+		if err != nil {
+			s.log.Error("unable to queue webhook delivery", logger.PairArgs("err", err.Error(), "subscription_id", sub.Id, "event", event))
+			continue
+		}
+		queued = true
+	}
+
+	if queued {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Ping sends test event to the subscription immediately and returns the delivery result,
+// ping is never retried
+func (s *Sender) Ping(sub *Subscription) (*Delivery, error) {
+	d, err := s.newDelivery(sub, EventPing, map[string]string{"subscription_id": sub.Id})
+	if err != nil {
+		return nil, err
+	}
+	s.attempt(sub, d, time.Now())
+	if d.Status == DeliveryStatusPending {
+		d.Status = DeliveryStatusFailed
+	}
+	if err = s.storage.SaveDelivery(d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// ProcessDue sends all pending deliveries which time has come
+func (s *Sender) ProcessDue() {
+	now := time.Now()
+	list, err := s.storage.DueDeliveries(now, dueDeliveriesLimit)
+	if err != nil {
+		s.log.Error("unable to get pending webhook deliveries", logger.PairArgs("err", err.Error()))
+		return
+	}
+
+	for _, d := range list {
+		sub, err := s.storage.GetSubscription(d.MerchantId, d.SubscriptionId)
+		if err != nil {
+			continue
+		}
+
+		if !sub.Enabled {
+			d.Status = DeliveryStatusFailed
+			d.Error = "subscription is disabled"
+		} else {
+			s.attempt(sub, d, now)
+		}
+
+		if err = s.storage.SaveDelivery(d); err != nil && err != ErrNotFound {
+			s.log.Error("unable to save webhook delivery", logger.PairArgs("err", err.Error(), "delivery_id", d.Id))
+		}
+	}
+}
+
+func (s *Sender) newDelivery(sub *Subscription, event string, data interface{}) (*Delivery, error) {
+	now := time.Now()
+	evt := &Event{
+		Id:         bson.NewObjectId().Hex(),
+		Type:       event,
+		MerchantId: sub.MerchantId,
+		CreatedAt:  now,
+		Data:       data,
+	}
+	payload, err := json.Marshal(evt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Delivery{
+		Id:             bson.NewObjectId().Hex(),
+		SubscriptionId: sub.Id,
+		MerchantId:     sub.MerchantId,
+		EventId:        evt.Id,
+		Event:          event,
+		Payload:        payload,
+		Status:         DeliveryStatusPending,
+		CreatedAt:      now,
+		NextAttemptAt:  now,
+	}, nil
+}
+
+// attempt sends the delivery once and schedules next attempt if it failed
+func (s *Sender) attempt(sub *Subscription, d *Delivery, now time.Time) {
+	d.Attempts++
+	d.ResponseCode, d.Error = s.send(sub, d)
+
+	if d.Error == "" {
+		d.Status = DeliveryStatusSucceeded
+		d.DeliveredAt = &now
+		return
+	}
+
+	if d.Attempts >= s.cfg.MaxAttempts {
+		d.Status = DeliveryStatusFailed
+		return
+	}
+	d.NextAttemptAt = now.Add(s.backoff(d.Attempts))
+}
+
+func (s *Sender) send(sub *Subscription, d *Delivery) (int, string) {
+	req, err := http.NewRequest(http.MethodPost, sub.Url, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err.Error()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(HeaderEvent, d.Event)
+	req.Header.Set(HeaderDelivery, d.Id)
+	req.Header.Set(HeaderSignature, Sign(sub.Secret, time.Now(), d.Payload))
+
+	rsp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err.Error()
+	}
+	defer rsp.Body.Close()
+
+	// the response body isn't kept in the delivery log, otherwise the log would expose responses of any url
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(rsp.Body, responseBodyLimit))
+	if rsp.StatusCode < http.StatusOK || rsp.StatusCode >= http.StatusMultipleChoices {
+		return rsp.StatusCode, fmt.Sprintf("unexpected response status %d", rsp.StatusCode)
+	}
+	return rsp.StatusCode, ""
+}
+
+func (s *Sender) backoff(attempts int) time.Duration {
+	delay := s.cfg.RetryInterval
+	for i := 1; i < attempts && delay < s.cfg.MaxRetryInterval; i++ {
+		delay *= 2
+	}
+	if delay > s.cfg.MaxRetryInterval {
+		delay = s.cfg.MaxRetryInterval
+	}
+	return delay
+}
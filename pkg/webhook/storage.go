@@ -0,0 +1,223 @@
+package webhook
+
+import (
+	"github.com/paysuper/paysuper-management-api/pkg/keyset"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	deliveryLogSize = 500
+)
+
+// Storage keeps subscriptions and the delivery log
+type Storage interface {
+	// CreateSubscription
+	CreateSubscription(sub *Subscription) error
+	// UpdateSubscription returns ErrNotFound if the subscription is unknown
+	UpdateSubscription(sub *Subscription) error
+	// DeleteSubscription removes subscription with its delivery log
+	DeleteSubscription(merchantId, id string) error
+	// GetSubscription returns ErrNotFound if the merchant has no subscription with the identifier
+	GetSubscription(merchantId, id string) (*Subscription, error)
+	// ListSubscriptions returns subscriptions of the merchant
+	ListSubscriptions(merchantId string) ([]*Subscription, error)
+	// ListEventSubscriptions returns enabled subscriptions of all merchants which receive the event
+	ListEventSubscriptions(event string) ([]*Subscription, error)
+	// SaveDelivery creates or replaces the delivery
+	SaveDelivery(d *Delivery) error
+	// ListDeliveries returns log of the subscription, the newest deliveries come first,
+	// the page starts after the position if it's set, otherwise after the offset
+	ListDeliveries(merchantId, subscriptionId string, limit, offset int, pos *keyset.Position) (*DeliveryList, error)
+	// DueDeliveries returns pending deliveries with the next attempt before the time
+	DueDeliveries(before time.Time, limit int) ([]*Delivery, error)
+}
+
+type memoryStorage struct {
+	mx            sync.RWMutex
+	subscriptions map[string]*Subscription
+	deliveries    map[string][]*Delivery
+}
+
+// NewMemoryStorage returns storage keeping subscriptions in the process memory
+func NewMemoryStorage() Storage {
+	return &memoryStorage{
+		subscriptions: make(map[string]*Subscription),
+		deliveries:    make(map[string][]*Delivery),
+	}
+}
+
+// CreateSubscription
+func (s *memoryStorage) CreateSubscription(sub *Subscription) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.subscriptions[sub.Id] = copySubscription(sub)
+	return nil
+}
+
+// UpdateSubscription
+func (s *memoryStorage) UpdateSubscription(sub *Subscription) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if old, ok := s.subscriptions[sub.Id]; !ok || old.MerchantId != sub.MerchantId {
+		return ErrNotFound
+	}
+	s.subscriptions[sub.Id] = copySubscription(sub)
+	return nil
+}
+
+// DeleteSubscription
+func (s *memoryStorage) DeleteSubscription(merchantId, id string) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if sub, ok := s.subscriptions[id]; !ok || sub.MerchantId != merchantId {
+		return ErrNotFound
+	}
+	delete(s.subscriptions, id)
+	delete(s.deliveries, id)
+	return nil
+}
+
+// GetSubscription
+func (s *memoryStorage) GetSubscription(merchantId, id string) (*Subscription, error) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	sub, ok := s.subscriptions[id]
+	if !ok || sub.MerchantId != merchantId {
+		return nil, ErrNotFound
+	}
+	return copySubscription(sub), nil
+}
+
+// ListSubscriptions
+func (s *memoryStorage) ListSubscriptions(merchantId string) ([]*Subscription, error) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	list := make([]*Subscription, 0)
+	for _, sub := range s.subscriptions {
+		if sub.MerchantId == merchantId {
+			list = append(list, copySubscription(sub))
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+	return list, nil
+}
+
+// ListEventSubscriptions
+func (s *memoryStorage) ListEventSubscriptions(event string) ([]*Subscription, error) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	list := make([]*Subscription, 0)
+	for _, sub := range s.subscriptions {
+		if sub.Subscribed(event) {
+			list = append(list, copySubscription(sub))
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+	return list, nil
+}
+
+// SaveDelivery
+func (s *memoryStorage) SaveDelivery(d *Delivery) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if _, ok := s.subscriptions[d.SubscriptionId]; !ok {
+		return ErrNotFound
+	}
+
+	cp := *d
+	log := s.deliveries[d.SubscriptionId]
+	for i, item := range log {
+		if item.Id == d.Id {
+			log[i] = &cp
+			return nil
+		}
+	}
+
+	log = append(log, &cp)
+	if len(log) > deliveryLogSize {
+		log = log[len(log)-deliveryLogSize:]
+	}
+	s.deliveries[d.SubscriptionId] = log
+	return nil
+}
+
+// ListDeliveries
+func (s *memoryStorage) ListDeliveries(merchantId, subscriptionId string, limit, offset int, pos *keyset.Position) (*DeliveryList, error) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	if sub, ok := s.subscriptions[subscriptionId]; !ok || sub.MerchantId != merchantId {
+		return nil, ErrNotFound
+	}
+
+	log := s.deliveries[subscriptionId]
+	list := &DeliveryList{Count: len(log), Items: make([]*Delivery, 0)}
+
+	if pos == nil {
+		for i := len(log) - 1 - offset; i >= 0 && len(list.Items) < limit; i-- {
+			cp := *log[i]
+			list.Items = append(list.Items, &cp)
+		}
+		return list, nil
+	}
+
+	matched := make([]*Delivery, 0)
+	for i := len(log) - 1; i >= 0; i-- {
+		if pos.Match(log[i].CreatedAt, log[i].Id) {
+			matched = append(matched, log[i])
+		}
+	}
+
+	// the backward page is the newer items nearest to the position, they are at the end of the newest first list
+	if pos.Backward && len(matched) > limit {
+		matched = matched[len(matched)-limit:]
+	}
+
+	for i := 0; i < len(matched) && len(list.Items) < limit; i++ {
+		cp := *matched[i]
+		list.Items = append(list.Items, &cp)
+	}
+	return list, nil
+}
+
+// DueDeliveries
+func (s *memoryStorage) DueDeliveries(before time.Time, limit int) ([]*Delivery, error) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	list := make([]*Delivery, 0)
+	for _, log := range s.deliveries {
+		for _, d := range log {
+			if d.Status == DeliveryStatusPending && !d.NextAttemptAt.After(before) {
+				cp := *d
+				list = append(list, &cp)
+			}
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].NextAttemptAt.Before(list[j].NextAttemptAt)
+	})
+	if len(list) > limit {
+		list = list[:limit]
+	}
+	return list, nil
+}
+
+func copySubscription(sub *Subscription) *Subscription {
+	cp := *sub
+	cp.Events = append([]string(nil), sub.Events...)
+	return &cp
+}
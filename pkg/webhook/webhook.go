@@ -0,0 +1,176 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/paysuper/paysuper-management-api/pkg/keyset"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	EventMerchantStatusChanged       = "merchant.status_changed"
+	EventRoyaltyReportGenerated      = "royalty_report.generated"
+	EventRoyaltyReportAccepted       = "royalty_report.accepted"
+	EventRoyaltyReportStatusChanged  = "royalty_report.status_changed"
+	EventPayoutDocumentStatusChanged = "payout_document.status_changed"
+	EventVatReportStatusChanged      = "vat_report.status_changed"
+	EventKeyProductStockLow          = "key_product.stock_low"
+	EventPing                        = "ping"
+
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusSucceeded = "succeeded"
+	DeliveryStatusFailed    = "failed"
+
+	HeaderEvent     = "X-PaySuper-Event"
+	HeaderDelivery  = "X-PaySuper-Delivery"
+	HeaderSignature = "X-PaySuper-Signature"
+
+	signatureVersion = "v1"
+	secretPrefix     = "whsec_"
+	secretLength     = 32
+)
+
+var (
+	ErrNotFound = errors.New("webhook subscription not found")
+
+	// Events is a list of events which merchant may subscribe to
+	Events = []string{
+		EventMerchantStatusChanged,
+		EventRoyaltyReportGenerated,
+		EventRoyaltyReportAccepted,
+		EventRoyaltyReportStatusChanged,
+		EventPayoutDocumentStatusChanged,
+		EventVatReportStatusChanged,
+		EventKeyProductStockLow,
+	}
+)
+
+// Subscription is a merchant endpoint receiving the events
+type Subscription struct {
+	Id         string    `json:"id" bson:"_id"`
+	MerchantId string    `json:"merchant_id" bson:"merchant_id"`
+	Url        string    `json:"url" bson:"url"`
+	Events     []string  `json:"events" bson:"events"`
+	Enabled    bool      `json:"enabled" bson:"enabled"`
+	Secret     string    `json:"secret,omitempty" bson:"secret"`
+	CreatedAt  time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// Subscribed checks that the subscription is active and receives the event
+func (s *Subscription) Subscribed(event string) bool {
+	if !s.Enabled {
+		return false
+	}
+	for _, e := range s.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Public returns copy of the subscription without secret
+func (s *Subscription) Public() *Subscription {
+	cp := *s
+	cp.Secret = ""
+	return &cp
+}
+
+// Event is a body of the delivery request
+type Event struct {
+	Id         string      `json:"id"`
+	Type       string      `json:"type"`
+	MerchantId string      `json:"merchant_id,omitempty"`
+	CreatedAt  time.Time   `json:"created_at"`
+	Data       interface{} `json:"data"`
+}
+
+// Delivery is a log record of the event sending to the subscription
+type Delivery struct {
+	Id             string          `json:"id" bson:"_id"`
+	SubscriptionId string          `json:"subscription_id" bson:"subscription_id"`
+	MerchantId     string          `json:"merchant_id" bson:"merchant_id"`
+	EventId        string          `json:"event_id" bson:"event_id"`
+	Event          string          `json:"event" bson:"event"`
+	Payload        json.RawMessage `json:"payload" bson:"payload"`
+	Status         string          `json:"status" bson:"status"`
+	Attempts       int             `json:"attempts" bson:"attempts"`
+	ResponseCode   int             `json:"response_code,omitempty" bson:"response_code"`
+	Error          string          `json:"error,omitempty" bson:"error"`
+	CreatedAt      time.Time       `json:"created_at" bson:"created_at"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at" bson:"next_attempt_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty" bson:"delivered_at"`
+}
+
+// Position returns position of the delivery in the delivery log
+func (d *Delivery) Position() keyset.Position {
+	return keyset.Position{CreatedAt: d.CreatedAt, Id: d.Id}
+}
+
+// DeliveryList
+type DeliveryList struct {
+	Count int         `json:"count"`
+	Items []*Delivery `json:"items"`
+}
+
+// IsEvent checks that merchant can subscribe to the event
+func IsEvent(event string) bool {
+	for _, e := range Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// NewSecret generates random secret for signing of the deliveries
+func NewSecret() (string, error) {
+	b := make([]byte, secretLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return secretPrefix + hex.EncodeToString(b), nil
+}
+
+// Sign returns value of the signature header, it contains the timestamp and HMAC-SHA256
+// of the string "timestamp.body" calculated with the subscription secret
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	return fmt.Sprintf("t=%s,%s=%s", ts, signatureVersion, computeSignature(secret, ts, body))
+}
+
+// Verify checks the signature header value, it is used by the receivers and in tests
+func Verify(secret, header string, body []byte) bool {
+	var ts, sig string
+	for _, part := range strings.Split(header, ",") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "t":
+			ts = kv[1]
+		case signatureVersion:
+			sig = kv[1]
+		}
+	}
+	if ts == "" || sig == "" {
+		return false
+	}
+	return hmac.Equal([]byte(sig), []byte(computeSignature(secret, ts, body)))
+}
+
+func computeSignature(secret, ts string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}